@@ -0,0 +1,732 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AnnotationNode is the typed syntax tree of a single @api annotation.
+// Two forms are accepted:
+//
+//	@api.doc.param("id", "path", "string", required=true)
+//	@api.endpoint GET /users/{id} auth=required
+//
+// The parenthesised form takes a comma-separated argument list and may span
+// several comment lines; the legacy form takes whitespace-separated
+// arguments up to the end of the line.
+type AnnotationNode struct {
+	Name []string        `json:"name"`
+	Args []AnnotationArg `json:"args,omitempty"`
+	Call bool            `json:"call"`
+	Text string          `json:"text"`
+	Pos  token.Pos       `json:"-"`
+
+	lines []token.Pos // position of each line of Text
+}
+
+// AnnotationArg is a positional (Name == "") or named argument
+type AnnotationArg struct {
+	Name  string          `json:"name,omitempty"`
+	Value AnnotationValue `json:"value"`
+}
+
+// ValueKind classifies annotation argument values
+type ValueKind int
+
+const (
+	WordValue   ValueKind = iota // bare word such as GET, User, []User or 100/minute
+	StringValue                  // double- or back-quoted string
+	NumberValue                  // integer or floating point literal
+	BoolValue                    // true or false
+	JSONValue                    // JSON object or array literal
+)
+
+// String returns the name of the kind
+func (k ValueKind) String() string {
+	switch k {
+	case StringValue:
+		return "string"
+	case NumberValue:
+		return "number"
+	case BoolValue:
+		return "bool"
+	case JSONValue:
+		return "json"
+	default:
+		return "word"
+	}
+}
+
+// AnnotationValue is a single argument value. Raw holds the source text;
+// the typed fields are set according to Kind.
+type AnnotationValue struct {
+	Kind   ValueKind   `json:"kind"`
+	Raw    string      `json:"raw"`
+	Str    string      `json:"-"`
+	Number float64     `json:"-"`
+	Bool   bool        `json:"-"`
+	JSON   interface{} `json:"-"`
+	Offset int         `json:"-"`
+}
+
+// String returns the value as text: strings unquoted, all other kinds as
+// written in the source
+func (v AnnotationValue) String() string {
+	if v.Kind == StringValue || v.Kind == WordValue {
+		return v.Str
+	}
+	return v.Raw
+}
+
+// Interface returns the value as a Go value: the decoded JSON, a float64,
+// a bool or the text
+func (v AnnotationValue) Interface() interface{} {
+	switch v.Kind {
+	case JSONValue:
+		return v.JSON
+	case NumberValue:
+		return v.Number
+	case BoolValue:
+		return v.Bool
+	default:
+		return v.String()
+	}
+}
+
+// Key returns the dotted annotation name without the "@api." prefix
+func (n *AnnotationNode) Key() string {
+	return strings.Join(n.Name, ".")
+}
+
+// Positional returns the positional arguments in source order
+func (n *AnnotationNode) Positional() []AnnotationValue {
+	var values []AnnotationValue
+	for _, arg := range n.Args {
+		if arg.Name == "" {
+			values = append(values, arg.Value)
+		}
+	}
+	return values
+}
+
+// Named returns the value of the named argument name
+func (n *AnnotationNode) Named(name string) (AnnotationValue, bool) {
+	for _, arg := range n.Args {
+		if arg.Name == name {
+			return arg.Value, true
+		}
+	}
+	return AnnotationValue{}, false
+}
+
+// PosOf returns the source position of the byte at offset in Text
+func (n *AnnotationNode) PosOf(offset int) token.Pos {
+	return textPos(n.Pos, n.lines, n.Text, offset)
+}
+
+// textPos maps an offset into annotation text that was joined from several
+// comment lines back to a source position
+func textPos(pos token.Pos, lines []token.Pos, text string, offset int) token.Pos {
+	if pos == token.NoPos {
+		return token.NoPos
+	}
+	line, lineStart := 0, 0
+	for i := 0; i < offset && i < len(text); i++ {
+		if text[i] == '\n' {
+			line++
+			lineStart = i + 1
+		}
+	}
+	if line >= len(lines) {
+		return pos + token.Pos(offset)
+	}
+	return lines[line] + token.Pos(offset-lineStart)
+}
+
+// httpVerbs are the methods recognised by the legacy endpoint form
+var httpVerbs = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+// Compat returns the flat Annotation view of the node. Value is the first
+// positional argument, named arguments become Config entries (JSON literals
+// decoded, everything else as text) and further positional arguments are
+// listed under Config["args"]. The legacy "@api.endpoint GET /path" form
+// lifts the verb into Config["method"] so that Value is the path.
+func (n *AnnotationNode) Compat() Annotation {
+	annotation := Annotation{
+		Type: "api",
+		Key:  n.Key(),
+		Node: n,
+	}
+
+	verb, positional := n.arguments()
+	if verb != "" {
+		annotation.Config = map[string]interface{}{"method": verb}
+	}
+
+	if len(positional) > 0 {
+		annotation.Value = positional[0].String()
+	}
+	if len(positional) > 1 {
+		args := make([]string, len(positional))
+		for i, value := range positional {
+			args[i] = value.String()
+		}
+		if annotation.Config == nil {
+			annotation.Config = make(map[string]interface{})
+		}
+		annotation.Config["args"] = args
+	}
+
+	for _, arg := range n.Args {
+		if arg.Name == "" {
+			continue
+		}
+		if annotation.Config == nil {
+			annotation.Config = make(map[string]interface{})
+		}
+		if arg.Value.Kind == JSONValue {
+			annotation.Config[arg.Name] = arg.Value.JSON
+		} else {
+			annotation.Config[arg.Name] = arg.Value.String()
+		}
+	}
+
+	return annotation
+}
+
+// arguments returns the positional arguments with a leading legacy HTTP verb
+// split off, as in "@api.endpoint GET /users"
+func (n *AnnotationNode) arguments() (string, []AnnotationValue) {
+	positional := n.Positional()
+	if len(positional) >= 2 && positional[0].Kind == WordValue && httpVerbs[positional[0].Str] &&
+		strings.HasPrefix(positional[1].String(), "/") {
+		return positional[0].Str, positional[1:]
+	}
+	return "", positional
+}
+
+// AnnotationSyntaxError reports malformed annotation text. Offset is the
+// byte offset into the annotation text at which the problem was found.
+// Incomplete is set when the text ended inside a string, argument list or
+// JSON literal, which a continuation line may complete.
+type AnnotationSyntaxError struct {
+	Offset     int
+	Msg        string
+	Incomplete bool
+}
+
+func (e *AnnotationSyntaxError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Msg)
+}
+
+// Unwrap makes incomplete annotations match errIncompleteAnnotation
+func (e *AnnotationSyntaxError) Unwrap() error {
+	if e.Incomplete {
+		return errIncompleteAnnotation
+	}
+	return nil
+}
+
+// errIncompleteAnnotation matches syntax errors whose Incomplete flag is set
+var errIncompleteAnnotation = errors.New("unterminated annotation")
+
+// Annotation tokens
+type annotationTokenKind int
+
+const (
+	tokEOF annotationTokenKind = iota
+	tokWord
+	tokString
+	tokJSON
+	tokLParen
+	tokRParen
+	tokComma
+	tokEquals
+)
+
+type annotationToken struct {
+	kind   annotationTokenKind
+	text   string // source text
+	value  string // unquoted text of strings
+	offset int
+}
+
+// annotationLexer splits annotation text into tokens
+type annotationLexer struct {
+	src string
+	pos int
+}
+
+func isAnnotationSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isWordDelimiter reports whether c ends a bare word outside brackets
+func isWordDelimiter(c byte) bool {
+	return isAnnotationSpace(c) || strings.IndexByte(",()=\"`", c) >= 0
+}
+
+func (l *annotationLexer) next() (annotationToken, error) {
+	for l.pos < len(l.src) && isAnnotationSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return annotationToken{kind: tokEOF, offset: start}, nil
+	}
+
+	switch c := l.src[l.pos]; c {
+	case '(':
+		l.pos++
+		return annotationToken{kind: tokLParen, text: "(", offset: start}, nil
+	case ')':
+		l.pos++
+		return annotationToken{kind: tokRParen, text: ")", offset: start}, nil
+	case ',':
+		l.pos++
+		return annotationToken{kind: tokComma, text: ",", offset: start}, nil
+	case '=':
+		l.pos++
+		return annotationToken{kind: tokEquals, text: "=", offset: start}, nil
+	case '"', '`':
+		end, err := scanQuoted(l.src, start)
+		if err != nil {
+			return annotationToken{}, err
+		}
+		text := l.src[start:end]
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return annotationToken{}, &AnnotationSyntaxError{Offset: start, Msg: fmt.Sprintf("invalid string %s: %v", text, err)}
+		}
+		l.pos = end
+		return annotationToken{kind: tokString, text: text, value: value, offset: start}, nil
+	case '{', '[':
+		// A balanced literal followed by a delimiter is JSON; otherwise
+		// it starts a word such as "[]User"
+		end, err := scanBalanced(l.src, start)
+		if err != nil {
+			return annotationToken{}, err
+		}
+		if (end == len(l.src) || isWordDelimiter(l.src[end])) && json.Valid([]byte(l.src[start:end])) {
+			l.pos = end
+			return annotationToken{kind: tokJSON, text: l.src[start:end], offset: start}, nil
+		}
+		if c == '{' {
+			return annotationToken{}, invalidJSON(l.src[start:end], start)
+		}
+	}
+
+	end, err := scanWord(l.src, start)
+	if err != nil {
+		return annotationToken{}, err
+	}
+	l.pos = end
+	return annotationToken{kind: tokWord, text: l.src[start:end], value: l.src[start:end], offset: start}, nil
+}
+
+// invalidJSON reports the first error in the JSON literal text found at start
+func invalidJSON(text string, start int) error {
+	var value interface{}
+	err := json.Unmarshal([]byte(text), &value)
+	var jsonErr *json.SyntaxError
+	if errors.As(err, &jsonErr) && jsonErr.Offset > 0 {
+		return &AnnotationSyntaxError{Offset: start + int(jsonErr.Offset) - 1, Msg: fmt.Sprintf("invalid JSON literal: %v", err)}
+	}
+	return &AnnotationSyntaxError{Offset: start, Msg: "invalid JSON literal"}
+}
+
+// scanQuoted returns the end offset of the string literal starting at start
+func scanQuoted(src string, start int) (int, error) {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case '\n':
+			if quote == '"' {
+				return 0, &AnnotationSyntaxError{Offset: start, Msg: "string literal not terminated", Incomplete: true}
+			}
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, &AnnotationSyntaxError{Offset: start, Msg: "string literal not terminated", Incomplete: true}
+}
+
+// scanBalanced returns the end offset of the bracketed text starting at
+// start, skipping over brackets inside string literals
+func scanBalanced(src string, start int) (int, error) {
+	var stack []byte
+	for i := start; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			end, err := scanQuoted(src, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) == 0 || (c == '}') != (stack[len(stack)-1] == '{') {
+				return 0, &AnnotationSyntaxError{Offset: i, Msg: fmt.Sprintf("unexpected %q", c)}
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, &AnnotationSyntaxError{Offset: start, Msg: fmt.Sprintf("unclosed %q", src[start]), Incomplete: true}
+}
+
+// scanWord returns the end offset of the bare word starting at start.
+// Brackets and braces inside a word, as in "map[string]User" or
+// "/users/{id}", are kept together with it.
+func scanWord(src string, start int) (int, error) {
+	depth := 0
+	i := start
+	for ; i < len(src); i++ {
+		c := src[i]
+		if depth == 0 && isWordDelimiter(c) {
+			break
+		}
+		switch c {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			if depth == 0 {
+				return 0, &AnnotationSyntaxError{Offset: i, Msg: fmt.Sprintf("unexpected %q", c)}
+			}
+			depth--
+		case '\n':
+			return 0, &AnnotationSyntaxError{Offset: i, Msg: "unclosed bracket in word"}
+		}
+	}
+	if depth > 0 {
+		return 0, &AnnotationSyntaxError{Offset: start, Msg: "unclosed bracket in word", Incomplete: true}
+	}
+	return i, nil
+}
+
+// ParseAnnotation parses the text of one annotation, starting at "@api".
+// Errors are *AnnotationSyntaxError values; those caused by text ending too
+// early additionally match errIncompleteAnnotation with errors.Is.
+func ParseAnnotation(text string) (*AnnotationNode, error) {
+	if !strings.HasPrefix(text, "@api") {
+		return nil, &AnnotationSyntaxError{Offset: 0, Msg: "annotation must start with @api"}
+	}
+
+	node := &AnnotationNode{Text: text}
+
+	// Dotted name
+	pos := len("@api")
+	for pos < len(text) && text[pos] == '.' {
+		pos++
+		start := pos
+		for pos < len(text) && isIdentByte(text[pos], pos == start) {
+			pos++
+		}
+		if pos == start {
+			return nil, &AnnotationSyntaxError{Offset: start, Msg: "expected name after '.'"}
+		}
+		node.Name = append(node.Name, text[start:pos])
+	}
+	if len(node.Name) == 0 {
+		return nil, &AnnotationSyntaxError{Offset: pos, Msg: "expected '.' and a name after @api"}
+	}
+	if pos < len(text) && !isWordDelimiter(text[pos]) {
+		return nil, &AnnotationSyntaxError{Offset: pos, Msg: fmt.Sprintf("unexpected %q in annotation name", text[pos])}
+	}
+
+	lexer := &annotationLexer{src: text, pos: pos}
+	tok, err := lexer.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.kind == tokLParen && tok.offset == pos {
+		node.Call = true
+		if err := parseCallArgs(lexer, node); err != nil {
+			return nil, err
+		}
+		tok, err = lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != tokEOF {
+			return nil, &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("unexpected %q after argument list", tok.text)}
+		}
+		return node, nil
+	}
+
+	// Legacy form: arguments separated by whitespace or commas, ending at
+	// the end of the first line
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		lexer.src = text[:newline]
+	}
+	for tok.kind != tokEOF {
+		switch tok.kind {
+		case tokComma:
+		case tokLParen, tokRParen, tokEquals:
+			return nil, &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+		default:
+			arg, err := parseArg(lexer, tok)
+			if err != nil {
+				return nil, err
+			}
+			node.Args = append(node.Args, arg)
+		}
+		if tok, err = lexer.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// parseCallArgs parses "arg, arg, name=value)" after the opening parenthesis
+func parseCallArgs(lexer *annotationLexer, node *AnnotationNode) error {
+	for {
+		tok, err := lexer.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokRParen:
+			return nil
+		case tokEOF:
+			return &AnnotationSyntaxError{Offset: tok.offset, Msg: "missing ')'", Incomplete: true}
+		case tokComma, tokLParen, tokEquals:
+			return &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("expected argument, found %q", tok.text)}
+		}
+
+		arg, err := parseArg(lexer, tok)
+		if err != nil {
+			return err
+		}
+		node.Args = append(node.Args, arg)
+
+		tok, err = lexer.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokComma:
+		case tokRParen:
+			return nil
+		case tokEOF:
+			return &AnnotationSyntaxError{Offset: tok.offset, Msg: "missing ')'", Incomplete: true}
+		default:
+			return &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("expected ',' or ')', found %q", tok.text)}
+		}
+	}
+}
+
+// parseArg parses a value, or a "name=value" pair when tok is a word
+// followed by '='
+func parseArg(lexer *annotationLexer, tok annotationToken) (AnnotationArg, error) {
+	if tok.kind == tokWord {
+		saved := lexer.pos
+		next, err := lexer.next()
+		if err == nil && next.kind == tokEquals {
+			if !isIdent(tok.text) {
+				return AnnotationArg{}, &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("invalid argument name %q", tok.text)}
+			}
+			valueTok, err := lexer.next()
+			if err != nil {
+				return AnnotationArg{}, err
+			}
+			value, err := tokenValue(valueTok)
+			if err != nil {
+				return AnnotationArg{}, err
+			}
+			return AnnotationArg{Name: tok.text, Value: value}, nil
+		}
+		lexer.pos = saved
+	}
+
+	value, err := tokenValue(tok)
+	if err != nil {
+		return AnnotationArg{}, err
+	}
+	return AnnotationArg{Value: value}, nil
+}
+
+// tokenValue converts a value token into a typed value
+func tokenValue(tok annotationToken) (AnnotationValue, error) {
+	value := AnnotationValue{Raw: tok.text, Str: tok.value, Offset: tok.offset}
+
+	switch tok.kind {
+	case tokString:
+		value.Kind = StringValue
+	case tokJSON:
+		value.Kind = JSONValue
+		if err := json.Unmarshal([]byte(tok.text), &value.JSON); err != nil {
+			return value, &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("invalid JSON literal: %v", err)}
+		}
+	case tokWord:
+		if tok.text == "true" || tok.text == "false" {
+			value.Kind = BoolValue
+			value.Bool = tok.text == "true"
+		} else if number, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			value.Kind = NumberValue
+			value.Number = number
+		} else {
+			value.Kind = WordValue
+		}
+	case tokEOF:
+		return value, &AnnotationSyntaxError{Offset: tok.offset, Msg: "missing value", Incomplete: true}
+	default:
+		return value, &AnnotationSyntaxError{Offset: tok.offset, Msg: fmt.Sprintf("expected value, found %q", tok.text)}
+	}
+
+	return value, nil
+}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func isIdent(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isIdentByte(s[i], i == 0) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// commentLine is one line of comment text with the position of its first byte
+type commentLine struct {
+	text string
+	pos  token.Pos
+}
+
+// commentLines splits a comment group into trimmed text lines, removing
+// comment markers and the leading '*' decoration of block comment lines
+func commentLines(group *ast.CommentGroup) []commentLine {
+	var lines []commentLine
+	for _, comment := range group.List {
+		text := comment.Text[2:]
+		block := strings.HasPrefix(comment.Text, "/*")
+		if block {
+			text = strings.TrimSuffix(text, "*/")
+		}
+
+		offset := 2
+		for i, line := range strings.Split(text, "\n") {
+			trimmed := strings.TrimLeft(line, " \t")
+			if block && i > 0 && strings.HasPrefix(trimmed, "*") {
+				trimmed = strings.TrimLeft(trimmed[1:], " \t")
+			}
+			lines = append(lines, commentLine{
+				text: strings.TrimRight(trimmed, " \t\r"),
+				pos:  comment.Slash + token.Pos(offset+len(line)-len(trimmed)),
+			})
+			offset += len(line) + 1
+		}
+	}
+	return lines
+}
+
+// parseAnnotationGroup parses every annotation in a comment group. An
+// annotation whose brackets, quotes or argument list are still open at the
+// end of a line continues on the following lines. Malformed annotations are
+// returned as errors alongside the annotations that parsed. A line that
+// mentions an annotation in the middle of a sentence is prose, not an
+// annotation.
+func parseAnnotationGroup(group *ast.CommentGroup) ([]*AnnotationNode, []annotationError) {
+	if group == nil {
+		return nil, nil
+	}
+
+	var nodes []*AnnotationNode
+	var errs []annotationError
+	lines := commentLines(group)
+	lastAnnotation := -1 // index of the last line of the previous annotation
+
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i].text, "@api.") {
+			continue
+		}
+		if i > 0 && i-1 != lastAnnotation && continuesProse(lines[i-1].text, lines[i].text) {
+			continue
+		}
+
+		start := lines[i]
+		text := start.text
+		linePos := []token.Pos{start.pos}
+		node, err := ParseAnnotation(text)
+		for errors.Is(err, errIncompleteAnnotation) && i+1 < len(lines) &&
+			!strings.HasPrefix(lines[i+1].text, "@api.") {
+			i++
+			text += "\n" + lines[i].text
+			linePos = append(linePos, lines[i].pos)
+			node, err = ParseAnnotation(text)
+		}
+		lastAnnotation = i
+
+		if err != nil {
+			errs = append(errs, annotationError{pos: start.pos, lines: linePos, text: text, err: err})
+			continue
+		}
+		node.Pos = start.pos
+		node.lines = linePos
+		nodes = append(nodes, node)
+	}
+
+	return nodes, errs
+}
+
+// proseMention matches an annotation followed by an ordinary lower-case
+// word, as in "@api.field.default(value) gives it a default."
+var proseMention = regexp.MustCompile(`^@api\.[\w.]+(\([^()]*\))?\s+[a-z][a-z']*[,.;:]?(\s|$)`)
+
+// continuesProse reports whether line, which starts with "@api.", carries on
+// a sentence of the previous comment line: prev, which is not part of an
+// annotation, is unfinished prose and the annotation is followed by more
+// words
+func continuesProse(prev, line string) bool {
+	if prev == "" || strings.ContainsAny(prev[len(prev)-1:], ".:!?") {
+		return false
+	}
+	return proseMention.MatchString(line)
+}
+
+// annotationError is an annotation that failed to parse
+type annotationError struct {
+	pos   token.Pos
+	lines []token.Pos
+	text  string
+	err   error
+}
+
+// errorPos returns the position at which the syntax error was found
+func (e annotationError) errorPos() token.Pos {
+	var syntaxErr *AnnotationSyntaxError
+	if errors.As(e.err, &syntaxErr) {
+		return textPos(e.pos, e.lines, e.text, syntaxErr.Offset)
+	}
+	return e.pos
+}
+
+// message describes the error without the offset, which errorPos reports
+func (e annotationError) message() string {
+	var syntaxErr *AnnotationSyntaxError
+	if errors.As(e.err, &syntaxErr) {
+		return syntaxErr.Msg
+	}
+	return e.err.Error()
+}
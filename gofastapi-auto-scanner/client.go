@@ -0,0 +1,548 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// clientIdentifiers are declared by the generated client and cannot be
+// used for models
+var clientIdentifiers = []string{
+	"Client", "New", "Option", "Authenticator", "AuthenticatorFunc", "HTTPDoer",
+	"WithHTTPClient", "WithBearerToken", "WithAPIKey", "WithAuthenticator", "WithUserAgent",
+	"APIError", "errorDecoder",
+}
+
+// routePrefix returns the path a generator mounts the API routes under
+func routePrefix(config *FrameworkConfig) string {
+	if config.Type == FrameworkGin {
+		return "/api/v1"
+	}
+	return ""
+}
+
+// GenerateClient returns a Go client package for routes with a method per
+// route. Request and response types are declared in the client from the
+// scanned structs, so it has no dependency on the scanned packages.
+func GenerateClient(routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) (string, error) {
+	g := &clientGenerator{
+		types:   newOpenAPIBuilder(packages),
+		prefix:  routePrefix(config),
+		errors:  make(map[string]string),
+		imports: map[string]bool{"bytes": true, "context": true, "encoding/json": true, "fmt": true, "io": true, "net/http": true, "net/url": true, "strings": true},
+		names:   make(map[string]bool),
+		title:   strings.Title(string(config.Type)),
+	}
+	for _, name := range clientIdentifiers {
+		g.types.taken[name] = true
+	}
+
+	var methods strings.Builder
+	for _, route := range stdlibRoutes(routes) {
+		methods.WriteString(g.method(route))
+	}
+
+	var src strings.Builder
+	src.WriteString(fmt.Sprintf("// Package client is a typed client for the generated %s API. It is\n", g.title))
+	src.WriteString("// generated together with the server; regenerate both after changing routes.\n")
+	src.WriteString("package client\n\n")
+	body := g.runtime() + methods.String() + g.models() + g.errorTypes()
+	if strings.Contains(body, "time.") {
+		g.imports["time"] = true
+	}
+	src.WriteString(renderImportBlock(g.imports, nil))
+	src.WriteString(body)
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format client: %v", err)
+	}
+	return string(formatted), nil
+}
+
+// writeClient adds the client package in outputDir/client to out
+func writeClient(out *OutputSet, outputDir string, routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) error {
+	content, err := GenerateClient(routes, packages, config)
+	if err != nil {
+		return err
+	}
+	out.Add(filepath.Join(outputDir, "client", "client.go"), content)
+	return nil
+}
+
+// clientGenerator collects the methods, models and error types of a client
+type clientGenerator struct {
+	types   *openAPIBuilder   // resolves and names the scanned structs
+	prefix  string            // path the server mounts routes under
+	errors  map[string]string // error body type -> error type name
+	imports map[string]bool
+	names   map[string]bool // method names in use
+	title   string
+}
+
+// clientParam is a parameter of a client method
+type clientParam struct {
+	name string // Go parameter name
+	key  string // path wildcard or query key
+	typ  string
+}
+
+// method renders the client method of one route
+func (g *clientGenerator) method(route APIRoute) string {
+	name := route.Function
+	if !isExportedName(name) {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	for base, n := name, 2; g.names[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	g.names[name] = true
+
+	path := templatePath(route.Path)
+	bound := make(map[string]BoundParam)
+	var bodyType string
+	var query []clientParam
+	if route.Binding != nil {
+		for _, param := range route.Binding.Params {
+			switch param.Source {
+			case SourcePath:
+				bound[param.Key] = param
+			case SourceQuery:
+				query = append(query, clientParam{name: clientParamName(param.Key), key: param.Key, typ: g.goType(param.Type, route.ImportPath)})
+			case SourceBody:
+				bodyType = g.goType(param.Type, route.ImportPath)
+			}
+		}
+	}
+	if bodyType == "" && route.RequestType != "" {
+		bodyType = g.goType(route.RequestType, route.ImportPath)
+	}
+
+	params := []string{"ctx context.Context"}
+	var pathParams []clientParam
+	for _, wildcard := range pathWildcards(path) {
+		param := clientParam{name: clientParamName(wildcard), key: wildcard, typ: "string"}
+		if binding, ok := bound[wildcard]; ok {
+			param.typ = g.goType(binding.Type, route.ImportPath)
+		}
+		pathParams = append(pathParams, param)
+		params = append(params, param.name+" "+param.typ)
+	}
+	for _, param := range query {
+		params = append(params, param.name+" "+param.typ)
+	}
+	if bodyType != "" {
+		params = append(params, "body "+bodyType)
+	}
+
+	success := route.SuccessResponse()
+	resultType := ""
+	if success.Type != "" && success.Status != http.StatusNoContent {
+		resultType = g.goType(success.Type, route.ImportPath)
+		if g.isModel(resultType) {
+			// Structs are returned by pointer
+			resultType = "*" + resultType
+		}
+	}
+
+	var m strings.Builder
+	summary := route.Docs.Summary
+	if summary == "" {
+		summary = "calls " + strings.ToUpper(route.Method) + " " + g.prefix + path
+	} else {
+		summary = fmt.Sprintf("calls %s %s: %s", strings.ToUpper(route.Method), g.prefix+path, summary)
+	}
+	m.WriteString(fmt.Sprintf("// %s %s\n", name, summary))
+	if resultType != "" {
+		m.WriteString(fmt.Sprintf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(params, ", "), resultType))
+	} else {
+		m.WriteString(fmt.Sprintf("func (c *Client) %s(%s) error {\n", name, strings.Join(params, ", ")))
+	}
+
+	// Path
+	pathExpr := fmt.Sprintf("%q", g.prefix+path)
+	if len(pathParams) > 0 {
+		format := g.prefix + path
+		var args []string
+		for _, param := range pathParams {
+			format = strings.Replace(format, "{"+param.key+"}", "%s", 1)
+			args = append(args, fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", param.name))
+		}
+		pathExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(args, ", "))
+	}
+	m.WriteString(fmt.Sprintf("\tpath := %s\n", pathExpr))
+
+	// Query
+	queryExpr := "nil"
+	if len(query) > 0 {
+		queryExpr = "query"
+		m.WriteString("\tquery := url.Values{}\n")
+		for _, param := range query {
+			if param.typ == "bool" {
+				m.WriteString(fmt.Sprintf("\tif %s {\n", param.name))
+			} else {
+				m.WriteString(fmt.Sprintf("\tif %s != %s {\n", param.name, zeroValue(param.typ)))
+			}
+			m.WriteString(fmt.Sprintf("\t\tquery.Set(%q, fmt.Sprint(%s))\n\t}\n", param.key, param.name))
+		}
+	}
+
+	bodyExpr := "nil"
+	if bodyType != "" {
+		bodyExpr = "body"
+	}
+	errorsExpr := g.errorDecoders(route)
+
+	method := "http.Method" + strings.Title(strings.ToLower(route.Method))
+	if resultType != "" {
+		m.WriteString(fmt.Sprintf("\tvar result %s\n", strings.TrimPrefix(resultType, "*")))
+		m.WriteString(fmt.Sprintf("\tif err := c.do(ctx, %s, path, %s, %s, &result, %s); err != nil {\n", method, queryExpr, bodyExpr, errorsExpr))
+		m.WriteString(fmt.Sprintf("\t\treturn %s, err\n\t}\n", zeroValue(resultType)))
+		if strings.HasPrefix(resultType, "*") {
+			m.WriteString("\treturn &result, nil\n}\n\n")
+		} else {
+			m.WriteString("\treturn result, nil\n}\n\n")
+		}
+	} else {
+		m.WriteString(fmt.Sprintf("\treturn c.do(ctx, %s, path, %s, %s, nil, %s)\n}\n\n", method, queryExpr, bodyExpr, errorsExpr))
+	}
+	return m.String()
+}
+
+// errorDecoders returns the expression mapping the declared error
+// statuses of route to decoders of their typed errors
+func (g *clientGenerator) errorDecoders(route APIRoute) string {
+	var entries []string
+	for _, response := range route.Responses {
+		if response.Status < 400 || response.Type == "" {
+			continue
+		}
+		bodyType := strings.TrimPrefix(g.goType(response.Type, route.ImportPath), "*")
+		if bodyType == "interface{}" {
+			continue
+		}
+		errorType, ok := g.errors[bodyType]
+		if !ok {
+			errorType = strings.NewReplacer("[]", "List", "map[", "Map", "]", "", ".", "", "*", "").Replace(bodyType)
+			if !strings.HasSuffix(errorType, "Error") {
+				errorType += "Error"
+			} else {
+				errorType += "Response"
+			}
+			g.errors[bodyType] = errorType
+		}
+		entries = append(entries, fmt.Sprintf("%d: decode%s", response.Status, errorType))
+	}
+	if len(entries) == 0 {
+		return "nil"
+	}
+	return "map[int]errorDecoder{" + strings.Join(entries, ", ") + "}"
+}
+
+// goType returns the client spelling of a scanned type. Structs become
+// client models; types the client cannot declare fall back to interface{}.
+func (g *clientGenerator) goType(typ, importPath string) string {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return "*" + g.goType(typ[1:], importPath)
+	case strings.HasPrefix(typ, "["):
+		end := strings.Index(typ, "]")
+		if end < 0 {
+			return "interface{}"
+		}
+		return "[]" + g.goType(typ[end+1:], importPath)
+	case strings.HasPrefix(typ, "map["):
+		end := matchingBracket(typ, len("map"))
+		if end < 0 {
+			return "interface{}"
+		}
+		return "map[" + g.goType(typ[len("map["):end], importPath) + "]" + g.goType(typ[end+1:], importPath)
+	case typ == "any" || typ == "interface{}":
+		return "interface{}"
+	case typ == "json.RawMessage" || typ == "encoding/json.RawMessage":
+		return "json.RawMessage"
+	case modelTypeNames[typ] && typ != "map" && typ != "interface" && typ != "error":
+		return typ
+	}
+	if key := g.types.resolve(typ, importPath); key != "" {
+		return g.types.component(key)
+	}
+	return "interface{}"
+}
+
+// isModel reports whether typ is a client model
+func (g *clientGenerator) isModel(typ string) bool {
+	for _, name := range g.types.names {
+		if name == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// models renders a struct for every scanned struct the methods refer to,
+// including the structs their fields refer to
+func (g *clientGenerator) models() string {
+	rendered := make(map[string]bool)
+	var out strings.Builder
+	for {
+		var keys []string
+		for key := range g.types.names {
+			if !rendered[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return out.String()
+		}
+		sort.Slice(keys, func(i, j int) bool { return g.types.names[keys[i]] < g.types.names[keys[j]] })
+		for _, key := range keys {
+			rendered[key] = true
+			out.WriteString(g.model(key))
+		}
+	}
+}
+
+// model renders the client struct of one scanned struct
+func (g *clientGenerator) model(key string) string {
+	ref := g.types.structs[key]
+	name := g.types.names[key]
+	var out strings.Builder
+	if doc := docText(ref.info.Doc); doc != "" {
+		out.WriteString(goComment(doc, ""))
+	} else {
+		out.WriteString(fmt.Sprintf("// %s mirrors %s of package %s\n", name, ref.info.Name, ref.pkg.Name))
+	}
+	out.WriteString(fmt.Sprintf("type %s struct {\n", name))
+	for _, field := range ref.info.Fields {
+		typ := field.Type
+		if field.QualifiedType != "" {
+			typ = field.QualifiedType
+		}
+		if promotedField(field) {
+			if embedded := g.goType(typ, ref.pkg.ImportPath); g.isModel(strings.TrimPrefix(embedded, "*")) {
+				out.WriteString(fmt.Sprintf("\t%s\n", embedded))
+				continue
+			}
+		}
+		jsonName, omitEmpty, skip := fieldJSON(field)
+		if skip {
+			continue
+		}
+		tag := jsonName
+		if omitEmpty {
+			tag += ",omitempty"
+		}
+		out.WriteString(fmt.Sprintf("\t%s %s `json:%q`\n", field.Name, g.goType(typ, ref.pkg.ImportPath), tag))
+	}
+	out.WriteString("}\n\n")
+	return out.String()
+}
+
+// errorTypes renders a typed error for every error body a route declares
+func (g *clientGenerator) errorTypes() string {
+	var bodyTypes []string
+	for bodyType := range g.errors {
+		bodyTypes = append(bodyTypes, bodyType)
+	}
+	sort.Strings(bodyTypes)
+
+	var out strings.Builder
+	for _, bodyType := range bodyTypes {
+		errorType := g.errors[bodyType]
+		out.WriteString(fmt.Sprintf(`// %s is returned for error responses with a %s body
+type %s struct {
+	StatusCode int
+	Body       %s
+}
+
+func (e *%s) Error() string {
+	return fmt.Sprintf("%%d %%s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func decode%s(status int, body []byte) error {
+	e := &%s{StatusCode: status}
+	if err := json.Unmarshal(body, &e.Body); err != nil {
+		return newAPIError(status, body)
+	}
+	return e
+}
+
+`, errorType, bodyType, errorType, bodyType, errorType, errorType, errorType))
+	}
+	return out.String()
+}
+
+// runtime renders the client type, its options and the request helper
+func (g *clientGenerator) runtime() string {
+	return fmt.Sprintf(`// HTTPDoer sends HTTP requests. *http.Client implements it.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Authenticator adds credentials to an outgoing request
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to Authenticator
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate calls f(req)
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error { return f(req) }
+
+// Client calls the %s API
+type Client struct {
+	baseURL        string
+	httpClient     HTTPDoer
+	authenticators []Authenticator
+	userAgent      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with doer instead of http.DefaultClient
+func WithHTTPClient(doer HTTPDoer) Option {
+	return func(c *Client) { c.httpClient = doer }
+}
+
+// WithBearerToken authenticates requests with a bearer token
+func WithBearerToken(token string) Option {
+	return WithAuthenticator(AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}))
+}
+
+// WithAPIKey authenticates requests with an API key sent in header
+func WithAPIKey(header, key string) Option {
+	return WithAuthenticator(AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	}))
+}
+
+// WithAuthenticator adds an authentication hook run before every request
+func WithAuthenticator(auth Authenticator) Option {
+	return func(c *Client) { c.authenticators = append(c.authenticators, auth) }
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New creates a client for the server at baseURL, such as
+// "http://localhost:8080"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// APIError is returned for error responses without a declared body type
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	message := strings.TrimSpace(string(e.Body))
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+	if message == "" {
+		return fmt.Sprintf("%%d %%s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%%d %%s: %%s", e.StatusCode, http.StatusText(e.StatusCode), message)
+}
+
+func newAPIError(status int, body []byte) error {
+	return &APIError{StatusCode: status, Body: body}
+}
+
+// errorDecoder turns an error response into a typed error
+type errorDecoder func(status int, body []byte) error
+
+// do sends a request with an optional JSON body and decodes a JSON
+// response into result. Error responses are decoded by the decoder
+// registered for their status, or returned as *APIError.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}, decoders map[int]errorDecoder) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request body: %%w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for _, auth := range c.authenticators {
+		if err := auth.Authenticate(req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if decode, ok := decoders[resp.StatusCode]; ok {
+			return decode(resp.StatusCode, data)
+		}
+		return newAPIError(resp.StatusCode, data)
+	}
+	if result == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("decoding %%s %%s response: %%w", method, path, err)
+	}
+	return nil
+}
+
+`, g.title)
+}
+
+// clientParamName returns a Go parameter name for a path or query key
+func clientParamName(key string) string {
+	name := goParamName(key)
+	switch name {
+	case "body", "path", "query", "result", "c", "err":
+		return name + "Param"
+	}
+	return name
+}
@@ -0,0 +1,1260 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepositoryBinding describes the generated repository method that serves
+// a route. Handlers call it when no service method is bound to the route.
+type RepositoryBinding struct {
+	Model     string   `json:"model"`
+	Operation string   `json:"operation"`
+	Key       string   `json:"key,omitempty"`       // path wildcard holding the primary key
+	KeyField  string   `json:"key_field,omitempty"` // model field of the primary key
+	KeyType   string   `json:"key_type,omitempty"`
+	Filters   []string `json:"filters,omitempty"` // query parameters a list filters on by equality
+}
+
+// repositoryOperations are the route operations a generated repository
+// can serve
+var repositoryOperations = map[string]bool{
+	"list": true, "get": true, "create": true, "update": true, "delete": true,
+	"search": true, "bulk_create": true,
+}
+
+// setupDatabaseCall is the statement setupRoutes uses to migrate the
+// database
+const setupDatabaseCall = "	// Tables of the generated repositories\n	s.setupDatabase()\n\n"
+
+// databaseEnabled reports whether a generated server stores its models
+// through generated repositories
+func databaseEnabled(config *FrameworkConfig) bool {
+	return config.Database != nil && config.Database.Type != ""
+}
+
+// sqlDialect describes the SQL of a supported database and the
+// database/sql driver the generated server uses for it
+type sqlDialect struct {
+	Name     string
+	Driver   string
+	Module   string
+	Version  string
+	Like     string            // case-insensitive pattern match operator
+	NoLimit  string            // LIMIT argument that returns every row
+	Numbered bool              // $1 placeholders instead of ?
+	Types    map[string]string // Go type -> column type
+}
+
+var sqliteDialect = &sqlDialect{
+	Name: "sqlite", Driver: "sqlite3", Module: "github.com/mattn/go-sqlite3", Version: "v1.14.22",
+	Like: "LIKE", NoLimit: "-1",
+	Types: map[string]string{
+		"string": "TEXT", "bool": "BOOLEAN", "[]byte": "BLOB", "time.Time": "TIMESTAMP",
+		"int": "INTEGER", "int8": "INTEGER", "int16": "INTEGER", "int32": "INTEGER", "int64": "INTEGER",
+		"uint": "INTEGER", "uint8": "INTEGER", "uint16": "INTEGER", "uint32": "INTEGER", "uint64": "INTEGER",
+		"float32": "REAL", "float64": "REAL",
+	},
+}
+
+var postgresDialect = &sqlDialect{
+	Name: "postgres", Driver: "postgres", Module: "github.com/lib/pq", Version: "v1.10.9",
+	Like: "ILIKE", NoLimit: "nil", Numbered: true,
+	Types: map[string]string{
+		"string": "TEXT", "bool": "BOOLEAN", "[]byte": "BYTEA", "time.Time": "TIMESTAMPTZ",
+		"int": "BIGINT", "int8": "SMALLINT", "int16": "SMALLINT", "int32": "INTEGER", "int64": "BIGINT",
+		"uint": "BIGINT", "uint8": "SMALLINT", "uint16": "INTEGER", "uint32": "BIGINT", "uint64": "BIGINT",
+		"float32": "REAL", "float64": "DOUBLE PRECISION",
+	},
+}
+
+// sqlDialects maps the accepted DatabaseConfig types to their dialect
+var sqlDialects = map[string]*sqlDialect{
+	"sqlite": sqliteDialect, "sqlite3": sqliteDialect,
+	"postgres": postgresDialect, "postgresql": postgresDialect,
+	"memory": memoryDialect,
+}
+
+// databaseDialect returns the dialect of a database config
+func databaseDialect(config *DatabaseConfig) (*sqlDialect, error) {
+	dialect, ok := sqlDialects[strings.ToLower(config.Type)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type %q: expected sqlite, postgres or memory", config.Type)
+	}
+	return dialect, nil
+}
+
+// placeholder returns the bind parameter of the n-th argument, counting
+// from 1
+func (d *sqlDialect) placeholder(n int) string {
+	if d.Numbered {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// columnType returns the column type of a Go type, or "" when the type
+// cannot be stored in a single column. Pointers make the column nullable.
+func (d *sqlDialect) columnType(goType string) string {
+	base := strings.TrimPrefix(goType, "*")
+	if base == "[]uint8" {
+		base = "[]byte"
+	}
+	if strings.HasPrefix(base, "*") {
+		return ""
+	}
+	return d.Types[base]
+}
+
+// autoKey returns the definition of an integer primary key column whose
+// values the database assigns
+func (d *sqlDialect) autoKey(sqlType string) string {
+	switch {
+	case d == sqliteDialect:
+		return "INTEGER PRIMARY KEY"
+	case sqlType == "SMALLINT":
+		return "SMALLSERIAL PRIMARY KEY"
+	case sqlType == "INTEGER":
+		return "SERIAL PRIMARY KEY"
+	default:
+		return "BIGSERIAL PRIMARY KEY"
+	}
+}
+
+// databaseURL returns the data source name a generated server connects to
+// when DATABASE_URL is unset: a file name for SQLite and a connection URL
+// for PostgreSQL
+func databaseURL(config *DatabaseConfig) string {
+	if dialect, err := databaseDialect(config); err == nil && dialect == sqliteDialect {
+		if config.Name == "" {
+			return "app.db"
+		}
+		return config.Name
+	}
+
+	host, port, name := config.Host, config.Port, config.Name
+	if host == "" {
+		host = "localhost"
+	}
+	if port == 0 {
+		port = 5432
+	}
+	if name == "" {
+		name = "app"
+	}
+	u := url.URL{Scheme: "postgres", Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/" + name}
+	switch {
+	case config.User != "" && config.Password != "":
+		u.User = url.UserPassword(config.User, config.Password)
+	case config.User != "":
+		u.User = url.User(config.User)
+	}
+	u.RawQuery = "sslmode=disable"
+	if config.SSL {
+		u.RawQuery = "sslmode=require"
+	}
+	return u.String()
+}
+
+// repositoryModel is a scanned struct stored in a table
+type repositoryModel struct {
+	Name    string // model type in the generated package
+	Type    string // repository type
+	Table   string
+	Columns []repositoryColumn // in field order, including the key
+	Key     repositoryColumn
+	AutoKey bool // the database assigns integer keys
+}
+
+// repositoryColumn is a model field stored in a column
+type repositoryColumn struct {
+	Field   string
+	Column  string
+	JSON    string // JSON name, "" for fields hidden from JSON
+	Type    string // Go type
+	SQL     string // column type
+	Unique  bool   // @api.field.unique
+	Index   bool   // @api.field.index
+	Default string // SQL literal of @api.field.default
+}
+
+// nullable reports whether the column accepts NULL
+func (c repositoryColumn) nullable() bool {
+	return strings.HasPrefix(c.Type, "*")
+}
+
+// text reports whether the column holds a string
+func (c repositoryColumn) text() bool {
+	return strings.TrimPrefix(c.Type, "*") == "string"
+}
+
+// stamp reports whether the repository maintains the column as a creation
+// or update timestamp
+func (c repositoryColumn) stamp() bool {
+	return c.Type == "time.Time" && (c.Field == "CreatedAt" || c.Field == "UpdatedAt")
+}
+
+// searchColumns are the text columns Search matches against
+func (m *repositoryModel) searchColumns() []repositoryColumn {
+	var columns []repositoryColumn
+	for _, column := range m.Columns {
+		if column.text() && column.Field != m.Key.Field {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// repositoryPlan lists the models that get a repository and the structs
+// whose routes each repository serves
+type repositoryPlan struct {
+	dialect *sqlDialect
+	models  []*repositoryModel
+	owners  map[string]*repositoryModel // keyed by importPath + "." + struct
+}
+
+// newRepositoryPlan finds the database models among the scanned structs.
+// A struct is a model when it carries @api.db.table, when a field holding
+// it does, as "users []User" in a service, or when any of its fields has a
+// db tag; for the in-memory store, every struct with a primary key is one.
+// Structs only used embedded in others need their own @api.db.table.
+// The table defaults to the plural snake case name and the primary key is
+// the column named by @api.db.primary_key, the field marked @api.field.id
+// or the field named ID.
+func newRepositoryPlan(packages map[string]*PackageInfo, config *DatabaseConfig) (*repositoryPlan, error) {
+	dialect, err := databaseDialect(config)
+	if err != nil {
+		return nil, err
+	}
+	plan := &repositoryPlan{dialect: dialect, owners: make(map[string]*repositoryModel)}
+
+	paths := make([]string, 0, len(packages))
+	for path := range packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// Tables declared on a field belong to the field's element type
+	type fieldTable struct{ table, key, owner string }
+	fieldTables := make(map[string]fieldTable)
+	embedded := make(map[string]bool)
+	taken := make(map[string]bool)
+	for name := range stdlibModelNames {
+		taken[name] = true
+	}
+	for _, path := range paths {
+		for _, structInfo := range packages[path].Structs {
+			taken[structInfo.Name] = true
+			for _, field := range structInfo.Fields {
+				elementPath, element := fieldElementType(field, path)
+				if element == "" {
+					continue
+				}
+				if field.Embedded {
+					embedded[elementPath+"."+element] = true
+				}
+				table := annotationValue(field.Annotations, "db.table")
+				if _, seen := fieldTables[elementPath+"."+element]; table != "" && !seen {
+					fieldTables[elementPath+"."+element] = fieldTable{
+						table: table,
+						key:   annotationValue(field.Annotations, "db.primary_key"),
+						owner: path + "." + structInfo.Name,
+					}
+				}
+			}
+		}
+	}
+
+	declared := make(map[string]bool)
+	inflector := NewInflector(nil)
+	for _, path := range paths {
+		for _, structInfo := range packages[path].Structs {
+			if declared[structInfo.Name] || stdlibModelNames[structInfo.Name] {
+				continue
+			}
+			declared[structInfo.Name] = true
+
+			id := path + "." + structInfo.Name
+			table := annotationValue(structInfo.Annotations, "db.table")
+			key := annotationValue(structInfo.Annotations, "db.primary_key")
+			ref, onField := fieldTables[id]
+			if table == "" && onField {
+				table = ref.table
+				if key == "" {
+					key = ref.key
+				}
+			}
+			// The in-memory store keeps every struct with a key
+			explicit := table != ""
+			if !explicit && (embedded[id] || dialect != memoryDialect && !hasDBTags(structInfo)) {
+				continue
+			}
+			if table == "" {
+				words := inflector.Words(structInfo.Name)
+				words[len(words)-1] = inflector.Pluralize(words[len(words)-1])
+				table = strings.Join(words, "_")
+			}
+
+			model, err := newRepositoryModel(dialect, packages[path], structInfo, table, key)
+			if err != nil {
+				if explicit {
+					return nil, fmt.Errorf("model %s: %v", structInfo.Name, err)
+				}
+				continue
+			}
+			model.Type = structInfo.Name + "Repository"
+			for n := 2; taken[model.Type]; n++ {
+				model.Type = fmt.Sprintf("%sRepository%d", structInfo.Name, n)
+			}
+			taken[model.Type] = true
+
+			plan.models = append(plan.models, model)
+			plan.owners[id] = model
+			if onField {
+				if _, owned := plan.owners[ref.owner]; !owned {
+					plan.owners[ref.owner] = model
+				}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// newRepositoryModel derives the columns and key of a model. Fields of
+// embedded structs from the same package are stored in the model's table;
+// fields without a column type, unexported fields and those tagged db:"-"
+// are not stored. @api.field.string(n) stores a string in a VARCHAR(n)
+// column, @api.field.unique and @api.field.index index the column and
+// @api.field.default(value) gives it a default.
+func newRepositoryModel(dialect *sqlDialect, pkg *PackageInfo, structInfo StructInfo, table, key string) (*repositoryModel, error) {
+	model := &repositoryModel{Name: structInfo.Name, Table: table}
+
+	seen := make(map[string]bool)
+	for _, field := range structInfo.Fields {
+		if !field.Embedded {
+			seen[field.Name] = true
+		}
+	}
+	var marked string
+	var collect func(fields []FieldInfo, depth int)
+	collect = func(fields []FieldInfo, depth int) {
+		for _, field := range fields {
+			column := dbTagName(field)
+			if column == "-" {
+				continue
+			}
+			if field.Embedded {
+				for _, inner := range pkg.Structs {
+					if inner.Name == field.Type && depth < 8 {
+						collect(inner.Fields, depth+1)
+					}
+				}
+				continue
+			}
+			if !isExportedName(field.Name) || depth > 0 && seen[field.Name] {
+				continue
+			}
+			sqlType := dialect.columnType(field.Type)
+			if sqlType == "" {
+				continue
+			}
+			seen[field.Name] = true
+			if column == "" {
+				column = strings.Join(NewInflector(nil).Words(field.Name), "_")
+			}
+			if marked == "" && hasAnnotation(field.Annotations, "field.id") {
+				marked = field.Name
+			}
+			jsonName := fieldJSONName(field)
+			for _, tag := range field.Tags {
+				if tag.Key == "json" && tag.Value == "-" {
+					jsonName = ""
+				}
+			}
+			stored := repositoryColumn{
+				Field: field.Name, Column: column, JSON: jsonName, Type: field.Type, SQL: sqlType,
+				Unique: hasAnnotation(field.Annotations, "field.unique"),
+				Index:  hasAnnotation(field.Annotations, "field.index"),
+			}
+			if size, err := strconv.Atoi(annotationValue(field.Annotations, "field.string")); err == nil && size > 0 && stored.text() {
+				stored.SQL = fmt.Sprintf("VARCHAR(%d)", size)
+			}
+			for _, annotation := range field.Annotations {
+				if annotation.Key == "field.default" {
+					stored.Default = sqlDefault(annotation, stored.SQL)
+					break
+				}
+			}
+			model.Columns = append(model.Columns, stored)
+		}
+	}
+	collect(structInfo.Fields, 0)
+	if len(model.Columns) == 0 {
+		return nil, fmt.Errorf("no field can be stored in a column")
+	}
+
+	found := false
+	for _, column := range model.Columns {
+		switch {
+		case key != "" && (column.Column == key || column.Field == key),
+			key == "" && marked != "" && column.Field == marked,
+			key == "" && marked == "" && column.Field == "ID":
+			model.Key, found = column, true
+		}
+		if found {
+			break
+		}
+	}
+	switch {
+	case !found && key != "":
+		return nil, fmt.Errorf("primary key %q is not a column of table %s", key, table)
+	case !found:
+		return nil, fmt.Errorf("no primary key: add @api.db.primary_key or an ID field")
+	case model.Key.nullable():
+		return nil, fmt.Errorf("primary key %s cannot be a pointer", model.Key.Field)
+	}
+	if _, integer := scalarKinds[model.Key.Type]; integer && strings.Contains(model.Key.Type, "int") {
+		model.AutoKey = true
+	} else if model.Key.Type != "string" {
+		return nil, fmt.Errorf("primary key %s must be a string or an integer, not %s", model.Key.Field, model.Key.Type)
+	}
+	return model, nil
+}
+
+// bind returns a copy of routes in which every unbound route the plan's
+// repositories can serve carries a RepositoryBinding
+func (plan *repositoryPlan) bind(routes []APIRoute) []APIRoute {
+	bound := make([]APIRoute, len(routes))
+	copy(bound, routes)
+	for i, route := range bound {
+		operation, _ := route.Metadata["operation"].(string)
+		model := plan.owners[route.ImportPath+"."+route.Struct]
+		if model == nil || !repositoryOperations[operation] {
+			continue
+		}
+		binding := &RepositoryBinding{Model: model.Name, Operation: operation}
+		switch operation {
+		case "get", "update", "delete":
+			wildcards := pathPlaceholders(route.Path)
+			if len(wildcards) == 0 {
+				continue
+			}
+			binding.Key = wildcards[len(wildcards)-1]
+			binding.KeyField = model.Key.Field
+			binding.KeyType = model.Key.Type
+		case "list":
+			if plan.dialect == memoryDialect {
+				for _, column := range model.filterColumns() {
+					binding.Filters = append(binding.Filters, column.JSON)
+				}
+			}
+		case "search":
+			if len(model.searchColumns()) == 0 {
+				continue
+			}
+		}
+		bound[i].Repository = binding
+	}
+	return bound
+}
+
+// servedByRepository reports whether the generated handler of a route calls
+// its repository, which it does unless a wired service method is bound
+func servedByRepository(route APIRoute, config *FrameworkConfig) bool {
+	wired := config.Wiring != nil && config.Wiring.Enabled
+	return route.Repository != nil && (route.Binding == nil || !wired)
+}
+
+// bindRepositories binds the routes that the repositories generated for
+// config serve
+func bindRepositories(routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) ([]APIRoute, error) {
+	plan, err := newRepositoryPlan(packages, config.Database)
+	if err != nil {
+		return nil, err
+	}
+	return plan.bind(routes), nil
+}
+
+// fieldElementType returns the import path and name of the struct a field
+// holds directly, through a pointer, or as the elements of a slice or map
+func fieldElementType(field FieldInfo, importPath string) (string, string) {
+	spelled := field.QualifiedType
+	if spelled == "" {
+		spelled = field.Type
+	}
+	for {
+		switch {
+		case strings.HasPrefix(spelled, "[]"):
+			spelled = spelled[2:]
+		case strings.HasPrefix(spelled, "*"):
+			spelled = spelled[1:]
+		case strings.HasPrefix(spelled, "map[") && strings.Contains(spelled, "]"):
+			spelled = spelled[strings.Index(spelled, "]")+1:]
+		default:
+			if i := strings.LastIndex(spelled, "."); i >= 0 {
+				if field.QualifiedType == "" {
+					return "", ""
+				}
+				return spelled[:i], spelled[i+1:]
+			}
+			if !isIdent(spelled) {
+				return "", ""
+			}
+			return importPath, spelled
+		}
+	}
+}
+
+// dbTagName returns the column name in a field's db tag, "-" for fields
+// that are not stored, or "" when the tag does not name one
+func dbTagName(field FieldInfo) string {
+	for _, tag := range field.Tags {
+		if tag.Key == "db" {
+			return strings.Split(tag.Value, ",")[0]
+		}
+	}
+	return ""
+}
+
+// hasDBTags reports whether any field of a struct has a db tag
+func hasDBTags(structInfo StructInfo) bool {
+	for _, field := range structInfo.Fields {
+		for _, tag := range field.Tags {
+			if tag.Key == "db" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// annotationValue returns the value of the first annotation with key, or
+// "" when there is none
+func annotationValue(annotations []Annotation, key string) string {
+	for _, annotation := range annotations {
+		if annotation.Key == key {
+			return annotation.Value
+		}
+	}
+	return ""
+}
+
+// sqlIdent quotes a table or column name
+func sqlIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlDefault renders the value of an @api.field.default annotation as a
+// literal of a column type. Numbers and booleans are written as they are,
+// everything else as a string.
+func sqlDefault(annotation Annotation, sqlType string) string {
+	kind := StringValue
+	if annotation.Node != nil {
+		if values := annotation.Node.Positional(); len(values) > 0 {
+			kind = values[0].Kind
+		}
+	}
+	switch {
+	case kind == BoolValue && sqlType == "BOOLEAN":
+		return strings.ToUpper(annotation.Value)
+	case kind == NumberValue && sqlType != "BOOLEAN" && !strings.HasPrefix(sqlType, "VARCHAR") && sqlType != "TEXT":
+		return annotation.Value
+	}
+	return "'" + strings.ReplaceAll(annotation.Value, "'", "''") + "'"
+}
+
+// GenerateRepositories returns the database layer of a generated server:
+// repositories.go with a database/sql repository per model, migrations.go,
+// which applies the files written by writeMigrations, and, when testing is
+// enabled, repositories_test.go, which runs every repository against an
+// empty SQLite file or, for PostgreSQL, the database named by
+// TEST_DATABASE_URL. The in-memory store needs neither a database nor
+// migrations: repositories.go keeps the items in maps.
+func GenerateRepositories(packages map[string]*PackageInfo, config *FrameworkConfig) (map[string]string, error) {
+	plan, err := newRepositoryPlan(packages, config.Database)
+	if err != nil {
+		return nil, err
+	}
+	if plan.dialect == memoryDialect {
+		files := map[string]string{"repositories.go": plan.memorySource()}
+		if config.Testing != nil && config.Testing.Enabled {
+			files["repositories_test.go"] = plan.memoryTests()
+		}
+		return files, nil
+	}
+
+	files := map[string]string{
+		"repositories.go": plan.source(config.Database),
+		"migrations.go":   plan.migrationRunner(),
+	}
+	if config.Testing != nil && config.Testing.Enabled {
+		files["repositories_test.go"] = plan.tests()
+	}
+	return files, nil
+}
+
+// writeRepositories adds the files of GenerateRepositories in outputDir
+// to out
+func writeRepositories(out *OutputSet, outputDir string, packages map[string]*PackageInfo, config *FrameworkConfig) error {
+	files, err := GenerateRepositories(packages, config)
+	if err != nil {
+		return err
+	}
+	for name, content := range files {
+		out.Add(filepath.Join(outputDir, name), content)
+	}
+	return nil
+}
+
+// source renders repositories.go
+func (plan *repositoryPlan) source(config *DatabaseConfig) string {
+	d := plan.dialect
+	imports := map[string]bool{
+		"context": true, "database/sql": true, "errors": true, "fmt": true,
+		"log": true, "net/http": true, "os": true, "strings": true, "time": true,
+		d.Module: true,
+	}
+
+	var repositories, fields, constructors strings.Builder
+	stringKeys := false
+	for _, model := range plan.models {
+		stringKeys = stringKeys || !model.AutoKey
+		repositories.WriteString(plan.repository(model))
+		fields.WriteString(fmt.Sprintf("	%s *%s\n", model.Name, model.Type))
+		constructors.WriteString(fmt.Sprintf("		%s: New%s(db),\n", model.Name, model.Type))
+	}
+	if stringKeys {
+		imports["crypto/rand"] = true
+		imports["encoding/hex"] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString(renderImportBlock(imports, map[string]string{d.Module: "_"}))
+	b.WriteString(fmt.Sprintf(`// ErrNotFound is returned by repositories when no row has the requested key
+var ErrNotFound = errors.New("record not found")
+
+// defaultPageSize bounds the list and search responses of requests that do
+// not set a limit
+const defaultPageSize = 100
+
+// databaseDriver and defaultDatabaseURL select the configured database.
+// The DATABASE_URL environment variable overrides the URL.
+const (
+	databaseDriver     = %q
+	defaultDatabaseURL = %q
+)
+
+// OpenDatabase returns a connection pool for url. Connections are made on
+// first use.
+func OpenDatabase(url string) (*sql.DB, error) {
+	db, err := sql.Open(databaseDriver, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %%v", err)
+	}
+%s	return db, nil
+}
+
+// Repositories holds the repository of every model
+type Repositories struct {
+	db *sql.DB
+
+%s}
+
+// NewRepositories creates the repositories of every model over db
+func NewRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		db: db,
+
+%s	}
+}
+
+// repositories is opened once at startup; tests may replace it
+var repositories = mustNewRepositories()
+
+func mustNewRepositories() *Repositories {
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		url = defaultDatabaseURL
+	}
+	db, err := OpenDatabase(url)
+	if err != nil {
+		log.Fatalf("Failed to open database: %%v", err)
+	}
+	return NewRepositories(db)
+}
+
+// repositoryStatus maps a repository error to an HTTP status code
+func repositoryStatus(err error) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// sqlExecutor runs statements on a *sql.DB or inside a *sql.Tx
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is a *sql.Row or *sql.Rows positioned on a row
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryLimit returns the LIMIT argument for limit, where zero or less
+// means no limit
+func queryLimit(limit int) interface{} {
+	if limit <= 0 {
+		return %s
+	}
+	return limit
+}
+
+// likePattern matches values containing q, with q's wildcards escaped
+func likePattern(q string) string {
+	return "%%" + strings.NewReplacer("\\", "\\\\", "%%", "\\%%", "_", "\\_").Replace(q) + "%%"
+}
+
+// expectRow returns ErrNotFound when a statement changed no row
+func expectRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// timestamp returns the current time at the precision databases store
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+`, d.Driver, databaseURL(config), plan.poolSettings(), fields.String(), constructors.String(), d.NoLimit))
+
+	if stringKeys {
+		b.WriteString(`// newKey returns a random key for a row whose string key is empty
+func newKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate key: %v", err))
+	}
+	return hex.EncodeToString(key)
+}
+
+`)
+	}
+	b.WriteString(repositories.String())
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// poolSettings returns the statements OpenDatabase runs to configure the
+// pool. SQLite allows a single writer, so its pool holds one connection.
+func (plan *repositoryPlan) poolSettings() string {
+	if plan.dialect == sqliteDialect {
+		return "	db.SetMaxOpenConns(1)\n"
+	}
+	return ""
+}
+
+// repository renders the repository type of a model
+func (plan *repositoryPlan) repository(model *repositoryModel) string {
+	d := plan.dialect
+	table := sqlIdent(model.Table)
+	key := sqlIdent(model.Key.Column)
+
+	var names, targets []string
+	for _, column := range model.Columns {
+		names = append(names, sqlIdent(column.Column))
+		targets = append(targets, "&item."+column.Field)
+	}
+	selectList := strings.Join(names, ", ")
+
+	// insertStatement returns the statement inserting the columns given and
+	// the arguments that follow it in the call
+	insertStatement := func(columns []repositoryColumn, returning string) (string, string) {
+		if len(columns) == 0 {
+			return fmt.Sprintf("`INSERT INTO %s DEFAULT VALUES%s`", table, returning), ""
+		}
+		var names, params, args []string
+		for i, column := range columns {
+			names = append(names, sqlIdent(column.Column))
+			params = append(params, d.placeholder(i+1))
+			args = append(args, ", item."+column.Field)
+		}
+		statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)%s", table, strings.Join(names, ", "), strings.Join(params, ", "), returning)
+		return "`" + statement + "`", strings.Join(args, "")
+	}
+
+	var values []repositoryColumn
+	var sets, setArgs, stamps []string
+	for _, column := range model.Columns {
+		if column.Field == model.Key.Field {
+			continue
+		}
+		values = append(values, column)
+		if column.stamp() {
+			stamps = append(stamps, column.Field)
+		}
+		if column.Field == "CreatedAt" && column.stamp() {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", sqlIdent(column.Column), d.placeholder(len(sets)+1)))
+		setArgs = append(setArgs, "item."+column.Field)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`// %[1]s stores %[2]s values in the %[3]s table
+type %[1]s struct {
+	db *sql.DB
+}
+
+// New%[1]s creates a repository over db
+func New%[1]s(db *sql.DB) *%[1]s {
+	return &%[1]s{db: db}
+}
+
+// scan reads a row selected with every column
+func (r *%[1]s) scan(row rowScanner) (%[2]s, error) {
+	var item %[2]s
+	err := row.Scan(%[4]s)
+	return item, err
+}
+
+// query returns the rows of a SELECT of every column
+func (r *%[1]s) query(ctx context.Context, query string, args ...interface{}) ([]%[2]s, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %[2]s: %%v", err)
+	}
+	defer rows.Close()
+
+	items := []%[2]s{}
+	for rows.Next() {
+		item, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %[2]s: %%v", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query %[2]s: %%v", err)
+	}
+	return items, nil
+}
+
+// Get returns the %[2]s with the given key, or ErrNotFound
+func (r *%[1]s) Get(ctx context.Context, key %[5]s) (*%[2]s, error) {
+	item, err := r.scan(r.db.QueryRowContext(ctx, `+"`SELECT %[6]s FROM %[7]s WHERE %[8]s = %[9]s`"+`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %[2]s: %%v", err)
+	}
+	return &item, nil
+}
+
+// List returns up to limit rows in key order after skipping offset rows.
+// A limit of zero or less returns every remaining row.
+func (r *%[1]s) List(ctx context.Context, limit, offset int) ([]%[2]s, error) {
+	return r.query(ctx, `+"`SELECT %[6]s FROM %[7]s ORDER BY %[8]s LIMIT %[9]s OFFSET %[10]s`"+`, queryLimit(limit), offset)
+}
+
+`, model.Type, model.Name, model.Table, strings.Join(targets, ", "), model.Key.Type,
+		selectList, table, key, d.placeholder(1), d.placeholder(2)))
+
+	if columns := model.searchColumns(); len(columns) > 0 {
+		var matches, args []string
+		for i, column := range columns {
+			// Numbered placeholders bind the pattern once
+			if !d.Numbered || i == 0 {
+				args = append(args, "pattern")
+			}
+			matches = append(matches, fmt.Sprintf(`%s %s %s ESCAPE '\'`, sqlIdent(column.Column), d.Like, d.placeholder(1)))
+		}
+		n := len(args)
+		b.WriteString(fmt.Sprintf(`// Search returns the rows whose text columns contain q, ignoring case,
+// paginated like List
+func (r *%[1]s) Search(ctx context.Context, q string, limit, offset int) ([]%[2]s, error) {
+	pattern := likePattern(q)
+	return r.query(ctx, `+"`SELECT %[3]s FROM %[4]s WHERE %[5]s ORDER BY %[6]s LIMIT %[7]s OFFSET %[8]s`"+`, %[9]s, queryLimit(limit), offset)
+}
+
+`, model.Type, model.Name, selectList, table, strings.Join(matches, " OR "), key,
+			d.placeholder(n+1), d.placeholder(n+2), strings.Join(args, ", ")))
+	}
+
+	// insert fills in stamps and keys, then writes the row
+	var insert strings.Builder
+	if len(stamps) > 0 {
+		insert.WriteString("	now := timestamp()\n")
+		for _, field := range stamps {
+			if field == "CreatedAt" {
+				insert.WriteString("	if item.CreatedAt.IsZero() {\n		item.CreatedAt = now\n	}\n")
+			} else {
+				insert.WriteString(fmt.Sprintf("	item.%s = now\n", field))
+			}
+		}
+	}
+	all := append([]repositoryColumn{model.Key}, values...)
+	if model.AutoKey {
+		statement, args := insertStatement(values, " RETURNING "+key)
+		insert.WriteString(fmt.Sprintf("	if item.%s == 0 {\n", model.Key.Field))
+		insert.WriteString(fmt.Sprintf("		return db.QueryRowContext(ctx, %s%s).Scan(&item.%s)\n", statement, args, model.Key.Field))
+		insert.WriteString("	}\n")
+	} else {
+		insert.WriteString(fmt.Sprintf("	if item.%s == \"\" {\n		item.%s = newKey()\n	}\n", model.Key.Field, model.Key.Field))
+	}
+	statement, args := insertStatement(all, "")
+	insert.WriteString(fmt.Sprintf("	_, err := db.ExecContext(ctx, %s%s)\n	return err\n", statement, args))
+
+	var update strings.Builder
+	for _, field := range stamps {
+		if field != "CreatedAt" {
+			update.WriteString(fmt.Sprintf("	item.%s = timestamp()\n", field))
+		}
+	}
+	setArgs = append(setArgs, "item."+model.Key.Field)
+	if len(sets) == 0 {
+		// A table of only a key has nothing to update but its existence
+		sets = append(sets, fmt.Sprintf("%s = %s", key, key))
+	}
+	update.WriteString(fmt.Sprintf("	result, err := r.db.ExecContext(ctx, `UPDATE %s SET %s WHERE %s = %s`, %s)\n",
+		table, strings.Join(sets, ", "), key, d.placeholder(len(setArgs)), strings.Join(setArgs, ", ")))
+
+	b.WriteString(fmt.Sprintf(`// insert writes item, filling in its key and timestamps when unset
+func (r *%[1]s) insert(ctx context.Context, db sqlExecutor, item *%[2]s) error {
+%[3]s}
+
+// Create inserts item, filling in its key and timestamps when unset
+func (r *%[1]s) Create(ctx context.Context, item *%[2]s) error {
+	if err := r.insert(ctx, r.db, item); err != nil {
+		return fmt.Errorf("failed to create %[2]s: %%v", err)
+	}
+	return nil
+}
+
+// BulkInsert inserts items in one transaction, filling in their keys and
+// timestamps when unset. No item is stored when any insert fails.
+func (r *%[1]s) BulkInsert(ctx context.Context, items []%[2]s) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %%v", err)
+	}
+	for i := range items {
+		if err := r.insert(ctx, tx, &items[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert %[2]s %%d: %%v", i, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to insert %[2]s: %%v", err)
+	}
+	return nil
+}
+
+// Update replaces the row with item's key, or returns ErrNotFound
+func (r *%[1]s) Update(ctx context.Context, item *%[2]s) error {
+%[4]s	if err != nil {
+		return fmt.Errorf("failed to update %[2]s: %%v", err)
+	}
+	return expectRow(result)
+}
+
+// Delete removes the row with the given key, or returns ErrNotFound
+func (r *%[1]s) Delete(ctx context.Context, key %[5]s) error {
+	result, err := r.db.ExecContext(ctx, `+"`DELETE FROM %[6]s WHERE %[7]s = %[8]s`"+`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete %[2]s: %%v", err)
+	}
+	return expectRow(result)
+}
+
+`, model.Type, model.Name, insert.String(), update.String(), model.Key.Type, table, key, d.placeholder(1)))
+
+	return b.String()
+}
+
+// writeRepositoryHandler renders the body of a handler that serves its
+// route through the bound repository
+func writeRepositoryHandler(b *strings.Builder, dialect HandlerDialect, route APIRoute, uses *DialectUses, imports map[string]bool) {
+	binding := route.Repository
+	repository := "repositories." + binding.Model
+	ctx := dialect.RequestContext()
+	status := route.SuccessResponse().Status
+	fail := indent(dialect.Respond("repositoryStatus(err)", `map[string]string{"error": err.Error()}`), 2)
+
+	if binding.Key != "" {
+		uses.Path = true
+		writeScalarDecode(b, dialect, "keyParam", BoundParam{Name: "key", Type: binding.KeyType, Source: SourcePath, Key: binding.Key},
+			dialect.PathParam(binding.Key), imports)
+	}
+
+	switch binding.Operation {
+	case "list", "search":
+		uses.Query = true
+		for _, name := range []string{"limit", "offset"} {
+			writeScalarDecode(b, dialect, name+"Param", BoundParam{Name: name, Type: "int", Source: SourceQuery, Key: name},
+				dialect.QueryParam(name), imports)
+		}
+		b.WriteString("	if limitParam <= 0 {\n		limitParam = defaultPageSize\n	}\n")
+		switch {
+		case binding.Operation == "search":
+			b.WriteString(fmt.Sprintf("	result, err := %s.Search(%s, %s, limitParam, offsetParam)\n", repository, ctx, dialect.QueryParam("q")))
+		case len(binding.Filters) > 0:
+			b.WriteString("	filter := make(map[string]string)\n")
+			for _, name := range binding.Filters {
+				b.WriteString(fmt.Sprintf("	if value := %s; value != \"\" {\n		filter[%q] = value\n	}\n", dialect.QueryParam(name), name))
+			}
+			b.WriteString(fmt.Sprintf("	result, err := %s.Filter(%s, filter, limitParam, offsetParam)\n", repository, ctx))
+		default:
+			b.WriteString(fmt.Sprintf("	result, err := %s.List(%s, limitParam, offsetParam)\n", repository, ctx))
+		}
+	case "get":
+		b.WriteString(fmt.Sprintf("	result, err := %s.Get(%s, keyParam)\n", repository, ctx))
+	case "create", "update", "bulk_create":
+		uses.Body = true
+		itemType := binding.Model
+		method := "Create"
+		if binding.Operation == "bulk_create" {
+			itemType, method = "[]"+binding.Model, "BulkInsert"
+		}
+		b.WriteString(fmt.Sprintf("	var item %s\n", itemType))
+		b.WriteString(fmt.Sprintf("	if err := %s; err != nil {\n", dialect.DecodeBody("&item")))
+		b.WriteString(indent(dialect.Respond("http.StatusBadRequest",
+			`map[string]string{"error": "invalid request body: " + err.Error()}`), 2))
+		b.WriteString("	}\n")
+		switch binding.Operation {
+		case "update":
+			b.WriteString(fmt.Sprintf("	item.%s = keyParam\n", binding.KeyField))
+			b.WriteString(fmt.Sprintf("	if err := %s.Update(%s, &item); err != nil {\n", repository, ctx))
+		case "bulk_create":
+			b.WriteString(fmt.Sprintf("	if err := %s.%s(%s, item); err != nil {\n", repository, method, ctx))
+		default:
+			b.WriteString(fmt.Sprintf("	if err := %s.%s(%s, &item); err != nil {\n", repository, method, ctx))
+		}
+		b.WriteString(fail)
+		b.WriteString("	}\n\n")
+		b.WriteString(indent(finalStatement(dialect.Respond(statusExpr("http", status), "item")), 1))
+		return
+	case "delete":
+		b.WriteString(fmt.Sprintf("	if err := %s.Delete(%s, keyParam); err != nil {\n", repository, ctx))
+		b.WriteString(fail)
+		b.WriteString("	}\n\n")
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		b.WriteString(indent(finalStatement(dialect.RespondEmpty(statusExpr("http", status))), 1))
+		return
+	}
+
+	b.WriteString("	if err != nil {\n")
+	b.WriteString(fail)
+	b.WriteString("	}\n\n")
+	b.WriteString(indent(finalStatement(dialect.Respond(statusExpr("http", status), "result")), 1))
+}
+
+// tests renders repositories_test.go
+func (plan *repositoryPlan) tests() string {
+	imports := map[string]bool{"context": true, "database/sql": true, "errors": true, "os": true, "testing": true}
+
+	var b strings.Builder
+	if plan.dialect == sqliteDialect {
+		imports["path/filepath"] = true
+		b.WriteString(`// TestMain points the handlers at an empty SQLite file
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "repositories")
+	if err != nil {
+		panic(err)
+	}
+	db, err := OpenDatabase(filepath.Join(dir, "test.db"))
+	if err != nil {
+		panic(err)
+	}
+	repositories = NewRepositories(db)
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testDatabase returns an empty SQLite file with the schema of every model
+func testDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := MigrateDatabase(context.Background(), db, true); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+`)
+	} else {
+		b.WriteString(`// TestMain points the handlers at TEST_DATABASE_URL when it is set
+func TestMain(m *testing.M) {
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		db, err := OpenDatabase(url)
+		if err != nil {
+			panic(err)
+		}
+		repositories = NewRepositories(db)
+	}
+	os.Exit(m.Run())
+}
+
+// testDatabase returns the database named by TEST_DATABASE_URL with the
+// schema of every model. Its tables are emptied by the tests.
+func testDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := OpenDatabase(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := MigrateDatabase(context.Background(), db, true); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+`)
+	}
+
+	for _, model := range plan.models {
+		setup := fmt.Sprintf(`	db := testDatabase(t)
+	if _, err := db.ExecContext(ctx, %q); err != nil {
+		t.Fatal(err)
+	}
+	repository := New%s(db)
+`, "DELETE FROM "+sqlIdent(model.Table), model.Type)
+		b.WriteString(plan.modelTest(model, setup))
+	}
+
+	return "package main\n\n" + renderImportBlock(imports, nil) + strings.TrimSuffix(b.String(), "\n")
+}
+
+// modelTest renders the test of a model's repository. setup declares the
+// empty repository under test.
+func (plan *repositoryPlan) modelTest(model *repositoryModel, setup string) string {
+	key := model.Key.Field
+	var sample *repositoryColumn
+	for _, column := range model.searchColumns() {
+		if !column.nullable() {
+			column := column
+			sample = &column
+			break
+		}
+	}
+	fill := func(variable, value string) string {
+		if sample == nil {
+			return ""
+		}
+		return fmt.Sprintf("	%s.%s = %q\n", variable, sample.Field, value)
+	}
+	unset := fmt.Sprintf("%q", "")
+	if model.AutoKey {
+		unset = "0"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`func Test%s(t *testing.T) {
+	ctx := context.Background()
+%s
+	var first, second, third %s
+%s%s%s	if err := repository.Create(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.%s == %s {
+		t.Fatal("expected Create to fill in the key")
+	}
+	got, err := repository.Get(ctx, first.%s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.%s != first.%s {
+		t.Errorf("Get returned %%+v, want %%+v", got, first)
+	}
+	if err := repository.BulkInsert(ctx, []%s{second, third}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range []struct{ limit, offset, want int }{{0, 0, 3}, {2, 0, 2}, {10, 2, 1}} {
+		items, err := repository.List(ctx, page.limit, page.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != page.want {
+			t.Errorf("List(%%d, %%d) returned %%d items, want %%d", page.limit, page.offset, len(items), page.want)
+		}
+	}
+`, model.Type, setup, model.Name,
+		fill("first", "alpha"), fill("second", "beta"), fill("third", "gamma"),
+		key, unset, key, key, key, model.Name))
+
+	if sample != nil {
+		b.WriteString(fmt.Sprintf(`
+	found, err := repository.Search(ctx, "LPH", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].%[1]s != first.%[1]s {
+		t.Errorf("Search returned %%+v, want the first item", found)
+	}
+	if found, err := repository.Search(ctx, "%%", 0, 0); err != nil || len(found) != 0 {
+		t.Errorf("Search must match wildcards literally, got %%d items: %%v", len(found), err)
+	}
+
+	first.%[2]s = "omega"
+	if err := repository.Update(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repository.Get(ctx, first.%[1]s); err != nil || got.%[2]s != "omega" {
+		t.Errorf("Get after Update returned %%+v: %%v", got, err)
+	}
+`, key, sample.Field))
+	} else {
+		b.WriteString(`
+	if err := repository.Update(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+`)
+	}
+
+	b.WriteString(fmt.Sprintf(`
+	if err := repository.Delete(ctx, first.%[1]s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.Get(ctx, first.%[1]s); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete returned %%v, want ErrNotFound", err)
+	}
+	if err := repository.Delete(ctx, first.%[1]s); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete returned %%v, want ErrNotFound", err)
+	}
+	if err := repository.Update(ctx, &first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a deleted row returned %%v, want ErrNotFound", err)
+	}
+}
+
+`, key))
+	return b.String()
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Severity ranks a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes
+const (
+	DiagSyntax            = "syntax"              // Go source that does not parse
+	DiagTypeError         = "type-error"          // Go source that does not type-check
+	DiagAnnotationSyntax  = "annotation-syntax"   // malformed @api annotation
+	DiagUnknownAnnotation = "unknown-annotation"  // @api key that nothing reads
+	DiagUnknownArgument   = "unknown-argument"    // named argument the key does not take
+	DiagArgumentCount     = "argument-count"      // too few or too many positional arguments
+	DiagArgumentType      = "argument-type"       // argument of the wrong kind
+	DiagUnbindable        = "unbindable-endpoint" // @api.endpoint the generated server cannot call
+	DiagMethodConflict    = "method-conflict"     // contradicting HTTP methods on one declaration
+	DiagRouteConflict     = "route-conflict"      // two routes for one method and path
+	DiagUnrouted          = "unrouted"            // struct-level @api.route without a handler
+)
+
+// Diagnostic is a problem found in the scanned source, positioned at the
+// annotation or declaration it concerns
+type Diagnostic struct {
+	Pos      token.Position `json:"pos"`
+	Severity Severity       `json:"severity"`
+	Code     string         `json:"code"`
+	Message  string         `json:"message"`
+}
+
+// String formats the diagnostic the way the go tool reports errors
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", d.Pos, d.Severity, d.Message, d.Code)
+}
+
+// report records a diagnostic at pos
+func (ag *APIGenerator) report(pos token.Pos, severity Severity, code, format string, args ...interface{}) {
+	ag.diagnostics = append(ag.diagnostics, Diagnostic{
+		Pos:      ag.fset.Position(pos),
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// reportParseError records the errors of a Go file that failed to parse
+func (ag *APIGenerator) reportParseError(filePath string, err error) {
+	if list, ok := err.(scanner.ErrorList); ok {
+		for _, e := range list {
+			ag.diagnostics = append(ag.diagnostics, Diagnostic{Pos: e.Pos, Severity: SeverityError, Code: DiagSyntax, Message: e.Msg})
+		}
+		return
+	}
+	ag.diagnostics = append(ag.diagnostics, Diagnostic{
+		Pos:      token.Position{Filename: filePath},
+		Severity: SeverityError,
+		Code:     DiagSyntax,
+		Message:  err.Error(),
+	})
+}
+
+// reportTypeError records an error of the type checker. Soft errors, such
+// as unused imports, leave the type information intact and are warnings.
+// The checker reports details of an error, such as the other declaration of
+// a redeclared name, as separate errors following it whose message starts
+// with a tab; they are folded into the error they belong to.
+func (ag *APIGenerator) reportTypeError(err error) {
+	typeErr, ok := err.(types.Error)
+	if !ok {
+		ag.diagnostics = append(ag.diagnostics, Diagnostic{Severity: SeverityError, Code: DiagTypeError, Message: err.Error()})
+		return
+	}
+	if last := len(ag.diagnostics) - 1; strings.HasPrefix(typeErr.Msg, "\t") && last >= 0 && ag.diagnostics[last].Code == DiagTypeError {
+		ag.diagnostics[last].Message += fmt.Sprintf(" (%s at %s)", strings.TrimSpace(typeErr.Msg), ag.fset.Position(typeErr.Pos))
+		return
+	}
+	severity := SeverityError
+	if typeErr.Soft {
+		severity = SeverityWarning
+	}
+	ag.report(typeErr.Pos, severity, DiagTypeError, "%s", typeErr.Msg)
+}
+
+// Diagnostics returns the problems found while scanning, ordered by position
+func (ag *APIGenerator) Diagnostics() []Diagnostic {
+	diagnostics := append([]Diagnostic(nil), ag.diagnostics...)
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// Lint checks every scanned annotation and the route table and returns the
+// scan diagnostics together with the problems found, ordered by position.
+// Endpoints the generated handlers cannot call are errors when wiring is
+// set, since wired handlers call them, and warnings otherwise.
+func (ag *APIGenerator) Lint(wiring bool) []Diagnostic {
+	linter := &APIGenerator{fset: ag.fset}
+
+	for _, pkg := range ag.pkgs {
+		for _, structInfo := range pkg.Structs {
+			linter.lintAnnotations(structInfo.Annotations)
+			for _, field := range structInfo.Fields {
+				linter.lintAnnotations(field.Annotations)
+			}
+		}
+		for _, funcInfo := range pkg.Functions {
+			linter.lintAnnotations(funcInfo.Annotations)
+			linter.lintMethods(funcInfo.Annotations)
+			linter.lintEndpoint(pkg, funcInfo, wiring)
+		}
+	}
+
+	diagnostics := append(ag.Diagnostics(), linter.diagnostics...)
+	diagnostics = append(diagnostics, ag.BuildRouteTable().Diagnostics()...)
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// lintAnnotations checks annotation keys and arguments against annotationSpecs
+func (ag *APIGenerator) lintAnnotations(annotations []Annotation) {
+	for _, annotation := range annotations {
+		node := annotation.Node
+		if node == nil {
+			continue
+		}
+
+		spec, ok := lookupAnnotationSpec(node.Key())
+		if !ok {
+			ag.report(node.Pos, SeverityError, DiagUnknownAnnotation, "unknown annotation @api.%s", node.Key())
+			continue
+		}
+
+		_, positional := node.arguments()
+		if len(positional) < spec.required {
+			ag.report(node.Pos, SeverityError, DiagArgumentCount, "@api.%s takes at least %d argument(s), got %d", node.Key(), spec.required, len(positional))
+		}
+		if !spec.variadic && len(positional) > len(spec.args) {
+			ag.report(node.PosOf(positional[len(spec.args)].Offset), SeverityError, DiagArgumentCount,
+				"@api.%s takes at most %d argument(s), got %d", node.Key(), len(spec.args), len(positional))
+		}
+		for i, value := range positional {
+			if i >= len(spec.args) && !spec.variadic {
+				break
+			}
+			kind := spec.args[len(spec.args)-1]
+			if i < len(spec.args) {
+				kind = spec.args[i]
+			}
+			if !kind.accepts(value) {
+				ag.report(node.PosOf(value.Offset), SeverityError, DiagArgumentType,
+					"argument %d of @api.%s must be %s, got %s %s", i+1, node.Key(), kind, value.Kind, value.Raw)
+			}
+		}
+
+		for _, arg := range node.Args {
+			if arg.Name == "" || spec.named == nil {
+				continue
+			}
+			kind, ok := spec.named[arg.Name]
+			if !ok {
+				ag.report(node.PosOf(arg.Value.Offset), SeverityError, DiagUnknownArgument, "@api.%s has no argument %q", node.Key(), arg.Name)
+				continue
+			}
+			if !kind.accepts(arg.Value) {
+				ag.report(node.PosOf(arg.Value.Offset), SeverityError, DiagArgumentType,
+					"argument %s of @api.%s must be %s, got %s %s", arg.Name, node.Key(), kind, arg.Value.Kind, arg.Value.Raw)
+			}
+		}
+	}
+}
+
+// lintMethods reports declarations whose annotations name different HTTP
+// methods, for example "@api.endpoint POST /users" with "@api.method(GET)"
+func (ag *APIGenerator) lintMethods(annotations []Annotation) {
+	first, firstPos := "", token.NoPos
+	for _, annotation := range annotations {
+		if annotation.Node == nil {
+			continue
+		}
+		method := ""
+		switch annotation.Key {
+		case "endpoint":
+			if value, ok := annotation.Config["method"].(string); ok {
+				method = strings.ToUpper(value)
+			}
+		case "method":
+			method = strings.ToUpper(annotation.Value)
+		}
+		if method == "" {
+			continue
+		}
+
+		if first == "" {
+			first, firstPos = method, annotation.Node.Pos
+		} else if method != first {
+			ag.report(annotation.Node.Pos, SeverityError, DiagMethodConflict,
+				"method %s conflicts with method %s at %s", method, first, ag.fset.Position(firstPos))
+		}
+	}
+}
+
+// lintEndpoint reports @api.endpoint functions the generated server cannot call
+func (ag *APIGenerator) lintEndpoint(pkg *PackageInfo, funcInfo MethodInfo, wiring bool) {
+	severity := SeverityWarning
+	if wiring {
+		severity = SeverityError
+	}
+	for _, annotation := range funcInfo.Annotations {
+		if annotation.Key != "endpoint" || annotation.Node == nil || annotation.Value == "" {
+			continue
+		}
+		if _, err := bindRoute(pkg, funcInfo, annotation.Value); err != nil {
+			ag.report(annotation.Node.Pos, severity, DiagUnbindable,
+				"@api.endpoint %s on %s cannot be bound: %v", annotation.Value, funcInfo.Name, err)
+		}
+	}
+}
+
+// sortDiagnostics orders diagnostics by file, line and column
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Pos, diagnostics[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// hasErrors reports whether any diagnostic has error severity
+func hasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDiagnostics prints diagnostics one per line, or as a JSON array when
+// asJSON is set
+func WriteDiagnostics(w io.Writer, diagnostics []Diagnostic, asJSON bool) error {
+	if asJSON {
+		if diagnostics == nil {
+			diagnostics = []Diagnostic{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diagnostics)
+	}
+	for _, d := range diagnostics {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runLint implements the lint command: it scans the directories given in
+// args (the project's roots by default), prints the diagnostics and
+// returns the process exit code, 1 when any error was found. Unbindable
+// endpoints are errors when the project enables wiring.
+func runLint(project *ProjectConfig, args []string) int {
+	config := project.GeneratorConfig()
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print diagnostics as a JSON array")
+	wiring := flags.Bool("wiring", project.Wiring != nil && project.Wiring.Enabled,
+		"report endpoints that wired handlers cannot call as errors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = config.ScanRoots()
+	}
+
+	generator := NewAPIGenerator(config)
+	for _, root := range roots {
+		if err := generator.ScanDirectory(root); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", root, err)
+			return 2
+		}
+	}
+
+	diagnostics := generator.Lint(*wiring)
+	if err := WriteDiagnostics(os.Stdout, diagnostics, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing diagnostics: %v\n", err)
+		return 2
+	}
+	if hasErrors(diagnostics) {
+		return 1
+	}
+	return 0
+}
+
+// argKind is the kind of value an annotation argument accepts
+type argKind int
+
+const (
+	argAny    argKind = iota
+	argText           // string or bare word
+	argNumber         // number
+	argBool           // true or false
+	argJSON           // JSON object or array
+	argMethod         // HTTP method such as GET
+	argPath           // route path starting with "/"
+)
+
+// String describes the kind for diagnostics
+func (k argKind) String() string {
+	switch k {
+	case argText:
+		return "text"
+	case argNumber:
+		return "a number"
+	case argBool:
+		return "true or false"
+	case argJSON:
+		return "a JSON literal"
+	case argMethod:
+		return "an HTTP method"
+	case argPath:
+		return "a path starting with /"
+	default:
+		return "any value"
+	}
+}
+
+// accepts reports whether value is of kind k
+func (k argKind) accepts(value AnnotationValue) bool {
+	isText := value.Kind == StringValue || value.Kind == WordValue
+	switch k {
+	case argText:
+		return isText
+	case argNumber:
+		return value.Kind == NumberValue
+	case argBool:
+		return value.Kind == BoolValue
+	case argJSON:
+		return value.Kind == JSONValue
+	case argMethod:
+		return isText && httpVerbs[strings.ToUpper(value.String())]
+	case argPath:
+		return isText && strings.HasPrefix(value.String(), "/")
+	default:
+		return true
+	}
+}
+
+// annotationSpec describes the arguments an annotation key takes
+type annotationSpec struct {
+	args     []argKind          // kinds of the positional arguments
+	required int                // number of mandatory positional arguments
+	variadic bool               // the last positional kind repeats
+	named    map[string]argKind // accepted named arguments; nil accepts any
+	prefix   bool               // the spec also covers keys below this one
+}
+
+// annotationSpecs lists every annotation key the generator reads
+var annotationSpecs = map[string]annotationSpec{
+	"route":           {args: []argKind{argPath}, required: 1, named: map[string]argKind{"methods": argText, "auth": argText}},
+	"endpoint":        {args: []argKind{argPath}, required: 1, named: map[string]argKind{"method": argMethod, "auth": argText}},
+	"method":          {args: []argKind{argMethod}, required: 1, named: map[string]argKind{}},
+	"methods":         {args: []argKind{argMethod}, required: 1, variadic: true, named: map[string]argKind{}},
+	"auth.required":   {named: map[string]argKind{}},
+	"auth.optional":   {named: map[string]argKind{}},
+	"auth.jwt":        {named: map[string]argKind{}},
+	"request":         {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"response":        {args: []argKind{argNumber, argText}, required: 1, named: map[string]argKind{"description": argText}},
+	"rate_limit":      {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"model":           {named: map[string]argKind{}},
+	"constructor":     {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"resource":        {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"db.table":        {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"db.primary_key":  {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"doc.title":       {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"doc.description": {args: []argKind{argText}, required: 1, named: map[string]argKind{}},
+	"doc.param": {
+		args:     []argKind{argText, argText, argText, argText},
+		required: 1,
+		named:    map[string]argKind{"required": argBool, "description": argText},
+	},
+	"doc.example": {args: []argKind{argAny}, required: 1, named: map[string]argKind{"status": argNumber}},
+	"field":       {args: []argKind{argAny}, variadic: true, prefix: true},
+	"validation":  {args: []argKind{argAny}, variadic: true, prefix: true},
+}
+
+// lookupAnnotationSpec finds the spec of key, falling back to the closest
+// enclosing prefix spec such as "validation" for "validation.required"
+func lookupAnnotationSpec(key string) (annotationSpec, bool) {
+	if spec, ok := annotationSpecs[key]; ok {
+		return spec, true
+	}
+	for idx := strings.LastIndex(key, "."); idx > 0; idx = strings.LastIndex(key, ".") {
+		key = key[:idx]
+		if spec, ok := annotationSpecs[key]; ok && spec.prefix {
+			return spec, true
+		}
+	}
+	return annotationSpec{}, false
+}
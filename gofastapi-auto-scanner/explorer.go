@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultDocsPath is where the API explorer is mounted when the
+// documentation config does not name a path
+const defaultDocsPath = "/docs"
+
+// docsEnabled reports whether a generated server serves its docs
+func docsEnabled(config *FrameworkConfig) bool {
+	return config.Docs != nil && config.Docs.Enabled
+}
+
+// docsPath returns the mount path of the API explorer, with a leading and
+// without a trailing slash
+func docsPath(config *FrameworkConfig) string {
+	path := defaultDocsPath
+	if config.Docs != nil && strings.Trim(config.Docs.Path, "/") != "" {
+		path = "/" + strings.Trim(config.Docs.Path, "/")
+	}
+	return path
+}
+
+// GenerateDocsServer returns docs.go of a generated server. It embeds the
+// explorer and the OpenAPI document written to the docs directory and
+// mounts them at the configured docs path with setupDocs.
+func GenerateDocsServer(config *FrameworkConfig) (string, error) {
+	var imports, mount string
+	switch config.Type {
+	case FrameworkGin:
+		imports = `"github.com/gin-gonic/gin"`
+		mount = `	handler := gin.WrapH(docsHandler())
+	s.router.GET(docsPath, handler)
+	s.router.GET(docsPath+"/*filepath", handler)`
+	case FrameworkEcho:
+		imports = `"github.com/labstack/echo/v4"`
+		mount = `	handler := echo.WrapHandler(docsHandler())
+	s.e.GET(docsPath, handler)
+	s.e.GET(docsPath+"/*", handler)`
+	case FrameworkChi:
+		mount = `	s.router.Mount(docsPath, docsHandler())`
+	case FrameworkFiber:
+		imports = `"github.com/gofiber/fiber/v2/middleware/adaptor"`
+		mount = `	s.app.Use(docsPath, adaptor.HTTPHandler(docsHandler()))`
+	case FrameworkStdlib:
+		mount = `	s.mux.Handle("GET "+docsPath+"/", docsHandler())`
+	default:
+		return "", fmt.Errorf("no docs explorer for framework %s", config.Type)
+	}
+	if imports != "" {
+		imports = "\n\n\t" + imports
+	}
+
+	return fmt.Sprintf(`package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"%s
+)
+
+// docsFiles holds the API explorer and the OpenAPI document it loads
+//
+//go:embed docs/index.html docs/openapi.json
+var docsFiles embed.FS
+
+// docsPath is where the API explorer is served
+const docsPath = %q
+
+// docsHandler serves the embedded explorer and OpenAPI document below docsPath
+func docsHandler() http.Handler {
+	files, err := fs.Sub(docsFiles, "docs")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(docsPath, http.FileServer(http.FS(files)))
+}
+
+// setupDocs mounts the API explorer at docsPath
+func (s *Server) setupDocs() {
+%s
+}
+`, imports, docsPath(config), mount), nil
+}
+
+// writeDocsExplorer adds the explorer page and the OpenAPI document in
+// the docs directory and docs.go, which embeds them, in outputDir to out
+func writeDocsExplorer(out *OutputSet, outputDir string, config *FrameworkConfig, doc *OpenAPIDocument) error {
+	docsDir := filepath.Join(outputDir, "docs")
+	if err := WriteOpenAPIFiles(out, docsDir, doc); err != nil {
+		return err
+	}
+	out.Add(filepath.Join(docsDir, "index.html"), explorerHTML)
+	content, err := GenerateDocsServer(config)
+	if err != nil {
+		return err
+	}
+	out.Add(filepath.Join(outputDir, "docs.go"), content)
+	return nil
+}
+
+// setupDocsCall is the statement setupRoutes uses to mount the explorer
+const setupDocsCall = "	// API explorer and OpenAPI document\n	s.setupDocs()\n\n"
+
+// explorerHTML is a self-contained page that renders openapi.json from its
+// own directory and sends requests to the running service. It loads no
+// external assets so that it works offline.
+const explorerHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>API Explorer</title>
+<style>
+  * { box-sizing: border-box; }
+  body { margin: 0; font: 14px/1.5 system-ui, -apple-system, "Segoe UI", sans-serif; color: #1f2328; background: #f6f8fa; }
+  header { padding: 16px 24px; background: #24292f; color: #fff; display: flex; flex-wrap: wrap; gap: 12px; align-items: center; }
+  header h1 { margin: 0; font-size: 20px; flex: 1; }
+  header .version { font-size: 12px; padding: 2px 8px; border-radius: 10px; background: #57606a; }
+  header input { width: 320px; max-width: 100%; }
+  main { max-width: 1100px; margin: 0 auto; padding: 16px 24px 48px; }
+  h2 { margin: 24px 0 8px; font-size: 16px; text-transform: capitalize; }
+  details.op { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; margin: 8px 0; }
+  details.op > summary { cursor: pointer; padding: 8px 12px; display: flex; gap: 12px; align-items: center; list-style: none; }
+  details.op[open] > summary { border-bottom: 1px solid #d0d7de; }
+  .method { font: bold 12px monospace; min-width: 64px; text-align: center; padding: 3px 0; border-radius: 4px; color: #fff; background: #57606a; }
+  .GET { background: #0969da; } .POST { background: #1a7f37; } .PUT { background: #9a6700; }
+  .PATCH { background: #8250df; } .DELETE { background: #cf222e; }
+  .path { font-family: monospace; font-weight: 600; }
+  .summary { color: #57606a; flex: 1; }
+  .lock { font-size: 12px; color: #9a6700; }
+  .body { padding: 12px; }
+  .body p { margin: 0 0 12px; white-space: pre-wrap; }
+  label { display: block; margin: 6px 0 2px; font-weight: 600; }
+  label small { font-weight: normal; color: #57606a; }
+  input, textarea { font: 13px monospace; padding: 6px; border: 1px solid #d0d7de; border-radius: 4px; width: 100%; }
+  textarea { min-height: 120px; }
+  button { margin-top: 10px; padding: 6px 16px; border: 0; border-radius: 4px; background: #1a7f37; color: #fff; font-weight: 600; cursor: pointer; }
+  pre { background: #f6f8fa; border: 1px solid #d0d7de; border-radius: 4px; padding: 8px; overflow: auto; max-height: 400px; margin: 8px 0 0; }
+  .status { font-weight: 600; margin-top: 10px; }
+  .error { color: #cf222e; }
+</style>
+</head>
+<body>
+<header>
+  <h1 id="title">API Explorer</h1>
+  <span class="version" id="version"></span>
+  <input id="token" type="password" placeholder="Bearer token" autocomplete="off" aria-label="Bearer token">
+</header>
+<main id="content"><p>Loading the OpenAPI document&hellip;</p></main>
+<script>
+(function () {
+  "use strict";
+  var base = location.pathname.replace(/\/(index\.html)?$/, "");
+  var content = document.getElementById("content");
+  var tokenInput = document.getElementById("token");
+  tokenInput.value = sessionStorage.getItem("explorer-token") || "";
+  tokenInput.addEventListener("input", function () { sessionStorage.setItem("explorer-token", tokenInput.value); });
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") { node.textContent = attrs[key]; } else { node.setAttribute(key, attrs[key]); }
+    });
+    (children || []).forEach(function (child) { if (child) { node.appendChild(child); } });
+    return node;
+  }
+
+  function resolve(spec, schema) {
+    while (schema && schema.$ref) {
+      schema = spec.components.schemas[schema.$ref.split("/").pop()];
+    }
+    return schema || {};
+  }
+
+  // sample builds a placeholder value for a schema
+  function sample(spec, schema, depth) {
+    schema = resolve(spec, schema);
+    if (schema.examples && schema.examples.length) { return schema.examples[0]; }
+    if (schema.enum && schema.enum.length) { return schema.enum[0]; }
+    var type = Array.isArray(schema.type) ? schema.type[0] : schema.type;
+    if (depth > 4) { return null; }
+    switch (type) {
+      case "object":
+        var value = {};
+        Object.keys(schema.properties || {}).forEach(function (name) {
+          value[name] = sample(spec, schema.properties[name], depth + 1);
+        });
+        return value;
+      case "array": return [sample(spec, schema.items, depth + 1)];
+      case "integer": case "number": return 0;
+      case "boolean": return false;
+      case "string":
+        if (schema.format === "date-time") { return new Date().toISOString(); }
+        return schema.format || "string";
+    }
+    return null;
+  }
+
+  function operation(spec, server, path, method, op) {
+    var secured = (op.security || spec.security || []).some(function (req) { return Object.keys(req).length > 0; });
+    var summary = el("summary", {}, [
+      el("span", { "class": "method " + method.toUpperCase(), text: method.toUpperCase() }),
+      el("span", { "class": "path", text: path }),
+      el("span", { "class": "summary", text: op.summary || "" }),
+      secured ? el("span", { "class": "lock", text: "auth" }) : null
+    ]);
+    var body = el("div", { "class": "body" });
+    if (op.description && op.description !== op.summary) {
+      body.appendChild(el("p", { text: op.description }));
+    }
+
+    var inputs = [];
+    (op.parameters || []).forEach(function (param) {
+      var input = el("input", { placeholder: param.required ? "required" : "" });
+      var schema = resolve(spec, param.schema);
+      if (schema.examples && schema.examples.length) { input.value = schema.examples[0]; }
+      inputs.push({ param: param, input: input });
+      body.appendChild(el("label", {}, [
+        document.createTextNode(param.name + " "),
+        el("small", { text: "(" + param.in + ")" + (param.description ? " " + param.description : "") })
+      ]));
+      body.appendChild(input);
+    });
+
+    var textarea = null;
+    if (op.requestBody) {
+      var media = (op.requestBody.content || {})["application/json"] || {};
+      var example = media.example !== undefined ? media.example : sample(spec, media.schema, 0);
+      textarea = el("textarea", {});
+      textarea.value = JSON.stringify(example, null, 2);
+      body.appendChild(el("label", { text: "Request body" }));
+      body.appendChild(textarea);
+    }
+
+    var responses = Object.keys(op.responses || {}).map(function (code) {
+      return code + " " + (op.responses[code].description || "");
+    }).join("\n");
+    if (responses) {
+      body.appendChild(el("label", { text: "Responses" }));
+      body.appendChild(el("pre", { text: responses }));
+    }
+
+    var status = el("div", { "class": "status" });
+    var output = el("pre", { hidden: "hidden" });
+    var send = el("button", { type: "button", text: "Send request" });
+    send.addEventListener("click", function () {
+      var url = path, query = [], headers = { "Accept": "application/json" };
+      var missing = inputs.filter(function (item) { return item.param.required && !item.input.value; });
+      if (missing.length) {
+        status.className = "status error";
+        status.textContent = "Missing " + missing.map(function (item) { return item.param.name; }).join(", ");
+        return;
+      }
+      inputs.forEach(function (item) {
+        var value = item.input.value;
+        if (!value) { return; }
+        if (item.param.in === "path") {
+          url = url.replace("{" + item.param.name + "}", encodeURIComponent(value));
+        } else if (item.param.in === "header") {
+          headers[item.param.name] = value;
+        } else {
+          query.push(encodeURIComponent(item.param.name) + "=" + encodeURIComponent(value));
+        }
+      });
+      if (query.length) { url += "?" + query.join("&"); }
+      var init = { method: method.toUpperCase(), headers: headers };
+      if (textarea) {
+        headers["Content-Type"] = "application/json";
+        init.body = textarea.value;
+      }
+      if (tokenInput.value) { headers["Authorization"] = "Bearer " + tokenInput.value; }
+      status.className = "status";
+      status.textContent = "Sending…";
+      var started = Date.now();
+      fetch(server + url, init).then(function (response) {
+        return response.text().then(function (text) {
+          status.textContent = response.status + " " + response.statusText + " (" + (Date.now() - started) + " ms)";
+          status.className = response.ok ? "status" : "status error";
+          try { text = JSON.stringify(JSON.parse(text), null, 2); } catch (e) { /* not JSON */ }
+          output.textContent = text || "(empty body)";
+          output.hidden = false;
+        });
+      }).catch(function (err) {
+        status.className = "status error";
+        status.textContent = String(err);
+      });
+    });
+    body.appendChild(send);
+    body.appendChild(status);
+    body.appendChild(output);
+    return el("details", { "class": "op" }, [summary, body]);
+  }
+
+  function render(spec) {
+    var info = spec.info || {};
+    document.title = (info.title || "API") + " - Explorer";
+    document.getElementById("title").textContent = info.title || "API Explorer";
+    document.getElementById("version").textContent = info.version || "";
+    var server = spec.servers && spec.servers.length ? spec.servers[0].url.replace(/\/$/, "") : "";
+    spec.components = spec.components || {};
+    spec.components.schemas = spec.components.schemas || {};
+
+    content.textContent = "";
+    if (info.description) { content.appendChild(el("p", { text: info.description })); }
+    var groups = {};
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      ["get", "post", "put", "patch", "delete", "head", "options"].forEach(function (method) {
+        var op = spec.paths[path][method];
+        if (!op) { return; }
+        var tag = (op.tags && op.tags[0]) || "default";
+        (groups[tag] = groups[tag] || []).push(operation(spec, server, path, method, op));
+      });
+    });
+    Object.keys(groups).sort().forEach(function (tag) {
+      content.appendChild(el("h2", { text: tag }));
+      groups[tag].forEach(function (node) { content.appendChild(node); });
+    });
+
+    var names = Object.keys(spec.components.schemas).sort();
+    if (names.length) {
+      content.appendChild(el("h2", { text: "Schemas" }));
+      names.forEach(function (name) {
+        content.appendChild(el("details", { "class": "op" }, [
+          el("summary", {}, [el("span", { "class": "path", text: name })]),
+          el("div", { "class": "body" }, [el("pre", { text: JSON.stringify(spec.components.schemas[name], null, 2) })])
+        ]));
+      });
+    }
+  }
+
+  fetch(base + "/openapi.json").then(function (response) {
+    if (!response.ok) { throw new Error("openapi.json: " + response.status); }
+    return response.json();
+  }).then(render).catch(function (err) {
+    content.textContent = "";
+    content.appendChild(el("p", { "class": "error", text: "Could not load the API description: " + err }));
+  });
+})();
+</script>
+</body>
+</html>
+`
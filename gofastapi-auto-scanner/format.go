@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownImports maps the package names generated code refers to onto their
+// import paths. A name with several candidates is resolved against the
+// other imports of the file.
+var knownImports = map[string][]string{
+	"bufio":     {"bufio"},
+	"bytes":     {"bytes"},
+	"context":   {"context"},
+	"embed":     {"embed"},
+	"errors":    {"errors"},
+	"filepath":  {"path/filepath"},
+	"flag":      {"flag"},
+	"fmt":       {"fmt"},
+	"fs":        {"io/fs"},
+	"hex":       {"encoding/hex"},
+	"http":      {"net/http"},
+	"httptest":  {"net/http/httptest"},
+	"io":        {"io"},
+	"json":      {"encoding/json"},
+	"log":       {"log"},
+	"math":      {"math"},
+	"os":        {"os"},
+	"reflect":   {"reflect"},
+	"regexp":    {"regexp"},
+	"runtime":   {"runtime"},
+	"signal":    {"os/signal"},
+	"sort":      {"sort"},
+	"sql":       {"database/sql"},
+	"strconv":   {"strconv"},
+	"strings":   {"strings"},
+	"sync":      {"sync"},
+	"syscall":   {"syscall"},
+	"testing":   {"testing"},
+	"time":      {"time"},
+	"unicode":   {"unicode"},
+	"url":       {"net/url"},
+	"assert":    {"github.com/stretchr/testify/assert"},
+	"require":   {"github.com/stretchr/testify/require"},
+	"chi":       {"github.com/go-chi/chi/v5"},
+	"echo":      {"github.com/labstack/echo/v4"},
+	"fiber":     {"github.com/gofiber/fiber/v2"},
+	"gin":       {"github.com/gin-gonic/gin"},
+	"godotenv":  {"github.com/joho/godotenv"},
+	"jwt":       {"github.com/golang-jwt/jwt/v4"},
+	"zap":       {"go.uber.org/zap"},
+	"adaptor":   {"github.com/gofiber/fiber/v2/middleware/adaptor"},
+	"cors":      {"github.com/gofiber/fiber/v2/middleware/cors"},
+	"logger":    {"github.com/gofiber/fiber/v2/middleware/logger"},
+	"recover":   {"github.com/gofiber/fiber/v2/middleware/recover"},
+	"requestid": {"github.com/gofiber/fiber/v2/middleware/requestid"},
+	"middleware": {
+		"github.com/labstack/echo/v4/middleware",
+		"github.com/go-chi/chi/v5/middleware",
+	},
+}
+
+// importName returns the name an import path is referred to by when the
+// import has no explicit name, and whether the name is certain
+func importName(importPath string) (string, bool) {
+	for name, paths := range knownImports {
+		for _, candidate := range paths {
+			if candidate == importPath {
+				return name, true
+			}
+		}
+	}
+	name := path.Base(importPath)
+	if isStdlibPath(importPath) {
+		if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+			name = path.Base(path.Dir(importPath))
+		}
+		return name, true
+	}
+	return name, false
+}
+
+// resolveImport picks the import path of a package name referred to by a
+// file that imports paths
+func resolveImport(name string, paths []string) (string, bool) {
+	candidates := knownImports[name]
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	for _, candidate := range candidates {
+		module := strings.Join(strings.SplitN(candidate, "/", 4)[:3], "/")
+		for _, imported := range paths {
+			if strings.HasPrefix(imported, module) {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// packageDeclarations returns the package-level names declared by the Go
+// files of a package, which shadow package names
+func packageDeclarations(files []*ast.File) map[string]bool {
+	declared := make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					declared[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						declared[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							declared[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return declared
+}
+
+// parseGoSource parses a generated Go file, reporting syntax errors with
+// the file name
+func parseGoSource(fset *token.FileSet, filename, src string) (*ast.File, error) {
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %v", err)
+	}
+	return file, nil
+}
+
+// formatGoSource adds the missing imports of a generated Go file, drops
+// its unused ones and formats it like gofmt. declared holds the
+// package-level names of the other files of its package.
+func formatGoSource(filename, src string, declared map[string]bool) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parseGoSource(fset, filename, src)
+	if err != nil {
+		return "", err
+	}
+
+	// An unparenthesized import is dropped with its declaration
+	owners := make(map[*ast.ImportSpec]ast.Node)
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+			for _, spec := range decl.Specs {
+				owners[spec.(*ast.ImportSpec)] = spec
+				if !decl.Lparen.IsValid() {
+					owners[spec.(*ast.ImportSpec)] = decl
+				}
+			}
+		}
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	imported := make(map[string]bool)
+	var paths []string
+	used := usedPackageNames(file)
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		paths = append(paths, importPath)
+		name, certain := importName(importPath)
+		if spec.Name != nil {
+			name, certain = spec.Name.Name, true
+		}
+		imported[name] = true
+		if !certain || name == "_" || name == "." || importPath == "C" || used[name] {
+			continue
+		}
+		// Drop the whole lines of the unused import
+		start := fset.Position(owners[spec].Pos()).Offset
+		end := fset.Position(owners[spec].End()).Offset
+		for start > 0 && src[start-1] != '\n' {
+			start--
+		}
+		if newline := strings.IndexByte(src[end:], '\n'); newline >= 0 {
+			end += newline + 1
+		}
+		edits = append(edits, edit{start: start, end: end})
+	}
+
+	var missing []string
+	for name := range used {
+		if imported[name] || declared[name] {
+			continue
+		}
+		if importPath, ok := resolveImport(name, paths); ok {
+			missing = append(missing, importPath)
+			paths = append(paths, importPath)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		var std, other strings.Builder
+		for _, importPath := range missing {
+			if isStdlibPath(importPath) {
+				std.WriteString("\t" + strconv.Quote(importPath) + "\n")
+			} else {
+				other.WriteString("\t" + strconv.Quote(importPath) + "\n")
+			}
+		}
+		var block *ast.GenDecl
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && decl.Lparen.IsValid() {
+				block = decl
+				break
+			}
+		}
+		if block != nil {
+			lparen := fset.Position(block.Lparen).Offset + 1
+			rparen := fset.Position(block.Rparen).Offset
+			if std.Len() > 0 {
+				edits = append(edits, edit{start: lparen, end: lparen, text: "\n" + strings.TrimSuffix(std.String(), "\n")})
+			}
+			if other.Len() > 0 {
+				edits = append(edits, edit{start: rparen, end: rparen, text: "\n" + other.String()})
+			}
+		} else {
+			at := fset.Position(file.Name.End()).Offset
+			text := "\n\nimport (\n" + std.String()
+			if std.Len() > 0 && other.Len() > 0 {
+				text += "\n"
+			}
+			edits = append(edits, edit{start: at, end: at, text: text + other.String() + ")"})
+		}
+	}
+
+	// Apply the edits from the end so earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		src = src[:e.start] + e.text + src[e.end:]
+	}
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return "", fmt.Errorf("generated code does not parse: %s: %v", filename, err)
+	}
+	return string(formatted), nil
+}
+
+// usedPackageNames returns the unresolved identifiers a file qualifies
+// selectors with, which are the packages it refers to
+func usedPackageNames(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if _, ok := node.(*ast.ImportSpec); ok {
+			return false
+		}
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// FormatGo runs every Go file of the set through import fixing and gofmt,
+// failing on the first file that does not parse
+func (o *OutputSet) FormatGo() error {
+	packages := make(map[string][]string)
+	for _, filename := range o.Paths() {
+		if strings.HasSuffix(filename, ".go") {
+			dir := filepath.Dir(filename)
+			packages[dir] = append(packages[dir], filename)
+		}
+	}
+
+	for _, filenames := range packages {
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, filename := range filenames {
+			file, err := parseGoSource(fset, filename, o.files[filename])
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+		declared := packageDeclarations(files)
+		for _, filename := range filenames {
+			formatted, err := formatGoSource(filename, o.files[filename], declared)
+			if err != nil {
+				return err
+			}
+			o.files[filename] = formatted
+		}
+	}
+	return nil
+}
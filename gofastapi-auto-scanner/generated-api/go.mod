@@ -1,8 +0,0 @@
-module autogenerated-api
-
-go 1.21
-
-require (
-	github.com/gin-gonic/gin v1.10.0
-	github.com/golang-jwt/jwt/v4 v4.5.2
-)
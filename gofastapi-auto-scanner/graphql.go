@@ -0,0 +1,1684 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GraphQLConfig controls the GraphQL endpoint served next to the REST
+// routes. Its resolvers call the scanned methods, so they need wiring;
+// without it every field resolves to a "not implemented" error.
+type GraphQLConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"`
+}
+
+// defaultGraphQLPath is where the GraphQL endpoint is mounted when the
+// config does not name a path
+const defaultGraphQLPath = "/graphql"
+
+// graphqlEnabled reports whether a generated server serves GraphQL
+func graphqlEnabled(config *FrameworkConfig) bool {
+	return config.GraphQL != nil && config.GraphQL.Enabled
+}
+
+// graphqlPath returns the mount path of the GraphQL endpoint, with a
+// leading and without a trailing slash
+func graphqlPath(config *FrameworkConfig) string {
+	path := defaultGraphQLPath
+	if config.GraphQL != nil && strings.Trim(config.GraphQL.Path, "/") != "" {
+		path = "/" + strings.Trim(config.GraphQL.Path, "/")
+	}
+	return path
+}
+
+// setupGraphQLCall is the statement setupRoutes uses to mount the endpoint
+const setupGraphQLCall = "	// GraphQL endpoint\n	s.setupGraphQL()\n\n"
+
+// graphqlQueryOperations are the smart mapping operations that read data
+// and become Query fields; the other operations become Mutation fields
+var graphqlQueryOperations = map[string]bool{
+	"get": true, "list": true, "search": true, "get_by": true, "count": true, "exists": true,
+}
+
+// graphqlName matches the names GraphQL allows for fields and arguments
+var graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// graphqlReservedNames are the built-in and root types of the schema,
+// which scanned structs cannot be named after
+var graphqlReservedNames = []string{"Query", "Mutation", "Subscription", "String", "Int", "Float", "Boolean", "ID", "Time", "JSON"}
+
+// GenerateGraphQL returns the GraphQL sources of a generated server:
+// schema.graphql with a Query or Mutation field per route and graphql.go,
+// which serves the schema with a minimal executor built on the standard
+// library and resolves every field by calling the route's service method
+func GenerateGraphQL(routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) (map[string]string, error) {
+	mount, mountImport, err := graphqlMount(routes, config)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &graphqlGenerator{
+		types:   newOpenAPIBuilder(packages),
+		outputs: make(map[string]bool),
+		inputs:  make(map[string]string),
+		scalars: make(map[string]bool),
+		names:   make(map[string]bool),
+		wired:   config.Wiring != nil && config.Wiring.Enabled,
+		plan:    newWiringPlan(routes),
+		imports: map[string]bool{
+			"bytes": true, "context": true, "embed": true, "encoding/json": true, "errors": true, "fmt": true,
+			"io": true, "net/http": true, "strconv": true, "strings": true, "unicode/utf16": true,
+		},
+	}
+	if mountImport != "" {
+		g.imports[mountImport] = true
+	}
+	for _, name := range graphqlReservedNames {
+		g.types.taken[name] = true
+	}
+
+	var fields []graphqlRootField
+	for _, route := range stdlibRoutes(routes) {
+		fields = append(fields, g.rootField(route))
+	}
+
+	title := strings.Title(string(config.Type))
+	var schema strings.Builder
+	schema.WriteString(fmt.Sprintf("# GraphQL schema of the generated %s API. It is generated together with\n", title))
+	schema.WriteString("# the server; regenerate both after changing routes.\n")
+	roots := g.roots(fields)
+	objects := g.objects()
+	inputs := g.inputTypes()
+	schema.WriteString(g.scalarTypes())
+	schema.WriteString(roots)
+	schema.WriteString(objects)
+	schema.WriteString(inputs)
+
+	var resolvers strings.Builder
+	for _, field := range fields {
+		resolvers.WriteString(g.resolver(field))
+	}
+
+	var code strings.Builder
+	code.WriteString("package main\n\n")
+	code.WriteString(renderImportBlock(g.imports, g.plan.aliases))
+	code.WriteString(fmt.Sprintf(`// graphqlFiles holds the schema served by the GraphQL endpoint
+//
+//go:embed schema.graphql
+var graphqlFiles embed.FS
+
+// graphqlPath is where the GraphQL endpoint is served
+const graphqlPath = %q
+
+// setupGraphQL mounts the GraphQL endpoint at graphqlPath
+func (s *Server) setupGraphQL() {
+%s
+}
+
+`, graphqlPath(config), mount))
+	code.WriteString(g.typeTable(fields))
+	code.WriteString(resolvers.String())
+	code.WriteString(graphqlRuntime)
+
+	return map[string]string{
+		"schema.graphql": schema.String(),
+		"graphql.go":     code.String(),
+	}, nil
+}
+
+// writeGraphQL adds schema.graphql and graphql.go in outputDir to out
+func writeGraphQL(out *OutputSet, outputDir string, routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) error {
+	files, err := GenerateGraphQL(routes, packages, config)
+	if err != nil {
+		return err
+	}
+	for name, content := range files {
+		out.Add(filepath.Join(outputDir, name), content)
+	}
+	return nil
+}
+
+// graphqlMount returns the body of setupGraphQL for the framework and the
+// package it imports. When one of the exposed routes requires
+// authentication the whole endpoint does, since a single request can reach
+// any field.
+func graphqlMount(routes []APIRoute, config *FrameworkConfig) (mount, importPath string, err error) {
+	auth := false
+	if config.Auth != nil && config.Auth.Required {
+		for _, route := range stdlibRoutes(routes) {
+			auth = auth || route.Auth.Required
+		}
+	}
+
+	switch config.Type {
+	case FrameworkGin:
+		importPath = "github.com/gin-gonic/gin"
+		if auth {
+			return `	handler := gin.WrapH(graphqlHandler())
+	s.router.GET(graphqlPath, AuthMiddleware(s.config.JWTSecret), handler)
+	s.router.POST(graphqlPath, AuthMiddleware(s.config.JWTSecret), handler)`, importPath, nil
+		}
+		return `	handler := gin.WrapH(graphqlHandler())
+	s.router.GET(graphqlPath, handler)
+	s.router.POST(graphqlPath, handler)`, importPath, nil
+	case FrameworkEcho:
+		importPath = "github.com/labstack/echo/v4"
+		if auth {
+			return `	handler := echo.WrapHandler(graphqlHandler())
+	s.e.GET(graphqlPath, handler, AuthMiddleware(s.config.JWTSecret))
+	s.e.POST(graphqlPath, handler, AuthMiddleware(s.config.JWTSecret))`, importPath, nil
+		}
+		return `	handler := echo.WrapHandler(graphqlHandler())
+	s.e.GET(graphqlPath, handler)
+	s.e.POST(graphqlPath, handler)`, importPath, nil
+	case FrameworkChi:
+		if auth {
+			return `	s.router.With(AuthMiddleware(s.config.JWTSecret)).Handle(graphqlPath, graphqlHandler())`, importPath, nil
+		}
+		return `	s.router.Handle(graphqlPath, graphqlHandler())`, importPath, nil
+	case FrameworkFiber:
+		importPath = "github.com/gofiber/fiber/v2/middleware/adaptor"
+		if auth {
+			return `	s.app.All(graphqlPath, AuthMiddleware(s.config.JWTSecret), adaptor.HTTPHandler(graphqlHandler()))`, importPath, nil
+		}
+		return `	s.app.All(graphqlPath, adaptor.HTTPHandler(graphqlHandler()))`, importPath, nil
+	case FrameworkStdlib:
+		if auth {
+			return `	s.mux.Handle(graphqlPath, AuthMiddleware(s.config.JWTSecret)(graphqlHandler()))`, importPath, nil
+		}
+		return `	s.mux.Handle(graphqlPath, graphqlHandler())`, importPath, nil
+	}
+	return "", "", fmt.Errorf("no GraphQL endpoint for framework %s", config.Type)
+}
+
+// graphqlGenerator collects the types and resolvers of a GraphQL schema
+type graphqlGenerator struct {
+	types   *openAPIBuilder     // resolves and names the scanned structs
+	outputs map[string]bool     // structs used as object types
+	inputs  map[string]string   // struct key -> input type name
+	scalars map[string]bool     // custom scalars the schema uses
+	names   map[string]bool     // root field names in use
+	fields  map[string][]string // object type -> "name: Type" of its fields
+	wired   bool
+	plan    *wiringPlan
+	imports map[string]bool
+}
+
+// graphqlRootField is a Query or Mutation field and the route it resolves
+type graphqlRootField struct {
+	route APIRoute
+	root  string
+	name  string
+	args  []graphqlArgument
+	typ   string
+}
+
+// graphqlArgument is an argument of a root field and the parameter of the
+// bound method it is decoded into
+type graphqlArgument struct {
+	name  string
+	typ   string
+	param *BoundParam
+}
+
+// rootField describes the field of one route. Smart mapping routes are
+// placed by their operation and other routes by their method: GET routes
+// are queries and the rest mutations.
+func (g *graphqlGenerator) rootField(route APIRoute) graphqlRootField {
+	field := graphqlRootField{route: route, root: "Mutation"}
+	operation, _ := route.Metadata["operation"].(string)
+	switch method := strings.ToUpper(route.Method); {
+	case operation != "" && operation != "custom":
+		if graphqlQueryOperations[operation] {
+			field.root = "Query"
+		}
+	case method == http.MethodGet || method == http.MethodHead:
+		field.root = "Query"
+	}
+
+	field.name = goName(route.Function, false)
+	for base, n := field.name, 2; g.names[field.name]; n++ {
+		field.name = fmt.Sprintf("%s%d", base, n)
+	}
+	g.names[field.name] = true
+
+	taken := make(map[string]bool)
+	addArgument := func(name, typ string, param *BoundParam) {
+		for base, n := name, 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		taken[name] = true
+		field.args = append(field.args, graphqlArgument{name: name, typ: typ, param: param})
+	}
+
+	if route.Binding != nil {
+		for i := range route.Binding.Params {
+			param := &route.Binding.Params[i]
+			switch param.Source {
+			case SourcePath:
+				addArgument(param.Name, g.gqlType(param.Type, route.ImportPath, false)+"!", param)
+			case SourceQuery:
+				addArgument(param.Name, g.gqlType(param.Type, route.ImportPath, false), param)
+			case SourceBody:
+				addArgument("input", g.gqlType(param.Type, route.ImportPath, true)+"!", param)
+			}
+		}
+		field.typ = "Boolean"
+		if route.Binding.Result != "" {
+			field.typ = g.gqlType(route.Binding.Result, route.ImportPath, false)
+		}
+		return field
+	}
+
+	// Unbound routes keep their path parameters so the schema stays stable
+	// once they are bound
+	for _, wildcard := range pathWildcards(templatePath(route.Path)) {
+		if graphqlName.MatchString(wildcard) {
+			addArgument(wildcard, "String!", nil)
+		}
+	}
+	field.typ = g.scalar("JSON")
+	if success := route.SuccessResponse(); success.Type != "" {
+		field.typ = g.gqlType(success.Type, route.ImportPath, false)
+	}
+	return field
+}
+
+// gqlType maps a scanned Go type to a GraphQL type the way encoding/json
+// encodes it: integers become Int, time.Time the Time scalar, []byte a
+// base64 String and slices lists. Structs become their object type, or
+// their input type for arguments; maps and types that cannot be described
+// become the JSON scalar. The outermost type is nullable.
+func (g *graphqlGenerator) gqlType(typ, importPath string, input bool) string {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return g.gqlType(typ[1:], importPath, input)
+	case typ == "[]byte" || typ == "[]uint8":
+		return "String"
+	case strings.HasPrefix(typ, "["):
+		end := strings.Index(typ, "]")
+		if end < 0 {
+			return g.scalar("JSON")
+		}
+		elem := typ[end+1:]
+		inner := g.gqlType(elem, importPath, input)
+		if graphqlNonNull(elem) {
+			inner += "!"
+		}
+		return "[" + inner + "]"
+	case strings.HasPrefix(typ, "map["):
+		return g.scalar("JSON")
+	case typ == "time.Time":
+		return g.scalar("Time")
+	}
+	if schema := builtinSchema(typ); schema != nil {
+		switch schema.Type {
+		case "string":
+			return "String"
+		case "boolean":
+			return "Boolean"
+		case "integer":
+			return "Int"
+		case "number":
+			return "Float"
+		}
+		return g.scalar("JSON")
+	}
+	key := g.types.resolve(typ, importPath)
+	if key == "" || !g.hasFields(key, make(map[string]bool)) {
+		return g.scalar("JSON")
+	}
+	if input {
+		return g.input(key)
+	}
+	g.outputs[key] = true
+	return g.types.component(key)
+}
+
+// graphqlNonNull reports whether a Go value of typ never encodes to null
+func graphqlNonNull(typ string) bool {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case strings.HasPrefix(typ, "*"), strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["):
+		return false
+	case typ == "interface{}" || typ == "any" || strings.HasSuffix(typ, "json.RawMessage"):
+		return false
+	}
+	return true
+}
+
+// scalar records the use of a custom scalar and returns its name
+func (g *graphqlGenerator) scalar(name string) string {
+	g.scalars[name] = true
+	return name
+}
+
+// input returns the input type name of a struct: its object type name
+// suffixed with Input
+func (g *graphqlGenerator) input(key string) string {
+	if name, ok := g.inputs[key]; ok {
+		return name
+	}
+	base := g.types.component(key) + "Input"
+	name := base
+	for n := 2; g.types.taken[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	g.types.taken[name] = true
+	g.inputs[key] = name
+	return name
+}
+
+// hasFields reports whether a struct encodes to an object with at least
+// one field GraphQL can name; GraphQL does not allow empty types
+func (g *graphqlGenerator) hasFields(key string, seen map[string]bool) bool {
+	seen[key] = true
+	ref := g.types.structs[key]
+	for _, field := range ref.info.Fields {
+		if promotedField(field) {
+			typ := field.Type
+			if field.QualifiedType != "" {
+				typ = field.QualifiedType
+			}
+			if embedded := g.types.resolve(strings.TrimPrefix(typ, "*"), ref.pkg.ImportPath); embedded != "" {
+				if !seen[embedded] && g.hasFields(embedded, seen) {
+					return true
+				}
+				continue
+			}
+		}
+		if name, _, skip := fieldJSON(field); !skip && graphqlName.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// graphqlTypeField is a field of an object or input type
+type graphqlTypeField struct {
+	name string
+	typ  string
+}
+
+// structFields lists the fields of a struct the way encoding/json encodes
+// it, with the fields of embedded structs promoted. Fields of the struct
+// itself take precedence, and fields whose JSON name is not a GraphQL name
+// are left out. Input fields are all optional, like those of a JSON body.
+func (g *graphqlGenerator) structFields(key string, input bool, seen map[string]bool) []graphqlTypeField {
+	seen[key] = true
+	ref := g.types.structs[key]
+	own := make(map[string]bool)
+	for _, field := range ref.info.Fields {
+		if name, _, skip := fieldJSON(field); !skip && !promotedField(field) {
+			own[name] = true
+		}
+	}
+
+	var fields []graphqlTypeField
+	added := make(map[string]bool)
+	for _, field := range ref.info.Fields {
+		typ := field.Type
+		if field.QualifiedType != "" {
+			typ = field.QualifiedType
+		}
+		if promotedField(field) {
+			if embedded := g.types.resolve(strings.TrimPrefix(typ, "*"), ref.pkg.ImportPath); embedded != "" {
+				if seen[embedded] {
+					continue
+				}
+				for _, promoted := range g.structFields(embedded, input, seen) {
+					if own[promoted.name] || added[promoted.name] {
+						continue
+					}
+					if strings.HasPrefix(typ, "*") {
+						// A nil pointer omits every promoted field
+						promoted.typ = strings.TrimSuffix(promoted.typ, "!")
+					}
+					added[promoted.name] = true
+					fields = append(fields, promoted)
+				}
+				continue
+			}
+		}
+		name, omitEmpty, skip := fieldJSON(field)
+		if skip || added[name] || !graphqlName.MatchString(name) || strings.HasPrefix(name, "__") {
+			continue
+		}
+		added[name] = true
+		fieldType := g.gqlType(typ, ref.pkg.ImportPath, input)
+		if !input && !omitEmpty && graphqlNonNull(typ) {
+			fieldType += "!"
+		}
+		fields = append(fields, graphqlTypeField{name: name, typ: fieldType})
+	}
+	return fields
+}
+
+// roots renders the Query and Mutation types. GraphQL requires a Query
+// type, so an API without queries gets a placeholder field.
+func (g *graphqlGenerator) roots(fields []graphqlRootField) string {
+	var out strings.Builder
+	for _, root := range []string{"Query", "Mutation"} {
+		var body strings.Builder
+		for _, field := range fields {
+			if field.root != root {
+				continue
+			}
+			description := field.route.Docs.Summary
+			if description == "" {
+				description = humanize(field.route.Function)
+			}
+			description += fmt.Sprintf(" (%s %s)", strings.ToUpper(field.route.Method), templatePath(field.route.Path))
+			body.WriteString(graphqlDescription(description, "  "))
+
+			var args []string
+			for _, arg := range field.args {
+				args = append(args, arg.name+": "+arg.typ)
+			}
+			signature := field.name
+			if len(args) > 0 {
+				signature += "(" + strings.Join(args, ", ") + ")"
+			}
+			body.WriteString(fmt.Sprintf("  %s: %s\n", signature, field.typ))
+		}
+		if body.Len() == 0 {
+			if root == "Mutation" {
+				continue
+			}
+			body.WriteString(graphqlDescription("Placeholder; the API has no read operations", "  "))
+			body.WriteString("  _: Boolean\n")
+		}
+		out.WriteString(fmt.Sprintf("\ntype %s {\n%s}\n", root, body.String()))
+	}
+	return out.String()
+}
+
+// objects renders an object type for every struct the root fields refer
+// to, including the structs their fields refer to
+func (g *graphqlGenerator) objects() string {
+	g.fields = make(map[string][]string)
+	rendered := make(map[string]bool)
+	var out strings.Builder
+	for {
+		var keys []string
+		for key := range g.outputs {
+			if !rendered[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return out.String()
+		}
+		sort.Slice(keys, func(i, j int) bool { return g.types.names[keys[i]] < g.types.names[keys[j]] })
+		for _, key := range keys {
+			rendered[key] = true
+			name := g.types.names[key]
+			out.WriteString("\n")
+			out.WriteString(graphqlDescription(docText(g.types.structs[key].info.Doc), ""))
+			out.WriteString(fmt.Sprintf("type %s {\n", name))
+			for _, field := range g.structFields(key, false, make(map[string]bool)) {
+				out.WriteString(fmt.Sprintf("  %s: %s\n", field.name, field.typ))
+				g.fields[name] = append(g.fields[name], fmt.Sprintf("%q: {Type: %q}", field.name, field.typ))
+			}
+			out.WriteString("}\n")
+		}
+	}
+}
+
+// inputTypes renders an input type for every struct an argument decodes
+// into, including the structs their fields refer to
+func (g *graphqlGenerator) inputTypes() string {
+	rendered := make(map[string]bool)
+	var out strings.Builder
+	for {
+		var keys []string
+		for key := range g.inputs {
+			if !rendered[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return out.String()
+		}
+		sort.Slice(keys, func(i, j int) bool { return g.inputs[keys[i]] < g.inputs[keys[j]] })
+		for _, key := range keys {
+			rendered[key] = true
+			out.WriteString("\n")
+			out.WriteString(graphqlDescription(docText(g.types.structs[key].info.Doc), ""))
+			out.WriteString(fmt.Sprintf("input %s {\n", g.inputs[key]))
+			for _, field := range g.structFields(key, true, make(map[string]bool)) {
+				out.WriteString(fmt.Sprintf("  %s: %s\n", field.name, field.typ))
+			}
+			out.WriteString("}\n")
+		}
+	}
+}
+
+// scalarTypes declares the custom scalars the schema uses
+func (g *graphqlGenerator) scalarTypes() string {
+	var out strings.Builder
+	if g.scalars["Time"] {
+		out.WriteString("\n" + graphqlDescription("An RFC 3339 date and time", "") + "scalar Time\n")
+	}
+	if g.scalars["JSON"] {
+		out.WriteString("\n" + graphqlDescription("Any JSON value", "") + "scalar JSON\n")
+	}
+	return out.String()
+}
+
+// graphqlDescription renders text as a block string description
+func graphqlDescription(text, indent string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), `"""`, `\"""`)
+	if text == "" {
+		return ""
+	}
+	if !strings.ContainsAny(text, "\n\"") {
+		return indent + `"""` + text + `"""` + "\n"
+	}
+	var out strings.Builder
+	out.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(text, "\n") {
+		out.WriteString(strings.TrimRight(indent+strings.TrimSpace(line), " ") + "\n")
+	}
+	out.WriteString(indent + `"""` + "\n")
+	return out.String()
+}
+
+// typeTable renders graphqlTypes, the types the executor checks selections
+// against, with the resolver of every root field
+func (g *graphqlGenerator) typeTable(fields []graphqlRootField) string {
+	var out strings.Builder
+	out.WriteString("// graphqlTypes lists the fields of every object type in the schema. Root\n")
+	out.WriteString("// fields call their resolver; the fields of other types are read from the\n")
+	out.WriteString("// JSON encoding of their parent.\n")
+	out.WriteString("var graphqlTypes = map[string]map[string]graphqlField{\n")
+
+	for _, root := range []string{"Query", "Mutation"} {
+		var entries []string
+		for _, field := range fields {
+			if field.root != root {
+				continue
+			}
+			var args []string
+			for _, arg := range field.args {
+				args = append(args, fmt.Sprintf("%q: %q", arg.name, arg.typ))
+			}
+			entry := fmt.Sprintf("%q: {Type: %q", field.name, field.typ)
+			if len(args) > 0 {
+				entry += fmt.Sprintf(", Args: map[string]string{%s}", strings.Join(args, ", "))
+			}
+			entries = append(entries, entry+fmt.Sprintf(", Resolve: %s}", graphqlResolverName(field.name)))
+		}
+		if len(entries) == 0 {
+			if root == "Mutation" {
+				continue
+			}
+			entries = append(entries, `"_": {Type: "Boolean"}`)
+		}
+		g.writeTypeEntries(&out, root, entries)
+	}
+
+	var names []string
+	for name := range g.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.writeTypeEntries(&out, name, g.fields[name])
+	}
+	out.WriteString("}\n\n")
+	return out.String()
+}
+
+func (g *graphqlGenerator) writeTypeEntries(out *strings.Builder, name string, entries []string) {
+	out.WriteString(fmt.Sprintf("	%q: {\n", name))
+	for _, entry := range entries {
+		out.WriteString("		" + entry + ",\n")
+	}
+	out.WriteString("	},\n")
+}
+
+// graphqlResolverName returns the name of the resolver of a root field
+func graphqlResolverName(field string) string {
+	return "resolve" + strings.ToUpper(field[:1]) + field[1:]
+}
+
+// resolver renders the resolver of a root field, which decodes the
+// arguments and calls the bound service method
+func (g *graphqlGenerator) resolver(field graphqlRootField) string {
+	name := graphqlResolverName(field.name)
+	route := field.route
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("// %s resolves %s.%s like %s %s\n", name, field.root, field.name, strings.ToUpper(route.Method), templatePath(route.Path)))
+	out.WriteString(fmt.Sprintf("func %s(ctx context.Context, args map[string]interface{}) (interface{}, error) {\n", name))
+	if route.Binding == nil || !g.wired {
+		out.WriteString(fmt.Sprintf("	// %s is not bound to a service method\n", route.Function))
+		out.WriteString(fmt.Sprintf("	return nil, errors.New(%q)\n", route.Function+" is not implemented"))
+		out.WriteString("}\n\n")
+		return out.String()
+	}
+
+	binding := route.Binding
+	argNames := make(map[*BoundParam]string)
+	for _, arg := range field.args {
+		argNames[arg.param] = arg.name
+	}
+	var callArgs []string
+	for i := range binding.Params {
+		param := &binding.Params[i]
+		if param.Source == SourceContext {
+			callArgs = append(callArgs, "ctx")
+			continue
+		}
+		for _, path := range qualifiedTypeImports(param.Type) {
+			g.imports[path] = true
+		}
+		variable := param.Name + "Arg"
+		out.WriteString(fmt.Sprintf("	var %s %s\n", variable, renderQualifiedType(strings.TrimPrefix(param.Type, "*"), g.plan.aliases)))
+		out.WriteString(fmt.Sprintf("	if err := graphqlArg(args, %q, &%s); err != nil {\n", argNames[param], variable))
+		out.WriteString("		return nil, err\n")
+		out.WriteString("	}\n")
+		if strings.HasPrefix(param.Type, "*") {
+			variable = "&" + variable
+		}
+		callArgs = append(callArgs, variable)
+	}
+
+	callee := g.plan.aliases[binding.ImportPath] + "." + binding.Function
+	if binding.Receiver != "" {
+		callee = "services." + g.plan.fields[binding.ImportPath+"."+binding.Receiver] + "." + binding.Function
+	} else {
+		g.imports[binding.ImportPath] = true
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(callArgs, ", "))
+
+	switch {
+	case binding.Result != "" && binding.ReturnsError:
+		out.WriteString(fmt.Sprintf("	return %s\n", call))
+	case binding.Result != "":
+		out.WriteString(fmt.Sprintf("	return %s, nil\n", call))
+	case binding.ReturnsError:
+		out.WriteString(fmt.Sprintf("	if err := %s; err != nil {\n", call))
+		out.WriteString("		return nil, err\n")
+		out.WriteString("	}\n")
+		out.WriteString("	return true, nil\n")
+	default:
+		out.WriteString(fmt.Sprintf("	%s\n", call))
+		out.WriteString("	return true, nil\n")
+	}
+	out.WriteString("}\n\n")
+	return out.String()
+}
+
+// graphqlRuntime is the request handler and executor of the generated
+// endpoint. It supports queries and mutations with arguments, variables,
+// aliases, fragments and the @skip and @include directives; introspection
+// is not supported, and GET without a query returns the schema instead.
+const graphqlRuntime = `// graphqlResolver resolves a root field by calling a service method
+type graphqlResolver func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// graphqlField is a field of an object type: its type in schema notation,
+// the types of its arguments and, for root fields, its resolver
+type graphqlField struct {
+	Type    string
+	Args    map[string]string
+	Resolve graphqlResolver
+}
+
+// graphqlRequest is a GraphQL request sent as a JSON body or as query
+// parameters
+type graphqlRequest struct {
+	Query         string                 ` + "`json:\"query\"`" + `
+	OperationName string                 ` + "`json:\"operationName\"`" + `
+	Variables     map[string]interface{} ` + "`json:\"variables\"`" + `
+}
+
+// graphqlResponse is the result of a request. Data is nil when the request
+// failed before execution.
+type graphqlResponse struct {
+	Data   interface{}    ` + "`json:\"data,omitempty\"`" + `
+	Errors []graphqlError ` + "`json:\"errors,omitempty\"`" + `
+}
+
+// graphqlError reports a failed request or field. Service errors that
+// implement StatusCode() int carry the status in their extensions.
+type graphqlError struct {
+	Message    string                 ` + "`json:\"message\"`" + `
+	Path       []interface{}          ` + "`json:\"path,omitempty\"`" + `
+	Extensions map[string]interface{} ` + "`json:\"extensions,omitempty\"`" + `
+}
+
+// graphqlHandler serves POST requests with a JSON body and GET requests with
+// query parameters. Mutations must use POST; a GET without a query returns
+// the schema.
+func graphqlHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphqlRequest
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			if query.Get("query") == "" {
+				schema, err := graphqlFiles.ReadFile("schema.graphql")
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Write(schema)
+				return
+			}
+			request.Query = query.Get("query")
+			request.OperationName = query.Get("operationName")
+			if variables := query.Get("variables"); variables != "" {
+				if err := graphqlDecode(strings.NewReader(variables), &request.Variables); err != nil {
+					writeGraphQLResponse(w, http.StatusBadRequest, graphqlFailure("invalid variables: "+err.Error()))
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := graphqlDecode(r.Body, &request); err != nil {
+				writeGraphQLResponse(w, http.StatusBadRequest, graphqlFailure("invalid request body: "+err.Error()))
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeGraphQLResponse(w, http.StatusMethodNotAllowed, graphqlFailure("GraphQL requests must use GET or POST"))
+			return
+		}
+
+		response := executeGraphQL(r.Context(), request, r.Method == http.MethodGet)
+		status := http.StatusOK
+		if response.Data == nil {
+			status = http.StatusBadRequest
+		}
+		writeGraphQLResponse(w, status, response)
+	})
+}
+
+func writeGraphQLResponse(w http.ResponseWriter, status int, response graphqlResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func graphqlFailure(message string) graphqlResponse {
+	return graphqlResponse{Errors: []graphqlError{{Message: message}}}
+}
+
+// graphqlDecode decodes JSON keeping numbers as json.Number
+func graphqlDecode(r io.Reader, target interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// graphqlArg decodes the argument name into target by way of its JSON
+// encoding, leaving target unchanged when the argument is absent or null
+func graphqlArg(args map[string]interface{}, name string, target interface{}) error {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, target)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for argument %q: %v", name, err)
+	}
+	return nil
+}
+
+// executeGraphQL parses, validates and executes a request. readOnly
+// requests may not run mutations.
+func executeGraphQL(ctx context.Context, request graphqlRequest, readOnly bool) graphqlResponse {
+	document, err := parseGraphQL(request.Query)
+	if err != nil {
+		return graphqlFailure(err.Error())
+	}
+	operation, err := document.operation(request.OperationName)
+	if err != nil {
+		return graphqlFailure(err.Error())
+	}
+
+	root := "Query"
+	switch operation.kind {
+	case "mutation":
+		if readOnly {
+			return graphqlFailure("mutations must be sent with POST")
+		}
+		if graphqlTypes["Mutation"] == nil {
+			return graphqlFailure("the schema has no mutations")
+		}
+		root = "Mutation"
+	case "subscription":
+		return graphqlFailure("subscriptions are not supported")
+	}
+
+	e := &graphqlExecutor{ctx: ctx, document: document, declared: make(map[string]bool)}
+	if e.variables, err = operation.coerceVariables(request.Variables); err != nil {
+		return graphqlFailure(err.Error())
+	}
+	for _, variable := range operation.variables {
+		e.declared[variable.name] = true
+	}
+	if err := e.validate(root, operation.selections); err != nil {
+		return graphqlFailure(err.Error())
+	}
+
+	data := e.object(root, nil, operation.selections, nil)
+	return graphqlResponse{Data: data, Errors: e.errors}
+}
+
+// graphqlExecutor executes one operation, collecting field errors
+type graphqlExecutor struct {
+	ctx       context.Context
+	document  *graphqlDocument
+	variables map[string]interface{}
+	declared  map[string]bool
+	errors    []graphqlError
+}
+
+// validate checks that every selected field exists with known and
+// required arguments, and that objects and only objects have subfields
+func (e *graphqlExecutor) validate(typeName string, selections []graphqlSelection) error {
+	fields, err := e.collect(typeName, selections)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.name == "__typename" {
+			if len(field.selections) > 0 {
+				return fmt.Errorf("field \"__typename\" has no subfields")
+			}
+			continue
+		}
+		if strings.HasPrefix(field.name, "__") {
+			return fmt.Errorf("introspection is not supported; GET %s returns the schema", graphqlPath)
+		}
+		definition, ok := graphqlTypes[typeName][field.name]
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %q", field.name, typeName)
+		}
+		for name := range field.arguments {
+			if _, ok := definition.Args[name]; !ok {
+				return fmt.Errorf("unknown argument %q on field %q", name, field.name)
+			}
+		}
+		for name, typ := range definition.Args {
+			if _, ok := field.arguments[name]; !ok && strings.HasSuffix(typ, "!") {
+				return fmt.Errorf("field %q requires argument %q of type %s", field.name, name, typ)
+			}
+		}
+
+		named := strings.Trim(definition.Type, "[]!")
+		_, object := graphqlTypes[named]
+		switch {
+		case object && len(field.selections) == 0:
+			return fmt.Errorf("field %q of type %s must select subfields", field.name, definition.Type)
+		case !object && len(field.selections) > 0:
+			return fmt.Errorf("field %q of type %s has no subfields", field.name, definition.Type)
+		case object:
+			if err := e.validate(named, field.selections); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collect flattens the fields selected on typeName, following fragments and
+// applying @skip and @include, and merges fields with the same response key
+func (e *graphqlExecutor) collect(typeName string, selections []graphqlSelection) ([]graphqlSelection, error) {
+	var fields []graphqlSelection
+	index := make(map[string]int)
+	visited := make(map[string]bool)
+
+	var walk func([]graphqlSelection) error
+	walk = func(selections []graphqlSelection) error {
+		for _, selection := range selections {
+			include, err := e.included(selection.directives)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+			switch {
+			case selection.spread != "":
+				if visited[selection.spread] {
+					continue
+				}
+				visited[selection.spread] = true
+				fragment, ok := e.document.fragments[selection.spread]
+				if !ok {
+					return fmt.Errorf("unknown fragment %q", selection.spread)
+				}
+				if fragment.on == typeName {
+					if err := walk(fragment.selections); err != nil {
+						return err
+					}
+				}
+			case selection.name == "":
+				if selection.on == "" || selection.on == typeName {
+					if err := walk(selection.selections); err != nil {
+						return err
+					}
+				}
+			default:
+				key := selection.responseKey()
+				i, ok := index[key]
+				if !ok {
+					index[key] = len(fields)
+					fields = append(fields, selection)
+					continue
+				}
+				if fields[i].name != selection.name {
+					return fmt.Errorf("fields %q and %q conflict because they both respond as %q", fields[i].name, selection.name, key)
+				}
+				merged := append([]graphqlSelection(nil), fields[i].selections...)
+				fields[i].selections = append(merged, selection.selections...)
+			}
+		}
+		return nil
+	}
+	return fields, walk(selections)
+}
+
+// included applies the @skip and @include directives
+func (e *graphqlExecutor) included(directives []graphqlDirective) (bool, error) {
+	for _, directive := range directives {
+		if directive.name != "skip" && directive.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", directive.name)
+		}
+		value, err := e.value(directive.arguments["if"])
+		if err != nil {
+			return false, err
+		}
+		condition, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a Boolean argument \"if\"", directive.name)
+		}
+		if condition == (directive.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// object executes a selection on an object. Root fields call their
+// resolver; other fields are read from value, the object's JSON encoding.
+func (e *graphqlExecutor) object(typeName string, value map[string]interface{}, selections []graphqlSelection, path []interface{}) graphqlObject {
+	fields, _ := e.collect(typeName, selections)
+	object := make(graphqlObject, 0, len(fields))
+	for _, field := range fields {
+		key := field.responseKey()
+		fieldPath := append(append([]interface{}(nil), path...), key)
+		if field.name == "__typename" {
+			object = append(object, graphqlEntry{key: key, value: typeName})
+			continue
+		}
+
+		definition := graphqlTypes[typeName][field.name]
+		result := value[field.name]
+		if definition.Resolve != nil {
+			resolved, err := e.resolve(definition, field)
+			if err != nil {
+				e.errors = append(e.errors, graphqlFieldError(err, fieldPath))
+				object = append(object, graphqlEntry{key: key})
+				continue
+			}
+			result = resolved
+		}
+		object = append(object, graphqlEntry{key: key, value: e.complete(definition.Type, result, field.selections, fieldPath)})
+	}
+	return object
+}
+
+// resolve calls the resolver of a root field and returns the generic form
+// of the result's JSON encoding
+func (e *graphqlExecutor) resolve(definition graphqlField, field graphqlSelection) (interface{}, error) {
+	args := make(map[string]interface{})
+	for name, literal := range field.arguments {
+		value, err := e.value(literal)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil && strings.HasSuffix(definition.Args[name], "!") {
+			return nil, fmt.Errorf("argument %q of type %s must not be null", name, definition.Args[name])
+		}
+		args[name] = value
+	}
+
+	result, err := definition.Resolve(e.ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var plain interface{}
+	return plain, graphqlDecode(bytes.NewReader(data), &plain)
+}
+
+// complete applies the selection of a field to its value
+func (e *graphqlExecutor) complete(typ string, value interface{}, selections []graphqlSelection, path []interface{}) interface{} {
+	typ = strings.TrimSuffix(typ, "!")
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		if !strings.HasPrefix(typ, "[") {
+			return value
+		}
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = e.complete(typ[1:len(typ)-1], item, selections, append(append([]interface{}(nil), path...), i))
+		}
+		return list
+	case map[string]interface{}:
+		if _, object := graphqlTypes[typ]; object {
+			return e.object(typ, value, selections, path)
+		}
+	}
+	return value
+}
+
+// value resolves the variables in an argument value
+func (e *graphqlExecutor) value(literal interface{}) (interface{}, error) {
+	switch literal := literal.(type) {
+	case graphqlVariableRef:
+		if !e.declared[string(literal)] {
+			return nil, fmt.Errorf("variable $%s is not defined", literal)
+		}
+		return e.variables[string(literal)], nil
+	case []interface{}:
+		list := make([]interface{}, len(literal))
+		for i, item := range literal {
+			value, err := e.value(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(literal))
+		for name, item := range literal {
+			value, err := e.value(item)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = value
+		}
+		return object, nil
+	}
+	return literal, nil
+}
+
+func graphqlFieldError(err error, path []interface{}) graphqlError {
+	fieldError := graphqlError{Message: err.Error(), Path: path}
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) {
+		fieldError.Extensions = map[string]interface{}{"status": coder.StatusCode()}
+	}
+	return fieldError
+}
+
+// graphqlObject is a response object that keeps the order of its selection
+type graphqlObject []graphqlEntry
+
+type graphqlEntry struct {
+	key   string
+	value interface{}
+}
+
+func (o graphqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, entry := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(entry.key)
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// GraphQL documents
+
+type graphqlDocument struct {
+	operations []*graphqlOperation
+	fragments  map[string]*graphqlFragment
+}
+
+type graphqlOperation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	variables  []graphqlVariable
+	selections []graphqlSelection
+}
+
+type graphqlVariable struct {
+	name       string
+	typ        string
+	value      interface{}
+	hasDefault bool
+}
+
+type graphqlFragment struct {
+	on         string
+	selections []graphqlSelection
+}
+
+// graphqlSelection is a field, a fragment spread or, when it has neither a
+// name nor a spread, an inline fragment
+type graphqlSelection struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	directives []graphqlDirective
+	selections []graphqlSelection
+	spread     string
+	on         string
+}
+
+func (s graphqlSelection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type graphqlDirective struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// graphqlVariableRef is a variable used as an argument value
+type graphqlVariableRef string
+
+// operation picks the operation to execute
+func (d *graphqlDocument) operation(name string) (*graphqlOperation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, errors.New("operationName is required for a document with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, operation := range d.operations {
+		if operation.name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables applies defaults and checks required variables
+func (o *graphqlOperation) coerceVariables(provided map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, variable := range o.variables {
+		value, ok := provided[variable.name]
+		if !ok && variable.hasDefault {
+			value, ok = variable.value, true
+		}
+		if value == nil && strings.HasSuffix(variable.typ, "!") {
+			return nil, fmt.Errorf("variable $%s of type %s is required", variable.name, variable.typ)
+		}
+		if ok {
+			values[variable.name] = value
+		}
+	}
+	return values, nil
+}
+
+// Token kinds
+const (
+	graphqlEOF = iota
+	graphqlPunct
+	graphqlNameToken
+	graphqlNumber
+	graphqlString
+)
+
+type graphqlToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// graphqlParser is a recursive descent parser of executable documents. It
+// panics with a graphqlSyntaxError, which parseGraphQL recovers.
+type graphqlParser struct {
+	src   string
+	pos   int
+	token graphqlToken
+}
+
+type graphqlSyntaxError struct{ message string }
+
+// parseGraphQL parses a document of operations and fragments
+func parseGraphQL(source string) (document *graphqlDocument, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxError, ok := r.(graphqlSyntaxError)
+			if !ok {
+				panic(r)
+			}
+			document, err = nil, errors.New(syntaxError.message)
+		}
+	}()
+
+	p := &graphqlParser{src: source}
+	p.lex()
+	document = &graphqlDocument{fragments: make(map[string]*graphqlFragment)}
+	for p.token.kind != graphqlEOF {
+		switch {
+		case p.is("{"):
+			document.operations = append(document.operations, &graphqlOperation{kind: "query", selections: p.selectionSet()})
+		case p.token.kind == graphqlNameToken && (p.token.value == "query" || p.token.value == "mutation" || p.token.value == "subscription"):
+			document.operations = append(document.operations, p.operation())
+		case p.token.kind == graphqlNameToken && p.token.value == "fragment":
+			p.lex()
+			name := p.name()
+			if _, exists := document.fragments[name]; exists || name == "on" {
+				p.fail("invalid or repeated fragment name %q", name)
+			}
+			p.keyword("on")
+			fragment := &graphqlFragment{on: p.name()}
+			p.directives()
+			fragment.selections = p.selectionSet()
+			document.fragments[name] = fragment
+		default:
+			p.fail("unexpected %s", p.describe())
+		}
+	}
+	if len(document.operations) == 0 {
+		return nil, errors.New("the document contains no operation")
+	}
+	return document, nil
+}
+
+func (p *graphqlParser) fail(format string, args ...interface{}) {
+	line, column := 1, 1
+	for _, c := range p.src[:p.token.pos] {
+		if c == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	panic(graphqlSyntaxError{fmt.Sprintf("syntax error at %d:%d: ", line, column) + fmt.Sprintf(format, args...)})
+}
+
+func (p *graphqlParser) describe() string {
+	if p.token.kind == graphqlEOF {
+		return "end of document"
+	}
+	return strconv.Quote(p.token.value)
+}
+
+func (p *graphqlParser) is(punct string) bool {
+	return p.token.kind == graphqlPunct && p.token.value == punct
+}
+
+func (p *graphqlParser) skip(punct string) bool {
+	if p.is(punct) {
+		p.lex()
+		return true
+	}
+	return false
+}
+
+func (p *graphqlParser) expect(punct string) {
+	if !p.skip(punct) {
+		p.fail("expected %q, found %s", punct, p.describe())
+	}
+}
+
+func (p *graphqlParser) name() string {
+	if p.token.kind != graphqlNameToken {
+		p.fail("expected a name, found %s", p.describe())
+	}
+	name := p.token.value
+	p.lex()
+	return name
+}
+
+func (p *graphqlParser) keyword(word string) {
+	if p.token.kind != graphqlNameToken || p.token.value != word {
+		p.fail("expected %q, found %s", word, p.describe())
+	}
+	p.lex()
+}
+
+func (p *graphqlParser) operation() *graphqlOperation {
+	operation := &graphqlOperation{kind: p.name()}
+	if p.token.kind == graphqlNameToken {
+		operation.name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			variable := graphqlVariable{name: p.name()}
+			p.expect(":")
+			variable.typ = p.typeRef()
+			if p.skip("=") {
+				variable.value, variable.hasDefault = p.value(true), true
+			}
+			p.directives()
+			operation.variables = append(operation.variables, variable)
+		}
+	}
+	p.directives()
+	operation.selections = p.selectionSet()
+	return operation
+}
+
+func (p *graphqlParser) typeRef() string {
+	typ := ""
+	if p.skip("[") {
+		typ = "[" + p.typeRef() + "]"
+		p.expect("]")
+	} else {
+		typ = p.name()
+	}
+	if p.skip("!") {
+		typ += "!"
+	}
+	return typ
+}
+
+func (p *graphqlParser) selectionSet() []graphqlSelection {
+	p.expect("{")
+	var selections []graphqlSelection
+	for !p.skip("}") {
+		selections = append(selections, p.selection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *graphqlParser) selection() graphqlSelection {
+	if p.skip("...") {
+		if p.token.kind == graphqlNameToken && p.token.value != "on" {
+			return graphqlSelection{spread: p.name(), directives: p.directives()}
+		}
+		var fragment graphqlSelection
+		if p.token.kind == graphqlNameToken {
+			p.lex()
+			fragment.on = p.name()
+		}
+		fragment.directives = p.directives()
+		fragment.selections = p.selectionSet()
+		return fragment
+	}
+
+	field := graphqlSelection{name: p.name()}
+	if p.skip(":") {
+		field.alias, field.name = field.name, p.name()
+	}
+	field.arguments = p.arguments()
+	field.directives = p.directives()
+	if p.is("{") {
+		field.selections = p.selectionSet()
+	}
+	return field
+}
+
+func (p *graphqlParser) arguments() map[string]interface{} {
+	arguments := make(map[string]interface{})
+	if !p.skip("(") {
+		return arguments
+	}
+	for !p.skip(")") {
+		name := p.name()
+		if _, exists := arguments[name]; exists {
+			p.fail("argument %q is repeated", name)
+		}
+		p.expect(":")
+		arguments[name] = p.value(false)
+	}
+	return arguments
+}
+
+func (p *graphqlParser) directives() []graphqlDirective {
+	var directives []graphqlDirective
+	for p.skip("@") {
+		directives = append(directives, graphqlDirective{name: p.name(), arguments: p.arguments()})
+	}
+	return directives
+}
+
+// value parses a value: variables become graphqlVariableRef, numbers
+// json.Number, enum values strings and objects maps. constant values, such
+// as variable defaults, may not use variables.
+func (p *graphqlParser) value(constant bool) interface{} {
+	token := p.token
+	switch token.kind {
+	case graphqlPunct:
+		switch {
+		case p.skip("$"):
+			if constant {
+				p.fail("variables are not allowed here")
+			}
+			return graphqlVariableRef(p.name())
+		case p.skip("["):
+			list := []interface{}{}
+			for !p.skip("]") {
+				list = append(list, p.value(constant))
+			}
+			return list
+		case p.skip("{"):
+			object := make(map[string]interface{})
+			for !p.skip("}") {
+				name := p.name()
+				p.expect(":")
+				object[name] = p.value(constant)
+			}
+			return object
+		}
+	case graphqlNumber:
+		p.lex()
+		return json.Number(token.value)
+	case graphqlString:
+		p.lex()
+		return token.value
+	case graphqlNameToken:
+		p.lex()
+		switch token.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return token.value
+	}
+	p.fail("unexpected %s", p.describe())
+	return nil
+}
+
+// lex reads the next token, skipping whitespace, commas and comments
+func (p *graphqlParser) lex() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+			continue
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		case strings.HasPrefix(p.src[p.pos:], "\ufeff"):
+			p.pos += len("\ufeff")
+			continue
+		}
+		break
+	}
+
+	start := p.pos
+	p.token = graphqlToken{pos: start}
+	if p.pos >= len(p.src) {
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.token.kind, p.token.value = graphqlPunct, "..."
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		p.pos++
+		p.token.kind, p.token.value = graphqlPunct, string(c)
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		for p.pos < len(p.src) && graphqlNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.token.kind, p.token.value = graphqlNameToken, p.src[start:p.pos]
+	case c == '-' || c >= '0' && c <= '9':
+		p.pos++
+		for p.pos < len(p.src) && (graphqlNameChar(p.src[p.pos]) || p.src[p.pos] == '.' ||
+			(p.src[p.pos] == '+' || p.src[p.pos] == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		p.token.kind, p.token.value = graphqlNumber, p.src[start:p.pos]
+		if _, err := strconv.ParseFloat(p.token.value, 64); err != nil || strings.Trim(p.token.value, "0123456789.eE+-") != "" {
+			p.fail("invalid number %s", p.token.value)
+		}
+	case strings.HasPrefix(p.src[p.pos:], "\"\"\""):
+		p.token.kind, p.token.value = graphqlString, p.blockString()
+	case c == '"':
+		p.token.kind, p.token.value = graphqlString, p.stringValue()
+	default:
+		p.fail("unexpected character %q", c)
+	}
+}
+
+func graphqlNameChar(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// stringValue reads a quoted string with its escape sequences
+func (p *graphqlParser) stringValue() string {
+	var b strings.Builder
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			p.fail("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return b.String()
+		case c != '\\':
+			b.WriteByte(c)
+		case p.pos >= len(p.src):
+			p.fail("unterminated string")
+		default:
+			escape := p.src[p.pos]
+			p.pos++
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r := p.hexRune()
+				if utf16.IsSurrogate(r) && strings.HasPrefix(p.src[p.pos:], "\\u") {
+					p.pos += 2
+					r = utf16.DecodeRune(r, p.hexRune())
+				}
+				b.WriteRune(r)
+			default:
+				p.fail("invalid escape sequence \\%c", escape)
+			}
+		}
+	}
+}
+
+func (p *graphqlParser) hexRune() rune {
+	if p.pos+4 > len(p.src) {
+		p.fail("invalid unicode escape")
+	}
+	code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		p.fail("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(code)
+}
+
+// blockString reads a """ string, removing its common indentation and
+// its leading and trailing blank lines
+func (p *graphqlParser) blockString() string {
+	p.pos += 3
+	end := p.pos
+	for {
+		next := strings.Index(p.src[end:], "\"\"\"")
+		if next < 0 {
+			p.fail("unterminated block string")
+		}
+		end += next
+		if end > 0 && p.src[end-1] == '\\' {
+			end += 3
+			continue
+		}
+		break
+	}
+	raw := strings.ReplaceAll(p.src[p.pos:end], "\\\"\"\"", "\"\"\"")
+	p.pos = end + 3
+
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (common < 0 || len(line)-len(trimmed) < common) {
+			common = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && common > 0; i++ {
+		if len(lines[i]) >= common {
+			lines[i] = lines[i][common:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+`
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CaseStyle selects how the words of a multi-word name are joined in a path
+type CaseStyle string
+
+const (
+	CaseKebab      CaseStyle = "kebab" // order-items
+	CaseSnake      CaseStyle = "snake" // order_items
+	CaseLowerCamel CaseStyle = "camel" // orderItems
+)
+
+// NamingConfig customises how struct and method names become path segments
+type NamingConfig struct {
+	Style         CaseStyle         `json:"style,omitempty" yaml:"style,omitempty"`
+	Irregular     map[string]string `json:"irregular,omitempty" yaml:"irregular,omitempty"`           // singular -> plural, merged with the defaults
+	Uncountable   []string          `json:"uncountable,omitempty" yaml:"uncountable,omitempty"`       // words with no plural form
+	Resources     map[string]string `json:"resources,omitempty" yaml:"resources,omitempty"`           // struct name -> path segment
+	StripSuffixes []string          `json:"strip_suffixes,omitempty" yaml:"strip_suffixes,omitempty"` // replaces the default suffixes when set
+}
+
+// Inflector turns Go identifiers into resource path segments, for example
+// "OrderItemService" into "order-items"
+type Inflector struct {
+	style         CaseStyle
+	irregular     map[string]string
+	uncountable   map[string]bool
+	resources     map[string]string
+	stripSuffixes []string
+}
+
+// defaultIrregularPlurals lists plurals the suffix rules get wrong
+var defaultIrregularPlurals = map[string]string{
+	"person": "people", "man": "men", "woman": "women", "child": "children",
+	"mouse": "mice", "goose": "geese", "foot": "feet", "tooth": "teeth", "ox": "oxen",
+	"datum": "data", "medium": "media", "criterion": "criteria", "phenomenon": "phenomena",
+	"index": "indexes", "matrix": "matrices", "vertex": "vertices",
+	"leaf": "leaves", "life": "lives", "wife": "wives", "knife": "knives",
+	"half": "halves", "shelf": "shelves", "wolf": "wolves", "thief": "thieves",
+	"hero": "heroes", "potato": "potatoes", "tomato": "tomatoes", "echo": "echoes",
+	"quiz": "quizzes", "analysis": "analyses", "axis": "axes", "basis": "bases",
+	"crisis": "crises", "thesis": "theses", "diagnosis": "diagnoses",
+	"hypothesis": "hypotheses", "synopsis": "synopses", "ellipsis": "ellipses",
+	"parenthesis": "parentheses", "synthesis": "syntheses", "oasis": "oases",
+}
+
+// defaultUncountable lists words that are the same in singular and plural
+var defaultUncountable = []string{
+	"equipment", "information", "money", "news", "series", "species", "sheep",
+	"fish", "deer", "metadata", "feedback", "software", "hardware", "staff",
+	"settings", "analytics", "auth",
+}
+
+// defaultStripSuffixes are role suffixes that do not name the resource,
+// so that "UserService" serves /users
+var defaultStripSuffixes = []string{"Service", "Controller", "Handler"}
+
+// NewInflector creates an inflector from config, which may be nil
+func NewInflector(config *NamingConfig) *Inflector {
+	in := &Inflector{
+		style:         CaseKebab,
+		irregular:     make(map[string]string),
+		uncountable:   make(map[string]bool),
+		resources:     make(map[string]string),
+		stripSuffixes: defaultStripSuffixes,
+	}
+	for singular, plural := range defaultIrregularPlurals {
+		in.irregular[singular] = plural
+	}
+	for _, word := range defaultUncountable {
+		in.uncountable[word] = true
+	}
+
+	if config == nil {
+		return in
+	}
+	if config.Style != "" {
+		in.style = config.Style
+	}
+	for singular, plural := range config.Irregular {
+		in.irregular[strings.ToLower(singular)] = strings.ToLower(plural)
+	}
+	for _, word := range config.Uncountable {
+		in.uncountable[strings.ToLower(word)] = true
+	}
+	for name, segment := range config.Resources {
+		in.resources[name] = segment
+	}
+	if config.StripSuffixes != nil {
+		in.stripSuffixes = config.StripSuffixes
+	}
+	return in
+}
+
+// Pluralize returns the plural of a lower-case English noun
+func (in *Inflector) Pluralize(word string) string {
+	if word == "" || in.uncountable[word] {
+		return word
+	}
+	if plural, ok := in.irregular[word]; ok {
+		return plural
+	}
+	for _, plural := range in.irregular {
+		if plural == word {
+			return word
+		}
+	}
+
+	if isPlural(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es" // address -> addresses, status -> statuses
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return strings.TrimSuffix(word, "y") + "ies" // category -> categories
+	}
+	return word + "s"
+}
+
+// isPlural reports whether a word already has a regular plural ending:
+// "ies", "es" after a sibilant, or "s" after a consonant that takes a
+// plain "s", as in "categories", "boxes" and "users"
+func isPlural(word string) bool {
+	for _, suffix := range []string{"ies", "ses", "xes", "zes", "ches", "shes"} {
+		if strings.HasSuffix(word, suffix) && len(word) > len(suffix) {
+			return true
+		}
+	}
+	if len(word) < 3 || !strings.HasSuffix(word, "s") {
+		return false
+	}
+	before := word[len(word)-2]
+	return !strings.ContainsRune("aeiousxz", rune(before))
+}
+
+// Words splits a Go identifier into lower-case words. Runs of capitals are
+// kept together as acronyms, so "HTTPServer" gives "http", "server".
+func (in *Inflector) Words(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		boundary := i == len(runes)
+		if !boundary {
+			prev, cur := runes[i-1], runes[i]
+			switch {
+			case cur == '_' || cur == '-' || cur == ' ':
+				boundary = true
+			case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+				boundary = true
+			case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+				boundary = true
+			}
+		}
+		if !boundary {
+			continue
+		}
+		word := strings.Trim(string(runes[start:i]), "_- ")
+		if word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+		start = i
+	}
+	return words
+}
+
+// Join combines lower-case words in the configured case style
+func (in *Inflector) Join(words []string) string {
+	switch in.style {
+	case CaseSnake:
+		return strings.Join(words, "_")
+	case CaseLowerCamel:
+		var b strings.Builder
+		for i, word := range words {
+			if i > 0 && word != "" {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+			b.WriteString(word)
+		}
+		return b.String()
+	default:
+		return strings.Join(words, "-")
+	}
+}
+
+// Segment converts an identifier into a path segment without pluralizing,
+// as used for fields in "/users/by/email-address"
+func (in *Inflector) Segment(name string) string {
+	return in.Join(in.Words(name))
+}
+
+// RoleSuffix returns the role suffix a struct name ends in, such as
+// "Service" for "UserService", or "" if it has none
+func (in *Inflector) RoleSuffix(structName string) string {
+	for _, suffix := range in.stripSuffixes {
+		if strings.HasSuffix(structName, suffix) && len(structName) > len(suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// Resource returns the plural path segment for a struct: the configured
+// override if there is one, otherwise the name without role suffixes with
+// its last word pluralized
+func (in *Inflector) Resource(structName string) string {
+	if segment, ok := in.resources[structName]; ok {
+		return segment
+	}
+
+	name := strings.TrimSuffix(structName, in.RoleSuffix(structName))
+	words := in.Words(name)
+	if len(words) == 0 {
+		return strings.ToLower(structName)
+	}
+	words[len(words)-1] = in.Pluralize(words[len(words)-1])
+	return in.Join(words)
+}
+
+// resourceName returns the path segment of a struct, honouring an
+// @api.resource annotation on it before the inflector's rules
+func (ag *APIGenerator) resourceName(structInfo StructInfo) string {
+	for _, annotation := range structInfo.Annotations {
+		if annotation.Key == "resource" && annotation.Value != "" {
+			return strings.Trim(annotation.Value, "/")
+		}
+	}
+	return ag.inflector.Resource(structInfo.Name)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// loadedPackage is a parsed and type-checked Go package ready for extraction
+type loadedPackage struct {
+	Dir        string
+	Name       string
+	ImportPath string
+	Module     *ModuleInfo
+	Files      []*ast.File
+	Types      *types.Package
+	Info       *types.Info
+	Errors     []error
+}
+
+// newImporter returns the importer used to resolve dependencies of scanned
+// packages. It type-checks imports from source so that scanning works
+// without compiled export data and without network access. Imports are
+// located by the go command run in dir, which must lie inside a module for
+// that module's own packages and requirements to resolve; an empty dir
+// uses the working directory.
+func newImporter(fset *token.FileSet, dir string) types.Importer {
+	ctxt := build.Default
+	ctxt.Dir = dir
+	// Cgo files would need the cgo tool; their pure Go fallbacks declare
+	// the same API
+	ctxt.CgoEnabled = false
+	return &sourceImporter{
+		ctxt:     &ctxt,
+		fset:     fset,
+		sizes:    types.SizesFor("gc", ctxt.GOARCH),
+		packages: make(map[string]*types.Package),
+	}
+}
+
+// sourceImporter is the source importer of go/importer with a build context
+// of its own, so that importers of different modules can look up packages
+// from their own directories without sharing build.Default
+type sourceImporter struct {
+	ctxt     *build.Context
+	fset     *token.FileSet
+	sizes    types.Sizes
+	packages map[string]*types.Package
+}
+
+// importing marks a package whose import is in progress, to detect cycles
+var importing types.Package
+
+func (imp *sourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, ".", 0)
+}
+
+func (imp *sourceImporter) ImportFrom(path, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	if abs, err := filepath.Abs(srcDir); err == nil {
+		srcDir = abs
+	}
+	bp, err := imp.ctxt.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if bp.ImportPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	if pkg, exists := imp.packages[bp.ImportPath]; exists {
+		if pkg == &importing {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	imp.packages[bp.ImportPath] = &importing
+	defer func() {
+		if imp.packages[bp.ImportPath] == &importing {
+			delete(imp.packages, bp.ImportPath)
+		}
+	}()
+
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(imp.fset, filepath.Join(bp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// Only the exported API is needed, so errors in function bodies do not
+	// matter; any other error leaves the package unsafe to use
+	var firstErr error
+	conf := types.Config{
+		IgnoreFuncBodies: true,
+		Importer:         imp,
+		Sizes:            imp.sizes,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); firstErr == nil && (!ok || !typeErr.Soft) {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, imp.fset, files, nil)
+	if firstErr != nil {
+		return nil, fmt.Errorf("type-checking package %q failed (%v)", bp.ImportPath, firstErr)
+	}
+
+	imp.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// loadPackages parses the given files of one directory and type-checks them
+// as whole packages. Files are grouped by their package clause so that a
+// directory holding, for example, both "foo" and "foo_test" yields two packages.
+func (ag *APIGenerator) loadPackages(dir string, files []string) []*loadedPackage {
+	sort.Strings(files)
+
+	byName := make(map[string]*loadedPackage)
+	var order []string
+
+	for _, filePath := range files {
+		file, err := parser.ParseFile(ag.fset, filePath, nil, parser.ParseComments)
+		if err != nil {
+			ag.reportParseError(filePath, err)
+			continue
+		}
+
+		name := file.Name.Name
+		lp, exists := byName[name]
+		if !exists {
+			lp = &loadedPackage{
+				Dir:  dir,
+				Name: name,
+			}
+			lp.ImportPath, lp.Module = ag.importPathFor(dir, name)
+			byName[name] = lp
+			order = append(order, name)
+		}
+		lp.Files = append(lp.Files, file)
+	}
+
+	var loaded []*loadedPackage
+	for _, name := range order {
+		lp := byName[name]
+		ag.typeCheck(lp)
+		loaded = append(loaded, lp)
+	}
+
+	return loaded
+}
+
+// importerFor returns the importer for packages of mod. Each module gets its
+// own importer so that dependencies are cached per module and resolved at
+// the versions that module requires.
+func (ag *APIGenerator) importerFor(mod *ModuleInfo) types.Importer {
+	if mod == nil {
+		return ag.importer
+	}
+	if ag.moduleImporters == nil {
+		ag.moduleImporters = make(map[string]types.Importer)
+	}
+	imp, exists := ag.moduleImporters[mod.Dir]
+	if !exists {
+		imp = newImporter(ag.fset, mod.Dir)
+		ag.moduleImporters[mod.Dir] = imp
+	}
+	return imp
+}
+
+// typeCheck runs go/types over a loaded package. Type errors do not abort the
+// scan: they are recorded on the package and reported as diagnostics, and the
+// partially checked type information is still used wherever it is valid.
+func (ag *APIGenerator) typeCheck(lp *loadedPackage) {
+	lp.Info = &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+
+	conf := types.Config{
+		Importer:    ag.importerFor(lp.Module),
+		FakeImportC: true,
+		Error: func(err error) {
+			lp.Errors = append(lp.Errors, err)
+			ag.reportTypeError(err)
+		},
+	}
+
+	lp.Types, _ = conf.Check(lp.ImportPath, ag.fset, lp.Files, lp.Info)
+}
+
+// importPathFor resolves the import path of the package named name in dir.
+// Module-aware resolution through go.mod and go.work is preferred; outside
+// any module the path falls back to the directory-based heuristic.
+func (ag *APIGenerator) importPathFor(dir, name string) (string, *ModuleInfo) {
+	importPath, mod, err := ag.modules.Resolve(dir)
+	if err != nil {
+		log.Printf("Error resolving module for %s: %v", dir, err)
+	}
+	if importPath == "" {
+		importPath = legacyImportPath(dir, name)
+	}
+
+	// External test packages live beside the package they test
+	if strings.HasSuffix(name, "_test") && !strings.HasSuffix(importPath, "_test") {
+		importPath += "_test"
+	}
+
+	return importPath, mod
+}
+
+// legacyImportPath derives an import path from the directory alone
+func legacyImportPath(dir, name string) string {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if strings.HasPrefix(dir, "src/") {
+		return dir[4:]
+	}
+	if dir == "." {
+		return name
+	}
+	return dir
+}
+
+// resolvedType returns the type go/types computed for expr, or nil when no
+// type information is available or the type could not be resolved.
+func (ag *APIGenerator) resolvedType(expr ast.Expr) types.Type {
+	if ag.current == nil || ag.current.Info == nil {
+		return nil
+	}
+
+	t := ag.current.Info.TypeOf(expr)
+	if t == nil || strings.Contains(types.TypeString(t, nil), "invalid type") {
+		return nil
+	}
+
+	return t
+}
+
+// localQualifier prints types of the package being scanned unqualified and
+// types of other packages with their package name, mirroring how they are
+// spelled in source.
+func (ag *APIGenerator) localQualifier(pkg *types.Package) string {
+	if ag.current != nil && pkg == ag.current.Types {
+		return ""
+	}
+	return pkg.Name()
+}
+
+// fullQualifier qualifies every named type with its full import path
+func fullQualifier(pkg *types.Package) string {
+	return pkg.Path()
+}
+
+// getQualifiedTypeString returns the package-qualified spelling of a type
+// expression, e.g. "time.Time" or "example.com/app/models.User".
+func (ag *APIGenerator) getQualifiedTypeString(expr ast.Expr) string {
+	if t := ag.resolvedType(expr); t != nil {
+		return types.TypeString(t, fullQualifier)
+	}
+	return ""
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
+	"go/types"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +20,9 @@ import (
 type PackageInfo struct {
 	Name         string        `json:"name"`
 	ImportPath   string        `json:"import_path"`
+	Dir          string        `json:"dir"`
+	Module       string        `json:"module,omitempty"`
+	ModuleDir    string        `json:"-"`
 	Structs      []StructInfo  `json:"structs"`
 	Interfaces   []MethodInfo  `json:"interfaces"`
 	Functions    []MethodInfo  `json:"functions"`
@@ -29,14 +36,17 @@ type StructInfo struct {
 	Methods     []MethodInfo `json:"methods"`
 	Annotations []Annotation `json:"annotations"`
 	Doc         string       `json:"doc"`
+	Pos         token.Position `json:"pos"`
 }
 
 // FieldInfo represents struct field information
 type FieldInfo struct {
-	Name        string       `json:"name"`
-	Type        string       `json:"type"`
-	Tags        []TagInfo    `json:"tags"`
-	Annotations []Annotation `json:"annotations"`
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	QualifiedType string       `json:"qualified_type,omitempty"`
+	Tags          []TagInfo    `json:"tags"`
+	Annotations   []Annotation `json:"annotations"`
+	Embedded      bool         `json:"embedded,omitempty"` // Name is the embedded type name
 }
 
 // MethodInfo represents method/function information
@@ -47,12 +57,14 @@ type MethodInfo struct {
 	Returns     []Parameter  `json:"returns,omitempty"`
 	Annotations []Annotation `json:"annotations"`
 	Doc         string       `json:"doc"`
+	Pos         token.Position `json:"pos"`
 }
 
 // Parameter represents function parameter or return value
 type Parameter struct {
-	Name string `json:"name,omitempty"`
-	Type string `json:"type"`
+	Name          string `json:"name,omitempty"`
+	Type          string `json:"type"`
+	QualifiedType string `json:"qualified_type,omitempty"`
 }
 
 // TagInfo represents struct field tag information
@@ -61,23 +73,34 @@ type TagInfo struct {
 	Value string `json:"value"`
 }
 
-// Annotation represents API generation annotation
+// Annotation represents API generation annotation. It is the flat
+// compatibility view of the parsed AnnotationNode kept in Node.
 type Annotation struct {
 	Type   string                 `json:"type"`
 	Key    string                 `json:"key"`
 	Value  string                 `json:"value"`
 	Config map[string]interface{} `json:"config,omitempty"`
+	Pos    token.Position         `json:"pos"`
+	Node   *AnnotationNode        `json:"-"`
 }
 
 // APIGenerator represents the main scanner and generator
 type APIGenerator struct {
-	fset    *token.FileSet
-	pkgs    map[string]*PackageInfo
-	config  *GeneratorConfig
+	fset     *token.FileSet
+	pkgs     map[string]*PackageInfo
+	config   *GeneratorConfig
+	importer types.Importer
+	modules  *ModuleResolver
+	moduleImporters map[string]types.Importer
+	current  *loadedPackage
+	diagnostics []Diagnostic
+	inflector *Inflector
+	mapper   *MethodMapper
 }
 
 // GeneratorConfig contains configuration for API generation
 type GeneratorConfig struct {
+	Roots           []string `json:"roots,omitempty"` // directories scanned when none are named, "." when empty
 	IncludePatterns []string `json:"include_patterns"`
 	ExcludePatterns []string `json:"exclude_patterns"`
 	ScanAnnotations bool     `json:"scan_annotations"`
@@ -85,27 +108,61 @@ type GeneratorConfig struct {
 	SmartMapping    bool     `json:"smart_mapping"`
 	OutputDir       string   `json:"output_dir"`
 	PackageName     string   `json:"package_name"`
+	Naming          *NamingConfig `json:"naming,omitempty"`
+	Mappings        *MappingConfig `json:"mappings,omitempty"`
+}
+
+// ScanRoots returns the configured scan roots, or the current directory
+func (c *GeneratorConfig) ScanRoots() []string {
+	if len(c.Roots) == 0 {
+		return []string{"."}
+	}
+	return c.Roots
 }
 
 // NewAPIGenerator creates a new API generator instance
 func NewAPIGenerator(config *GeneratorConfig) *APIGenerator {
+	fset := token.NewFileSet()
+	mapper, err := NewMethodMapper(config.Mappings)
+	if err != nil {
+		log.Printf("Warning: invalid smart mappings, using the defaults: %v", err)
+		mapper, _ = NewMethodMapper(nil)
+	}
 	return &APIGenerator{
-		fset:   token.NewFileSet(),
-		pkgs:   make(map[string]*PackageInfo),
-		config: config,
+		fset:     fset,
+		pkgs:     make(map[string]*PackageInfo),
+		config:   config,
+		importer: newImporter(fset, ""),
+		modules:  NewModuleResolver(),
+		inflector: NewInflector(config.Naming),
+		mapper:   mapper,
 	}
 }
 
-// ScanDirectory scans a directory for Go packages
+// ScanDirectory scans a directory for Go packages. Matching files are
+// grouped by directory and each package is type-checked as a whole, so the
+// extracted parameter, return and field types are resolved rather than
+// guessed from syntax. Include and exclude patterns are matched by
+// matchScanPattern against paths relative to root.
 func (ag *APIGenerator) ScanDirectory(root string) error {
+	filesByDir := make(map[string][]string)
+
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
 
 		// Skip directories that should be excluded
 		for _, pattern := range ag.config.ExcludePatterns {
-			if matched, _ := filepath.Match(pattern, path); matched {
+			if matchScanPattern(pattern, rel) {
 				if info.IsDir() {
 					return filepath.SkipDir
 				}
@@ -121,7 +178,7 @@ func (ag *APIGenerator) ScanDirectory(root string) error {
 		// Check if file matches include patterns
 		included := len(ag.config.IncludePatterns) == 0
 		for _, pattern := range ag.config.IncludePatterns {
-			if matched, _ := filepath.Match(pattern, path); matched {
+			if matchScanPattern(pattern, rel) {
 				included = true
 				break
 			}
@@ -131,16 +188,54 @@ func (ag *APIGenerator) ScanDirectory(root string) error {
 			return nil
 		}
 
-		// Parse the file
-		return ag.scanFile(path)
+		dir := filepath.Dir(path)
+		filesByDir[dir] = append(filesByDir[dir], path)
+		return nil
 	})
+	if err != nil {
+		return err
+	}
 
-	// Post-processing: associate all methods with their structs
-	if err == nil {
-		ag.associateMethodsWithStructs()
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
 	}
+	sort.Strings(dirs)
 
-	return err
+	// Load, type-check and scan each package
+	for _, dir := range dirs {
+		for _, lp := range ag.loadPackages(dir, filesByDir[dir]) {
+			ag.scanPackage(lp)
+		}
+	}
+
+	// Post-processing: associate all methods with their structs
+	ag.associateMethodsWithStructs()
+
+	return nil
+}
+
+// matchScanPattern reports whether a slash-separated path relative to a
+// scan root matches an include or exclude pattern. A pattern without a
+// slash, such as "*_test.go", matches the base name at any depth; one with
+// a slash matches the path or one of its parent directories, so "vendor/*"
+// matches everything under vendor.
+func matchScanPattern(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(pattern, "/") {
+		matched, _ := filepath.Match(pattern, filepath.Base(rel))
+		return matched
+	}
+	for {
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+		i := strings.LastIndex(rel, "/")
+		if i < 0 {
+			return false
+		}
+		rel = rel[:i]
+	}
 }
 
 // associateMethodsWithStructs ensures all methods are properly associated with their structs
@@ -173,89 +268,55 @@ func (ag *APIGenerator) associateMethodsWithStructs() {
 	}
 }
 
-// scanFile scans a single Go file
-func (ag *APIGenerator) scanFile(filePath string) error {
-	node, err := parser.ParseFile(ag.fset, filePath, nil, parser.ParseComments)
-	if err != nil {
-		log.Printf("Error parsing file %s: %v", filePath, err)
-		return nil
-	}
+// scanPackage extracts structs, functions and imports from a type-checked package
+func (ag *APIGenerator) scanPackage(lp *loadedPackage) {
+	ag.current = lp
+	defer func() { ag.current = nil }()
 
 	pkgInfo := &PackageInfo{
-		Name: node.Name.Name,
+		Name:       lp.Name,
+		ImportPath: lp.ImportPath,
+		Dir:        lp.Dir,
 	}
-
-	// Scan imports
-	if node.Imports != nil {
-		for _, imp := range node.Imports {
-			importPath := strings.Trim(imp.Path.Value, `"`)
-			pkgInfo.Imports = append(pkgInfo.Imports, importPath)
-		}
+	if lp.Module != nil {
+		pkgInfo.Module = lp.Module.Path
+		pkgInfo.ModuleDir = lp.Module.Dir
 	}
 
-	// Scan declarations
-	ast.Inspect(node, func(n ast.Node) bool {
-		switch x := n.(type) {
-		case *ast.GenDecl:
-			if x.Tok == token.TYPE {
-				ag.scanTypeDeclaration(x, pkgInfo)
-			}
-		case *ast.FuncDecl:
-			ag.scanFunction(x, pkgInfo)
-		}
-		return true
-	})
-
-	// Store package info
-	dir := filepath.Dir(filePath)
-	if len(dir) > 4 && dir[:4] == "src/" {
-		pkgInfo.ImportPath = filepath.ToSlash(dir[4:])
-	} else {
-		pkgInfo.ImportPath = filepath.ToSlash(dir)
-	}
-	if ag.pkgs[dir] == nil {
-		ag.pkgs[dir] = pkgInfo
-	} else {
-		// Merge with existing package info, preserving method associations
-		existingPkg := ag.pkgs[dir]
-
-		// Add new structs
-		for _, newStruct := range pkgInfo.Structs {
-			// Check if struct already exists
-			found := false
-			for i := range existingPkg.Structs {
-				if existingPkg.Structs[i].Name == newStruct.Name {
-					// Merge methods into existing struct
-					existingPkg.Structs[i].Methods = append(existingPkg.Structs[i].Methods, newStruct.Methods...)
-					found = true
-					break
-				}
-			}
-			if !found {
-				existingPkg.Structs = append(existingPkg.Structs, newStruct)
+	seenImports := make(map[string]bool)
+	for _, file := range lp.Files {
+		// Scan imports
+		for _, imp := range file.Imports {
+			importPath := strings.Trim(imp.Path.Value, `"`)
+			if !seenImports[importPath] {
+				seenImports[importPath] = true
+				pkgInfo.Imports = append(pkgInfo.Imports, importPath)
 			}
 		}
 
-		// Re-process all functions to ensure methods are properly associated
-		for _, funcInfo := range pkgInfo.Functions {
-			existingPkg.Functions = append(existingPkg.Functions, funcInfo)
-
-			// If this is a method, try to associate it with a struct
-			if funcInfo.Receiver != "" {
-				receiverName := strings.TrimPrefix(funcInfo.Receiver, "*")
-				for i := range existingPkg.Structs {
-					if existingPkg.Structs[i].Name == receiverName {
-						existingPkg.Structs[i].Methods = append(existingPkg.Structs[i].Methods, funcInfo)
-						break
-					}
+		// Scan declarations
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.GenDecl:
+				if x.Tok == token.TYPE {
+					ag.scanTypeDeclaration(x, pkgInfo)
 				}
+			case *ast.FuncDecl:
+				ag.scanFunction(x, pkgInfo)
 			}
-		}
+			return true
+		})
+	}
 
+	// Store package info keyed by import path
+	if existingPkg := ag.pkgs[lp.ImportPath]; existingPkg != nil {
+		existingPkg.Structs = append(existingPkg.Structs, pkgInfo.Structs...)
+		existingPkg.Interfaces = append(existingPkg.Interfaces, pkgInfo.Interfaces...)
+		existingPkg.Functions = append(existingPkg.Functions, pkgInfo.Functions...)
 		existingPkg.Imports = append(existingPkg.Imports, pkgInfo.Imports...)
+		return
 	}
-
-	return nil
+	ag.pkgs[lp.ImportPath] = pkgInfo
 }
 
 // scanTypeDeclaration scans type declarations for structs and interfaces
@@ -269,6 +330,7 @@ func (ag *APIGenerator) scanTypeDeclaration(decl *ast.GenDecl, pkgInfo *PackageI
 		switch t := typeSpec.Type.(type) {
 		case *ast.StructType:
 			structInfo := ag.scanStruct(typeSpec.Name.Name, t, decl.Doc)
+			structInfo.Pos = ag.fset.Position(typeSpec.Pos())
 			pkgInfo.Structs = append(pkgInfo.Structs, structInfo)
 		case *ast.InterfaceType:
 			// Handle interface types
@@ -286,7 +348,7 @@ func (ag *APIGenerator) scanStruct(name string, structType *ast.StructType, doc
 	structInfo := StructInfo{
 		Name:        name,
 		Doc:         ag.getCommentText(doc),
-		Annotations: ag.parseAnnotations(doc),
+		Annotations: ag.parseComments(doc),
 		Fields:      make([]FieldInfo, 0),
 		Methods:     make([]MethodInfo, 0),
 	}
@@ -295,25 +357,55 @@ func (ag *APIGenerator) scanStruct(name string, structType *ast.StructType, doc
 		for _, field := range structType.Fields.List {
 			for _, fieldName := range field.Names {
 				fieldInfo := FieldInfo{
-					Name:        fieldName.Name,
-					Type:        ag.getTypeString(field.Type),
-					Tags:        ag.parseFieldTags(field.Tag),
-					Annotations: ag.parseAnnotations(field.Doc),
+					Name:          fieldName.Name,
+					Type:          ag.getTypeString(field.Type),
+					QualifiedType: ag.getQualifiedTypeString(field.Type),
+					Tags:          ag.parseFieldTags(field.Tag),
+					Annotations:   ag.parseComments(field.Doc),
 				}
 				structInfo.Fields = append(structInfo.Fields, fieldInfo)
 			}
+			if len(field.Names) == 0 {
+				structInfo.Fields = append(structInfo.Fields, FieldInfo{
+					Name:          embeddedFieldName(field.Type),
+					Type:          ag.getTypeString(field.Type),
+					QualifiedType: ag.getQualifiedTypeString(field.Type),
+					Tags:          ag.parseFieldTags(field.Tag),
+					Annotations:   ag.parseComments(field.Doc),
+					Embedded:      true,
+				})
+			}
 		}
 	}
 
 	return structInfo
 }
 
+// embeddedFieldName returns the implicit field name of an embedded type,
+// which is its type name without pointer, package and type arguments
+func embeddedFieldName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedFieldName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedFieldName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(t.X)
+	}
+	return ""
+}
+
 // scanFunction analyzes a function declaration
 func (ag *APIGenerator) scanFunction(decl *ast.FuncDecl, pkgInfo *PackageInfo) {
 	methodInfo := MethodInfo{
 		Name:        decl.Name.Name,
 		Doc:         ag.getCommentText(decl.Doc),
-		Annotations: ag.parseAnnotations(decl.Doc),
+		Annotations: ag.parseComments(decl.Doc),
+		Pos:         ag.fset.Position(decl.Name.Pos()),
 	}
 
 	// Check if this is a method (has receiver)
@@ -324,40 +416,12 @@ func (ag *APIGenerator) scanFunction(decl *ast.FuncDecl, pkgInfo *PackageInfo) {
 
 	// Parse parameters
 	if decl.Type.Params != nil {
-		for _, param := range decl.Type.Params.List {
-			paramType := ag.getTypeString(param.Type)
-			if len(param.Names) > 0 {
-				for _, name := range param.Names {
-					methodInfo.Parameters = append(methodInfo.Parameters, Parameter{
-						Name: name.Name,
-						Type: paramType,
-					})
-				}
-			} else {
-				methodInfo.Parameters = append(methodInfo.Parameters, Parameter{
-					Type: paramType,
-				})
-			}
-		}
+		methodInfo.Parameters = ag.scanFieldList(decl.Type.Params)
 	}
 
 	// Parse return values
 	if decl.Type.Results != nil {
-		for _, result := range decl.Type.Results.List {
-			resultType := ag.getTypeString(result.Type)
-			if len(result.Names) > 0 {
-				for _, name := range result.Names {
-					methodInfo.Returns = append(methodInfo.Returns, Parameter{
-						Name: name.Name,
-						Type: resultType,
-					})
-				}
-			} else {
-				methodInfo.Returns = append(methodInfo.Returns, Parameter{
-					Type: resultType,
-				})
-			}
-		}
+		methodInfo.Returns = ag.scanFieldList(decl.Type.Results)
 	}
 
 	// Add to package functions list
@@ -378,77 +442,44 @@ func (ag *APIGenerator) scanFunction(decl *ast.FuncDecl, pkgInfo *PackageInfo) {
 	}
 }
 
-// parseAnnotations extracts API generation annotations from comments
-func (ag *APIGenerator) parseComments(commentGroup *ast.CommentGroup) []Annotation {
-	var annotations []Annotation
-	if commentGroup == nil {
-		return annotations
-	}
-
-	for _, comment := range commentGroup.List {
-		text := strings.TrimSpace(comment.Text)
-		// Look for @api annotations
-		if strings.HasPrefix(text, "// @api.") || strings.HasPrefix(text, "/* @api.") {
-			annotation := ag.parseAnnotationLine(text)
-			if annotation != nil {
-				annotations = append(annotations, *annotation)
-			}
+// scanFieldList converts a parameter or result list into resolved parameters
+func (ag *APIGenerator) scanFieldList(list *ast.FieldList) []Parameter {
+	var params []Parameter
+	for _, field := range list.List {
+		param := Parameter{
+			Type:          ag.getTypeString(field.Type),
+			QualifiedType: ag.getQualifiedTypeString(field.Type),
+		}
+		if len(field.Names) == 0 {
+			params = append(params, param)
+			continue
+		}
+		for _, name := range field.Names {
+			param.Name = name.Name
+			params = append(params, param)
 		}
 	}
-
-	return annotations
-}
-
-func (ag *APIGenerator) parseAnnotations(commentGroup *ast.CommentGroup) []Annotation {
-	return ag.parseComments(commentGroup)
+	return params
 }
 
-// parseAnnotationLine parses a single annotation line
-func (ag *APIGenerator) parseAnnotationLine(line string) *Annotation {
-	// Remove comment markers
-	line = strings.TrimPrefix(line, "//")
-	line = strings.TrimPrefix(line, "/*")
-	line = strings.TrimSuffix(line, "*/")
-	line = strings.TrimSpace(line)
-
-	// Check if it's an API annotation
-	if !strings.HasPrefix(line, "@api.") {
-		return nil
-	}
-
-	parts := strings.SplitN(line, " ", 3)
-	if len(parts) < 2 {
-		return nil
-	}
-
-	annotation := &Annotation{
-		Type: "api",
-		Key:  strings.TrimPrefix(parts[0], "@api."),
+// parseComments extracts API generation annotations from comments
+func (ag *APIGenerator) parseComments(commentGroup *ast.CommentGroup) []Annotation {
+	var annotations []Annotation
+	if commentGroup == nil {
+		return annotations
 	}
 
-	if len(parts) >= 2 {
-		annotation.Value = parts[1]
+	nodes, errs := parseAnnotationGroup(commentGroup)
+	for _, node := range nodes {
+		annotation := node.Compat()
+		annotation.Pos = ag.fset.Position(node.Pos)
+		annotations = append(annotations, annotation)
 	}
-
-	// Parse configuration if available
-	if len(parts) >= 3 {
-		config := make(map[string]interface{})
-		// Simple key=value parsing
-		configStr := strings.Join(parts[2:], " ")
-		kvPairs := strings.FieldsFunc(configStr, func(r rune) bool {
-			return r == ',' || r == ' '
-		})
-		for _, kv := range kvPairs {
-			if equalIndex := strings.Index(kv, "="); equalIndex > 0 {
-				key := kv[:equalIndex]
-				value := kv[equalIndex+1:]
-				config[key] = value
-			}
-		}
-		annotation.Config = config
+	for _, annErr := range errs {
+		ag.report(annErr.errorPos(), SeverityError, DiagAnnotationSyntax, "invalid annotation: %s", annErr.message())
 	}
 
-	return annotation
+	return annotations
 }
 
 // parseFieldTags parses struct field tags
@@ -463,25 +494,45 @@ func (ag *APIGenerator) parseFieldTags(tag *ast.BasicLit) []TagInfo {
 		return tags
 	}
 
-	// Simple tag parsing - split by space and then by :
-	parts := strings.Split(tagStr, " ")
-	for _, part := range parts {
-		if part == "" {
-			continue
+	// Parse key:"value" pairs the way reflect.StructTag does, so that
+	// quoted values may contain spaces
+	for tagStr != "" {
+		tagStr = strings.TrimLeft(tagStr, " ")
+		colonIndex := strings.Index(tagStr, ":\"")
+		if colonIndex <= 0 || strings.ContainsAny(tagStr[:colonIndex], " \"") {
+			break
+		}
+		key := tagStr[:colonIndex]
+		rest := tagStr[colonIndex+1:]
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
 		}
-		if colonIndex := strings.Index(part, ":"); colonIndex > 0 {
-			tags = append(tags, TagInfo{
-				Key:   part[:colonIndex],
-				Value: strings.Trim(part[colonIndex+1:], `"`),
-			})
+		if end >= len(rest) {
+			break
 		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			break
+		}
+		tags = append(tags, TagInfo{Key: key, Value: value})
+		tagStr = rest[end+1:]
 	}
 
 	return tags
 }
 
-// getTypeString converts AST type expression to string
+// getTypeString converts AST type expression to string. When type
+// information is available the resolved type is printed relative to the
+// scanned package; otherwise the expression is rendered syntactically.
 func (ag *APIGenerator) getTypeString(expr ast.Expr) string {
+	if t := ag.resolvedType(expr); t != nil {
+		return types.TypeString(t, ag.localQualifier)
+	}
+
 	switch t := expr.(type) {
 	case *ast.Ident:
 		return t.Name
@@ -524,8 +575,13 @@ func (ag *APIGenerator) generateSmartRoutes(pkg *PackageInfo, structInfo StructI
 	var routes []APIRoute
 
 	// Scan all methods in the struct and generate smart routes
+	resource := ag.resourceName(structInfo)
 	for _, method := range structInfo.Methods {
-		mapping, found := ag.SmartMethodMapping(method.Name, structInfo.Name)
+		// Methods with their own @api.endpoint are routed by it alone
+		if hasAnnotation(method.Annotations, "endpoint") {
+			continue
+		}
+		mapping, found := ag.mapMethod(method.Name, resource)
 
 		if found && mapping.AutoGenerate {
 			// Build parameters based on method signature and operation type
@@ -535,12 +591,13 @@ func (ag *APIGenerator) generateSmartRoutes(pkg *PackageInfo, structInfo StructI
 			responses := ag.buildResponsesForOperation(method, mapping.Operation)
 
 			route := APIRoute{
-				Path:      mapping.Path,
-				Method:    mapping.Method,
-				Struct:    structInfo.Name,
-				Function:  method.Name,
-				Package:   pkg.Name,
-				Parameter: parameters,
+				Path:       mapping.Path,
+				Method:     mapping.Method,
+				Struct:     structInfo.Name,
+				Function:   method.Name,
+				Package:    pkg.Name,
+				ImportPath: pkg.ImportPath,
+				Parameter:  parameters,
 				Response:  responses,
 				Metadata: map[string]interface{}{
 					"auto_generated":   true,
@@ -549,7 +606,11 @@ func (ag *APIGenerator) generateSmartRoutes(pkg *PackageInfo, structInfo StructI
 					"method_patterns":   mapping.Patterns,
 					"intelligent_route": true,
 				},
+				Binding: routeBinding(pkg, method, mapping.Path),
+				Source:  RouteSourceSmart,
+				Pos:     method.Pos,
 			}
+			ag.describeMessages(&route, method.Doc, method.Annotations, mapping.Operation)
 			routes = append(routes, route)
 		}
 	}
@@ -569,7 +630,7 @@ func (ag *APIGenerator) buildParametersForOperation(method MethodInfo, operation
 		if len(method.Parameters) > 0 {
 			params = append(params, method.Parameters[0])
 		}
-	case "update", "bulk_update", "activate", "deactivate", "archive", "restore":
+	case "update", "bulk_update", "activate", "deactivate", "archive", "restore", "publish", "unpublish":
 		params = append(params, Parameter{Name: "id", Type: "string"})
 		if len(method.Parameters) > 1 {
 			params = append(params, method.Parameters[1])
@@ -615,7 +676,7 @@ func (ag *APIGenerator) buildResponsesForOperation(method MethodInfo, operation
 		responses = append(responses, Parameter{Type: "bool"})
 	case "count":
 		responses = append(responses, Parameter{Type: "int"})
-	case "activate", "deactivate", "archive", "restore", "assign", "unassign":
+	case "activate", "deactivate", "archive", "restore", "publish", "unpublish", "assign", "unassign":
 		if len(method.Returns) > 0 {
 			responses = append(responses, method.Returns[0])
 		} else {
@@ -626,8 +687,15 @@ func (ag *APIGenerator) buildResponsesForOperation(method MethodInfo, operation
 	return responses
 }
 
-// GenerateAPIRoutes generates API routes from scanned packages
+// GenerateAPIRoutes generates the conflict-free route table from scanned
+// packages. Overlapping routes are merged by BuildRouteTable.
 func (ag *APIGenerator) GenerateAPIRoutes() []APIRoute {
+	return ag.BuildRouteTable().Routes
+}
+
+// collectRoutes gathers every candidate route: annotated, smart-mapped and
+// auto CRUD routes, possibly overlapping
+func (ag *APIGenerator) collectRoutes() []APIRoute {
 	var routes []APIRoute
 
 	for _, pkg := range ag.pkgs {
@@ -635,13 +703,17 @@ func (ag *APIGenerator) GenerateAPIRoutes() []APIRoute {
 			// Check for API annotations on the struct
 			for _, annotation := range structInfo.Annotations {
 				if annotation.Key == "route" {
+					config := withSiblingAnnotations(annotation.Config, structInfo.Annotations)
 					route := APIRoute{
-						Path:     annotation.Value,
-						Struct:   structInfo.Name,
-						Package:  pkg.Name,
-						Methods:  ag.extractMethodsFromConfig(annotation.Config),
-						Auth:     ag.extractAuthConfig(annotation.Config),
-						Metadata: annotation.Config,
+						Path:       annotation.Value,
+						Struct:     structInfo.Name,
+						Package:    pkg.Name,
+						ImportPath: pkg.ImportPath,
+						Methods:    ag.extractMethodsFromConfig(config),
+						Auth:       ag.extractAuthConfig(config),
+						Metadata:   annotation.Config,
+						Source:     RouteSourceAnnotation,
+						Pos:        annotation.Pos,
 					}
 					routes = append(routes, route)
 				}
@@ -653,8 +725,10 @@ func (ag *APIGenerator) GenerateAPIRoutes() []APIRoute {
 				routes = append(routes, smartRoutes...)
 			}
 
-			// Auto-generate basic CRUD routes if enabled
-			if ag.config.AutoCRUD {
+			// Auto-generate basic CRUD routes if enabled. Structs named
+			// for their role, such as TaskService, serve a model rather
+			// than being one, so only the model gets CRUD routes
+			if ag.config.AutoCRUD && ag.inflector.RoleSuffix(structInfo.Name) == "" {
 				crudRoutes := ag.generateCRUDRoutes(pkg, structInfo)
 				routes = append(routes, crudRoutes...)
 			}
@@ -664,16 +738,22 @@ func (ag *APIGenerator) GenerateAPIRoutes() []APIRoute {
 		for _, funcInfo := range pkg.Functions {
 			for _, annotation := range funcInfo.Annotations {
 				if annotation.Key == "endpoint" {
+					config := withSiblingAnnotations(annotation.Config, funcInfo.Annotations)
 					route := APIRoute{
-						Path:      annotation.Value,
-						Function:  funcInfo.Name,
-						Package:   pkg.Name,
-						Method:    ag.extractMethodFromConfig(annotation.Config),
-						Auth:      ag.extractAuthConfig(annotation.Config),
-						Parameter: ag.extractParameterInfo(funcInfo),
-						Response:  ag.extractResponseInfo(funcInfo),
-						Metadata:  annotation.Config,
+						Path:       annotation.Value,
+						Function:   funcInfo.Name,
+						Package:    pkg.Name,
+						ImportPath: pkg.ImportPath,
+						Method:     ag.extractMethodFromConfig(config),
+						Auth:       ag.extractAuthConfig(config),
+						Parameter:  ag.extractParameterInfo(funcInfo),
+						Response:   ag.extractResponseInfo(funcInfo),
+						Metadata:   annotation.Config,
+						Binding:    routeBinding(pkg, funcInfo, annotation.Value),
+						Source:     RouteSourceAnnotation,
+						Pos:        annotation.Pos,
 					}
+					ag.describeMessages(&route, funcInfo.Doc, funcInfo.Annotations, "")
 					routes = append(routes, route)
 				}
 			}
@@ -690,22 +770,201 @@ type APIRoute struct {
 	Struct    string            `json:"struct,omitempty"`
 	Function  string            `json:"function,omitempty"`
 	Package   string            `json:"package"`
+	ImportPath string           `json:"import_path,omitempty"`
 	Methods   []string          `json:"methods,omitempty"`
 	Auth      AuthConfig        `json:"auth"`
 	Parameter []Parameter       `json:"parameter,omitempty"`
 	Response  []Parameter       `json:"response,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata"`
+	Binding   *RouteBinding     `json:"binding,omitempty"`
+	Repository *RepositoryBinding `json:"repository,omitempty"`
+	RequestType string          `json:"request_type,omitempty"`
+	Responses []ResponseSpec    `json:"responses,omitempty"`
+	Source    RouteSource       `json:"source,omitempty"`
+	Pos       token.Position    `json:"pos"`
+	Docs      RouteDocs         `json:"docs"`
+}
+
+// RouteDocs documents a route for generated API references. It is taken
+// from the doc comment of the function the route calls and from its
+// @api.doc annotations, which take precedence.
+type RouteDocs struct {
+	Summary     string       `json:"summary,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Params      []DocParam   `json:"params,omitempty"`
+	Examples    []DocExample `json:"examples,omitempty"`
+}
+
+// DocParam is a parameter declared with
+// "@api.doc.param(name, in, type, description, required=true)"
+type DocParam struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// DocExample is an example given with "@api.doc.example(value)". Status
+// selects a response; without it the example is of the request body, or of
+// the success response when the route takes no body.
+type DocExample struct {
+	Status int         `json:"status,omitempty"`
+	Value  interface{} `json:"value"`
+}
+
+// ResponseSpec describes one response of a route: a status code and the Go
+// type of its body, empty when the response has no body
+type ResponseSpec struct {
+	Status      int    `json:"status"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SuccessResponse returns the first 2xx response of the route, or a plain
+// 200 response when none is declared
+func (r APIRoute) SuccessResponse() ResponseSpec {
+	for _, response := range r.Responses {
+		if response.Status >= 200 && response.Status < 300 {
+			return response
+		}
+	}
+	success := ResponseSpec{Status: http.StatusOK}
+	if len(r.Response) > 0 {
+		success.Type = r.Response[0].Type
+	}
+	return success
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	Required bool   `json:"required"`
-	Type     string `json:"type"`
-	JWT      JWTConfig `json:"jwt,omitempty"`
+	Required bool   `json:"required" yaml:"required"`
+	Type     string `json:"type" yaml:"type"`
+	JWT      JWTConfig `json:"jwt,omitempty" yaml:"jwt,omitempty"`
 }
 
 type JWTConfig struct {
-	Secret string `json:"secret"`
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+// describeMessages sets the request body type, the responses and the docs
+// of route. @api.request and @api.response annotations on the function the
+// route calls take precedence; otherwise the request type is the first
+// parameter decoded from the body and the response follows from the
+// operation: 201 for create, 204 for delete and 200 with the first result
+// for the rest.
+func (ag *APIGenerator) describeMessages(route *APIRoute, doc string, annotations []Annotation, operation string) {
+	route.Docs = describeDocs(doc, annotations)
+	for _, annotation := range annotations {
+		switch annotation.Key {
+		case "request":
+			if annotation.Value != "" {
+				route.RequestType = annotation.Value
+			}
+		case "response":
+			if response, ok := responseSpec(annotation); ok {
+				route.Responses = append(route.Responses, response)
+			}
+		}
+	}
+
+	if route.RequestType == "" {
+		for _, param := range route.Parameter {
+			if param.QualifiedType != "" && param.QualifiedType != "context.Context" && isBodyType(param.QualifiedType) {
+				route.RequestType = param.Type
+				break
+			}
+		}
+	}
+
+	if len(route.Responses) > 0 {
+		return
+	}
+	switch operation {
+	case "create":
+		success := ResponseSpec{Status: http.StatusCreated}
+		if len(route.Response) > 0 {
+			success.Type = route.Response[0].Type
+		} else if route.RequestType != "" {
+			success.Type = route.RequestType
+		}
+		route.Responses = []ResponseSpec{success}
+	case "delete", "bulk_delete":
+		route.Responses = []ResponseSpec{{Status: http.StatusNoContent}}
+	default:
+		route.Responses = []ResponseSpec{route.SuccessResponse()}
+	}
+}
+
+// responseSpec converts an "@api.response(status, Type)" annotation
+func responseSpec(annotation Annotation) (ResponseSpec, bool) {
+	if annotation.Node == nil {
+		return ResponseSpec{}, false
+	}
+	_, positional := annotation.Node.arguments()
+	if len(positional) == 0 || positional[0].Kind != NumberValue {
+		return ResponseSpec{}, false
+	}
+
+	response := ResponseSpec{Status: int(positional[0].Number)}
+	if len(positional) > 1 {
+		response.Type = positional[1].String()
+	}
+	if description, ok := annotation.Node.Named("description"); ok {
+		response.Description = description.String()
+	}
+	return response, true
+}
+
+// hasAnnotation reports whether annotations include one with key
+func hasAnnotation(annotations []Annotation, key string) bool {
+	for _, annotation := range annotations {
+		if annotation.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// withSiblingAnnotations returns config extended with the settings given by
+// separate annotations on the same declaration, such as
+// "@api.methods(GET, POST)", "@api.method(POST)" or "@api.auth.required".
+// Settings already present in config take precedence.
+func withSiblingAnnotations(config map[string]interface{}, annotations []Annotation) map[string]interface{} {
+	merged := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		merged[key] = value
+	}
+
+	setDefault := func(key string, value interface{}) {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
+	for _, annotation := range annotations {
+		switch annotation.Key {
+		case "method":
+			if annotation.Value != "" {
+				setDefault("method", strings.ToUpper(annotation.Value))
+			}
+		case "methods":
+			if annotation.Node != nil {
+				var methods []string
+				for _, value := range annotation.Node.Positional() {
+					methods = append(methods, strings.ToUpper(value.String()))
+				}
+				if len(methods) > 0 {
+					setDefault("methods", strings.Join(methods, ","))
+				}
+			}
+		case "auth.required":
+			setDefault("auth", "required")
+		case "auth.optional":
+			setDefault("auth", "optional")
+		}
+	}
+
+	return merged
 }
 
 // Helper methods for extracting configuration from annotations
@@ -753,116 +1012,10 @@ func (ag *APIGenerator) extractResponseInfo(funcInfo MethodInfo) []Parameter {
 	return funcInfo.Returns
 }
 
-// MethodMapping represents intelligent method-to-HTTP mappings
-type MethodMapping struct {
-	Patterns   []string `json:"patterns"`
-	Method     string   `json:"method"`
-	Path       string   `json:"path"`
-	Operation  string   `json:"operation"`
-	AutoGenerate bool   `json:"auto_generate"`
-}
-
-// SmartMethodMappings contains intelligent method mapping rules
-var SmartMethodMappings = []MethodMapping{
-	// CRUD operations
-	{Patterns: []string{"Get*", "Find*"}, Method: "GET", Path: "/{resource}/{id}", Operation: "get", AutoGenerate: true},
-	{Patterns: []string{"List*", "GetAll*", "FindAll*", "Query*"}, Method: "GET", Path: "/{resource}", Operation: "list", AutoGenerate: true},
-	{Patterns: []string{"Create*", "Add*", "New*", "Insert*"}, Method: "POST", Path: "/{resource}", Operation: "create", AutoGenerate: true},
-	{Patterns: []string{"Update*", "Modify*", "Edit*", "Change*"}, Method: "PUT", Path: "/{resource}/{id}", Operation: "update", AutoGenerate: true},
-	{Patterns: []string{"Delete*", "Remove*", "Destroy*"}, Method: "DELETE", Path: "/{resource}/{id}", Operation: "delete", AutoGenerate: true},
-
-	// Search and filter operations
-	{Patterns: []string{"Search*", "Query*", "Filter*"}, Method: "GET", Path: "/{resource}/search", Operation: "search", AutoGenerate: true},
-	{Patterns: []string{"Count*", "Total*"}, Method: "GET", Path: "/{resource}/count", Operation: "count", AutoGenerate: true},
-	{Patterns: []string{"Exists*", "Check*"}, Method: "GET", Path: "/{resource}/{id}/exists", Operation: "exists", AutoGenerate: true},
-
-	// Bulk operations
-	{Patterns: []string{"BulkCreate*", "BatchCreate*", "CreateMultiple*"}, Method: "POST", Path: "/{resource}/bulk", Operation: "bulk_create", AutoGenerate: true},
-	{Patterns: []string{"BulkUpdate*", "BatchUpdate*", "UpdateMultiple*"}, Method: "PUT", Path: "/{resource}/bulk", Operation: "bulk_update", AutoGenerate: true},
-	{Patterns: []string{"BulkDelete*", "BatchDelete*", "DeleteMultiple*"}, Method: "DELETE", Path: "/{resource}/bulk", Operation: "bulk_delete", AutoGenerate: true},
-
-	// Status and state operations
-	{Patterns: []string{"Activate*", "Enable*"}, Method: "PUT", Path: "/{resource}/{id}/activate", Operation: "activate", AutoGenerate: true},
-	{Patterns: []string{"Deactivate*", "Disable*"}, Method: "PUT", Path: "/{resource}/{id}/deactivate", Operation: "deactivate", AutoGenerate: true},
-	{Patterns: []string{"Archive*"}, Method: "PUT", Path: "/{resource}/{id}/archive", Operation: "archive", AutoGenerate: true},
-	{Patterns: []string{"Restore*", "Unarchive*"}, Method: "PUT", Path: "/{resource}/{id}/restore", Operation: "restore", AutoGenerate: true},
-
-	// Relationship operations
-	{Patterns: []string{"Get*By*", "Find*By*"}, Method: "GET", Path: "/{resource}/by/{field}", Operation: "get_by", AutoGenerate: true},
-	{Patterns: []string{"Assign*", "Link*"}, Method: "POST", Path: "/{resource}/{id}/assign", Operation: "assign", AutoGenerate: true},
-	{Patterns: []string{"Unassign*", "Unlink*"}, Method: "DELETE", Path: "/{resource}/{id}/assign", Operation: "unassign", AutoGenerate: true},
-}
-
-// SmartMethodMapping intelligently maps method names to HTTP routes
-func (ag *APIGenerator) SmartMethodMapping(methodName string, structName string) (MethodMapping, bool) {
-	methodLower := strings.ToLower(methodName)
-
-	for _, mapping := range SmartMethodMappings {
-		for _, pattern := range mapping.Patterns {
-			patternLower := strings.ToLower(pattern)
-			if ag.matchPattern(methodLower, patternLower) {
-				// Customize path for this method
-				customPath := ag.buildCustomPath(mapping.Path, methodName, structName)
-				mapping.Path = customPath
-				return mapping, true
-			}
-		}
-	}
-
-	// Default mapping for unrecognized methods
-	return MethodMapping{
-		Patterns:     []string{methodName},
-		Method:       "POST",
-		Path:         "/" + strings.ToLower(structName) + "/" + strings.ToLower(methodName),
-		Operation:    "custom",
-		AutoGenerate: false,
-	}, false
-}
-
-// matchPattern checks if a method name matches a pattern
-func (ag *APIGenerator) matchPattern(methodName, pattern string) bool {
-	if strings.HasSuffix(pattern, "*") {
-		prefix := strings.TrimSuffix(pattern, "*")
-		return strings.HasPrefix(methodName, prefix)
-	}
-
-	if strings.Contains(pattern, "*") {
-		// Handle more complex patterns like "Get*By*"
-		parts := strings.Split(pattern, "*")
-		if len(parts) == 2 {
-			return strings.HasPrefix(methodName, parts[0]) && strings.HasSuffix(methodName, parts[1])
-		}
-	}
-
-	return methodName == pattern
-}
-
-// buildCustomPath creates a custom path based on method name and pattern
-func (ag *APIGenerator) buildCustomPath(basePath, methodName, structName string) string {
-	path := basePath
-
-	// Replace {resource} with actual resource name
-	resourceName := strings.ToLower(structName) + "s"
-	path = strings.ReplaceAll(path, "{resource}", resourceName)
-
-	// Handle special patterns
-	if strings.Contains(methodName, "by") && strings.Contains(path, "/{field}") {
-		// Extract field name from "GetUserByEmail" -> "email"
-		parts := strings.Split(strings.ToLower(methodName), "by")
-		if len(parts) > 1 {
-			fieldName := parts[1]
-			path = strings.ReplaceAll(path, "/{field}", "/by/"+fieldName)
-		}
-	}
-
-	return path
-}
-
 // generateCRUDRoutes auto-generates CRUD routes for a struct
 func (ag *APIGenerator) generateCRUDRoutes(pkg *PackageInfo, structInfo StructInfo) []APIRoute {
 	var routes []APIRoute
-	structName := strings.ToLower(structInfo.Name)
-	pluralName := structName + "s"
+	pluralName := ag.resourceName(structInfo)
 
 	// GET /{resource} - List all
 	routes = append(routes, APIRoute{
@@ -936,222 +1089,26 @@ func (ag *APIGenerator) generateCRUDRoutes(pkg *PackageInfo, structInfo StructIn
 		},
 	})
 
-	return routes
-}
-
-// GenerateAPIServer generates a complete API server from the scanned information
-func (ag *APIGenerator) GenerateAPIServer() error {
-	routes := ag.GenerateAPIRoutes()
-
-	// Generate main.go
-	mainTemplate := `package main
-
-import (
-	"log"
-	"net/http"
-	"os"
-
-	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
-)
-
-type Config struct {
-	Port      string ` + "`json:\"port\" env:\"PORT\" default:\"8080\"`" + `
-	JWTSecret string ` + "`json:\"jwt_secret\" env:\"JWT_SECRET\"`" + `
-	RedisURL  string ` + "`json:\"redis_url\" env:\"REDIS_URL\"`" + `
-}
-
-type Server struct {
-	config *Config
-	router *gin.Engine
-}
-
-func NewServer(config *Config) *Server {
-	gin.SetMode(gin.ReleaseMode)
-	server := &Server{
-		config: config,
-		router: gin.New(),
-	}
-
-	server.setupRoutes()
-	return server
-}
-
-func (s *Server) setupRoutes() {
-	s.router.Use(gin.Logger())
-	s.router.Use(gin.Recovery())
-
-	// CORS middleware
-	s.router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+	// Bind the routes whose conventional method exists on the struct
+	for i := range routes {
+		routes[i].ImportPath = pkg.ImportPath
+		routes[i].Source = RouteSourceCRUD
+		routes[i].Pos = structInfo.Pos
+		var doc string
+		var annotations []Annotation
+		if method, ok := findStructMethod(structInfo, routes[i].Function); ok {
+			routes[i].Binding = routeBinding(pkg, method, routes[i].Path)
+			routes[i].Pos = method.Pos
+			doc, annotations = method.Doc, method.Annotations
 		}
-
-		c.Next()
-	})
-
-	// Health check
-	s.router.GET("/health", s.healthCheck)
-
-	// API v1 routes
-	v1 := s.router.Group("/api/v1")
-	{
-		{{range .Routes}}
-		v1.{{.Method|upper}}("{{.Path}}", s.{{.Function|title}})
-		{{end}}
-	}
-}
-
-func (s *Server) healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
-		"timestamp": "auto-generated",
-		"version":   "1.0.0",
-	})
-}
-
-{{range .Routes}}
-func (s *Server) {{.Function|title}}(c *gin.Context) {
-	// Auto-generated implementation for {{.Function}}
-	// TODO: Implement business logic
-
-	{{if .Auth.Required}}
-	// JWT authentication would be here
-	{{end}}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "{{.Function}} endpoint auto-generated",
-		"method": "{{.Method}}",
-		"path": "{{.Path}}",
-		"auto_generated": true,
-	})
-}
-{{end}}
-
-func main() {
-	config := &Config{
-		Port:      getEnv("PORT", "8080"),
-		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
-		RedisURL:  getEnv("REDIS_URL", "localhost:6379"),
-	}
-
-	server := NewServer(config)
-	log.Printf("Auto-generated API server starting on port %s", config.Port)
-
-	if err := server.router.Run(":" + config.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}`
-
-	// Execute template with routes data
-	// This is a simplified version - in production you'd use Go's text/template
-	output := strings.Replace(mainTemplate, `{{range .Routes}}`, generateRoutesSection(routes), 1)
-	output = strings.Replace(output, `{{end}}`, "", 2)
-
-	// Write main.go
-	err := os.WriteFile(filepath.Join(ag.config.OutputDir, "main.go"), []byte(output), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write main.go: %v", err)
-	}
-
-	// Generate go.mod
-	goModContent := `module ` + ag.config.PackageName + `
-
-go 1.21
-
-require (
-	github.com/gin-gonic/gin v1.10.0
-	github.com/golang-jwt/jwt/v4 v4.5.2
-)
-`
-	err = os.WriteFile(filepath.Join(ag.config.OutputDir, "go.mod"), []byte(goModContent), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write go.mod: %v", err)
-	}
-
-	// Generate README
-	readmeContent := `# Auto-Generated API Server
-
-## Overview
-This API server was auto-generated using gofastapi scanner.
-
-## Generated Routes
-` + generateRoutesTable(routes) + `
-
-## Usage
-1. Install dependencies:
-   ` + "```bash" + `
-   go mod tidy
-   ` + "```" + `
-
-2. Set environment variables:
-   ` + "```bash" + `
-   export PORT=8080
-   export JWT_SECRET=your-secret-key
-   export REDIS_URL=localhost:6379
-   ` + "```" + `
-
-3. Run the server:
-   ` + "```bash" + `
-   go run main.go
-   ` + "```" + `
-
-## API Documentation
-- Health Check: GET /health
-- Generated API: GET /api/v1/...
-
-## Notes
-This is an auto-generated API. You should implement the business logic in the handler functions.
-`
-
-	err = os.WriteFile(filepath.Join(ag.config.OutputDir, "README.md"), []byte(readmeContent), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write README.md: %v", err)
-	}
-
-	return nil
-}
-
-// Helper functions for template generation
-func generateRoutesSection(routes []APIRoute) string {
-	var result string
-	for _, route := range routes {
-		result += fmt.Sprintf(`		v1.%s("%s", s.%s)
-`, strings.ToUpper(route.Method), route.Path, route.Function)
-	}
-	return result
-}
-
-func generateRoutesTable(routes []APIRoute) string {
-	var result string
-	result += "| Method | Path | Function | Auth |\n"
-	result += "|--------|------|----------|------|\n"
-
-	for _, route := range routes {
-		authStatus := "❌"
-		if route.Auth.Required {
-			authStatus = "✅"
+		operation, _ := routes[i].Metadata["operation"].(string)
+		if operation == "create" || operation == "update" {
+			routes[i].RequestType = structInfo.Name
 		}
-		result += fmt.Sprintf("| %s | %s | %s | %s |\n",
-			strings.ToUpper(route.Method),
-			route.Path,
-			route.Function,
-			authStatus)
+		ag.describeMessages(&routes[i], doc, annotations, operation)
 	}
 
-	return result
+	return routes
 }
 
 // PrintSummary prints a summary of scanned packages and generated routes
@@ -1193,10 +1150,12 @@ func (ag *APIGenerator) PrintSummary() {
 
 // SaveAnalysis saves the analysis results to JSON
 func (ag *APIGenerator) SaveAnalysis(filename string) error {
+	table := ag.BuildRouteTable()
 	analysis := map[string]interface{}{
-		"packages": ag.pkgs,
-		"routes":   ag.GenerateAPIRoutes(),
-		"config":   ag.config,
+		"packages":        ag.pkgs,
+		"routes":          table.Routes,
+		"route_conflicts": table.Conflicts,
+		"config":          ag.config,
 	}
 
 	data, err := json.MarshalIndent(analysis, "", "  ")
@@ -1208,23 +1167,77 @@ func (ag *APIGenerator) SaveAnalysis(filename string) error {
 }
 
 func main() {
-	config := &GeneratorConfig{
-		IncludePatterns: []string{"*.go"},
-		ExcludePatterns: []string{"*_test.go", "vendor/*", ".git/*"},
-		ScanAnnotations: true,
-		AutoCRUD:        true,
-		SmartMapping:    true,
-		OutputDir:       "./generated-api",
-		PackageName:     "autogenerated-api",
+	// Lint mode: report annotation problems and exit
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(loadProject("", "", ""), os.Args[2:]))
+	}
+
+	// Proto mode: generate gRPC definitions for the scanned services
+	if len(os.Args) > 1 && os.Args[1] == "proto" {
+		os.Exit(runProto(loadProject("", "", "").GeneratorConfig(), os.Args[2:]))
+	}
+
+	// Migrations mode: write the next schema migration of the scanned models
+	if len(os.Args) > 1 && os.Args[1] == "migrations" {
+		os.Exit(runMigrations(loadProject("", "", "").GeneratorConfig(), os.Args[2:]))
 	}
 
+	// Import mode: generate an annotated Go service from an OpenAPI document
+	if len(os.Args) > 1 && os.Args[1] == "import-openapi" {
+		os.Exit(runImportOpenAPI(os.Args[2:]))
+	}
+
+	configFile := flag.String("config", "", "project file, gofastapi.yaml or gofastapi.json in the current directory by default")
+	env := flag.String("env", "", "environment overlay of the project file to apply, $"+ProjectEnvVar+" by default")
+	dryRun := flag.Bool("dry-run", false, "print a unified diff of what generation would change instead of writing it")
+	check := flag.Bool("check", false, "exit with status 1 when the generated code on disk is out of date")
+	framework := flag.String("framework", "", "generate a gin, echo, chi, fiber or stdlib server, overriding the project framework; a gin server in ./generated-api by default")
+	typeCheck := flag.Bool("typecheck", false, "type-check the generated code offline and report its errors by route")
+	templates := flag.String("templates", "", "directory of templates that shadow the built-in gin, echo, chi and fiber templates")
+	adopt := flag.Bool("adopt", false, "regenerate files without a checksum header, such as output of earlier versions, instead of writing .new files next to them")
+	flag.Parse()
+	preview := *dryRun || *check
+
+	project := loadProject(*configFile, *env, FrameworkType(*framework))
+	if *typeCheck {
+		project.Features.TypeCheck = true
+	}
+	if *templates != "" {
+		project.Templates = *templates
+	}
+	config := project.GeneratorConfig()
+	frameworkConfig := project.FrameworkConfig()
+
 	generator := NewAPIGenerator(config)
 
-	// Scan current directory
-	fmt.Println("🔍 Scanning Go files...")
-	err := generator.ScanDirectory(".")
+	// Scan the project roots
+	if !preview {
+		fmt.Println("🔍 Scanning Go files...")
+	}
+	for _, root := range config.ScanRoots() {
+		if err := generator.ScanDirectory(root); err != nil {
+			log.Fatalf("Error scanning %s: %v", root, err)
+		}
+	}
+	for _, diagnostic := range generator.Diagnostics() {
+		log.Print(diagnostic)
+	}
+	for _, diagnostic := range generator.BuildRouteTable().Diagnostics() {
+		log.Print(diagnostic)
+	}
+
+	// Render the server, which is previewed or written below
+	routes := generator.GenerateAPIRoutes()
+	outputDir := frameworkConfig.OutputDir
+	out, err := GetFrameworkRegistry().RenderFramework(frameworkConfig.Type, routes, generator.pkgs, frameworkConfig)
 	if err != nil {
-		log.Fatalf("Error scanning directory: %v", err)
+		log.Fatalf("Error generating API server: %v", err)
+	}
+	out.Adopt = *adopt
+
+	// Preview mode: diff or check the output without writing it
+	if preview {
+		os.Exit(reportOutput(out, *dryRun, *check))
 	}
 
 	// Print summary
@@ -1236,22 +1249,15 @@ func main() {
 		log.Printf("Warning: Failed to save analysis: %v", err)
 	}
 
-	// Create output directory
-	err = os.MkdirAll(config.OutputDir, 0755)
-	if err != nil {
-		log.Fatalf("Error creating output directory: %v", err)
-	}
-
-	// Generate API server
+	// Write API server
 	fmt.Println("\n🚀 Generating API server...")
-	err = generator.GenerateAPIServer()
-	if err != nil {
+	if err := out.Write(); err != nil {
 		log.Fatalf("Error generating API server: %v", err)
 	}
 
-	fmt.Printf("\n✅ API server generated in: %s\n", config.OutputDir)
+	fmt.Printf("\n✅ API server generated in: %s\n", outputDir)
 	fmt.Println("\nNext steps:")
-	fmt.Printf("   cd %s\n", config.OutputDir)
+	fmt.Printf("   cd %s\n", outputDir)
 	fmt.Println("   go mod tidy")
-	fmt.Println("   go run main.go")
+	fmt.Println("   go run .")
 }
\ No newline at end of file
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MethodMapping represents intelligent method-to-HTTP mappings. Patterns
+// are case-insensitive globs such as "Get*By*" or "{Publish,Release}*", or
+// regular expressions when prefixed with "re:". The path may use the
+// placeholders {resource}, {method}, {verb} and {field} as well as the
+// names of regexp capture groups; any other placeholder, such as {id}, is
+// left in place as a path parameter.
+type MethodMapping struct {
+	Patterns     []string `json:"patterns"`
+	Method       string   `json:"method"`
+	Path         string   `json:"path"`
+	Operation    string   `json:"operation"`
+	AutoGenerate bool     `json:"auto_generate"`
+	Priority     int      `json:"priority,omitempty"`
+}
+
+// SmartMethodMappings contains the default mapping rules. The first rule
+// that matches wins, so more specific patterns come first.
+var SmartMethodMappings = []MethodMapping{
+	// Relationship lookups, before the plain Get* and Find* rules
+	{Patterns: []string{"Get*By*", "Find*By*"}, Method: "GET", Path: "/{resource}/by/{field}", Operation: "get_by", AutoGenerate: true},
+
+	// Bulk operations, before the single-item rules
+	{Patterns: []string{"BulkCreate*", "BatchCreate*", "CreateMultiple*"}, Method: "POST", Path: "/{resource}/bulk", Operation: "bulk_create", AutoGenerate: true},
+	{Patterns: []string{"BulkUpdate*", "BatchUpdate*", "UpdateMultiple*"}, Method: "PUT", Path: "/{resource}/bulk", Operation: "bulk_update", AutoGenerate: true},
+	{Patterns: []string{"BulkDelete*", "BatchDelete*", "DeleteMultiple*"}, Method: "DELETE", Path: "/{resource}/bulk", Operation: "bulk_delete", AutoGenerate: true},
+
+	// CRUD operations
+	{Patterns: []string{"List*", "GetAll*", "FindAll*"}, Method: "GET", Path: "/{resource}", Operation: "list", AutoGenerate: true},
+	{Patterns: []string{"Get*", "Find*"}, Method: "GET", Path: "/{resource}/{id}", Operation: "get", AutoGenerate: true},
+	{Patterns: []string{"Create*", "Add*", "New*", "Insert*"}, Method: "POST", Path: "/{resource}", Operation: "create", AutoGenerate: true},
+	{Patterns: []string{"Update*", "Modify*", "Edit*", "Change*"}, Method: "PUT", Path: "/{resource}/{id}", Operation: "update", AutoGenerate: true},
+	{Patterns: []string{"Delete*", "Remove*", "Destroy*"}, Method: "DELETE", Path: "/{resource}/{id}", Operation: "delete", AutoGenerate: true},
+
+	// Search and filter operations
+	{Patterns: []string{"Search*", "Query*", "Filter*"}, Method: "GET", Path: "/{resource}/search", Operation: "search", AutoGenerate: true},
+	{Patterns: []string{"Count*", "Total*"}, Method: "GET", Path: "/{resource}/count", Operation: "count", AutoGenerate: true},
+	{Patterns: []string{"Exists*", "Check*"}, Method: "GET", Path: "/{resource}/{id}/exists", Operation: "exists", AutoGenerate: true},
+
+	// Status and state operations
+	{Patterns: []string{"Activate*", "Enable*"}, Method: "PUT", Path: "/{resource}/{id}/activate", Operation: "activate", AutoGenerate: true},
+	{Patterns: []string{"Deactivate*", "Disable*"}, Method: "PUT", Path: "/{resource}/{id}/deactivate", Operation: "deactivate", AutoGenerate: true},
+	{Patterns: []string{"Archive*"}, Method: "PUT", Path: "/{resource}/{id}/archive", Operation: "archive", AutoGenerate: true},
+	{Patterns: []string{"Restore*", "Unarchive*"}, Method: "PUT", Path: "/{resource}/{id}/restore", Operation: "restore", AutoGenerate: true},
+	{Patterns: []string{"Publish*"}, Method: "PUT", Path: "/{resource}/{id}/publish", Operation: "publish", AutoGenerate: true},
+	{Patterns: []string{"Unpublish*"}, Method: "PUT", Path: "/{resource}/{id}/unpublish", Operation: "unpublish", AutoGenerate: true},
+
+	// Relationship operations
+	{Patterns: []string{"Assign*", "Link*"}, Method: "POST", Path: "/{resource}/{id}/assign", Operation: "assign", AutoGenerate: true},
+	{Patterns: []string{"Unassign*", "Unlink*"}, Method: "DELETE", Path: "/{resource}/{id}/assign", Operation: "unassign", AutoGenerate: true},
+}
+
+// MappingMode selects how project rules combine with the default rules
+type MappingMode string
+
+const (
+	MappingMerge   MappingMode = "merge"   // project rules first, replacing defaults of the same operation
+	MappingReplace MappingMode = "replace" // project rules only
+)
+
+// MappingConfig is a project's smart mapping configuration, as read from
+// gofastapi-mappings.yaml or gofastapi-mappings.json
+type MappingConfig struct {
+	Mode    MappingMode   `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Rules   []MappingRule `json:"rules" yaml:"rules"`
+	Disable []string      `json:"disable,omitempty" yaml:"disable,omitempty"` // operations of default rules to drop
+}
+
+// MappingRule is a mapping rule as written in a project file. Rules are
+// tried by descending priority; rules of equal priority keep their order,
+// with project rules ahead of the defaults.
+type MappingRule struct {
+	Patterns     []string `json:"patterns" yaml:"patterns"`
+	Method       string   `json:"method" yaml:"method"`
+	Path         string   `json:"path" yaml:"path"`
+	Operation    string   `json:"operation" yaml:"operation"`
+	Priority     int      `json:"priority,omitempty" yaml:"priority,omitempty"`
+	AutoGenerate *bool    `json:"auto_generate,omitempty" yaml:"auto_generate,omitempty"` // defaults to true
+}
+
+// MappingFiles are the project files searched for mapping rules, in order
+var MappingFiles = []string{"gofastapi-mappings.yaml", "gofastapi-mappings.yml", "gofastapi-mappings.json"}
+
+// FindMappingFile returns the first mapping file present in dir, or ""
+func FindMappingFile(dir string) string {
+	for _, name := range MappingFiles {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// LoadMappingConfig reads and validates a YAML or JSON mapping file
+func LoadMappingConfig(file string) (*MappingConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &MappingConfig{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, config)
+	default:
+		err = json.Unmarshal(data, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+
+	if _, err := NewMethodMapper(config); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return config, nil
+}
+
+// MethodMapper matches method names against an ordered list of mapping rules
+type MethodMapper struct {
+	rules []compiledMapping
+}
+
+type compiledMapping struct {
+	mapping  MethodMapping
+	patterns []methodPattern
+}
+
+// methodPattern is a compiled pattern: either lower-case globs, one per
+// brace alternative, or a regular expression
+type methodPattern struct {
+	globs []string
+	re    *regexp.Regexp
+}
+
+// NewMethodMapper builds the rule list of a project configuration, which
+// may be nil for the defaults
+func NewMethodMapper(config *MappingConfig) (*MethodMapper, error) {
+	if config == nil {
+		config = &MappingConfig{}
+	}
+
+	var project []MethodMapping
+	for i, rule := range config.Rules {
+		mapping, err := rule.mapping()
+		if err != nil {
+			return nil, fmt.Errorf("mapping rule %d: %v", i+1, err)
+		}
+		project = append(project, mapping)
+	}
+
+	mappings := project
+	switch config.Mode {
+	case "", MappingMerge:
+		dropped := make(map[string]bool)
+		for _, operation := range config.Disable {
+			dropped[operation] = true
+		}
+		for _, mapping := range project {
+			dropped[mapping.Operation] = true
+		}
+		for _, mapping := range SmartMethodMappings {
+			if !dropped[mapping.Operation] {
+				mappings = append(mappings, mapping)
+			}
+		}
+	case MappingReplace:
+	default:
+		return nil, fmt.Errorf("unknown mapping mode %q, expected %q or %q", config.Mode, MappingMerge, MappingReplace)
+	}
+
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return mappings[i].Priority > mappings[j].Priority
+	})
+
+	mapper := &MethodMapper{}
+	for _, mapping := range mappings {
+		compiled := compiledMapping{mapping: mapping}
+		for _, pattern := range mapping.Patterns {
+			p, err := compileMethodPattern(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %v", mapping.Operation, err)
+			}
+			compiled.patterns = append(compiled.patterns, p)
+		}
+		mapper.rules = append(mapper.rules, compiled)
+	}
+	return mapper, nil
+}
+
+// mapping checks a project rule and fills in its defaults
+func (r MappingRule) mapping() (MethodMapping, error) {
+	if len(r.Patterns) == 0 {
+		return MethodMapping{}, fmt.Errorf("no patterns")
+	}
+	if r.Operation == "" {
+		return MethodMapping{}, fmt.Errorf("no operation")
+	}
+	method := strings.ToUpper(r.Method)
+	if !isHTTPMethod(method) {
+		return MethodMapping{}, fmt.Errorf("invalid HTTP method %q", r.Method)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return MethodMapping{}, fmt.Errorf("path %q must start with /", r.Path)
+	}
+
+	autoGenerate := true
+	if r.AutoGenerate != nil {
+		autoGenerate = *r.AutoGenerate
+	}
+	return MethodMapping{
+		Patterns:     r.Patterns,
+		Method:       method,
+		Path:         r.Path,
+		Operation:    r.Operation,
+		AutoGenerate: autoGenerate,
+		Priority:     r.Priority,
+	}, nil
+}
+
+// Rules returns the mapping rules in the order they are tried
+func (m *MethodMapper) Rules() []MethodMapping {
+	rules := make([]MethodMapping, len(m.rules))
+	for i, rule := range m.rules {
+		rules[i] = rule.mapping
+	}
+	return rules
+}
+
+// Match returns the first rule matching the method name, with the values
+// of any named regexp groups
+func (m *MethodMapper) Match(methodName string) (MethodMapping, map[string]string, bool) {
+	for _, rule := range m.rules {
+		for _, pattern := range rule.patterns {
+			if captures, ok := pattern.match(methodName); ok {
+				return rule.mapping, captures, true
+			}
+		}
+	}
+	return MethodMapping{}, nil, false
+}
+
+// compileMethodPattern compiles a glob or "re:" pattern
+func compileMethodPattern(pattern string) (methodPattern, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return methodPattern{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return methodPattern{re: re}, nil
+	}
+
+	var p methodPattern
+	for _, glob := range expandBraces(strings.ToLower(pattern)) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return methodPattern{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		p.globs = append(p.globs, glob)
+	}
+	return p, nil
+}
+
+// match reports whether the method name matches. Globs ignore case;
+// regular expressions are matched as written.
+func (p methodPattern) match(methodName string) (map[string]string, bool) {
+	if p.re != nil {
+		groups := p.re.FindStringSubmatch(methodName)
+		if groups == nil {
+			return nil, false
+		}
+		captures := make(map[string]string)
+		for i, name := range p.re.SubexpNames() {
+			if name != "" {
+				captures[name] = groups[i]
+			}
+		}
+		return captures, true
+	}
+
+	name := strings.ToLower(methodName)
+	for _, glob := range p.globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// expandBraces expands "{a,b}" alternatives, so "{get,find}*" gives
+// "get*" and "find*"
+func expandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}
+	}
+	end := strings.Index(pattern[start:], "}")
+	if end < 0 {
+		return []string{pattern}
+	}
+	end += start
+
+	var expanded []string
+	for _, alternative := range strings.Split(pattern[start+1:end], ",") {
+		for _, rest := range expandBraces(pattern[end+1:]) {
+			expanded = append(expanded, pattern[:start]+alternative+rest)
+		}
+	}
+	return expanded
+}
+
+// isHTTPMethod reports whether method is an upper-case HTTP method a
+// generated router can serve
+func isHTTPMethod(method string) bool {
+	switch method {
+	case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// SmartMethodMapping intelligently maps method names to HTTP routes
+func (ag *APIGenerator) SmartMethodMapping(methodName string, structName string) (MethodMapping, bool) {
+	return ag.mapMethod(methodName, ag.inflector.Resource(structName))
+}
+
+// mapMethod maps a method name to a route under the resource path segment
+func (ag *APIGenerator) mapMethod(methodName, resource string) (MethodMapping, bool) {
+	if mapping, captures, found := ag.mapper.Match(methodName); found {
+		mapping.Path = ag.buildCustomPath(mapping.Path, methodName, resource, captures)
+		return mapping, true
+	}
+
+	// Default mapping for unrecognized methods
+	return MethodMapping{
+		Patterns:     []string{methodName},
+		Method:       "POST",
+		Path:         "/" + resource + "/" + ag.inflector.Segment(methodName),
+		Operation:    "custom",
+		AutoGenerate: false,
+	}, false
+}
+
+// placeholderPattern matches a {name} placeholder in a path template
+var placeholderPattern = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// buildCustomPath fills in the placeholders of a path template
+func (ag *APIGenerator) buildCustomPath(template, methodName, resource string, captures map[string]string) string {
+	words := ag.inflector.Words(methodName)
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if value, ok := captures[name]; ok && value != "" {
+			return ag.inflector.Segment(value)
+		}
+
+		switch name {
+		case "resource":
+			return resource
+		case "method":
+			return ag.inflector.Join(words)
+		case "verb":
+			if len(words) > 0 {
+				return words[0]
+			}
+		case "field":
+			// "GetUserByEmail" -> "email"
+			for i := len(words) - 2; i >= 0; i-- {
+				if words[i] == "by" {
+					return ag.inflector.Join(words[i+1:])
+				}
+			}
+		}
+		return placeholder
+	})
+}
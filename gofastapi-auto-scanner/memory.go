@@ -0,0 +1,448 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// memoryDialect selects the in-memory store. It has no SQL; its column
+// types only decide which fields can be filtered and searched.
+var memoryDialect = &sqlDialect{Name: "memory", Types: sqliteDialect.Types}
+
+// memoryStore reports whether a generated server keeps its models in
+// memory instead of a database
+func memoryStore(config *FrameworkConfig) bool {
+	if !databaseEnabled(config) {
+		return false
+	}
+	dialect, err := databaseDialect(config.Database)
+	return err == nil && dialect == memoryDialect
+}
+
+// filterColumns are the columns Filter compares with query values: those
+// shown in JSON, except binary ones and those named like the pagination
+// parameters
+func (m *repositoryModel) filterColumns() []repositoryColumn {
+	var columns []repositoryColumn
+	for _, column := range m.Columns {
+		base := strings.TrimPrefix(column.Type, "*")
+		if column.JSON == "" || column.JSON == "limit" || column.JSON == "offset" || base == "[]byte" || base == "[]uint8" {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// memorySource renders repositories.go of the in-memory store: a
+// repository per model with the methods of the database repositories, over
+// a map guarded by a mutex, plus Filter for field equality
+func (plan *repositoryPlan) memorySource() string {
+	imports := map[string]bool{
+		"context": true, "errors": true, "fmt": true, "net/http": true, "reflect": true,
+		"sort": true, "strings": true, "sync": true, "time": true,
+	}
+
+	var repositories, fields, constructors strings.Builder
+	stringKeys := false
+	for _, model := range plan.models {
+		stringKeys = stringKeys || !model.AutoKey
+		repositories.WriteString(plan.memoryRepository(model))
+		fields.WriteString(fmt.Sprintf("	%s *%s\n", model.Name, model.Type))
+		constructors.WriteString(fmt.Sprintf("		%s: New%s(),\n", model.Name, model.Type))
+	}
+	if stringKeys {
+		imports["crypto/rand"] = true
+		imports["encoding/hex"] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString(renderImportBlock(imports, nil))
+	b.WriteString(fmt.Sprintf(`// ErrNotFound is returned by repositories when no item has the requested key
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned by repositories when an item is created with a
+// key that is already in use
+var ErrConflict = errors.New("record already exists")
+
+// defaultPageSize bounds the list and search responses of requests that do
+// not set a limit
+const defaultPageSize = 100
+
+// Repositories holds the in-memory store of every model
+type Repositories struct {
+%s}
+
+// NewRepositories creates an empty store for every model
+func NewRepositories() *Repositories {
+	return &Repositories{
+%s	}
+}
+
+// repositories holds the items of the running server; tests may replace it
+var repositories = NewRepositories()
+
+// setupDatabase has nothing to prepare: the in-memory stores start empty
+func (s *Server) setupDatabase() {}
+
+// repositoryStatus maps a repository error to an HTTP status code
+func repositoryStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// pageBounds returns the slice bounds of a page of n items. A limit of
+// zero or less returns every item after offset.
+func pageBounds(n, limit, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// containsFold reports whether value contains q, ignoring case
+func containsFold(value, q string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(q))
+}
+
+// filterValue formats a field the way Filter compares it with a query
+// value: times in RFC 3339, nil pointers as "" and everything else as
+// fmt prints it
+func filterValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// timestamp returns the current time at the precision databases store
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+`, fields.String(), constructors.String()))
+
+	if stringKeys {
+		b.WriteString(`// newKey returns a random key for an item whose string key is empty
+func newKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate key: %v", err))
+	}
+	return hex.EncodeToString(key)
+}
+
+`)
+	}
+	b.WriteString(repositories.String())
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// memoryRepository renders the in-memory repository of a model
+func (plan *repositoryPlan) memoryRepository(model *repositoryModel) string {
+	key := model.Key.Field
+
+	// insert fills in stamps and keys, then stores the item
+	var insert strings.Builder
+	var created, updated bool
+	for _, column := range model.Columns {
+		if column.stamp() {
+			created = created || column.Field == "CreatedAt"
+			updated = updated || column.Field == "UpdatedAt"
+		}
+	}
+	if created || updated {
+		insert.WriteString("	now := timestamp()\n")
+	}
+	if created {
+		insert.WriteString("	if item.CreatedAt.IsZero() {\n		item.CreatedAt = now\n	}\n")
+	}
+	if updated {
+		insert.WriteString("	item.UpdatedAt = now\n")
+	}
+	if model.AutoKey {
+		insert.WriteString(fmt.Sprintf("	if item.%[1]s == 0 {\n		r.next++\n		item.%[1]s = r.next\n	}\n", key))
+	} else {
+		insert.WriteString(fmt.Sprintf("	if item.%[1]s == \"\" {\n		item.%[1]s = newKey()\n	}\n", key))
+	}
+	insert.WriteString(fmt.Sprintf("	if _, exists := r.items[item.%s]; exists {\n		return ErrConflict\n	}\n", key))
+	if model.AutoKey {
+		insert.WriteString(fmt.Sprintf("	if item.%[1]s > r.next {\n		r.next = item.%[1]s\n	}\n", key))
+	}
+	insert.WriteString(fmt.Sprintf("	r.items[item.%s] = *item\n	return nil\n", key))
+
+	// Update keeps the creation time and refreshes the update time
+	var update strings.Builder
+	if created {
+		update.WriteString(fmt.Sprintf("	stored, ok := r.items[item.%s]\n", key))
+	} else {
+		update.WriteString(fmt.Sprintf("	_, ok := r.items[item.%s]\n", key))
+	}
+	update.WriteString("	if !ok {\n		return ErrNotFound\n	}\n")
+	if created {
+		update.WriteString("	item.CreatedAt = stored.CreatedAt\n")
+	}
+	if updated {
+		update.WriteString("	item.UpdatedAt = timestamp()\n")
+	}
+
+	next, save, restore := "", "", ""
+	if model.AutoKey {
+		next = fmt.Sprintf("	next  %s // highest key handed out\n", model.Key.Type)
+		save, restore = "	next := r.next\n", "			r.next = next\n"
+	}
+
+	var cases strings.Builder
+	for _, column := range model.filterColumns() {
+		cases.WriteString(fmt.Sprintf("		case %q:\n			field = item.%s\n", column.JSON, column.Field))
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`// %[1]s stores %[2]s values in memory. It is safe for concurrent use.
+type %[1]s struct {
+	mu    sync.RWMutex
+	items map[%[3]s]%[2]s
+%[4]s}
+
+// New%[1]s creates an empty repository
+func New%[1]s() *%[1]s {
+	return &%[1]s{items: make(map[%[3]s]%[2]s)}
+}
+
+// sorted returns the stored items in key order. Callers hold the lock.
+func (r *%[1]s) sorted() []%[2]s {
+	items := make([]%[2]s, 0, len(r.items))
+	for _, item := range r.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].%[5]s < items[j].%[5]s })
+	return items
+}
+
+// Get returns the %[2]s with the given key, or ErrNotFound
+func (r *%[1]s) Get(ctx context.Context, key %[3]s) (*%[2]s, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+// List returns up to limit items in key order after skipping offset items.
+// A limit of zero or less returns every remaining item.
+func (r *%[1]s) List(ctx context.Context, limit, offset int) ([]%[2]s, error) {
+	return r.Filter(ctx, nil, limit, offset)
+}
+
+// Filter returns the items whose fields, named as in JSON, equal the values
+// of filter, paginated like List. A name that is not a field of %[2]s
+// matches no item.
+func (r *%[1]s) Filter(ctx context.Context, filter map[string]string, limit, offset int) ([]%[2]s, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := []%[2]s{}
+	for _, item := range r.sorted() {
+		if r.matches(item, filter) {
+			items = append(items, item)
+		}
+	}
+	start, end := pageBounds(len(items), limit, offset)
+	return items[start:end], nil
+}
+
+// matches reports whether item has the field values of filter
+func (r *%[1]s) matches(item %[2]s, filter map[string]string) bool {
+	for name, value := range filter {
+		var field interface{}
+		switch name {
+%[6]s		default:
+			return false
+		}
+		if filterValue(field) != value {
+			return false
+		}
+	}
+	return true
+}
+
+`, model.Type, model.Name, model.Key.Type, next, key, cases.String()))
+
+	if columns := model.searchColumns(); len(columns) > 0 {
+		var matches []string
+		for _, column := range columns {
+			if column.nullable() {
+				matches = append(matches, fmt.Sprintf("item.%[1]s != nil && containsFold(*item.%[1]s, q)", column.Field))
+			} else {
+				matches = append(matches, fmt.Sprintf("containsFold(item.%s, q)", column.Field))
+			}
+		}
+		b.WriteString(fmt.Sprintf(`// Search returns the items whose text fields contain q, ignoring case,
+// paginated like List
+func (r *%[1]s) Search(ctx context.Context, q string, limit, offset int) ([]%[2]s, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := []%[2]s{}
+	for _, item := range r.sorted() {
+		if %[3]s {
+			items = append(items, item)
+		}
+	}
+	start, end := pageBounds(len(items), limit, offset)
+	return items[start:end], nil
+}
+
+`, model.Type, model.Name, strings.Join(matches, " || ")))
+	}
+
+	b.WriteString(fmt.Sprintf(`// insert stores item, filling in its key and timestamps when unset, or
+// returns ErrConflict when its key is in use. Callers hold the lock.
+func (r *%[1]s) insert(item *%[2]s) error {
+%[3]s}
+
+// Create stores item, filling in its key and timestamps when unset
+func (r *%[1]s) Create(ctx context.Context, item *%[2]s) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insert(item)
+}
+
+// BulkInsert stores items, filling in their keys and timestamps when
+// unset. No item is stored when any insert fails.
+func (r *%[1]s) BulkInsert(ctx context.Context, items []%[2]s) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+%[4]s	for i := range items {
+		if err := r.insert(&items[i]); err != nil {
+			for _, inserted := range items[:i] {
+				delete(r.items, inserted.%[5]s)
+			}
+%[6]s			return fmt.Errorf("failed to insert %[2]s %%d: %%w", i, err)
+		}
+	}
+	return nil
+}
+
+// Update replaces the item with item's key, or returns ErrNotFound
+func (r *%[1]s) Update(ctx context.Context, item *%[2]s) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+%[7]s	r.items[item.%[5]s] = *item
+	return nil
+}
+
+// Delete removes the item with the given key, or returns ErrNotFound
+func (r *%[1]s) Delete(ctx context.Context, key %[8]s) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.items, key)
+	return nil
+}
+
+`, model.Type, model.Name, insert.String(), save, key, restore, update.String(), model.Key.Type))
+
+	return b.String()
+}
+
+// memoryTests renders repositories_test.go of the in-memory store: the
+// tests of the database repositories, plus filtering and concurrent
+// creation
+func (plan *repositoryPlan) memoryTests() string {
+	imports := map[string]bool{"context": true, "errors": true, "sync": true, "testing": true}
+
+	var b strings.Builder
+	for _, model := range plan.models {
+		b.WriteString(plan.modelTest(model, fmt.Sprintf("	repository := New%s()\n", model.Type)))
+
+		var sample *repositoryColumn
+		for _, column := range model.filterColumns() {
+			if column.text() && !column.nullable() && column.Field != model.Key.Field {
+				column := column
+				sample = &column
+				break
+			}
+		}
+		filter := ""
+		if sample != nil {
+			filter = fmt.Sprintf(`	for _, value := range []string{"alpha", "beta"} {
+		var item %[1]s
+		item.%[2]s = value
+		if err := repository.Create(ctx, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := repository.Filter(ctx, map[string]string{%[3]q: "beta"}, 0, 0)
+	if err != nil || len(items) != 1 || items[0].%[2]s != "beta" {
+		t.Errorf("Filter returned %%+v: %%v", items, err)
+	}
+`, model.Name, sample.Field, sample.JSON)
+		}
+
+		b.WriteString(fmt.Sprintf(`func Test%[1]sFilter(t *testing.T) {
+	ctx := context.Background()
+	repository := New%[1]s()
+%[2]s	if items, err := repository.Filter(ctx, map[string]string{"no such field": ""}, 0, 0); err != nil || len(items) != 0 {
+		t.Errorf("Filter on an unknown field returned %%d items: %%v", len(items), err)
+	}
+
+	var item %[3]s
+	if err := repository.Create(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.Create(ctx, &item); !errors.Is(err, ErrConflict) {
+		t.Errorf("Create with a used key returned %%v, want ErrConflict", err)
+	}
+}
+
+func Test%[1]sConcurrency(t *testing.T) {
+	ctx := context.Background()
+	repository := New%[1]s()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var item %[3]s
+			if err := repository.Create(ctx, &item); err != nil {
+				t.Error(err)
+			}
+			if _, err := repository.List(ctx, 10, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if items, err := repository.List(ctx, 0, 0); err != nil || len(items) != 50 {
+		t.Errorf("List after concurrent creates returned %%d items: %%v", len(items), err)
+	}
+}
+
+`, model.Type, filter, model.Name))
+	}
+
+	return "package main\n\n" + renderImportBlock(imports, nil) + strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,947 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaSnapshot is the database schema of the scanned models as of the
+// last generated migration. It is saved next to the migrations so that the
+// next scan can be diffed against it.
+type SchemaSnapshot struct {
+	Dialect string        `json:"dialect"`
+	Version int           `json:"version"` // number of the last migration
+	Tables  []SchemaTable `json:"tables"`
+}
+
+// SchemaTable is the table of a model
+type SchemaTable struct {
+	Name    string         `json:"name"`
+	Model   string         `json:"model"`
+	Columns []SchemaColumn `json:"columns"`
+}
+
+// SchemaColumn is a stored model field
+type SchemaColumn struct {
+	Name     string `json:"name"`
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable,omitempty"`
+	Key      bool   `json:"key,omitempty"`
+	AutoKey  bool   `json:"auto_key,omitempty"`
+	Unique   bool   `json:"unique,omitempty"`
+	Index    bool   `json:"index,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// SchemaChange is one step of a migration. Destructive changes drop or
+// rewrite stored data: dropped tables and columns, type changes, columns
+// that become NOT NULL and tables rebuilt for a new primary key.
+type SchemaChange struct {
+	Kind        string   `json:"kind"` // create_table, drop_table, rename_table, rebuild_table, add_column, drop_column, rename_column, alter_column, add_index or drop_index
+	Table       string   `json:"table"`
+	Column      string   `json:"column,omitempty"`
+	Description string   `json:"description"`
+	Destructive bool     `json:"destructive,omitempty"`
+	Statements  []string `json:"statements"`
+}
+
+// SchemaMigration is a versioned migration file
+type SchemaMigration struct {
+	Version int
+	Name    string
+	Changes []SchemaChange
+	SQL     string
+}
+
+// FileName returns the name of the migration file, such as
+// 0002_add_column_products_note.sql
+func (m *SchemaMigration) FileName() string {
+	return fmt.Sprintf("%04d_%s.sql", m.Version, m.Name)
+}
+
+// Destructive returns the changes of the migration that drop or rewrite
+// stored data
+func (m *SchemaMigration) Destructive() []SchemaChange {
+	var changes []SchemaChange
+	for _, change := range m.Changes {
+		if change.Destructive {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// LoadSchemaSnapshot reads a snapshot file. A missing file yields nil.
+func LoadSchemaSnapshot(path string) (*SchemaSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	snapshot := &SchemaSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// Encode renders the snapshot as it is saved
+func (s *SchemaSnapshot) Encode() (string, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode schema snapshot: %v", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// Save writes the snapshot to path
+func (s *SchemaSnapshot) Save(path string) error {
+	data, err := s.Encode()
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// snapshot returns the schema of the plan's models
+func (plan *repositoryPlan) snapshot() *SchemaSnapshot {
+	snapshot := &SchemaSnapshot{Dialect: plan.dialect.Name}
+	for _, model := range plan.models {
+		table := SchemaTable{Name: model.Table, Model: model.Name}
+		for _, column := range model.Columns {
+			key := column.Field == model.Key.Field
+			table.Columns = append(table.Columns, SchemaColumn{
+				Name:     column.Column,
+				Field:    column.Field,
+				Type:     column.SQL,
+				Nullable: column.nullable(),
+				Key:      key,
+				AutoKey:  key && model.AutoKey,
+				Unique:   column.Unique && !key,
+				Index:    column.Index && !key && !column.Unique,
+				Default:  column.Default,
+			})
+		}
+		snapshot.Tables = append(snapshot.Tables, table)
+	}
+	return snapshot
+}
+
+// BuildSchemaSnapshot returns the schema of the database models among the
+// scanned packages
+func BuildSchemaSnapshot(packages map[string]*PackageInfo, config *DatabaseConfig) (*SchemaSnapshot, error) {
+	plan, err := newRepositoryPlan(packages, config)
+	if err != nil {
+		return nil, err
+	}
+	return plan.snapshot(), nil
+}
+
+// GenerateMigration diffs the models of packages against previous, the
+// snapshot saved with the last migration or nil before the first one. It
+// returns the next migration, nil when the schema did not change, and the
+// snapshot to save in place of previous.
+func GenerateMigration(previous *SchemaSnapshot, packages map[string]*PackageInfo, config *DatabaseConfig) (*SchemaMigration, *SchemaSnapshot, error) {
+	current, err := BuildSchemaSnapshot(packages, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current.Dialect == memoryDialect.Name {
+		return nil, nil, fmt.Errorf("the in-memory store has no schema to migrate")
+	}
+	changes, err := DiffSchemas(previous, current)
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous != nil {
+		current.Version = previous.Version
+	}
+	if len(changes) == 0 {
+		return nil, current, nil
+	}
+
+	current.Version++
+	migration := &SchemaMigration{Version: current.Version, Name: migrationName(changes), Changes: changes}
+	migration.SQL = renderMigration(migration)
+	return migration, current, nil
+}
+
+// DiffSchemas returns the changes that turn the schema old into new, in the
+// order a migration applies them. A nil old schema is empty. Tables are
+// matched by name, or by model when a model moved to another table, and
+// columns by name, or by field when a field was given another column.
+// SQLite cannot alter columns, so there a table whose columns change type,
+// nullability or default is rebuilt, as is a table whose primary key
+// changes in either database.
+func DiffSchemas(old, new *SchemaSnapshot) ([]SchemaChange, error) {
+	if old == nil {
+		old = &SchemaSnapshot{Dialect: new.Dialect}
+	}
+	if old.Dialect != new.Dialect {
+		return nil, fmt.Errorf("the migrations were written for %s, not %s: start a new migrations directory to change databases", old.Dialect, new.Dialect)
+	}
+	dialect, ok := sqlDialects[new.Dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type %q", new.Dialect)
+	}
+	diff := &schemaDiff{dialect: dialect}
+
+	oldTables := make(map[string]*SchemaTable)
+	for i := range old.Tables {
+		oldTables[old.Tables[i].Name] = &old.Tables[i]
+	}
+	newTables := make(map[string]bool)
+	for _, table := range new.Tables {
+		newTables[table.Name] = true
+	}
+	matched := make(map[string]bool)
+	for i := range new.Tables {
+		table := &new.Tables[i]
+		previous := oldTables[table.Name]
+		if previous == nil {
+			for j := range old.Tables {
+				candidate := &old.Tables[j]
+				if candidate.Model == table.Model && !newTables[candidate.Name] && !matched[candidate.Name] {
+					previous = candidate
+					break
+				}
+			}
+		}
+		if previous == nil {
+			diff.createTable(table)
+			continue
+		}
+		matched[previous.Name] = true
+		diff.alterTable(previous, table)
+	}
+	for i := range old.Tables {
+		if table := &old.Tables[i]; !matched[table.Name] {
+			diff.drops = append(diff.drops, SchemaChange{
+				Kind:        "drop_table",
+				Table:       table.Name,
+				Description: fmt.Sprintf("drop table %s", sqlIdent(table.Name)),
+				Destructive: true,
+				Statements:  []string{"DROP TABLE " + sqlIdent(table.Name)},
+			})
+		}
+	}
+
+	var changes []SchemaChange
+	for _, group := range [][]SchemaChange{diff.dropIndexes, diff.tables, diff.creates, diff.drops, diff.addIndexes} {
+		changes = append(changes, group...)
+	}
+	return changes, nil
+}
+
+// schemaDiff collects the changes of DiffSchemas by the phase they run in:
+// indexes are dropped before their columns and created after them
+type schemaDiff struct {
+	dialect     *sqlDialect
+	dropIndexes []SchemaChange
+	tables      []SchemaChange // renames, rebuilds and column changes
+	creates     []SchemaChange
+	drops       []SchemaChange
+	addIndexes  []SchemaChange
+}
+
+// schemaIndex is the index of a unique or indexed column
+type schemaIndex struct {
+	Name   string
+	Table  string
+	Column string
+	Unique bool
+}
+
+// tableIndexes returns the indexes of a table by name
+func tableIndexes(table *SchemaTable) map[string]schemaIndex {
+	indexes := make(map[string]schemaIndex)
+	for _, column := range table.Columns {
+		if column.Unique || column.Index {
+			index := schemaIndex{Table: table.Name, Column: column.Name, Unique: column.Unique}
+			index.Name = table.Name + "_" + column.Name + "_idx"
+			if column.Unique {
+				index.Name = table.Name + "_" + column.Name + "_key"
+			}
+			indexes[index.Name] = index
+		}
+	}
+	return indexes
+}
+
+// createTable adds a new table and its indexes
+func (diff *schemaDiff) createTable(table *SchemaTable) {
+	diff.creates = append(diff.creates, SchemaChange{
+		Kind:        "create_table",
+		Table:       table.Name,
+		Description: fmt.Sprintf("create table %s for %s", sqlIdent(table.Name), table.Model),
+		Statements:  []string{diff.createStatement(table, "IF NOT EXISTS "+sqlIdent(table.Name), nil)},
+	})
+	diff.addIndexes = append(diff.addIndexes, diff.indexChanges(nil, tableIndexes(table))...)
+}
+
+// createStatement renders the CREATE TABLE statement of table under name.
+// Columns in fill get a default so that existing rows can take them.
+func (diff *schemaDiff) createStatement(table *SchemaTable, name string, fill map[string]bool) string {
+	lines := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		lines[i] = fmt.Sprintf("	%s %s", sqlIdent(column.Name), diff.dialect.columnDefinition(column, fill[column.Name]))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)", name, strings.Join(lines, ",\n"))
+}
+
+// indexChanges returns the changes dropping the indexes of old missing
+// from new and creating those of new missing from old
+func (diff *schemaDiff) indexChanges(old, new map[string]schemaIndex) []SchemaChange {
+	var changes []SchemaChange
+	for _, name := range sortedKeys(new) {
+		if _, exists := old[name]; exists {
+			continue
+		}
+		index := new[name]
+		unique := ""
+		if index.Unique {
+			unique = "UNIQUE "
+		}
+		changes = append(changes, SchemaChange{
+			Kind:        "add_index",
+			Table:       index.Table,
+			Column:      index.Column,
+			Description: fmt.Sprintf("create %sindex %s", strings.ToLower(unique), sqlIdent(name)),
+			Statements:  []string{fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)", unique, sqlIdent(name), sqlIdent(index.Table), sqlIdent(index.Column))},
+		})
+	}
+	return changes
+}
+
+// alterTable adds the changes turning the table old into new
+func (diff *schemaDiff) alterTable(old, new *SchemaTable) {
+	d := diff.dialect
+	oldIndexes := tableIndexes(old)
+	if old.Name != new.Name {
+		diff.tables = append(diff.tables, SchemaChange{
+			Kind:        "rename_table",
+			Table:       new.Name,
+			Description: fmt.Sprintf("rename table %s to %s", sqlIdent(old.Name), sqlIdent(new.Name)),
+			Statements:  []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s", sqlIdent(old.Name), sqlIdent(new.Name))},
+		})
+	}
+	table := sqlIdent(new.Name)
+
+	// Pair the columns of both tables
+	oldColumns := make(map[string]*SchemaColumn)
+	for i := range old.Columns {
+		oldColumns[old.Columns[i].Name] = &old.Columns[i]
+	}
+	newColumns := make(map[string]bool)
+	for _, column := range new.Columns {
+		newColumns[column.Name] = true
+	}
+	source := make(map[string]*SchemaColumn) // new column -> old column
+	used := make(map[string]bool)
+	for i := range new.Columns {
+		column := &new.Columns[i]
+		previous := oldColumns[column.Name]
+		if previous == nil {
+			for j := range old.Columns {
+				candidate := &old.Columns[j]
+				if candidate.Field == column.Field && !newColumns[candidate.Name] && !used[candidate.Name] {
+					previous = candidate
+					break
+				}
+			}
+		}
+		if previous != nil {
+			source[column.Name] = previous
+			used[previous.Name] = true
+		}
+	}
+
+	// A new primary key, or any column change in SQLite, rebuilds the table
+	var reasons []string
+	destructive := false
+	rebuild := false
+	for _, column := range new.Columns {
+		previous := source[column.Name]
+		if column.Key && (previous == nil || !previous.Key || previous.AutoKey != column.AutoKey) {
+			reasons = append(reasons, fmt.Sprintf("primary key %s", sqlIdent(column.Name)))
+			rebuild, destructive = true, true
+		}
+		if previous != nil && d == sqliteDialect && (previous.Type != column.Type || previous.Nullable != column.Nullable || previous.Default != column.Default) {
+			rebuild = true
+		}
+	}
+
+	var columnChanges []SchemaChange
+	for _, column := range new.Columns {
+		previous := source[column.Name]
+		if previous == nil {
+			reasons = append(reasons, fmt.Sprintf("add column %s", sqlIdent(column.Name)))
+			columnChanges = append(columnChanges, SchemaChange{
+				Kind:        "add_column",
+				Table:       new.Name,
+				Column:      column.Name,
+				Description: fmt.Sprintf("add column %s to table %s", sqlIdent(column.Name), table),
+				Statements:  []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, sqlIdent(column.Name), d.columnDefinition(column, true))},
+			})
+			continue
+		}
+		if previous.Name != column.Name {
+			reasons = append(reasons, fmt.Sprintf("rename column %s to %s", sqlIdent(previous.Name), sqlIdent(column.Name)))
+			columnChanges = append(columnChanges, SchemaChange{
+				Kind:        "rename_column",
+				Table:       new.Name,
+				Column:      column.Name,
+				Description: fmt.Sprintf("rename column %s of table %s to %s", sqlIdent(previous.Name), table, sqlIdent(column.Name)),
+				Statements:  []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, sqlIdent(previous.Name), sqlIdent(column.Name))},
+			})
+		}
+		if change, ok := diff.alterColumn(new.Name, previous, column); ok {
+			reasons = append(reasons, change.Description)
+			destructive = destructive || change.Destructive
+			columnChanges = append(columnChanges, change)
+		}
+	}
+	for _, column := range old.Columns {
+		if !used[column.Name] {
+			reasons = append(reasons, fmt.Sprintf("drop column %s", sqlIdent(column.Name)))
+			destructive = true
+			columnChanges = append(columnChanges, SchemaChange{
+				Kind:        "drop_column",
+				Table:       new.Name,
+				Column:      column.Name,
+				Description: fmt.Sprintf("drop column %s of table %s", sqlIdent(column.Name), table),
+				Destructive: true,
+				Statements:  []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, sqlIdent(column.Name))},
+			})
+		}
+	}
+
+	newIndexes := tableIndexes(new)
+	if rebuild {
+		// The rebuilt table has no indexes: drop and recreate all of them
+		diff.dropIndexes = append(diff.dropIndexes, diff.dropIndexChanges(oldIndexes, nil)...)
+		diff.addIndexes = append(diff.addIndexes, diff.indexChanges(nil, newIndexes)...)
+		diff.tables = append(diff.tables, diff.rebuildTable(new, source, reasons, destructive))
+		return
+	}
+	diff.dropIndexes = append(diff.dropIndexes, diff.dropIndexChanges(oldIndexes, newIndexes)...)
+	diff.addIndexes = append(diff.addIndexes, diff.indexChanges(oldIndexes, newIndexes)...)
+	diff.tables = append(diff.tables, columnChanges...)
+}
+
+// dropIndexChanges returns the changes dropping the indexes of old missing
+// from new
+func (diff *schemaDiff) dropIndexChanges(old, new map[string]schemaIndex) []SchemaChange {
+	var changes []SchemaChange
+	for _, name := range sortedKeys(old) {
+		if _, kept := new[name]; kept {
+			continue
+		}
+		changes = append(changes, SchemaChange{
+			Kind:        "drop_index",
+			Table:       old[name].Table,
+			Column:      old[name].Column,
+			Description: fmt.Sprintf("drop index %s", sqlIdent(name)),
+			Statements:  []string{"DROP INDEX IF EXISTS " + sqlIdent(name)},
+		})
+	}
+	return changes
+}
+
+// alterColumn returns the change of a column's type, nullability or
+// default, if any. Columns that become NOT NULL have their NULLs replaced
+// by the zero value of the type.
+func (diff *schemaDiff) alterColumn(table string, old *SchemaColumn, new SchemaColumn) (SchemaChange, bool) {
+	d := diff.dialect
+	change := SchemaChange{Kind: "alter_column", Table: table, Column: new.Name}
+	column := sqlIdent(new.Name)
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", sqlIdent(table), column)
+
+	var details []string
+	if old.Type != new.Type {
+		details = append(details, fmt.Sprintf("change type from %s to %s", old.Type, new.Type))
+		change.Destructive = true
+		change.Statements = append(change.Statements, alter+fmt.Sprintf("TYPE %s USING CAST(%s AS %s)", new.Type, column, new.Type))
+	}
+	if old.Nullable && !new.Nullable && !new.Key {
+		details = append(details, "make NOT NULL")
+		change.Destructive = true
+		change.Statements = append(change.Statements,
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", sqlIdent(table), column, d.zeroValue(new.Type), column),
+			alter+"SET NOT NULL")
+	}
+	if !old.Nullable && new.Nullable {
+		details = append(details, "allow NULL")
+		change.Statements = append(change.Statements, alter+"DROP NOT NULL")
+	}
+	if old.Default != new.Default && new.Default != "" {
+		details = append(details, "set default "+new.Default)
+		change.Statements = append(change.Statements, alter+"SET DEFAULT "+new.Default)
+	} else if old.Default != new.Default {
+		details = append(details, "drop default")
+		change.Statements = append(change.Statements, alter+"DROP DEFAULT")
+	}
+	if len(details) == 0 {
+		return change, false
+	}
+	change.Description = fmt.Sprintf("%s column %s of table %s", strings.Join(details, ", "), column, sqlIdent(table))
+	return change, true
+}
+
+// rebuildTable returns the change that copies a table into a new one with
+// the schema of table. source maps the columns of table to the old columns
+// they are copied from.
+func (diff *schemaDiff) rebuildTable(table *SchemaTable, source map[string]*SchemaColumn, reasons []string, destructive bool) SchemaChange {
+	d := diff.dialect
+	name := sqlIdent(table.Name)
+	scratch := table.Name + "__rebuild"
+
+	fill := make(map[string]bool)
+	var targets, values []string
+	for _, column := range table.Columns {
+		previous := source[column.Name]
+		if previous == nil {
+			fill[column.Name] = true
+			continue
+		}
+		value := sqlIdent(previous.Name)
+		if previous.Type != column.Type {
+			value = fmt.Sprintf("CAST(%s AS %s)", value, column.Type)
+		}
+		if previous.Nullable && !column.Nullable {
+			value = fmt.Sprintf("COALESCE(%s, %s)", value, d.zeroValue(column.Type))
+		}
+		targets = append(targets, sqlIdent(column.Name))
+		values = append(values, value)
+	}
+
+	statements := []string{diff.createStatement(table, sqlIdent(scratch), fill)}
+	if len(targets) > 0 {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", sqlIdent(scratch), strings.Join(targets, ", "), strings.Join(values, ", "), name))
+	}
+	statements = append(statements,
+		"DROP TABLE "+name,
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", sqlIdent(scratch), name))
+	if d == postgresDialect {
+		statements = append(statements, fmt.Sprintf("ALTER INDEX %s RENAME TO %s", sqlIdent(scratch+"_pkey"), sqlIdent(table.Name+"_pkey")))
+		for _, column := range table.Columns {
+			if column.AutoKey {
+				statements = append(statements, fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+					strings.ReplaceAll(name, "'", "''"), strings.ReplaceAll(column.Name, "'", "''"), sqlIdent(column.Name), name))
+			}
+		}
+	}
+
+	return SchemaChange{
+		Kind:        "rebuild_table",
+		Table:       table.Name,
+		Description: fmt.Sprintf("rebuild table %s: %s", name, strings.Join(reasons, ", ")),
+		Destructive: destructive,
+		Statements:  statements,
+	}
+}
+
+// columnDefinition renders the type and constraints of a column. With fill
+// a NOT NULL column without a default gets the zero value of its type as
+// default, so that it can be added to a table that has rows.
+func (d *sqlDialect) columnDefinition(column SchemaColumn, fill bool) string {
+	switch {
+	case column.Key && column.AutoKey:
+		return d.autoKey(column.Type)
+	case column.Key:
+		return column.Type + " PRIMARY KEY"
+	}
+	definition := column.Type
+	if !column.Nullable {
+		definition += " NOT NULL"
+	}
+	switch {
+	case column.Default != "":
+		definition += " DEFAULT " + column.Default
+	case fill && !column.Nullable:
+		definition += " DEFAULT " + d.zeroValue(column.Type)
+	}
+	return definition
+}
+
+// zeroValue returns the literal of the Go zero value stored in a column
+func (d *sqlDialect) zeroValue(sqlType string) string {
+	switch {
+	case sqlType == "TEXT" || strings.HasPrefix(sqlType, "VARCHAR"):
+		return "''"
+	case sqlType == "BOOLEAN":
+		return "FALSE"
+	case sqlType == "BLOB":
+		return "X''"
+	case sqlType == "BYTEA":
+		return "''"
+	case strings.HasPrefix(sqlType, "TIMESTAMP"):
+		return "'0001-01-01 00:00:00+00:00'"
+	default:
+		return "0"
+	}
+}
+
+// migrationNamePattern matches the runs of characters migration names
+// replace with an underscore
+var migrationNamePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationName names a migration after its changes: create_tables for
+// one that only creates tables, the kind, table and column of a single
+// change, alter_<table> for changes to one table and update_schema
+// otherwise
+func migrationName(changes []SchemaChange) string {
+	creates := true
+	tables := make(map[string]bool)
+	var main []SchemaChange
+	for _, change := range changes {
+		creates = creates && (change.Kind == "create_table" || change.Kind == "add_index")
+		tables[change.Table] = true
+		if change.Kind != "add_index" && change.Kind != "drop_index" {
+			main = append(main, change)
+		}
+	}
+	if len(main) == 0 {
+		main = changes
+	}
+
+	name := "update_schema"
+	switch {
+	case creates:
+		name = "create_tables"
+	case len(main) == 1:
+		name = strings.Join([]string{main[0].Kind, main[0].Table, main[0].Column}, "_")
+	case len(tables) == 1:
+		name = "alter_" + main[0].Table
+	}
+	name = strings.Trim(migrationNamePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "_")
+	}
+	return name
+}
+
+// renderMigration renders the SQL file of a migration. Each destructive
+// change is listed in a "-- destructive:" header line, which the generated
+// migration runner looks for.
+func renderMigration(migration *SchemaMigration) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("-- %s\n", strings.TrimSuffix(migration.FileName(), ".sql")))
+	b.WriteString("-- Generated from the scanned models. Later scans add new migrations and\n")
+	b.WriteString("-- never rewrite this one.\n")
+	for _, change := range migration.Destructive() {
+		b.WriteString("-- destructive: " + change.Description + "\n")
+	}
+	for _, change := range migration.Changes {
+		b.WriteString("\n-- " + change.Description + "\n")
+		for _, statement := range change.Statements {
+			b.WriteString(statement + ";\n")
+		}
+	}
+	return b.String()
+}
+
+// writeMigrations adds the next migration of the configured database to
+// the migrations directory of outputDir, when the models changed since the
+// snapshot saved there, and the updated snapshot to out
+func writeMigrations(out *OutputSet, outputDir string, packages map[string]*PackageInfo, config *FrameworkConfig) error {
+	dir := filepath.Join(outputDir, "migrations")
+	migration, err := updateMigrations(out, dir, packages, config.Database)
+	if err != nil {
+		return err
+	}
+	if migration != nil {
+		for _, change := range migration.Destructive() {
+			log.Printf("Migration %s is destructive: %s", migration.FileName(), change.Description)
+		}
+	}
+	return nil
+}
+
+// updateMigrations adds the next migration and the schema snapshot in dir
+// to out and returns the migration, or nil when the schema did not change
+func updateMigrations(out *OutputSet, dir string, packages map[string]*PackageInfo, config *DatabaseConfig) (*SchemaMigration, error) {
+	snapshotPath := filepath.Join(dir, "schema.json")
+	previous, err := LoadSchemaSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	migration, snapshot, err := GenerateMigration(previous, packages, config)
+	if err != nil {
+		return nil, err
+	}
+	if migration != nil {
+		out.Add(filepath.Join(dir, migration.FileName()), migration.SQL)
+	}
+	data, err := snapshot.Encode()
+	if err != nil {
+		return nil, err
+	}
+	out.Add(snapshotPath, data)
+	return migration, nil
+}
+
+// runMigrations implements the migrations command: it scans the given
+// directories and writes the next migration of their models
+func runMigrations(config *GeneratorConfig, args []string) int {
+	flags := flag.NewFlagSet("migrations", flag.ContinueOnError)
+	dir := flags.String("dir", "./migrations", "directory holding the migrations and schema.json")
+	database := flags.String("database", "sqlite", "database the migrations are written for: sqlite or postgres")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = config.ScanRoots()
+	}
+
+	generator := NewAPIGenerator(config)
+	for _, root := range roots {
+		if err := generator.ScanDirectory(root); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", root, err)
+			return 2
+		}
+	}
+
+	out := NewOutputSet()
+	migration, err := updateMigrations(out, *dir, generator.pkgs, &DatabaseConfig{Type: *database})
+	if err == nil {
+		err = out.Write()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating migration: %v\n", err)
+		return 1
+	}
+	if migration == nil {
+		fmt.Println("✅ Schema is up to date")
+		return 0
+	}
+	for _, change := range migration.Changes {
+		fmt.Printf("   %s\n", change.Description)
+	}
+	for _, change := range migration.Destructive() {
+		fmt.Fprintf(os.Stderr, "⚠️  Destructive: %s\n", change.Description)
+	}
+	fmt.Printf("✅ Wrote %s\n", filepath.Join(*dir, migration.FileName()))
+	return 0
+}
+
+// migrationRunner renders migrations.go, which applies the embedded
+// migration files in order and records them in schema_migrations. The
+// server applies pending migrations at startup but stops before a
+// destructive one, which "go run . migrate -allow-destructive" applies.
+func (plan *repositoryPlan) migrationRunner() string {
+	d := plan.dialect
+	imports := map[string]bool{
+		"context": true, "database/sql": true, "embed": true, "flag": true, "fmt": true,
+		"io/fs": true, "log": true, "os": true, "sort": true, "strconv": true,
+		"strings": true, "time": true,
+	}
+	return "package main\n\n" + renderImportBlock(imports, nil) + fmt.Sprintf(`// migrationFiles holds the versioned migrations written by the generator
+// and the schema snapshot the next migration is diffed against
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change from the migrations directory
+type Migration struct {
+	Version     int
+	Name        string
+	SQL         string
+	Destructive bool // drops or rewrites stored data
+}
+
+// loadMigrations returns the embedded migrations ordered by version
+func loadMigrations() ([]Migration, error) {
+	paths, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "migrations/"), ".sql")
+		number, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("migration %%s does not start with a version number", path)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %%s and %%s have the same version", other, name)
+		}
+		seen[version] = name
+		data, err := migrationFiles.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Name:        name,
+			SQL:         string(data),
+			Destructive: strings.Contains(string(data), "\n-- destructive: "),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrationsTable records the applied migrations
+const migrationsTable = `+"`"+`CREATE TABLE IF NOT EXISTS "schema_migrations" (
+	"version" INTEGER PRIMARY KEY,
+	"name" TEXT NOT NULL,
+	"applied_at" %[1]s NOT NULL
+)`+"`"+`
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	if _, err := db.ExecContext(ctx, migrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %%v", err)
+	}
+	rows, err := db.QueryContext(ctx, `+"`"+`SELECT "version" FROM "schema_migrations"`+"`"+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %%v", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// MigrateDatabase applies the pending migrations in version order, each in
+// its own transaction, and returns those it applied. It stops before a
+// destructive migration unless allowDestructive is set.
+func MigrateDatabase(ctx context.Context, db *sql.DB, allowDestructive bool) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if migration.Destructive && !allowDestructive {
+			return done, fmt.Errorf("migration %%s drops or rewrites data: review it and run \"go run . migrate -allow-destructive\"", migration.Name)
+		}
+		if err := applyMigration(ctx, db, migration); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// applyMigration runs a migration and records it in one transaction
+func applyMigration(ctx context.Context, db *sql.DB, migration Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return fmt.Errorf("migration %%s failed: %%v", migration.Name, err)
+	}
+	record := `+"`"+`INSERT INTO "schema_migrations" ("version", "name", "applied_at") VALUES (%[2]s, %[3]s, %[4]s)`+"`"+`
+	if _, err := tx.ExecContext(ctx, record, migration.Version, migration.Name, timestamp()); err != nil {
+		return fmt.Errorf("failed to record migration %%s: %%v", migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+// setupDatabase applies the pending migrations the repositories need
+func (s *Server) setupDatabase() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := MigrateDatabase(ctx, repositories.db, false); err != nil {
+		log.Printf("Database is not ready: %%v", err)
+	}
+}
+
+// "go run . migrate" applies the pending migrations instead of starting the
+// server
+func init() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrateCommand(os.Args[2:]))
+	}
+}
+
+// migrateCommand applies the pending migrations, or with -status lists
+// every migration and whether it is applied
+func migrateCommand(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	allowDestructive := flags.Bool("allow-destructive", false, "apply migrations that drop or rewrite data")
+	status := flags.Bool("status", false, "list the migrations without applying them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	ctx := context.Background()
+
+	if *status {
+		migrations, err := loadMigrations()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		applied, err := appliedMigrations(ctx, repositories.db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, migration := range migrations {
+			state := "pending"
+			switch {
+			case applied[migration.Version]:
+				state = "applied"
+			case migration.Destructive:
+				state = "pending, destructive"
+			}
+			fmt.Printf("%%s\t%%s\n", migration.Name, state)
+		}
+		return 0
+	}
+
+	applied, err := MigrateDatabase(ctx, repositories.db, *allowDestructive)
+	for _, migration := range applied {
+		fmt.Printf("Applied %%s\n", migration.Name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(applied) == 0 {
+		fmt.Println("Database is up to date")
+	}
+	return 0
+}
+`, d.Types["time.Time"], d.placeholder(1), d.placeholder(2), d.placeholder(3))
+}
+
+// sortedKeys returns the keys of an index map in order
+func sortedKeys(indexes map[string]schemaIndex) []string {
+	keys := make([]string, 0, len(indexes))
+	for key := range indexes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModuleInfo describes the Go module a scanned package belongs to
+type ModuleInfo struct {
+	Path      string `json:"path"`
+	Dir       string `json:"dir"`
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// ModuleResolver maps package directories to import paths using the
+// nearest go.mod file and, when present, the enclosing go.work workspace
+type ModuleResolver struct {
+	modules    map[string]*ModuleInfo   // keyed by absolute module root
+	workspaces map[string][]*ModuleInfo // keyed by absolute go.work path
+	nearest    map[string]*ModuleInfo   // keyed by directory, nil when outside any module
+}
+
+// NewModuleResolver creates a new module resolver with empty caches
+func NewModuleResolver() *ModuleResolver {
+	return &ModuleResolver{
+		modules:    make(map[string]*ModuleInfo),
+		workspaces: make(map[string][]*ModuleInfo),
+		nearest:    make(map[string]*ModuleInfo),
+	}
+}
+
+// Resolve returns the import path of the package in dir together with the
+// module that contains it. An empty import path is returned when dir is not
+// inside any module.
+func (mr *ModuleResolver) Resolve(dir string) (string, *ModuleInfo, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve directory %s: %v", dir, err)
+	}
+
+	mod, err := mr.workspaceModule(absDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if mod == nil {
+		mod, err = mr.nearestModule(absDir)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if mod == nil {
+		return "", nil, nil
+	}
+
+	rel, err := filepath.Rel(mod.Dir, absDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to relate %s to module %s: %v", absDir, mod.Path, err)
+	}
+	if rel == "." {
+		return mod.Path, mod, nil
+	}
+	return mod.Path + "/" + filepath.ToSlash(rel), mod, nil
+}
+
+// nearestModule walks up from dir to the closest directory holding a go.mod.
+// The answer is cached for every directory visited on the way.
+func (mr *ModuleResolver) nearestModule(dir string) (*ModuleInfo, error) {
+	var visited []string
+	var found *ModuleInfo
+
+	for current := dir; ; {
+		if mod, cached := mr.nearest[current]; cached {
+			found = mod
+			break
+		}
+		visited = append(visited, current)
+
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			mod, err := mr.loadModule(current)
+			if err != nil {
+				return nil, err
+			}
+			found = mod
+			break
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	for _, d := range visited {
+		mr.nearest[d] = found
+	}
+	return found, nil
+}
+
+// workspaceModule returns the workspace module that contains dir, if dir is
+// inside a go.work workspace. GOWORK is honoured the same way the go command
+// honours it: "off" disables workspaces and a path selects a specific file.
+func (mr *ModuleResolver) workspaceModule(dir string) (*ModuleInfo, error) {
+	workFile := ""
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return nil, nil
+	case "":
+		workFile = findUp(dir, "go.work")
+	default:
+		workFile = gowork
+	}
+	if workFile == "" {
+		return nil, nil
+	}
+
+	mods, cached := mr.workspaces[workFile]
+	if !cached {
+		var err error
+		mods, err = mr.loadWorkspace(workFile)
+		if err != nil {
+			return nil, err
+		}
+		mr.workspaces[workFile] = mods
+	}
+
+	// The most deeply nested module root wins
+	for _, mod := range mods {
+		if mod.Dir == dir || strings.HasPrefix(dir, mod.Dir+string(filepath.Separator)) {
+			return mod, nil
+		}
+	}
+	return nil, nil
+}
+
+// loadWorkspace parses a go.work file and loads every module it uses
+func (mr *ModuleResolver) loadWorkspace(workFile string) ([]*ModuleInfo, error) {
+	directives, err := readModFile(workFile)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := filepath.Dir(workFile)
+	var mods []*ModuleInfo
+	for _, d := range directives {
+		if d.verb != "use" {
+			continue
+		}
+		modDir := d.arg
+		if !filepath.IsAbs(modDir) {
+			modDir = filepath.Join(workDir, filepath.FromSlash(modDir))
+		}
+		mod, err := mr.loadModule(filepath.Clean(modDir))
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+
+	sort.Slice(mods, func(i, j int) bool {
+		return len(mods[i].Dir) > len(mods[j].Dir)
+	})
+	return mods, nil
+}
+
+// loadModule reads the go.mod file in dir
+func (mr *ModuleResolver) loadModule(dir string) (*ModuleInfo, error) {
+	if mod := mr.modules[dir]; mod != nil {
+		return mod, nil
+	}
+
+	modFile := filepath.Join(dir, "go.mod")
+	directives, err := readModFile(modFile)
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &ModuleInfo{Dir: dir}
+	for _, d := range directives {
+		switch d.verb {
+		case "module":
+			mod.Path = d.arg
+		case "go":
+			mod.GoVersion = d.arg
+		}
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("%s: missing module directive", modFile)
+	}
+
+	mr.modules[dir] = mod
+	return mod, nil
+}
+
+// modDirective is a single "verb argument" line from a go.mod or go.work file
+type modDirective struct {
+	verb string
+	arg  string
+}
+
+// readModFile extracts the directives of a go.mod or go.work file. Block
+// forms such as "use ( ./a ./b )" are flattened into one directive per
+// line; only the first argument of each line is kept.
+func readModFile(path string) ([]modDirective, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var directives []modDirective
+	block := ""
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			arg, err := unquoteModArg(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+			}
+			directives = append(directives, modDirective{verb: block, arg: arg})
+			continue
+		}
+
+		if len(fields) >= 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		arg, err := unquoteModArg(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		directives = append(directives, modDirective{verb: fields[0], arg: arg})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	return directives, nil
+}
+
+// unquoteModArg strips Go string quoting from a go.mod argument
+func unquoteModArg(arg string) (string, error) {
+	if strings.HasPrefix(arg, `"`) || strings.HasPrefix(arg, "`") {
+		return strconv.Unquote(arg)
+	}
+	return arg, nil
+}
+
+// findUp returns the path of name in dir or its closest ancestor, or "" if
+// no ancestor contains it
+func findUp(dir, name string) string {
+	for {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,943 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification emitted
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPIDocument is an OpenAPI 3.1 document. Only the parts the generator
+// emits are modelled.
+type OpenAPIDocument struct {
+	OpenAPI    string                      `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                 `json:"info" yaml:"info"`
+	Servers    []OpenAPIServer             `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Tags       []OpenAPITag                `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths" yaml:"paths"`
+	Components *OpenAPIComponents          `json:"components,omitempty" yaml:"components,omitempty"`
+	Security   []map[string][]string       `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+// OpenAPIInfo is the info object of a document
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// OpenAPIServer is a server the API is served from
+type OpenAPIServer struct {
+	URL         string `json:"url" yaml:"url"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// OpenAPITag groups operations, one tag per resource
+type OpenAPITag struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// OpenAPIPathItem holds the operations of one path and the parameters
+// they share
+type OpenAPIPathItem struct {
+	Get        *OpenAPIOperation  `json:"get,omitempty" yaml:"get,omitempty"`
+	Put        *OpenAPIOperation  `json:"put,omitempty" yaml:"put,omitempty"`
+	Post       *OpenAPIOperation  `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete     *OpenAPIOperation  `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options    *OpenAPIOperation  `json:"options,omitempty" yaml:"options,omitempty"`
+	Head       *OpenAPIOperation  `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch      *OpenAPIOperation  `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Parameters []OpenAPIParameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// openAPIMethods lists the HTTP methods of a path item in document order
+var openAPIMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH"}
+
+// Operation returns the slot of the path item for an HTTP method, or nil
+// for a method OpenAPI has no field for
+func (p *OpenAPIPathItem) Operation(method string) **OpenAPIOperation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return &p.Get
+	case "PUT":
+		return &p.Put
+	case "POST":
+		return &p.Post
+	case "DELETE":
+		return &p.Delete
+	case "OPTIONS":
+		return &p.Options
+	case "HEAD":
+		return &p.Head
+	case "PATCH":
+		return &p.Patch
+	}
+	return nil
+}
+
+// OpenAPIOperation describes one method of a path
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId" yaml:"operationId"`
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+// OpenAPIParameter is a path, query or header parameter
+type OpenAPIParameter struct {
+	Ref         string  `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody is the body of an operation's request
+type OpenAPIRequestBody struct {
+	Ref         string                      `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool                        `json:"required,omitempty" yaml:"required,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+// OpenAPIResponse is one response of an operation
+type OpenAPIResponse struct {
+	Ref         string                      `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Description string                      `json:"description" yaml:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the schema and example of a body
+type OpenAPIMediaType struct {
+	Schema  *Schema     `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+// OpenAPIComponents holds the reusable parts of a document
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema                `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Parameters      map[string]*OpenAPIParameter      `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBodies   map[string]*OpenAPIRequestBody    `json:"requestBodies,omitempty" yaml:"requestBodies,omitempty"`
+	Responses       map[string]*OpenAPIResponse       `json:"responses,omitempty" yaml:"responses,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+}
+
+// OpenAPISecurityScheme describes how requests authenticate
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type" yaml:"type"`
+	Scheme       string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Schema is a JSON Schema as used by OpenAPI 3.1. Type is a string, or a
+// list such as ["string", "null"] for a nullable value. Nullable is the
+// OpenAPI 3.0 spelling, read from imported documents only.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Examples             []interface{}      `json:"examples,omitempty" yaml:"examples,omitempty"`
+	Example              interface{}        `json:"example,omitempty" yaml:"example,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
+}
+
+// OpenAPIOptions sets the document-level fields of an emitted document
+type OpenAPIOptions struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	// Security documents the authentication of routes that require it.
+	// Generated servers only enforce it when their auth config does.
+	Security bool
+}
+
+// openAPIOptions derives the document options of a framework config
+func openAPIOptions(config *FrameworkConfig) OpenAPIOptions {
+	options := OpenAPIOptions{
+		Title:    fmt.Sprintf("Generated %s API", strings.Title(string(config.Type))),
+		Version:  "1.0.0",
+		Security: config.Auth != nil && config.Auth.Required,
+	}
+	if docs := config.Docs; docs != nil {
+		if docs.Title != "" {
+			options.Title = docs.Title
+		}
+		if docs.Version != "" {
+			options.Version = docs.Version
+		}
+		if docs.Host != "" {
+			options.Servers = append(options.Servers, "http://"+strings.TrimSuffix(docs.Host, "/")+docs.BasePath)
+		} else if docs.BasePath != "" {
+			options.Servers = append(options.Servers, docs.BasePath)
+		}
+	}
+	return options
+}
+
+// OpenAPI builds the OpenAPI document of the scanned packages' route table
+func (ag *APIGenerator) OpenAPI(options OpenAPIOptions) *OpenAPIDocument {
+	return BuildOpenAPI(ag.GenerateAPIRoutes(), ag.pkgs, options)
+}
+
+// BuildOpenAPI builds an OpenAPI document from routes. Structs of packages
+// that routes accept or return, or that carry @api.model, become component
+// schemas.
+func BuildOpenAPI(routes []APIRoute, packages map[string]*PackageInfo, options OpenAPIOptions) *OpenAPIDocument {
+	b := newOpenAPIBuilder(packages)
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       options.Title,
+			Version:     options.Version,
+			Description: options.Description,
+		},
+		Paths: make(map[string]*OpenAPIPathItem),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "Generated API"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+	for _, url := range options.Servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: url})
+	}
+
+	// Routes with a method first, so that struct-level routes only fill
+	// the methods nothing else serves
+	ordered := append([]APIRoute(nil), routes...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Method != "" && ordered[j].Method == ""
+	})
+
+	tags := make(map[string]bool)
+	operationIDs := make(map[string]bool)
+	secured := false
+	for _, route := range ordered {
+		methods := []string{route.Method}
+		if route.Method == "" {
+			methods = route.Methods
+		}
+		path := templatePath(route.Path)
+		for _, method := range methods {
+			item := doc.Paths[path]
+			if item == nil {
+				item = &OpenAPIPathItem{}
+			}
+			slot := item.Operation(method)
+			if slot == nil || *slot != nil {
+				continue
+			}
+
+			operation := b.operation(route, method, path, operationIDs)
+			if options.Security && route.Auth.Required {
+				operation.Security = []map[string][]string{{"bearerAuth": {}}}
+				operation.Responses[strconv.Itoa(http.StatusUnauthorized)] = &OpenAPIResponse{Description: http.StatusText(http.StatusUnauthorized)}
+				secured = true
+			} else if options.Security && route.Auth.Type == "optional" {
+				operation.Security = []map[string][]string{{"bearerAuth": {}}, {}}
+				secured = true
+			}
+			*slot = operation
+			doc.Paths[path] = item
+			for _, tag := range operation.Tags {
+				tags[tag] = true
+			}
+		}
+	}
+
+	// Models declared with @api.model are documented even when no route
+	// refers to them
+	for _, key := range b.keys {
+		ref := b.structs[key]
+		for _, annotation := range ref.info.Annotations {
+			if annotation.Key == "model" {
+				b.component(key)
+			}
+		}
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, OpenAPITag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	if len(b.schemas) > 0 || secured {
+		doc.Components = &OpenAPIComponents{}
+		if len(b.schemas) > 0 {
+			doc.Components.Schemas = b.schemas
+		}
+		if secured {
+			doc.Components.SecuritySchemes = map[string]*OpenAPISecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			}
+		}
+	}
+	return doc
+}
+
+// JSON renders the document as indented JSON
+func (d *OpenAPIDocument) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// YAML renders the document as YAML
+func (d *OpenAPIDocument) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// WriteOpenAPIFiles adds openapi.json and openapi.yaml in dir to out
+func WriteOpenAPIFiles(out *OutputSet, dir string, doc *OpenAPIDocument) error {
+	jsonData, err := doc.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to render OpenAPI JSON: %v", err)
+	}
+	out.Add(filepath.Join(dir, "openapi.json"), string(jsonData))
+	yamlData, err := doc.YAML()
+	if err != nil {
+		return fmt.Errorf("failed to render OpenAPI YAML: %v", err)
+	}
+	out.Add(filepath.Join(dir, "openapi.yaml"), string(yamlData))
+	return nil
+}
+
+// openAPIBuilder resolves Go types to schemas, collecting the component
+// schemas of the structs it meets
+type openAPIBuilder struct {
+	structs map[string]structRef // keyed by import path + "." + name
+	byName  map[string][]string  // struct name -> keys
+	keys    []string             // sorted keys of structs
+	names   map[string]string    // key -> component name
+	taken   map[string]bool      // component names in use
+	schemas map[string]*Schema
+}
+
+// structRef is a scanned struct and the package declaring it
+type structRef struct {
+	pkg  *PackageInfo
+	info StructInfo
+}
+
+func newOpenAPIBuilder(packages map[string]*PackageInfo) *openAPIBuilder {
+	b := &openAPIBuilder{
+		structs: make(map[string]structRef),
+		byName:  make(map[string][]string),
+		names:   make(map[string]string),
+		taken:   make(map[string]bool),
+		schemas: make(map[string]*Schema),
+	}
+	for importPath, pkg := range packages {
+		for _, structInfo := range pkg.Structs {
+			key := importPath + "." + structInfo.Name
+			b.structs[key] = structRef{pkg: pkg, info: structInfo}
+			b.keys = append(b.keys, key)
+		}
+	}
+	sort.Strings(b.keys)
+	for _, key := range b.keys {
+		name := b.structs[key].info.Name
+		b.byName[name] = append(b.byName[name], key)
+	}
+	return b
+}
+
+// operation builds the operation of route for one method
+func (b *openAPIBuilder) operation(route APIRoute, method, path string, operationIDs map[string]bool) *OpenAPIOperation {
+	operation := &OpenAPIOperation{
+		OperationID: b.operationID(route, method, operationIDs),
+		Summary:     route.Docs.Summary,
+		Description: route.Docs.Description,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	if operation.Summary == "" && route.Function != "" {
+		operation.Summary = humanize(route.Function)
+	}
+	if tag := pathTag(path); tag != "" {
+		operation.Tags = []string{tag}
+	}
+	operation.Parameters = b.parameters(route, path)
+
+	// The request body and its example
+	var bodyExample interface{}
+	var responseExamples = make(map[int]interface{})
+	for _, example := range route.Docs.Examples {
+		if example.Status != 0 {
+			responseExamples[example.Status] = example.Value
+		} else if bodyExample == nil {
+			bodyExample = example.Value
+		}
+	}
+	if body := b.requestSchema(route); body != nil {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{"application/json": {Schema: body, Example: bodyExample}},
+		}
+	} else if bodyExample != nil {
+		responseExamples[route.SuccessResponse().Status] = bodyExample
+	}
+
+	responses := route.Responses
+	if len(responses) == 0 {
+		responses = []ResponseSpec{route.SuccessResponse()}
+	}
+	for _, spec := range responses {
+		response := &OpenAPIResponse{Description: spec.Description}
+		if response.Description == "" {
+			response.Description = http.StatusText(spec.Status)
+		}
+		if spec.Type != "" {
+			response.Content = map[string]OpenAPIMediaType{
+				"application/json": {Schema: b.schema(spec.Type, route.ImportPath), Example: responseExamples[spec.Status]},
+			}
+		}
+		operation.Responses[strconv.Itoa(spec.Status)] = response
+	}
+	return operation
+}
+
+// operationID returns a unique operation ID: the function name, qualified
+// by the struct or suffixed with the method when it is taken
+func (b *openAPIBuilder) operationID(route APIRoute, method string, taken map[string]bool) string {
+	candidates := []string{route.Function}
+	if route.Struct != "" {
+		candidates = append(candidates, route.Struct+route.Function)
+	}
+	if route.Function == "" {
+		candidates = []string{route.Struct + strings.Title(strings.ToLower(method))}
+	}
+	id := candidates[len(candidates)-1]
+	for _, candidate := range candidates {
+		if candidate != "" && !taken[candidate] {
+			id = candidate
+			break
+		}
+	}
+	for n := 2; taken[id]; n++ {
+		id = fmt.Sprintf("%s%d", candidates[len(candidates)-1], n)
+	}
+	taken[id] = true
+	return id
+}
+
+// parameters lists the path and query parameters of route: every path
+// wildcard, the query values the bound function reads and the parameters
+// declared with @api.doc.param
+func (b *openAPIBuilder) parameters(route APIRoute, path string) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	index := make(map[string]int)
+	add := func(param OpenAPIParameter) {
+		key := param.In + ":" + param.Name
+		if i, ok := index[key]; ok {
+			if param.Description != "" {
+				params[i].Description = param.Description
+			}
+			if param.Schema != nil {
+				params[i].Schema = param.Schema
+			}
+			params[i].Required = params[i].Required || param.Required
+			return
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+
+	bound := make(map[string]BoundParam)
+	if route.Binding != nil {
+		for _, param := range route.Binding.Params {
+			bound[param.Source+":"+param.Key] = param
+		}
+	}
+	declared := make(map[string]string)
+	for _, param := range route.Parameter {
+		declared[param.Name] = param.Type
+	}
+
+	for _, name := range pathWildcards(path) {
+		typ := declared[name]
+		if param, ok := bound[SourcePath+":"+name]; ok {
+			typ = param.Type
+		}
+		if typ == "" {
+			typ = "string"
+		}
+		add(OpenAPIParameter{Name: name, In: "path", Required: true, Schema: b.schema(typ, route.ImportPath)})
+	}
+
+	if route.Binding != nil {
+		for _, param := range route.Binding.Params {
+			if param.Source == SourceQuery {
+				add(OpenAPIParameter{Name: param.Key, In: "query", Schema: b.schema(param.Type, route.ImportPath)})
+			}
+		}
+	} else {
+		// Smart mapping lists and searches take these query parameters
+		for _, param := range route.Parameter {
+			if param.Name == "q" || param.Name == "limit" || param.Name == "offset" {
+				add(OpenAPIParameter{Name: param.Name, In: "query", Schema: b.schema(param.Type, route.ImportPath)})
+			}
+		}
+	}
+
+	for _, param := range route.Docs.Params {
+		in := param.In
+		if in == "" {
+			in = "query"
+		}
+		p := OpenAPIParameter{Name: param.Name, In: in, Description: param.Description, Required: param.Required || in == "path"}
+		if param.Type != "" {
+			p.Schema = b.schema(param.Type, route.ImportPath)
+		} else if _, exists := index[in+":"+param.Name]; !exists {
+			p.Schema = &Schema{Type: "string"}
+		}
+		add(p)
+	}
+	return params
+}
+
+// requestSchema returns the schema of the route's request body, or nil
+func (b *openAPIBuilder) requestSchema(route APIRoute) *Schema {
+	if route.Binding != nil {
+		for _, param := range route.Binding.Params {
+			if param.Source == SourceBody {
+				return b.schema(param.Type, route.ImportPath)
+			}
+		}
+	}
+	if route.RequestType != "" {
+		return b.schema(route.RequestType, route.ImportPath)
+	}
+	return nil
+}
+
+// schema returns the schema of a Go type string. Types are spelled either
+// relative to the package at importPath, such as "[]User", or with full
+// import paths, such as "*example.com/app/models.User".
+func (b *openAPIBuilder) schema(typ, importPath string) *Schema {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		schema := b.schema(typ[1:], importPath)
+		if name, ok := schema.Type.(string); ok && schema.Ref == "" {
+			schema.Type = []string{name, "null"}
+		}
+		return schema
+	case typ == "[]byte":
+		return &Schema{Type: "string", Format: "byte"}
+	case strings.HasPrefix(typ, "["):
+		end := strings.Index(typ, "]")
+		if end < 0 {
+			return &Schema{}
+		}
+		return &Schema{Type: "array", Items: b.schema(typ[end+1:], importPath)}
+	case strings.HasPrefix(typ, "map["):
+		end := matchingBracket(typ, len("map"))
+		if end < 0 {
+			return &Schema{Type: "object"}
+		}
+		return &Schema{Type: "object", AdditionalProperties: b.schema(typ[end+1:], importPath)}
+	case strings.HasPrefix(typ, "struct{"):
+		return &Schema{Type: "object"}
+	}
+
+	if schema := builtinSchema(typ); schema != nil {
+		return schema
+	}
+	if key := b.resolve(typ, importPath); key != "" {
+		return &Schema{Ref: "#/components/schemas/" + b.component(key)}
+	}
+	return &Schema{}
+}
+
+// resolve finds the struct a named type refers to, returning its key
+func (b *openAPIBuilder) resolve(typ, importPath string) string {
+	qualifier, name := "", typ
+	if dot := strings.LastIndex(typ, "."); dot >= 0 {
+		qualifier, name = typ[:dot], typ[dot+1:]
+	}
+	if _, ok := b.structs[qualifier+"."+name]; ok && qualifier != "" {
+		return qualifier + "." + name
+	}
+
+	keys := b.byName[name]
+	for _, key := range keys {
+		ref := b.structs[key]
+		if qualifier == "" && ref.pkg.ImportPath == importPath || qualifier != "" && qualifier == ref.pkg.Name {
+			return key
+		}
+	}
+	if qualifier == "" && len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// component returns the schema name of a struct, building its schema on
+// first use. Structs of the same name in different packages are told apart
+// by their package name.
+func (b *openAPIBuilder) component(key string) string {
+	if name, ok := b.names[key]; ok {
+		return name
+	}
+	ref := b.structs[key]
+	name := ref.info.Name
+	if b.taken[name] {
+		name = strings.Title(ref.pkg.Name) + ref.info.Name
+	}
+	for n := 2; b.taken[name]; n++ {
+		name = fmt.Sprintf("%s%d", ref.info.Name, n)
+	}
+	b.names[key] = name
+	b.taken[name] = true
+
+	schema := &Schema{Type: "object", Description: docText(ref.info.Doc)}
+	b.schemas[name] = schema
+	var promoted []*Schema
+	for _, field := range ref.info.Fields {
+		if promotedField(field) {
+			typ := field.Type
+			if field.QualifiedType != "" {
+				typ = field.QualifiedType
+			}
+			if key := b.resolve(strings.TrimPrefix(typ, "*"), ref.pkg.ImportPath); key != "" {
+				embedded := b.schemas[b.component(key)]
+				if strings.HasPrefix(typ, "*") {
+					// A nil pointer omits every promoted field
+					embedded = &Schema{Properties: embedded.Properties}
+				}
+				promoted = append(promoted, embedded)
+				continue
+			}
+		}
+		jsonName, omitEmpty, skip := fieldJSON(field)
+		if skip {
+			continue
+		}
+		typ := field.Type
+		if field.QualifiedType != "" {
+			typ = field.QualifiedType
+		}
+		property := b.schema(typ, ref.pkg.ImportPath)
+		required := applyFieldConstraints(property, field)
+		if schema.Properties == nil {
+			schema.Properties = make(map[string]*Schema)
+		}
+		schema.Properties[jsonName] = property
+		if required || !omitEmpty && !strings.HasPrefix(field.Type, "*") {
+			schema.Required = append(schema.Required, jsonName)
+		}
+	}
+
+	// Fields of the struct itself take precedence over promoted fields
+	for _, embedded := range promoted {
+		var jsonNames []string
+		for jsonName := range embedded.Properties {
+			jsonNames = append(jsonNames, jsonName)
+		}
+		sort.Strings(jsonNames)
+		for _, jsonName := range jsonNames {
+			if _, ok := schema.Properties[jsonName]; ok {
+				continue
+			}
+			if schema.Properties == nil {
+				schema.Properties = make(map[string]*Schema)
+			}
+			schema.Properties[jsonName] = embedded.Properties[jsonName]
+			if contains(embedded.Required, jsonName) {
+				schema.Required = append(schema.Required, jsonName)
+			}
+		}
+	}
+	return name
+}
+
+// promotedField reports whether the fields of an embedded struct are
+// promoted into the embedding struct's JSON object, which encoding/json
+// does unless the json tag names the field
+func promotedField(field FieldInfo) bool {
+	if !field.Embedded {
+		return false
+	}
+	for _, tag := range field.Tags {
+		if tag.Key == "json" {
+			return strings.Split(tag.Value, ",")[0] == ""
+		}
+	}
+	return true
+}
+
+// builtinSchema maps predeclared and well-known standard library types
+func builtinSchema(typ string) *Schema {
+	switch typ {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "uint", "uint8", "uint16", "byte", "uintptr":
+		return &Schema{Type: "integer"}
+	case "int32", "uint32", "rune":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int64", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}
+	case "time.Time":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "time.Duration":
+		return &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case "interface{}", "any", "encoding/json.RawMessage", "json.RawMessage":
+		return &Schema{}
+	}
+	return nil
+}
+
+// matchingBracket returns the index of the "]" closing the "[" at open
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// fieldJSON returns the JSON name of a field and whether it is omitted when
+// empty; skip is set for unexported fields and fields tagged "-"
+func fieldJSON(field FieldInfo) (name string, omitEmpty, skip bool) {
+	if field.Name == "" || !isExportedName(field.Name) {
+		return "", false, true
+	}
+	name = field.Name
+	for _, tag := range field.Tags {
+		if tag.Key != "json" {
+			continue
+		}
+		parts := strings.Split(tag.Value, ",")
+		if parts[0] == "-" && len(parts) == 1 {
+			return "", false, true
+		}
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		for _, option := range parts[1:] {
+			if option == "omitempty" || option == "omitzero" {
+				omitEmpty = true
+			}
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// applyFieldConstraints copies the validate and binding tag rules and the
+// example tag of a field onto its schema, reporting whether the field is
+// required
+func applyFieldConstraints(schema *Schema, field FieldInfo) bool {
+	required := false
+	for _, tag := range field.Tags {
+		switch tag.Key {
+		case "example":
+			schema.Examples = append(schema.Examples, tag.Value)
+		case "validate", "binding":
+			for _, rule := range strings.Split(tag.Value, ",") {
+				name, arg, _ := strings.Cut(rule, "=")
+				switch name {
+				case "required":
+					required = true
+				case "email":
+					schema.Format = "email"
+				case "url", "uri":
+					schema.Format = "uri"
+				case "uuid", "uuid4":
+					schema.Format = "uuid"
+				case "min", "gte":
+					setBound(schema, arg, true)
+				case "max", "lte":
+					setBound(schema, arg, false)
+				case "len":
+					setBound(schema, arg, true)
+					setBound(schema, arg, false)
+				case "oneof":
+					for _, value := range strings.Fields(arg) {
+						schema.Enum = append(schema.Enum, value)
+					}
+				}
+			}
+		}
+	}
+	return required
+}
+
+// setBound applies a min or max rule: a length for strings, a count for
+// arrays and a value for numbers
+func setBound(schema *Schema, arg string, lower bool) {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+	typ, _ := schema.Type.(string)
+	if types, ok := schema.Type.([]string); ok && len(types) > 0 {
+		typ = types[0]
+	}
+	count := int(n)
+	switch typ {
+	case "string":
+		if lower {
+			schema.MinLength = &count
+		} else {
+			schema.MaxLength = &count
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &count
+		} else {
+			schema.MaxItems = &count
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
+
+// versionSegment matches path prefixes such as "v1"
+var versionSegment = regexp.MustCompile(`^v[0-9]+$`)
+
+// pathTag returns the resource an operation is grouped under: the first
+// static path segment other than an "api" or version prefix
+func pathTag(path string) string {
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" || segment == "api" || versionSegment.MatchString(segment) || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		return segment
+	}
+	return ""
+}
+
+// humanize turns an identifier into a sentence, so "GetUserByEmail" reads
+// "Get user by email"
+func humanize(name string) string {
+	words := NewInflector(nil).Words(name)
+	if len(words) == 0 {
+		return name
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+// docText returns the prose of a doc comment: the lines before its first
+// @api annotation
+func docText(doc string) string {
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "@api") {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// describeDocs collects the docs of a route from the doc comment and the
+// @api.doc annotations of the function it calls. The first line of the
+// comment is the summary unless @api.doc.title gives one.
+func describeDocs(doc string, annotations []Annotation) RouteDocs {
+	var docs RouteDocs
+	if text := docText(doc); text != "" {
+		summary, _, _ := strings.Cut(text, "\n")
+		docs.Summary = strings.TrimSpace(summary)
+		docs.Description = text
+	}
+
+	for _, annotation := range annotations {
+		if annotation.Node == nil {
+			continue
+		}
+		positional := annotation.Node.Positional()
+		switch annotation.Key {
+		case "doc.title":
+			if len(positional) > 0 {
+				docs.Summary = positional[0].String()
+			}
+		case "doc.description":
+			if len(positional) > 0 {
+				docs.Description = positional[0].String()
+			}
+		case "doc.param":
+			if len(positional) == 0 {
+				continue
+			}
+			param := DocParam{Name: positional[0].String()}
+			fields := []*string{&param.In, &param.Type, &param.Description}
+			for i, value := range positional[1:] {
+				if i < len(fields) {
+					*fields[i] = value.String()
+				}
+			}
+			if value, ok := annotation.Node.Named("description"); ok {
+				param.Description = value.String()
+			}
+			if value, ok := annotation.Node.Named("required"); ok {
+				param.Required = value.Kind == BoolValue && value.Bool
+			}
+			docs.Params = append(docs.Params, param)
+		case "doc.example":
+			if len(positional) == 0 {
+				continue
+			}
+			example := DocExample{Value: positional[0].Interface()}
+			if value, ok := annotation.Node.Named("status"); ok && value.Kind == NumberValue {
+				example.Status = int(value.Number)
+			}
+			docs.Examples = append(docs.Examples, example)
+		}
+	}
+	return docs
+}
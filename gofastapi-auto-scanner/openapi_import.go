@@ -0,0 +1,1011 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIImportOptions controls the Go code generated from an OpenAPI
+// document
+type OpenAPIImportOptions struct {
+	// PackageName is the package of the generated files, "api" by default
+	PackageName string
+	// ServiceName is the struct whose methods implement the operations.
+	// It defaults to the document title followed by "Service".
+	ServiceName string
+}
+
+// LoadOpenAPI reads an OpenAPI 3.x document in JSON or YAML
+func LoadOpenAPI(path string) (*OpenAPIDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOpenAPI(data)
+}
+
+// ParseOpenAPI parses an OpenAPI 3.x document in JSON or YAML
+func ParseOpenAPI(data []byte) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(data, doc)
+	} else {
+		err = yaml.Unmarshal(data, doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q: only 3.x documents can be imported", doc.OpenAPI)
+	}
+	return doc, nil
+}
+
+// ImportOpenAPI generates models.go and service.go from the document at
+// specPath into outputDir
+func ImportOpenAPI(specPath, outputDir string, options OpenAPIImportOptions) error {
+	doc, err := LoadOpenAPI(specPath)
+	if err != nil {
+		return err
+	}
+	files, err := GenerateFromOpenAPI(doc, options)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runImportOpenAPI implements the import-openapi command
+func runImportOpenAPI(args []string) int {
+	flags := flag.NewFlagSet("import-openapi", flag.ContinueOnError)
+	outputDir := flags.String("out", ".", "directory to write the generated Go files to")
+	packageName := flags.String("package", "", "package name of the generated files")
+	serviceName := flags.String("service", "", "name of the generated service struct")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import-openapi [-out dir] [-package name] [-service name] openapi.yaml")
+		return 2
+	}
+
+	options := OpenAPIImportOptions{PackageName: *packageName, ServiceName: *serviceName}
+	if err := ImportOpenAPI(flags.Arg(0), *outputDir, options); err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	fmt.Printf("✅ Generated models.go and service.go in %s\n", *outputDir)
+	return 0
+}
+
+// GenerateFromOpenAPI returns the Go files for a document, keyed by file
+// name: models.go with a struct per object schema and service.go with a
+// service whose annotated methods scan back into the document's routes
+func GenerateFromOpenAPI(doc *OpenAPIDocument, options OpenAPIImportOptions) (map[string]string, error) {
+	imp := newOpenAPIImporter(doc, options)
+	if err := imp.collectModels(); err != nil {
+		return nil, err
+	}
+	service, err := imp.service()
+	if err != nil {
+		return nil, err
+	}
+	// Operations add the models of their inline bodies, so the models
+	// file is rendered last
+	models := imp.models()
+
+	files := make(map[string]string)
+	for name, src := range map[string]string{"models.go": models, "service.go": service} {
+		formatted, err := format.Source([]byte(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %v", name, err)
+		}
+		files[name] = string(formatted)
+	}
+	return files, nil
+}
+
+// openAPIImporter turns the schemas and operations of a document into Go
+// declarations
+type openAPIImporter struct {
+	doc         *OpenAPIDocument
+	packageName string
+	serviceName string
+
+	typeNames map[string]string // component schema name -> Go type name
+	taken     map[string]bool   // Go type names in use
+	decls     []string          // model declarations in order
+	imports   map[string]bool   // imports of models.go
+}
+
+func newOpenAPIImporter(doc *OpenAPIDocument, options OpenAPIImportOptions) *openAPIImporter {
+	imp := &openAPIImporter{
+		doc:         doc,
+		packageName: options.PackageName,
+		serviceName: options.ServiceName,
+		typeNames:   make(map[string]string),
+		taken:       make(map[string]bool),
+		imports:     make(map[string]bool),
+	}
+	if imp.packageName == "" {
+		imp.packageName = "api"
+	}
+	if imp.serviceName == "" {
+		imp.serviceName = goName(doc.Info.Title, true)
+		if imp.serviceName == "" || imp.serviceName == "API" {
+			imp.serviceName = "API"
+		}
+		imp.serviceName = strings.TrimSuffix(imp.serviceName, "Service") + "Service"
+	}
+	if doc.Components == nil {
+		doc.Components = &OpenAPIComponents{}
+	}
+	imp.taken[imp.serviceName] = true
+	imp.taken["New"+imp.serviceName] = true
+	imp.taken["ErrNotImplemented"] = true
+	return imp
+}
+
+// collectModels names a Go type for every component schema and declares it
+func (imp *openAPIImporter) collectModels() error {
+	var names []string
+	for name := range imp.doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		imp.typeNames[name] = imp.claim(goName(name, true))
+	}
+	for _, name := range names {
+		if imp.doc.Components.Schemas[name] == nil {
+			return fmt.Errorf("schema %s is empty", name)
+		}
+		imp.declare(imp.typeNames[name], imp.doc.Components.Schemas[name])
+	}
+	return nil
+}
+
+// claim reserves a Go type name, numbering it when it is taken
+func (imp *openAPIImporter) claim(name string) string {
+	if name == "" {
+		name = "Model"
+	}
+	unique := name
+	for n := 2; imp.taken[unique]; n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	imp.taken[unique] = true
+	return unique
+}
+
+// declare adds the declaration of a named type for schema
+func (imp *openAPIImporter) declare(name string, schema *Schema) {
+	var decl strings.Builder
+	if description := lowerFirst(schema.Description); description != "" {
+		for _, article := range []string{"a ", "an ", "the "} {
+			if strings.HasPrefix(description, article) {
+				description = "is " + description
+				break
+			}
+		}
+		decl.WriteString(goComment(name+" "+description, ""))
+	} else {
+		decl.WriteString(fmt.Sprintf("// %s is the %s schema\n", name, name))
+	}
+
+	resolved := imp.resolve(schema)
+	switch {
+	case isObjectSchema(resolved) || len(resolved.AllOf) > 0:
+		decl.WriteString(fmt.Sprintf("type %s struct {\n", name))
+		decl.WriteString(imp.structFields(name, resolved))
+		decl.WriteString("}\n")
+	case len(resolved.Enum) > 0 && schemaType(resolved) == "string":
+		decl.WriteString(fmt.Sprintf("type %s string\n\n", name))
+		decl.WriteString(fmt.Sprintf("// %s values\nconst (\n", name))
+		for _, value := range resolved.Enum {
+			text := fmt.Sprint(value)
+			decl.WriteString(fmt.Sprintf("\t%s %s = %s\n", name+goName(text, true), name, strconv.Quote(text)))
+		}
+		decl.WriteString(")\n")
+	default:
+		decl.WriteString(fmt.Sprintf("type %s %s\n", name, imp.goType(name, resolved, true)))
+	}
+	imp.decls = append(imp.decls, decl.String())
+}
+
+// structFields renders the fields of an object schema. allOf members that
+// are references are embedded; the properties of inline members are merged.
+func (imp *openAPIImporter) structFields(owner string, schema *Schema) string {
+	var fields strings.Builder
+	properties := make(map[string]*Schema)
+	required := make(map[string]bool)
+	addObject := func(object *Schema) {
+		for name, property := range object.Properties {
+			properties[name] = property
+		}
+		for _, name := range object.Required {
+			required[name] = true
+		}
+	}
+	for _, member := range schema.AllOf {
+		if member.Ref != "" {
+			fields.WriteString("\t" + imp.refType(member.Ref) + "\n")
+			continue
+		}
+		addObject(imp.resolve(member))
+	}
+	addObject(schema)
+
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	used := make(map[string]bool)
+	for _, jsonName := range names {
+		property := properties[jsonName]
+		fieldName := goName(jsonName, true)
+		if fieldName == "" {
+			fieldName = "Field"
+		}
+		for base, n := fieldName, 2; used[fieldName]; n++ {
+			fieldName = fmt.Sprintf("%s%d", base, n)
+		}
+		used[fieldName] = true
+
+		typ := imp.goType(owner+fieldName, property, required[jsonName])
+		tag := fmt.Sprintf(`json:"%s`, jsonName)
+		if !required[jsonName] {
+			tag += ",omitempty"
+		}
+		tag += `"`
+		if rules := validateRules(imp.resolveShallow(property)); rules != "" {
+			tag += fmt.Sprintf(` validate:"%s"`, rules)
+		}
+		if example := schemaExample(property); example != "" {
+			tag += fmt.Sprintf(" example:%s", strconv.Quote(example))
+		}
+		if property.Description != "" {
+			fields.WriteString(goComment(property.Description, "\t"))
+		}
+		fields.WriteString(fmt.Sprintf("\t%s %s `%s`\n", fieldName, typ, tag))
+	}
+	return fields.String()
+}
+
+// goType returns the Go type of a schema. Inline objects become named
+// structs called name; optional and nullable references become pointers.
+func (imp *openAPIImporter) goType(name string, schema *Schema, required bool) string {
+	if schema == nil {
+		return "interface{}"
+	}
+	if schema.Ref != "" {
+		typ := imp.refType(schema.Ref)
+		if target := imp.resolve(schema); isObjectSchema(target) && !required {
+			return "*" + typ
+		}
+		return typ
+	}
+	if len(schema.AllOf) == 1 {
+		return imp.goType(name, schema.AllOf[0], required)
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return "interface{}"
+	}
+
+	nullable := schema.Nullable || schemaNullable(schema)
+	var typ string
+	switch schemaType(schema) {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			imp.imports["time"] = true
+			typ = "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		default:
+			typ = "string"
+		}
+	case "integer":
+		switch schema.Format {
+		case "int32":
+			typ = "int32"
+		case "int64":
+			typ = "int64"
+		default:
+			typ = "int"
+		}
+	case "number":
+		if schema.Format == "float" {
+			typ = "float32"
+		} else {
+			typ = "float64"
+		}
+	case "boolean":
+		typ = "bool"
+	case "array":
+		return "[]" + imp.goType(singular(name), schema.Items, true)
+	case "object", "":
+		if len(schema.Properties) > 0 || len(schema.AllOf) > 0 {
+			typeName := imp.claim(name)
+			imp.declare(typeName, schema)
+			if !required || nullable {
+				return "*" + typeName
+			}
+			return typeName
+		}
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + imp.goType(name+"Value", schema.AdditionalProperties, true)
+		}
+		if schemaType(schema) == "object" {
+			return "map[string]interface{}"
+		}
+		return "interface{}"
+	default:
+		return "interface{}"
+	}
+	if nullable && required {
+		return "*" + typ
+	}
+	return typ
+}
+
+// refType returns the Go type of a component schema reference
+func (imp *openAPIImporter) refType(ref string) string {
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	if typ, ok := imp.typeNames[name]; ok {
+		return typ
+	}
+	return "interface{}"
+}
+
+// resolve follows schema references to the schema they name
+func (imp *openAPIImporter) resolve(schema *Schema) *Schema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 32; depth++ {
+		schema = imp.doc.Components.Schemas[schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]]
+	}
+	if schema == nil {
+		return &Schema{}
+	}
+	return schema
+}
+
+// resolveShallow returns the schema whose constraints apply to a property:
+// the property itself unless it only refers to another schema
+func (imp *openAPIImporter) resolveShallow(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{}
+	}
+	return schema
+}
+
+// models renders models.go
+func (imp *openAPIImporter) models() string {
+	var src strings.Builder
+	src.WriteString(imp.header())
+	src.WriteString(fmt.Sprintf("package %s\n\n", imp.packageName))
+	if imp.imports["time"] {
+		src.WriteString("import \"time\"\n\n")
+	}
+	for _, decl := range imp.decls {
+		src.WriteString(decl)
+		src.WriteString("\n")
+	}
+	return src.String()
+}
+
+// header is the comment at the top of every imported file
+func (imp *openAPIImporter) header() string {
+	title := imp.doc.Info.Title
+	if title == "" {
+		title = "the OpenAPI document"
+	}
+	return fmt.Sprintf("// Code imported from %s %s by gofastapi import-openapi.\n// Implement the service methods; the annotations describe the API.\n\n", title, imp.doc.Info.Version)
+}
+
+// importedOperation is an operation of the document with its path and method
+type importedOperation struct {
+	path      string
+	method    string
+	operation *OpenAPIOperation
+	shared    []OpenAPIParameter
+}
+
+// service renders service.go: the service struct and one annotated method
+// per operation
+func (imp *openAPIImporter) service() (string, error) {
+	var operations []importedOperation
+	var paths []string
+	for path := range imp.doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := imp.doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			if operation := *item.Operation(method); operation != nil {
+				operations = append(operations, importedOperation{path: path, method: method, operation: operation, shared: item.Parameters})
+			}
+		}
+	}
+
+	var methods strings.Builder
+	usedNames := make(map[string]bool)
+	for _, op := range operations {
+		method, err := imp.method(op, usedNames)
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %v", op.method, op.path, err)
+		}
+		methods.WriteString(method)
+	}
+
+	var src strings.Builder
+	src.WriteString(imp.header())
+	src.WriteString(fmt.Sprintf("package %s\n\n", imp.packageName))
+	imports := []string{`"context"`, `"errors"`}
+	if strings.Contains(methods.String(), "time.") {
+		imports = append(imports, `"time"`)
+	}
+	src.WriteString("import (\n\t" + strings.Join(imports, "\n\t") + "\n)\n\n")
+	src.WriteString("// ErrNotImplemented is returned by operations that have no implementation yet\n")
+	src.WriteString("var ErrNotImplemented = errors.New(\"not implemented\")\n\n")
+	if imp.doc.Info.Description != "" {
+		src.WriteString(goComment(fmt.Sprintf("%s implements %s. %s", imp.serviceName, imp.doc.Info.Title, imp.doc.Info.Description), ""))
+	} else {
+		src.WriteString(fmt.Sprintf("// %s implements the operations of %s\n", imp.serviceName, nonEmpty(imp.doc.Info.Title, "the API")))
+	}
+	src.WriteString(fmt.Sprintf("type %s struct{}\n\n", imp.serviceName))
+	src.WriteString(fmt.Sprintf("// New%s creates a %s\n", imp.serviceName, imp.serviceName))
+	src.WriteString(fmt.Sprintf("func New%s() *%s {\n\treturn &%s{}\n}\n\n", imp.serviceName, imp.serviceName, imp.serviceName))
+	src.WriteString(methods.String())
+	return src.String(), nil
+}
+
+// method renders the service method of one operation
+func (imp *openAPIImporter) method(op importedOperation, usedNames map[string]bool) (string, error) {
+	operation := op.operation
+	name := goName(operation.OperationID, true)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = goName(strings.ToLower(op.method)+" "+op.path, true)
+	}
+	for base, n := name, 2; usedNames[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	usedNames[name] = true
+
+	var annotations []string
+	annotations = append(annotations, fmt.Sprintf("@api.endpoint(%s)", strconv.Quote(op.path)))
+	annotations = append(annotations, fmt.Sprintf("@api.method(%s)", op.method))
+	switch imp.security(operation) {
+	case "required":
+		annotations = append(annotations, "@api.auth.required")
+	case "optional":
+		annotations = append(annotations, "@api.auth.optional")
+	}
+	if operation.Summary != "" {
+		annotations = append(annotations, fmt.Sprintf("@api.doc.title(%s)", strconv.Quote(operation.Summary)))
+	}
+	if operation.Description != "" && operation.Description != operation.Summary {
+		annotations = append(annotations, fmt.Sprintf("@api.doc.description(%s)", strconv.Quote(strings.TrimSpace(operation.Description))))
+	}
+
+	// Parameters: the context, then path and query values in document
+	// order, then the body
+	params := []string{"ctx context.Context"}
+	usedParams := map[string]bool{"ctx": true}
+	placeholders := pathPlaceholders(op.path)
+	for _, param := range imp.parameters(op) {
+		schema := imp.resolve(param.Schema)
+		paramType := scalarGoType(schema)
+		goParam := goParamName(param.Name)
+		for base, n := goParam, 2; usedParams[goParam]; n++ {
+			goParam = fmt.Sprintf("%s%d", base, n)
+		}
+
+		bound := false
+		switch param.In {
+		case "path":
+			bound = contains(placeholders, param.Name)
+		case "query":
+			bound = paramType != "" && goParam == param.Name
+		}
+		if bound {
+			usedParams[goParam] = true
+			params = append(params, goParam+" "+paramType)
+		}
+		if !bound || param.Description != "" || param.Required && param.In != "path" {
+			annotation := fmt.Sprintf("@api.doc.param(%s, %s, %s", strconv.Quote(param.Name), strconv.Quote(param.In), strconv.Quote(schemaTypeName(schema)))
+			if param.Description != "" {
+				annotation += ", " + strconv.Quote(param.Description)
+			}
+			if param.Required && param.In != "path" {
+				annotation += ", required=true"
+			}
+			annotations = append(annotations, annotation+")")
+		}
+	}
+
+	if body := imp.requestBody(operation); body != nil {
+		media, ok := jsonMedia(body.Content)
+		if ok && media.Schema != nil {
+			bodyType := imp.goType(name+"Request", media.Schema, true)
+			annotations = append(annotations, fmt.Sprintf("@api.request(%s)", annotationType(bodyType)))
+			bodyName := "body"
+			for n := 2; usedParams[bodyName]; n++ {
+				bodyName = fmt.Sprintf("body%d", n)
+			}
+			params = append(params, bodyName+" "+bodyType)
+		}
+	}
+
+	// Responses in status order; the first 2xx with a body is returned
+	resultType := ""
+	for _, status := range sortedStatuses(operation.Responses) {
+		response := imp.response(operation.Responses[status])
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			// "default" and ranges such as "5XX" have no route equivalent
+			continue
+		}
+		annotation := fmt.Sprintf("@api.response(%d", code)
+		if media, ok := jsonMedia(response.Content); ok && media.Schema != nil {
+			var typ string
+			if code >= 200 && code < 300 && resultType == "" {
+				typ = imp.goType(name+"Response", media.Schema, false)
+				resultType = typ
+			} else {
+				typ = imp.goType(name+goName(http.StatusText(code), true), media.Schema, true)
+			}
+			annotation += ", " + annotationType(strings.TrimPrefix(typ, "*"))
+			if media.Example != nil {
+				if example, err := json.Marshal(media.Example); err == nil && (bytes.HasPrefix(example, []byte("{")) || bytes.HasPrefix(example, []byte("["))) {
+					annotations = append(annotations, fmt.Sprintf("@api.doc.example(%s, status=%d)", example, code))
+				}
+			}
+		}
+		if response.Description != "" && response.Description != http.StatusText(code) {
+			annotation += ", description=" + strconv.Quote(response.Description)
+		}
+		annotations = append(annotations, annotation+")")
+	}
+
+	var src strings.Builder
+	src.WriteString(fmt.Sprintf("// %s handles %s %s\n//\n", name, op.method, op.path))
+	for _, annotation := range annotations {
+		src.WriteString("// " + annotation + "\n")
+	}
+	results := "error"
+	if resultType != "" {
+		results = fmt.Sprintf("(%s, error)", resultType)
+	}
+	src.WriteString(fmt.Sprintf("func (s *%s) %s(%s) %s {\n", imp.serviceName, name, strings.Join(params, ", "), results))
+	if resultType != "" {
+		src.WriteString(fmt.Sprintf("\treturn %s, ErrNotImplemented\n}\n\n", zeroValue(resultType)))
+	} else {
+		src.WriteString("\treturn ErrNotImplemented\n}\n\n")
+	}
+	return src.String(), nil
+}
+
+// parameters returns the parameters of an operation, including those
+// shared by its path item, with references resolved
+func (imp *openAPIImporter) parameters(op importedOperation) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	index := make(map[string]int)
+	for _, param := range append(append([]OpenAPIParameter(nil), op.shared...), op.operation.Parameters...) {
+		if param.Ref != "" {
+			resolved, ok := imp.doc.Components.Parameters[refName(param.Ref)]
+			if !ok {
+				continue
+			}
+			param = *resolved
+		}
+		key := param.In + ":" + param.Name
+		if i, ok := index[key]; ok {
+			params[i] = param
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+	return params
+}
+
+// requestBody resolves the request body of an operation
+func (imp *openAPIImporter) requestBody(operation *OpenAPIOperation) *OpenAPIRequestBody {
+	body := operation.RequestBody
+	if body != nil && body.Ref != "" {
+		body = imp.doc.Components.RequestBodies[refName(body.Ref)]
+	}
+	return body
+}
+
+// response resolves a response reference
+func (imp *openAPIImporter) response(response *OpenAPIResponse) *OpenAPIResponse {
+	if response != nil && response.Ref != "" {
+		response = imp.doc.Components.Responses[refName(response.Ref)]
+	}
+	if response == nil {
+		return &OpenAPIResponse{}
+	}
+	return response
+}
+
+// security reports whether an operation requires authentication: "required"
+// when every alternative names a scheme, "optional" when one is empty and
+// "" when none applies
+func (imp *openAPIImporter) security(operation *OpenAPIOperation) string {
+	requirements := operation.Security
+	if requirements == nil {
+		requirements = imp.doc.Security
+	}
+	if len(requirements) == 0 {
+		return ""
+	}
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			return "optional"
+		}
+	}
+	return "required"
+}
+
+// jsonMedia picks the JSON media type of a content map
+func jsonMedia(content map[string]OpenAPIMediaType) (OpenAPIMediaType, bool) {
+	if media, ok := content["application/json"]; ok {
+		return media, true
+	}
+	var types []string
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	for _, mediaType := range types {
+		if strings.HasSuffix(mediaType, "json") || mediaType == "*/*" {
+			return content[mediaType], true
+		}
+	}
+	return OpenAPIMediaType{}, false
+}
+
+// sortedStatuses orders response keys numerically, ranges and "default" last
+func sortedStatuses(responses map[string]*OpenAPIResponse) []string {
+	var statuses []string
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, errA := strconv.Atoi(statuses[i])
+		b, errB := strconv.Atoi(statuses[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		return statuses[i] < statuses[j]
+	})
+	return statuses
+}
+
+// schemaType returns the first non-null type of a schema
+func schemaType(schema *Schema) string {
+	switch typ := schema.Type.(type) {
+	case string:
+		return typ
+	case []string:
+		for _, name := range typ {
+			if name != "null" {
+				return name
+			}
+		}
+	case []interface{}:
+		for _, name := range typ {
+			if text, ok := name.(string); ok && text != "null" {
+				return text
+			}
+		}
+	}
+	if len(schema.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// schemaNullable reports whether a 3.1 type list admits null
+func schemaNullable(schema *Schema) bool {
+	switch typ := schema.Type.(type) {
+	case []string:
+		return contains(typ, "null")
+	case []interface{}:
+		for _, name := range typ {
+			if name == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isObjectSchema reports whether a schema declares an object with
+// properties, directly or by composition
+func isObjectSchema(schema *Schema) bool {
+	return schemaType(schema) == "object" && len(schema.Properties) > 0 || len(schema.AllOf) > 0
+}
+
+// scalarGoType returns the Go type of a path or query value, or "" when it
+// cannot be decoded from a string
+func scalarGoType(schema *Schema) string {
+	switch schemaType(schema) {
+	case "string", "":
+		return "string"
+	case "integer":
+		switch schema.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	return ""
+}
+
+// schemaTypeName describes a parameter schema for @api.doc.param
+func schemaTypeName(schema *Schema) string {
+	if typ := scalarGoType(schema); typ != "" {
+		return typ
+	}
+	if schemaType(schema) == "array" && schema.Items != nil {
+		if item := scalarGoType(schema.Items); item != "" {
+			return "[]" + item
+		}
+	}
+	return "string"
+}
+
+// validateRules converts schema constraints into a validate tag, the
+// inverse of applyFieldConstraints
+func validateRules(schema *Schema) string {
+	var rules []string
+	switch schema.Format {
+	case "email":
+		rules = append(rules, "email")
+	case "uri", "url":
+		rules = append(rules, "url")
+	case "uuid":
+		rules = append(rules, "uuid")
+	}
+	if schema.MinLength != nil {
+		rules = append(rules, fmt.Sprintf("min=%d", *schema.MinLength))
+	}
+	if schema.MaxLength != nil {
+		rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxLength))
+	}
+	if schema.MinItems != nil {
+		rules = append(rules, fmt.Sprintf("min=%d", *schema.MinItems))
+	}
+	if schema.MaxItems != nil {
+		rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxItems))
+	}
+	if schema.Minimum != nil {
+		rules = append(rules, "gte="+strconv.FormatFloat(*schema.Minimum, 'f', -1, 64))
+	}
+	if schema.Maximum != nil {
+		rules = append(rules, "lte="+strconv.FormatFloat(*schema.Maximum, 'f', -1, 64))
+	}
+	if len(schema.Enum) > 0 {
+		var values []string
+		for _, value := range schema.Enum {
+			text := fmt.Sprint(value)
+			if strings.ContainsAny(text, " ,\"") {
+				// oneof cannot express these values
+				values = nil
+				break
+			}
+			values = append(values, text)
+		}
+		if len(values) > 0 {
+			rules = append(rules, "oneof="+strings.Join(values, " "))
+		}
+	}
+	return strings.Join(rules, ",")
+}
+
+// schemaExample returns a scalar example of a schema for the example tag
+func schemaExample(schema *Schema) string {
+	example := schema.Example
+	if example == nil && len(schema.Examples) > 0 {
+		example = schema.Examples[0]
+	}
+	switch value := example.(type) {
+	case string:
+		return value
+	case int, int64, float64, bool:
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// simpleType matches type expressions annotations accept unquoted
+var simpleType = regexp.MustCompile(`^(\[\])*[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// annotationType spells a Go type as an annotation argument
+func annotationType(typ string) string {
+	if simpleType.MatchString(typ) {
+		return typ
+	}
+	return strconv.Quote(typ)
+}
+
+// zeroValue returns the zero value expression of a Go type
+func zeroValue(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "*"), strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["), typ == "interface{}":
+		return "nil"
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case scalarKinds[typ] > 0 || typ == "int" || typ == "uint":
+		return "0"
+	}
+	return typ + "{}"
+}
+
+// goInitialisms are spelled in capitals in Go identifiers
+var goInitialisms = map[string]bool{
+	"API": true, "DB": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "JWT": true, "SQL": true, "TLS": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// goName converts a schema, property or operation name into a Go
+// identifier, exported or not: "pet_id" and "petId" both become "PetID"
+func goName(name string, exported bool) string {
+	var words []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words = append(words, splitCamel(part)...)
+	}
+
+	var out strings.Builder
+	for i, word := range words {
+		upper := strings.ToUpper(word)
+		switch {
+		case i == 0 && !exported:
+			out.WriteString(strings.ToLower(word))
+		case goInitialisms[upper]:
+			out.WriteString(upper)
+		case len(upper) > 2 && strings.HasSuffix(upper, "S") && goInitialisms[upper[:len(upper)-1]]:
+			out.WriteString(upper[:len(upper)-1] + "s")
+		default:
+			out.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+		}
+	}
+	result := out.String()
+	if result != "" && unicode.IsDigit(rune(result[0])) {
+		result = "N" + result
+	}
+	return result
+}
+
+// splitCamel splits "petID" into "pet" and "ID" and "HTTPServer" into
+// "HTTP" and "Server"
+func splitCamel(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+			unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next) ||
+			unicode.IsLetter(prev) != unicode.IsLetter(cur) && unicode.IsDigit(prev) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// goParamName returns a parameter name for an OpenAPI parameter. Names that
+// are already Go identifiers are kept so that query values bind by name.
+func goParamName(name string) string {
+	if token.IsIdentifier(name) && !token.IsKeyword(name) && name != "ctx" {
+		return name
+	}
+	converted := goName(name, false)
+	if converted == "" || token.IsKeyword(converted) || converted == "ctx" {
+		converted += "Param"
+	}
+	return converted
+}
+
+// goComment renders text as a Go comment with the given indent
+func goComment(text, indent string) string {
+	var comment strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		comment.WriteString(strings.TrimRight(indent+"// "+strings.TrimSpace(line), " ") + "\n")
+	}
+	return comment.String()
+}
+
+// lowerFirst lowercases the first letter of a sentence
+func lowerFirst(text string) string {
+	if text == "" {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) > 1 && unicode.IsUpper(runes[1]) {
+		return text
+	}
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// singular names the item type of an inline array schema
+func singular(name string) string {
+	return name + "Item"
+}
+
+// refName returns the component name of a reference
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
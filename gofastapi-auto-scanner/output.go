@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// OutputSet collects the files of a generation run in memory. Nothing
+// touches the disk until Write, so a run can be previewed with Diff or
+// checked for stale files with Changes instead.
+//
+// Adopt replaces files on disk that have no checksum header instead of
+// treating them as edited by hand. It takes over output written before the
+// generator stamped checksum headers; from then on the headers protect it.
+type OutputSet struct {
+	Adopt bool
+	files map[string]string
+}
+
+// NewOutputSet creates an empty output set
+func NewOutputSet() *OutputSet {
+	return &OutputSet{files: make(map[string]string)}
+}
+
+// Add sets the content of the file at path, replacing earlier content
+func (o *OutputSet) Add(path, content string) {
+	o.files[filepath.Clean(path)] = content
+}
+
+// Content returns the content added for path
+func (o *OutputSet) Content(path string) (string, bool) {
+	content, ok := o.files[filepath.Clean(path)]
+	return content, ok
+}
+
+// Paths returns the paths of the set in sorted order
+func (o *OutputSet) Paths() []string {
+	paths := make([]string, 0, len(o.files))
+	for path := range o.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// resolve resolves every file of the set against the disk
+func (o *OutputSet) resolve() ([]*resolvedFile, error) {
+	var files []*resolvedFile
+	for _, path := range o.Paths() {
+		file, err := resolveFile(path, o.files[path], o.Adopt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Changes returns the files whose content on disk differs from the set,
+// including the ones that do not exist yet and the ones edited by hand
+func (o *OutputSet) Changes() ([]*resolvedFile, error) {
+	files, err := o.resolve()
+	if err != nil {
+		return nil, err
+	}
+	var changed []*resolvedFile
+	for _, file := range files {
+		if file.Changed() {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+// Write writes the set to disk. When any file was edited outside its user
+// regions nothing is written but the pending files of the conflicts, and
+// the returned error wraps an *EditedFileError per conflict.
+func (o *OutputSet) Write() error {
+	files, err := o.resolve()
+	if err != nil {
+		return err
+	}
+
+	var conflicts []error
+	for _, file := range files {
+		if file.Conflict == nil {
+			continue
+		}
+		if err := file.write(); err != nil {
+			return fmt.Errorf("failed to write %s: %v", file.Conflict.Pending, err)
+		}
+		conflicts = append(conflicts, file.Conflict)
+	}
+	if len(conflicts) > 0 {
+		return errors.Join(conflicts...)
+	}
+
+	for _, file := range files {
+		if err := file.write(); err != nil {
+			return fmt.Errorf("failed to write %s: %v", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// Diff writes a unified diff from the files on disk to the set and returns
+// the changed files. Files edited by hand are diffed against the content
+// that would go to their pending file.
+func (o *OutputSet) Diff(w io.Writer) ([]*resolvedFile, error) {
+	changed, err := o.Changes()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range changed {
+		diff := difflib.UnifiedDiff{
+			A:        diffLines(file.Previous),
+			B:        diffLines(file.Content),
+			FromFile: "a/" + filepath.ToSlash(file.Path),
+			ToFile:   "b/" + filepath.ToSlash(file.Path),
+			Context:  3,
+		}
+		if !file.Exists {
+			diff.FromFile = "/dev/null"
+		}
+		if file.Conflict != nil {
+			fmt.Fprintf(w, "# %v\n", file.Conflict)
+		}
+		if err := difflib.WriteUnifiedDiff(w, diff); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// diffLines splits text into lines that all end with a newline
+func diffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
+
+// reportOutput implements the dry-run and check modes: it prints the diff
+// from the disk to out when dryRun is set and lists the files that would
+// change. The returned exit status is 1 when check is set and the
+// generated code on disk is out of date.
+func reportOutput(out *OutputSet, dryRun, check bool) int {
+	var changed []*resolvedFile
+	var err error
+	if dryRun {
+		changed, err = out.Diff(os.Stdout)
+	} else {
+		changed, err = out.Changes()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(os.Stderr, "✅ Generated code is up to date")
+		return 0
+	}
+
+	for _, file := range changed {
+		switch {
+		case file.Conflict != nil && file.Conflict.Headerless:
+			fmt.Fprintf(os.Stderr, "   no header:      %s (regenerate it with -adopt)\n", file.Path)
+		case file.Conflict != nil:
+			fmt.Fprintf(os.Stderr, "   edited by hand: %s\n", file.Path)
+		case !file.Exists:
+			fmt.Fprintf(os.Stderr, "   missing:        %s\n", file.Path)
+		default:
+			fmt.Fprintf(os.Stderr, "   out of date:    %s\n", file.Path)
+		}
+	}
+	if check {
+		fmt.Fprintf(os.Stderr, "❌ %d generated files are out of date\n", len(changed))
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d generated files would change\n", len(changed))
+	return 0
+}
@@ -533,36 +533,36 @@ import (
 	"fmt"
 )
 
-type %sPlugin struct {
+type %[1]sPlugin struct {
 	config map[string]interface{}
 }
 
 func NewPlugin() Plugin {
-	return &%sPlugin{}
+	return &%[1]sPlugin{}
 }
 
-func (p *%sPlugin) GetName() string {
-	return "%s"
+func (p *%[1]sPlugin) GetName() string {
+	return "%[1]s"
 }
 
-func (p *%sPlugin) GetVersion() string {
+func (p *%[1]sPlugin) GetVersion() string {
 	return "1.0.0"
 }
 
-func (p *%sPlugin) GetDescription() string {
-	return "%s"
+func (p *%[1]sPlugin) GetDescription() string {
+	return %[2]q
 }
 
-func (p *%sPlugin) GetAuthor() string {
-	return "%s"
+func (p *%[1]sPlugin) GetAuthor() string {
+	return %[3]q
 }
 
-func (p *%sPlugin) Initialize(config map[string]interface{}) error {
+func (p *%[1]sPlugin) Initialize(config map[string]interface{}) error {
 	p.config = config
 	return nil
 }
 
-func (p *%sPlugin) Execute(ctx *PluginContext) error {
+func (p *%[1]sPlugin) Execute(ctx *PluginContext) error {
 	switch ctx.EventType {
 	case EventBeforeScan:
 		return p.handleBeforeScan(ctx)
@@ -577,15 +577,15 @@ func (p *%sPlugin) Execute(ctx *PluginContext) error {
 	}
 }
 
-func (p *%sPlugin) Cleanup() error {
+func (p *%[1]sPlugin) Cleanup() error {
 	return nil
 }
 
-func (p *%sPlugin) GetSupportedFrameworks() []string {
+func (p *%[1]sPlugin) GetSupportedFrameworks() []string {
 	return []string{"gin", "echo", "chi", "fiber"}
 }
 
-func (p *%sPlugin) GetSupportedEvents() []PluginEventType {
+func (p *%[1]sPlugin) GetSupportedEvents() []PluginEventType {
 	return []PluginEventType{
 		EventBeforeScan,
 		EventAfterScan,
@@ -594,11 +594,11 @@ func (p *%sPlugin) GetSupportedEvents() []PluginEventType {
 	}
 }
 
-func (p *%sPlugin) GetDependencies() []PluginDependency {
+func (p *%[1]sPlugin) GetDependencies() []PluginDependency {
 	return []PluginDependency{}
 }
 
-func (p *%sPlugin) GetConfigSchema() map[string]interface{} {
+func (p *%[1]sPlugin) GetConfigSchema() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
@@ -610,30 +610,30 @@ func (p *%sPlugin) GetConfigSchema() map[string]interface{} {
 	}
 }
 
-func (p *%sPlugin) ValidateConfig(config map[string]interface{}) error {
+func (p *%[1]sPlugin) ValidateConfig(config map[string]interface{}) error {
 	return nil
 }
 
-func (p *%sPlugin) handleBeforeScan(ctx *PluginContext) error {
+func (p *%[1]sPlugin) handleBeforeScan(ctx *PluginContext) error {
 	fmt.Printf("%%s: Before scan hook\n", p.GetName())
 	return nil
 }
 
-func (p *%sPlugin) handleAfterScan(ctx *PluginContext) error {
+func (p *%[1]sPlugin) handleAfterScan(ctx *PluginContext) error {
 	fmt.Printf("%%s: After scan hook\n", p.GetName())
 	return nil
 }
 
-func (p *%sPlugin) handleBeforeGeneration(ctx *PluginContext) error {
+func (p *%[1]sPlugin) handleBeforeGeneration(ctx *PluginContext) error {
 	fmt.Printf("%%s: Before generation hook\n", p.GetName())
 	return nil
 }
 
-func (p *%sPlugin) handleAfterGeneration(ctx *PluginContext) error {
+func (p *%[1]sPlugin) handleAfterGeneration(ctx *PluginContext) error {
 	fmt.Printf("%%s: After generation hook\n", p.GetName())
 	return nil
 }
-`, name, name, name, name, name, name, name, description, author, name, name, name, name, name)
+`, name, description, author)
 
 	if err := os.WriteFile(filepath.Join(pluginDir, "plugin.go"), []byte(pluginGo), 0644); err != nil {
 		return err
@@ -785,7 +785,7 @@ func (p *LoggingPlugin) Execute(ctx *PluginContext) error {
 func (p *LoggingPlugin) Cleanup() error { return nil }
 
 func (p *LoggingPlugin) GetSupportedFrameworks() []string {
-	return []string{"gin", "echo", "chi", "fiber"}
+	return []string{"gin", "echo", "chi", "fiber", "stdlib"}
 }
 
 func (p *LoggingPlugin) GetSupportedEvents() []PluginEventType {
@@ -872,7 +872,7 @@ func (p *MetricsPlugin) Cleanup() error {
 }
 
 func (p *MetricsPlugin) GetSupportedFrameworks() []string {
-	return []string{"gin", "echo", "chi", "fiber"}
+	return []string{"gin", "echo", "chi", "fiber", "stdlib"}
 }
 
 func (p *MetricsPlugin) GetSupportedEvents() []PluginEventType {
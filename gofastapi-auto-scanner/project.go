@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfig is a project's generator configuration, as read from
+// gofastapi.yaml or gofastapi.json. The sections from cors on are layered
+// over the defaults of the framework, field by field.
+//
+// String values may refer to the environment as ${VAR}, or ${VAR:-default}
+// for a variable that may be unset or empty; $$ stands for a literal $. An
+// unquoted value keeps the type it has after the substitution, so
+// "port: ${DB_PORT:-5432}" is a number.
+//
+// The environments mapping holds overlays by name, such as production. The
+// overlay of the selected environment is merged into the rest of the file
+// before it is decoded: mappings merge key by key, and any other value,
+// lists included, replaces the one it overlays.
+type ProjectConfig struct {
+	Roots     []string        `json:"roots" yaml:"roots"`                             // directories scanned
+	Include   []string        `json:"include" yaml:"include"`                         // patterns of the files scanned
+	Exclude   []string        `json:"exclude" yaml:"exclude"`                         // patterns of the files and directories skipped
+	Framework FrameworkType   `json:"framework,omitempty" yaml:"framework,omitempty"` // server generated, "" for the gin server in ./generated-api
+	OutputDir string          `json:"output_dir" yaml:"output_dir"`                   // directory the server is written to
+	Module    string          `json:"module" yaml:"module"`                           // module path of its go.mod
+	Templates string          `json:"templates,omitempty" yaml:"templates,omitempty"` // directory of templates shadowing the built-in ones
+	Features  ProjectFeatures `json:"features" yaml:"features"`
+	Naming    *NamingConfig   `json:"naming,omitempty" yaml:"naming,omitempty"`
+	Mappings  *MappingConfig  `json:"mappings,omitempty" yaml:"mappings,omitempty"` // smart mapping rules, instead of gofastapi-mappings.yaml
+
+	CORS       *CORSConfig          `json:"cors,omitempty" yaml:"cors,omitempty"`
+	Auth       *AuthConfig          `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Docs       *DocumentationConfig `json:"docs,omitempty" yaml:"docs,omitempty"`
+	Testing    *TestingConfig       `json:"testing,omitempty" yaml:"testing,omitempty"`
+	Deployment *DeploymentConfig    `json:"deployment,omitempty" yaml:"deployment,omitempty"`
+	Database   *DatabaseConfig      `json:"database,omitempty" yaml:"database,omitempty"`
+	Validation *ValidationConfig    `json:"validation,omitempty" yaml:"validation,omitempty"`
+	Wiring     *WiringConfig        `json:"wiring,omitempty" yaml:"wiring,omitempty"`
+	GraphQL    *GraphQLConfig       `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+
+	Environment string `json:"-" yaml:"-"` // overlay applied, "" for none
+}
+
+// ProjectFeatures switches the generator features on and off
+type ProjectFeatures struct {
+	ScanAnnotations bool `json:"scan_annotations" yaml:"scan_annotations"`
+	AutoCRUD        bool `json:"auto_crud" yaml:"auto_crud"`
+	SmartMapping    bool `json:"smart_mapping" yaml:"smart_mapping"`
+	TypeCheck       bool `json:"typecheck" yaml:"typecheck"` // type-check the output offline after generation
+}
+
+// ProjectOptions select how a project file is loaded
+type ProjectOptions struct {
+	Env       string        // environment overlay to apply, none when empty
+	Framework FrameworkType // framework overriding the one of the file
+}
+
+// ProjectFiles are the project files searched for, in order
+var ProjectFiles = []string{"gofastapi.yaml", "gofastapi.yml", "gofastapi.json"}
+
+// ProjectEnvVar names the environment of the project file when no -env
+// flag does
+const ProjectEnvVar = "GOFASTAPI_ENV"
+
+// environmentsKey is the top-level key of the environment overlays
+const environmentsKey = "environments"
+
+// FindProjectFile returns the first project file present in dir, or ""
+func FindProjectFile(dir string) string {
+	for _, name := range ProjectFiles {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// NewProjectConfig returns the configuration of a project without a
+// project file, generating framework or, when it is "", the Gin server
+// written to ./generated-api
+func NewProjectConfig(framework FrameworkType) (*ProjectConfig, error) {
+	project := &ProjectConfig{
+		Roots:     []string{"."},
+		Include:   []string{"*.go"},
+		Exclude:   []string{"*_test.go", "vendor/*", ".git/*", "generated-*api"},
+		Framework: framework,
+		OutputDir: "./generated-api",
+		Module:    "autogenerated-api",
+		Features: ProjectFeatures{
+			ScanAnnotations: true,
+			AutoCRUD:        true,
+			SmartMapping:    true,
+		},
+	}
+	server := framework
+	if server == "" {
+		server = FrameworkGin
+	}
+	generator, err := GetFrameworkRegistry().GetGenerator(server)
+	if err != nil {
+		return nil, err
+	}
+	defaults := generator.GetDefaultConfig()
+	defaults.Type = server
+	if framework != "" {
+		project.OutputDir = frameworkOutputDir(framework, defaults)
+		project.Module = frameworkModule(defaults)
+	}
+	project.CORS = defaults.CORS
+	project.Auth = defaults.Auth
+	project.Docs = defaults.Docs
+	project.Testing = defaults.Testing
+	project.Deployment = defaults.Deployment
+	project.Database = defaults.Database
+	project.Validation = defaults.Validation
+	project.Wiring = defaults.Wiring
+	project.GraphQL = defaults.GraphQL
+	return project, nil
+}
+
+// LoadProjectConfig reads and validates a YAML or JSON project file. Paths
+// in the file are relative to its directory.
+func LoadProjectConfig(file string, options ProjectOptions) (*ProjectConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is parsed as YAML below, which accepts more than JSON does
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(document.Content) > 0 {
+		root = document.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: line %d: expected a mapping of settings", file, root.Line)
+	}
+
+	// Check every key, those of the overlays not applied included
+	environments := removeKey(root, environmentsKey)
+	if environments != nil && environments.Tag == "!!null" {
+		environments = nil
+	}
+	problems := checkFields(root, reflect.TypeOf(ProjectConfig{}), "")
+	overlays := make(map[string]*yaml.Node)
+	if environments != nil {
+		if environments.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Errorf("line %d: %s: expected a mapping of environments", environments.Line, environmentsKey))
+		} else {
+			for i := 0; i+1 < len(environments.Content); i += 2 {
+				name, overlay := environments.Content[i].Value, environments.Content[i+1]
+				path := environmentsKey + "." + name
+				if overlay.Kind != yaml.MappingNode {
+					problems = append(problems, fmt.Errorf("line %d: %s: expected a mapping of settings", overlay.Line, path))
+					continue
+				}
+				problems = append(problems, checkFields(overlay, reflect.TypeOf(ProjectConfig{}), path)...)
+				overlays[name] = overlay
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: invalid project configuration:\n%v", file, errors.Join(problems...))
+	}
+
+	if options.Env != "" {
+		overlay, ok := overlays[options.Env]
+		if !ok {
+			names := make([]string, 0, len(overlays))
+			for name := range overlays {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("%s: environment %q is not defined, expected one of: %s", file, options.Env, strings.Join(names, ", "))
+		}
+		mergeNodes(root, overlay)
+	}
+	if problems := interpolate(root, ""); len(problems) > 0 {
+		return nil, fmt.Errorf("%s: invalid project configuration:\n%v", file, errors.Join(problems...))
+	}
+
+	// The sections are decoded over the defaults of the framework
+	framework := options.Framework
+	if framework == "" {
+		if node := mappingValue(root, "framework"); node != nil {
+			framework = FrameworkType(node.Value)
+		}
+	}
+	project, err := NewProjectConfig(framework)
+	if err != nil {
+		project, _ = NewProjectConfig("") // Validate reports the framework
+	}
+	if err := root.Decode(project); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	project.Framework = framework
+	project.Environment = options.Env
+
+	dir := filepath.Dir(file)
+	for i, scanRoot := range project.Roots {
+		project.Roots[i] = projectPath(dir, scanRoot)
+	}
+	project.OutputDir = projectPath(dir, project.OutputDir)
+	project.Templates = projectPath(dir, project.Templates)
+
+	if err := project.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return project, nil
+}
+
+// projectPath resolves a path of the project file against its directory
+func projectPath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Validate reports every setting of the project that the generator
+// cannot use
+func (p *ProjectConfig) Validate() error {
+	var problems []error
+	report := func(field, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if p.Framework != "" {
+		if _, err := GetFrameworkRegistry().GetGenerator(p.Framework); err != nil {
+			var frameworks []string
+			for _, framework := range GetFrameworkRegistry().ListFrameworks() {
+				frameworks = append(frameworks, string(framework))
+			}
+			sort.Strings(frameworks)
+			report("framework", "unsupported framework %q, expected one of: %s", p.Framework, strings.Join(frameworks, ", "))
+		}
+	}
+
+	if len(p.Roots) == 0 {
+		report("roots", "at least one directory is required")
+	}
+	for _, root := range p.Roots {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			report("roots", "%s is not a directory", root)
+		}
+	}
+	for _, pattern := range p.Include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			report("include", "invalid pattern %q", pattern)
+		}
+	}
+	for _, pattern := range p.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			report("exclude", "invalid pattern %q", pattern)
+		}
+	}
+	if p.OutputDir == "" {
+		report("output_dir", "a directory is required")
+	}
+	if p.Module == "" || strings.ContainsAny(p.Module, " \t\"'`") {
+		report("module", "invalid module path %q", p.Module)
+	}
+	if p.Templates != "" {
+		if info, err := os.Stat(p.Templates); err != nil || !info.IsDir() {
+			report("templates", "%s is not a directory", p.Templates)
+		}
+	}
+
+	if p.Naming != nil {
+		switch p.Naming.Style {
+		case "", CaseKebab, CaseSnake, CaseLowerCamel:
+		default:
+			report("naming.style", "unsupported style %q, expected kebab, snake or camel", p.Naming.Style)
+		}
+	}
+	if _, err := NewMethodMapper(p.Mappings); err != nil {
+		report("mappings", "%v", err)
+	}
+
+	if p.CORS != nil {
+		for _, method := range p.CORS.AllowMethods {
+			if !isHTTPMethod(strings.ToUpper(method)) {
+				report("cors.allow_methods", "unknown HTTP method %q", method)
+			}
+		}
+		if p.CORS.MaxAge < 0 {
+			report("cors.max_age", "must not be negative")
+		}
+	}
+	if p.Docs != nil {
+		switch p.Docs.Format {
+		case "", "swagger", "openapi", "redoc":
+		default:
+			report("docs.format", "unsupported format %q, expected swagger, openapi or redoc", p.Docs.Format)
+		}
+	}
+	if p.Testing != nil {
+		switch p.Testing.Framework {
+		case "", "testing", "testify", "ginkgo", "gomega":
+		default:
+			report("testing.framework", "unsupported framework %q, expected testing, testify, ginkgo or gomega", p.Testing.Framework)
+		}
+	}
+	if p.Deployment != nil {
+		switch p.Deployment.Type {
+		case "", "docker", "kubernetes", "serverless":
+		default:
+			report("deployment.type", "unsupported type %q, expected docker, kubernetes or serverless", p.Deployment.Type)
+		}
+	}
+	if p.Database != nil && p.Database.Type != "" {
+		if _, err := databaseDialect(p.Database); err != nil {
+			report("database.type", "%v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid project configuration:\n%v", errors.Join(problems...))
+	}
+	return nil
+}
+
+// GeneratorConfig returns the scanner configuration of the project. The
+// output directory is excluded from every root it lies in, so a server is
+// never scanned as part of the next generation.
+func (p *ProjectConfig) GeneratorConfig() *GeneratorConfig {
+	config := &GeneratorConfig{
+		Roots:           p.Roots,
+		IncludePatterns: p.Include,
+		ExcludePatterns: p.Exclude,
+		ScanAnnotations: p.Features.ScanAnnotations,
+		AutoCRUD:        p.Features.AutoCRUD,
+		SmartMapping:    p.Features.SmartMapping,
+		OutputDir:       p.OutputDir,
+		PackageName:     p.Module,
+		Naming:          p.Naming,
+		Mappings:        p.Mappings,
+	}
+	for _, root := range p.Roots {
+		rel, err := filepath.Rel(root, p.OutputDir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		config.ExcludePatterns = append(config.ExcludePatterns, filepath.ToSlash(rel)+"/*")
+	}
+	return config
+}
+
+// FrameworkConfig returns the configuration of the server generated, a Gin
+// server when the project names no framework, or nil when the framework is
+// not supported
+func (p *ProjectConfig) FrameworkConfig() *FrameworkConfig {
+	framework := p.Framework
+	if framework == "" {
+		framework = FrameworkGin
+	}
+	generator, err := GetFrameworkRegistry().GetGenerator(framework)
+	if err != nil {
+		return nil
+	}
+
+	config := generator.GetDefaultConfig()
+	config.Type = framework
+	config.TypeCheck = p.Features.TypeCheck
+	config.Templates = p.Templates
+	config.OutputDir = p.OutputDir
+	config.Module = p.Module
+	if p.CORS != nil {
+		config.CORS = p.CORS
+	}
+	if p.Auth != nil {
+		config.Auth = p.Auth
+	}
+	if p.Docs != nil {
+		config.Docs = p.Docs
+	}
+	if p.Testing != nil {
+		config.Testing = p.Testing
+	}
+	if p.Deployment != nil {
+		config.Deployment = p.Deployment
+	}
+	if p.Database != nil {
+		config.Database = p.Database
+	}
+	if p.Validation != nil {
+		config.Validation = p.Validation
+	}
+	if p.Wiring != nil {
+		config.Wiring = p.Wiring
+	}
+	if p.GraphQL != nil {
+		config.GraphQL = p.GraphQL
+	}
+	return config
+}
+
+// checkFields reports the keys under node that the yaml tags of t do not
+// declare, with their line and dotted path
+func checkFields(node *yaml.Node, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	var problems []error
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil // Decode reports the type error
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fields[name] = field.Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				problems = append(problems, fmt.Errorf("line %d: %s: unknown field", key.Line, joinFieldPath(path, key.Value)))
+				continue
+			}
+			problems = append(problems, checkFields(value, fieldType, joinFieldPath(path, key.Value))...)
+		}
+	case reflect.Slice:
+		if node.Kind == yaml.SequenceNode {
+			for i, item := range node.Content {
+				problems = append(problems, checkFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				problems = append(problems, checkFields(node.Content[i+1], t.Elem(), joinFieldPath(path, node.Content[i].Value))...)
+			}
+		}
+	}
+	return problems
+}
+
+// joinFieldPath appends a key to a dotted field path
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeKey removes key from a mapping node and returns its value, or nil
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// mergeNodes merges the mapping overlay into the mapping dst: mappings
+// merge key by key, any other value of overlay replaces the one in dst
+func mergeNodes(dst, overlay *yaml.Node) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != key.Value {
+				continue
+			}
+			if dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeNodes(dst.Content[j+1], value)
+			} else {
+				dst.Content[j+1] = value
+			}
+			replaced = true
+			break
+		}
+		if !replaced {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
+
+// envReference matches $$ and the ${VAR} and ${VAR:-default} references
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the environment references in the scalars under
+// node and reports the variables that are unset and have no default
+func interpolate(node *yaml.Node, path string) []error {
+	var problems []error
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = append(problems, interpolate(node.Content[i+1], joinFieldPath(path, node.Content[i].Value))...)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			problems = append(problems, interpolate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		node.Value = envReference.ReplaceAllStringFunc(node.Value, func(reference string) string {
+			if reference == "$$" {
+				return "$"
+			}
+			match := envReference.FindStringSubmatch(reference)
+			value, ok := os.LookupEnv(match[1])
+			if match[2] != "" && value == "" {
+				return match[3]
+			}
+			if !ok {
+				problems = append(problems, fmt.Errorf("line %d: %s: environment variable %s is not set", node.Line, path, match[1]))
+			}
+			return value
+		})
+		// Plain scalars are resolved again, so they may become numbers
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return problems
+}
+
+// loadProject reads the project file of the command line: file, or else
+// the one in the current directory, overlaid with the environment env or
+// else $GOFASTAPI_ENV. Without a project file the defaults apply.
+// framework, when set, overrides the framework of the project.
+func loadProject(file, env string, framework FrameworkType) *ProjectConfig {
+	if file == "" {
+		if file = FindProjectFile("."); file == "" && env != "" {
+			log.Fatalf("Error loading project: -env %s needs a project file", env)
+		}
+	}
+	if env == "" {
+		env = os.Getenv(ProjectEnvVar)
+	}
+
+	var project *ProjectConfig
+	var err error
+	if file != "" {
+		project, err = LoadProjectConfig(file, ProjectOptions{Env: env, Framework: framework})
+	} else {
+		project, err = NewProjectConfig(framework)
+	}
+	if err != nil {
+		log.Fatalf("Error loading project: %v", err)
+	}
+
+	// Project smart mapping rules may also have a file of their own
+	dir := "."
+	if file != "" {
+		dir = filepath.Dir(file)
+	}
+	if mappingFile := FindMappingFile(dir); mappingFile != "" {
+		if project.Mappings != nil {
+			log.Printf("Warning: %s is ignored, the project file has mappings", mappingFile)
+		} else if project.Mappings, err = LoadMappingConfig(mappingFile); err != nil {
+			log.Fatalf("Error loading mappings: %v", err)
+		}
+	}
+	return project
+}
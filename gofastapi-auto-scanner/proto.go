@@ -0,0 +1,1084 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProtoOptions controls the generated .proto file and adapter
+type ProtoOptions struct {
+	Package   string // proto package, "api.v1" by default
+	GoPackage string // import path of the Go package protoc-gen-go writes
+}
+
+// ProtoOutput holds the generated gRPC sources
+type ProtoOutput struct {
+	Proto   string // api.proto
+	Adapter string // Go package serving the proto services with the scanned structs
+}
+
+// ProtoLock records the number of every message field so that numbers stay
+// stable when structs change. Fields that disappear are reserved and their
+// numbers are never handed out again.
+type ProtoLock struct {
+	Messages map[string]*ProtoLockMessage `json:"messages"`
+}
+
+// ProtoLockMessage holds the field numbers of one message
+type ProtoLockMessage struct {
+	Fields   map[string]ProtoLockField `json:"fields"`
+	Reserved []ProtoLockField          `json:"reserved,omitempty"`
+}
+
+// ProtoLockField is a field number together with the type it was assigned
+// for. Changing a field's type gives it a new number.
+type ProtoLockField struct {
+	Name   string `json:"name,omitempty"`
+	Number int    `json:"number"`
+	Type   string `json:"type"`
+}
+
+// LoadProtoLock reads a lock file. A missing file yields an empty lock.
+func LoadProtoLock(path string) (*ProtoLock, error) {
+	lock := &ProtoLock{Messages: make(map[string]*ProtoLockMessage)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*ProtoLockMessage)
+	}
+	return lock, nil
+}
+
+// Save writes the lock to path
+func (l *ProtoLock) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode proto lock: %v", err)
+	}
+	return writeFile(path, string(data)+"\n")
+}
+
+// assign returns the numbers of a message's fields, given as proto names
+// with their types, in order. Numbers of fields missing from fields are
+// reserved.
+func (l *ProtoLock) assign(message string, names, types []string) []int {
+	m := l.Messages[message]
+	if m == nil {
+		m = &ProtoLockMessage{Fields: make(map[string]ProtoLockField)}
+		l.Messages[message] = m
+	}
+	if m.Fields == nil {
+		m.Fields = make(map[string]ProtoLockField)
+	}
+
+	present := make(map[string]bool)
+	for _, name := range names {
+		present[name] = true
+	}
+	var fieldNames []string
+	for name := range m.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	for _, name := range fieldNames {
+		if !present[name] {
+			m.reserve(name)
+		}
+	}
+
+	numbers := make([]int, len(names))
+	for i, name := range names {
+		if field, ok := m.Fields[name]; ok {
+			if field.Type == types[i] {
+				numbers[i] = field.Number
+				continue
+			}
+			m.reserve(name)
+		}
+		number := 0
+		for j, reserved := range m.Reserved {
+			if reserved.Name == name && reserved.Type == types[i] {
+				number = reserved.Number
+				m.Reserved = append(m.Reserved[:j], m.Reserved[j+1:]...)
+				break
+			}
+		}
+		if number == 0 {
+			number = m.next()
+		}
+		m.Fields[name] = ProtoLockField{Number: number, Type: types[i]}
+		numbers[i] = number
+	}
+	return numbers
+}
+
+// reserve retires the number of a field
+func (m *ProtoLockMessage) reserve(name string) {
+	field := m.Fields[name]
+	field.Name = name
+	m.Reserved = append(m.Reserved, field)
+	delete(m.Fields, name)
+}
+
+// next returns the lowest number above every number ever used
+func (m *ProtoLockMessage) next() int {
+	highest := 0
+	for _, field := range m.Fields {
+		if field.Number > highest {
+			highest = field.Number
+		}
+	}
+	for _, field := range m.Reserved {
+		if field.Number > highest {
+			highest = field.Number
+		}
+	}
+	if highest+1 >= 19000 && highest+1 <= 19999 {
+		// Reserved for the protobuf implementation
+		return 20000
+	}
+	return highest + 1
+}
+
+// runProto implements the proto subcommand
+func runProto(config *GeneratorConfig, args []string) int {
+	flags := flag.NewFlagSet("proto", flag.ContinueOnError)
+	outputDir := flags.String("out", "./proto", "directory to write api.proto and the grpcserver package to")
+	protoPackage := flags.String("package", "api.v1", "proto package")
+	goPackage := flags.String("go_package", "", "import path of the package protoc-gen-go generates, the -out package by default")
+	lockPath := flags.String("lock", "", "field number lock file, api.proto.lock in -out by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = config.ScanRoots()
+	}
+
+	options := ProtoOptions{Package: *protoPackage, GoPackage: *goPackage}
+	if options.GoPackage == "" {
+		importPath, _, err := NewModuleResolver().Resolve(*outputDir)
+		if err != nil || importPath == "" {
+			fmt.Fprintf(os.Stderr, "%s is not inside a Go module; pass -go_package\n", *outputDir)
+			return 2
+		}
+		options.GoPackage = importPath
+	}
+	if *lockPath == "" {
+		*lockPath = filepath.Join(*outputDir, "api.proto.lock")
+	}
+
+	generator := NewAPIGenerator(config)
+	for _, root := range roots {
+		if err := generator.ScanDirectory(root); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", root, err)
+			return 2
+		}
+	}
+
+	lock, err := LoadProtoLock(*lockPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	output, err := GenerateProto(generator.GenerateAPIRoutes(), generator.pkgs, lock, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating proto: %v\n", err)
+		return 1
+	}
+	if err := WriteProtoFiles(*outputDir, output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := lock.Save(*lockPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("✅ Generated api.proto and grpcserver/server.go in %s\n", *outputDir)
+	return 0
+}
+
+// WriteProtoFiles writes api.proto and grpcserver/server.go into dir
+func WriteProtoFiles(dir string, output *ProtoOutput) error {
+	if err := createDirectory(filepath.Join(dir, "grpcserver")); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "api.proto"), output.Proto); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "grpcserver", "server.go"), output.Adapter)
+}
+
+// GenerateProto turns every service struct the routes are bound to into a
+// proto service with an RPC per exported method, and returns the .proto
+// file with an adapter implementing the services with the structs. Field
+// numbers are taken from lock, which is updated with new fields.
+func GenerateProto(routes []APIRoute, packages map[string]*PackageInfo, lock *ProtoLock, options ProtoOptions) (*ProtoOutput, error) {
+	if options.Package == "" {
+		options.Package = "api.v1"
+	}
+	if options.GoPackage == "" {
+		return nil, fmt.Errorf("the Go package of the generated proto code is required")
+	}
+	g := &protoGenerator{
+		types:    newOpenAPIBuilder(packages),
+		lock:     lock,
+		options:  options,
+		aliases:  make(map[string]string),
+		imports:  make(map[string]bool),
+		fields:   make(map[string][]protoField),
+		wrappers: make(map[string][]protoField),
+	}
+	for _, name := range []string{"pb", "context", "time", "timestamppb", "durationpb"} {
+		g.aliasTaken(name)
+	}
+
+	seen := make(map[string]bool)
+	for _, route := range routes {
+		if route.Binding == nil || route.Binding.Receiver == "" {
+			continue
+		}
+		key := route.Binding.ImportPath + "." + route.Binding.Receiver
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		pkg := packages[route.Binding.ImportPath]
+		if pkg == nil {
+			continue
+		}
+		for _, structInfo := range pkg.Structs {
+			if structInfo.Name == route.Binding.Receiver {
+				g.services = append(g.services, g.service(pkg, structInfo))
+			}
+		}
+	}
+	if len(g.services) == 0 {
+		return nil, fmt.Errorf("no service struct is bound to a route")
+	}
+	sort.Slice(g.services, func(i, j int) bool { return g.services[i].name < g.services[j].name })
+
+	// Every message name is known once the methods are mapped, so the
+	// wrapper messages can be named without clashing
+	for _, service := range g.services {
+		for _, rpc := range service.rpcs {
+			g.nameWrappers(service, rpc)
+		}
+	}
+
+	adapter, err := format.Source([]byte(g.adapter()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format gRPC adapter: %v", err)
+	}
+	return &ProtoOutput{Proto: g.proto(), Adapter: string(adapter)}, nil
+}
+
+// protoGenerator maps scanned services and structs onto protobuf
+type protoGenerator struct {
+	types    *openAPIBuilder // resolves and names the scanned structs
+	lock     *ProtoLock
+	options  ProtoOptions
+	services []*protoService
+	aliases  map[string]string       // import path -> alias in the adapter
+	imports  map[string]bool         // proto imports
+	fields   map[string][]protoField // struct key -> message fields
+	wrappers map[string][]protoField // wrapper message name -> fields
+	taken    []string                // adapter aliases in use
+}
+
+// protoService is a service struct and the RPCs of its methods
+type protoService struct {
+	name    string // proto service name
+	goType  string // adapter spelling of the struct
+	doc     string
+	rpcs    []*protoRPC
+	skipped []string // methods that cannot be RPCs, with the reason
+}
+
+// protoRPC is one method of a service
+type protoRPC struct {
+	method     MethodInfo
+	context    bool         // the method takes a context.Context first
+	params     []protoField // parameters after the context
+	result     *protoType   // nil for methods without a result
+	returnsErr bool
+	request    string // request message
+	response   string // response message
+	wrapReq    bool   // request is a message of the parameters
+	wrapResp   bool   // response is a message holding the result
+}
+
+// protoField is a message field or method parameter
+type protoField struct {
+	name   string // proto field name
+	goName string // Go field or parameter name
+	goType string // Go type as scanned
+	typ    *protoType
+	number int
+}
+
+// protoType describes how a Go type is carried in a proto field and how the
+// adapter converts between the two
+type protoType struct {
+	proto   string     // proto type, such as "int64" or "Order"
+	label   string     // "repeated", "optional", "map" or ""
+	pbType  string     // Go type of the generated field
+	goType  string     // Go type in the adapter
+	message bool       // a message type; pbType is a pointer
+	toFunc  string     // function converting goType to pbType, if any
+	fromFn  string     // function converting pbType to goType, if any
+	same    bool       // goType and pbType are identical
+	elem    *protoType // the pointed to type of a pointer
+	to      func(expr string) string
+	from    func(expr string) string
+}
+
+// spec returns the field type as written in the .proto file
+func (t *protoType) spec() string {
+	switch t.label {
+	case "repeated", "optional":
+		return t.label + " " + t.proto
+	}
+	return t.proto
+}
+
+// protoScalars maps Go scalars to their proto type and generated Go type
+var protoScalars = map[string][2]string{
+	"string": {"string", "string"}, "bool": {"bool", "bool"},
+	"int": {"int64", "int64"}, "int8": {"int32", "int32"}, "int16": {"int32", "int32"},
+	"int32": {"int32", "int32"}, "int64": {"int64", "int64"},
+	"uint": {"uint64", "uint64"}, "uint8": {"uint32", "uint32"}, "uint16": {"uint32", "uint32"},
+	"uint32": {"uint32", "uint32"}, "uint64": {"uint64", "uint64"},
+	"float32": {"float", "float32"}, "float64": {"double", "float64"},
+	"byte": {"uint32", "uint32"}, "rune": {"int32", "int32"},
+}
+
+// protoMapKeys are the proto types allowed as map keys
+var protoMapKeys = map[string]bool{
+	"string": true, "bool": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+}
+
+// service maps the exported methods of a service struct
+func (g *protoGenerator) service(pkg *PackageInfo, structInfo StructInfo) *protoService {
+	service := &protoService{
+		name:   structInfo.Name,
+		goType: g.alias(pkg) + "." + structInfo.Name,
+		doc:    docText(structInfo.Doc),
+	}
+	for _, method := range structInfo.Methods {
+		if !isExportedName(method.Name) {
+			continue
+		}
+		rpc, err := g.rpc(method)
+		if err != nil {
+			service.skipped = append(service.skipped, fmt.Sprintf("%s is not an RPC: %v", method.Name, err))
+			continue
+		}
+		service.rpcs = append(service.rpcs, rpc)
+	}
+	return service
+}
+
+// rpc maps the signature of a method. Methods take an optional context
+// followed by parameters with a protobuf equivalent, and return an optional
+// result and an optional error.
+func (g *protoGenerator) rpc(method MethodInfo) (*protoRPC, error) {
+	rpc := &protoRPC{method: method}
+	params := method.Parameters
+	if len(params) > 0 && params[0].QualifiedType == "context.Context" {
+		rpc.context = true
+		params = params[1:]
+	}
+	for i, param := range params {
+		typ, err := g.typeOf(param.QualifiedType)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %v", paramDisplayName(param, i), err)
+		}
+		name := paramDisplayName(param, i)
+		rpc.params = append(rpc.params, protoField{name: protoFieldName(name), goName: name, typ: typ})
+	}
+
+	returns := method.Returns
+	if len(returns) > 0 && returns[len(returns)-1].QualifiedType == "error" {
+		rpc.returnsErr = true
+		returns = returns[:len(returns)-1]
+	}
+	switch len(returns) {
+	case 0:
+	case 1:
+		typ, err := g.typeOf(returns[0].QualifiedType)
+		if err != nil {
+			return nil, fmt.Errorf("result: %v", err)
+		}
+		rpc.result = typ
+	default:
+		return nil, fmt.Errorf("it returns %d values", len(method.Returns))
+	}
+	return rpc, nil
+}
+
+// nameWrappers picks the request and response messages of an RPC. A single
+// struct parameter or struct result is used as is; other signatures get a
+// <Method>Request or <Method>Response message.
+func (g *protoGenerator) nameWrappers(service *protoService, rpc *protoRPC) {
+	if len(rpc.params) == 1 && rpc.params[0].typ.message && rpc.params[0].typ.label == "" && !isWellKnown(rpc.params[0].typ.proto) {
+		rpc.request = rpc.params[0].typ.proto
+	} else {
+		rpc.wrapReq = true
+		rpc.request = g.wrapperName(service, rpc.method.Name+"Request")
+		g.wrappers[rpc.request] = rpc.params
+	}
+	if rpc.result != nil && rpc.result.message && rpc.result.label == "" && !isWellKnown(rpc.result.proto) {
+		rpc.response = rpc.result.proto
+	} else {
+		rpc.wrapResp = true
+		rpc.response = g.wrapperName(service, rpc.method.Name+"Response")
+		var fields []protoField
+		if rpc.result != nil {
+			fields = []protoField{{name: "result", goName: "result", typ: rpc.result}}
+		}
+		g.wrappers[rpc.response] = fields
+	}
+}
+
+// wrapperName claims a message name, prefixing it with the service name
+// when another message has it
+func (g *protoGenerator) wrapperName(service *protoService, name string) string {
+	if g.types.taken[name] {
+		name = service.name + name
+	}
+	base := name
+	for n := 2; g.types.taken[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	g.types.taken[name] = true
+	return name
+}
+
+// isWellKnown reports whether a message is a google.protobuf type
+func isWellKnown(message string) bool {
+	return strings.HasPrefix(message, "google.protobuf.")
+}
+
+// typeOf maps a fully qualified Go type to protobuf
+func (g *protoGenerator) typeOf(qualified string) (*protoType, error) {
+	typ := strings.TrimSpace(qualified)
+	if typ == "" {
+		return nil, fmt.Errorf("type could not be resolved")
+	}
+	identity := func(expr string) string { return expr }
+
+	if scalar, ok := protoScalars[typ]; ok {
+		t := &protoType{proto: scalar[0], pbType: scalar[1], goType: typ, same: typ == scalar[1], to: identity, from: identity}
+		if !t.same {
+			t.to = func(expr string) string { return scalar[1] + "(" + expr + ")" }
+			t.from = func(expr string) string { return typ + "(" + expr + ")" }
+		}
+		return t, nil
+	}
+
+	switch {
+	case typ == "time.Time":
+		g.imports["google/protobuf/timestamp.proto"] = true
+		return &protoType{
+			proto: "google.protobuf.Timestamp", pbType: "*timestamppb.Timestamp", goType: "time.Time", message: true,
+			toFunc: "timeToProto", fromFn: "timeFromProto",
+			to:   func(expr string) string { return "timeToProto(" + expr + ")" },
+			from: func(expr string) string { return "timeFromProto(" + expr + ")" },
+		}, nil
+	case typ == "time.Duration":
+		g.imports["google/protobuf/duration.proto"] = true
+		return &protoType{
+			proto: "google.protobuf.Duration", pbType: "*durationpb.Duration", goType: "time.Duration", message: true,
+			toFunc: "durationpb.New",
+			to:     func(expr string) string { return "durationpb.New(" + expr + ")" },
+			from:   func(expr string) string { return expr + ".AsDuration()" },
+		}, nil
+	case typ == "[]byte" || typ == "[]uint8":
+		return &protoType{proto: "bytes", pbType: "[]byte", goType: "[]byte", same: true, to: identity, from: identity}, nil
+
+	case strings.HasPrefix(typ, "*"):
+		elem, err := g.typeOf(typ[1:])
+		if err != nil {
+			return nil, err
+		}
+		if elem.label != "" || elem.pbType == "[]byte" {
+			return nil, fmt.Errorf("%s has no protobuf equivalent", typ)
+		}
+		t := &protoType{proto: elem.proto, goType: "*" + elem.goType, elem: elem}
+		if elem.message {
+			// Message fields are nil when unset already
+			t.pbType, t.message = elem.pbType, true
+			t.to = func(expr string) string { return "convertRef(" + expr + ", " + g.toFunc(elem) + ")" }
+			t.from = func(expr string) string { return "convertOptional(" + expr + ", " + g.fromFunc(elem) + ")" }
+			return t, nil
+		}
+		t.label, t.pbType = "optional", "*"+elem.pbType
+		if elem.same {
+			t.same, t.to, t.from = true, identity, identity
+			return t, nil
+		}
+		t.to = func(expr string) string { return "convertPtr(" + expr + ", " + g.toFunc(elem) + ")" }
+		t.from = func(expr string) string { return "convertPtr(" + expr + ", " + g.fromFunc(elem) + ")" }
+		return t, nil
+
+	case strings.HasPrefix(typ, "[]"):
+		elem, err := g.typeOf(typ[2:])
+		if err != nil {
+			return nil, err
+		}
+		if elem.label != "" {
+			return nil, fmt.Errorf("%s has no protobuf equivalent", typ)
+		}
+		t := &protoType{proto: elem.proto, label: "repeated", pbType: "[]" + elem.pbType, goType: "[]" + elem.goType, message: false}
+		if elem.same {
+			t.same, t.to, t.from = true, identity, identity
+			return t, nil
+		}
+		t.to = func(expr string) string { return "convertSlice(" + expr + ", " + g.toFunc(elem) + ")" }
+		t.from = func(expr string) string { return "convertSlice(" + expr + ", " + g.fromFunc(elem) + ")" }
+		return t, nil
+
+	case strings.HasPrefix(typ, "map["):
+		end := matchingBracket(typ, len("map"))
+		if end < 0 {
+			return nil, fmt.Errorf("%s has no protobuf equivalent", typ)
+		}
+		key, err := g.typeOf(typ[len("map["):end])
+		if err != nil {
+			return nil, err
+		}
+		value, err := g.typeOf(typ[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		if !protoMapKeys[key.proto] || key.label != "" || value.label != "" {
+			return nil, fmt.Errorf("%s has no protobuf equivalent", typ)
+		}
+		t := &protoType{
+			proto:  fmt.Sprintf("map<%s, %s>", key.proto, value.proto),
+			label:  "map",
+			pbType: fmt.Sprintf("map[%s]%s", key.pbType, value.pbType),
+			goType: fmt.Sprintf("map[%s]%s", key.goType, value.goType),
+		}
+		if key.same && value.same {
+			t.same, t.to, t.from = true, identity, identity
+			return t, nil
+		}
+		t.to = func(expr string) string {
+			return "convertMap(" + expr + ", " + g.toFunc(key) + ", " + g.toFunc(value) + ")"
+		}
+		t.from = func(expr string) string {
+			return "convertMap(" + expr + ", " + g.fromFunc(key) + ", " + g.fromFunc(value) + ")"
+		}
+		return t, nil
+	}
+
+	structKey := g.types.resolve(typ, "")
+	if structKey == "" || !strings.Contains(typ, ".") {
+		return nil, fmt.Errorf("%s has no protobuf equivalent", typ)
+	}
+	name := g.types.component(structKey)
+	ref := g.types.structs[structKey]
+	toFunc, fromFunc := lowerFirst(name)+"ToProto", lowerFirst(name)+"FromProto"
+	return &protoType{
+		proto:   name,
+		pbType:  "*pb." + name,
+		goType:  g.alias(ref.pkg) + "." + ref.info.Name,
+		message: true,
+		toFunc:  toFunc,
+		fromFn:  fromFunc,
+		to:      func(expr string) string { return toFunc + "(" + expr + ")" },
+		from:    func(expr string) string { return fromFunc + "(" + expr + ")" },
+	}, nil
+}
+
+// toFunc returns a function value converting t to its generated type
+func (g *protoGenerator) toFunc(t *protoType) string {
+	if t.toFunc != "" {
+		return t.toFunc
+	}
+	if t.same {
+		return fmt.Sprintf("func(v %s) %s { return v }", t.goType, t.pbType)
+	}
+	return fmt.Sprintf("func(v %s) %s { return %s }", t.goType, t.pbType, t.to("v"))
+}
+
+// fromFunc returns a function value converting t from its generated type
+func (g *protoGenerator) fromFunc(t *protoType) string {
+	if t.fromFn != "" {
+		return t.fromFn
+	}
+	if t.same {
+		return fmt.Sprintf("func(v %s) %s { return v }", t.pbType, t.goType)
+	}
+	return fmt.Sprintf("func(v %s) %s { return %s }", t.pbType, t.goType, t.from("v"))
+}
+
+// messageFields maps the fields of a struct, numbering them from the lock.
+// Fields without a protobuf equivalent are returned with a nil type.
+func (g *protoGenerator) messageFields(key string) []protoField {
+	if fields, ok := g.fields[key]; ok {
+		return fields
+	}
+	ref := g.types.structs[key]
+	var fields []protoField
+	used := make(map[string]bool)
+	for _, field := range ref.info.Fields {
+		if !isExportedName(field.Name) {
+			continue
+		}
+		if _, _, skip := fieldJSON(field); skip && !field.Embedded {
+			continue
+		}
+		name := protoFieldName(field.Name)
+		for base, n := name, 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[name] = true
+		typ, err := g.typeOf(field.QualifiedType)
+		if err != nil {
+			typ = nil
+		}
+		fields = append(fields, protoField{name: name, goName: field.Name, goType: field.Type, typ: typ})
+	}
+	g.fields[key] = fields
+	return fields
+}
+
+// number fills in the field numbers of a message from the lock
+func (g *protoGenerator) number(message string, fields []protoField) []protoField {
+	var names, types []string
+	for _, field := range fields {
+		if field.typ != nil {
+			names = append(names, field.name)
+			types = append(types, field.typ.spec())
+		}
+	}
+	numbers := g.lock.assign(message, names, types)
+	numbered := make([]protoField, len(fields))
+	copy(numbered, fields)
+	for i, j := 0, 0; i < len(numbered); i++ {
+		if numbered[i].typ != nil {
+			numbered[i].number = numbers[j]
+			j++
+		}
+	}
+	return numbered
+}
+
+// proto renders the .proto file
+func (g *protoGenerator) proto() string {
+	var messages strings.Builder
+	for _, key := range g.messageKeys() {
+		ref := g.types.structs[key]
+		name := g.types.names[key]
+		doc := docText(ref.info.Doc)
+		if doc == "" {
+			doc = fmt.Sprintf("%s mirrors %s.%s", name, ref.pkg.ImportPath, ref.info.Name)
+		}
+		messages.WriteString(g.message(name, doc, g.number(name, g.messageFields(key))))
+	}
+	var wrappers []string
+	for name := range g.wrappers {
+		wrappers = append(wrappers, name)
+	}
+	sort.Strings(wrappers)
+	for _, name := range wrappers {
+		messages.WriteString(g.message(name, "", g.number(name, g.wrappers[name])))
+	}
+
+	var out strings.Builder
+	out.WriteString("// Services of the scanned packages. Field numbers are recorded in the lock\n")
+	out.WriteString("// file; commit it with this file so that numbers survive regeneration.\n")
+	out.WriteString("syntax = \"proto3\";\n\n")
+	out.WriteString(fmt.Sprintf("package %s;\n\n", g.options.Package))
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		out.WriteString(fmt.Sprintf("import %q;\n", path))
+	}
+	if len(imports) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString(fmt.Sprintf("option go_package = %q;\n", g.options.GoPackage+";"+g.goPackageName()))
+
+	for _, service := range g.services {
+		out.WriteString("\n")
+		doc := service.doc
+		if doc == "" {
+			doc = service.name + " serves the methods of " + service.goType
+		}
+		out.WriteString(goComment(doc, ""))
+		out.WriteString(fmt.Sprintf("service %s {\n", service.name))
+		for _, rpc := range service.rpcs {
+			if doc := docText(rpc.method.Doc); doc != "" {
+				out.WriteString(goComment(doc, "  "))
+			}
+			out.WriteString(fmt.Sprintf("  rpc %s(%s) returns (%s);\n", rpc.method.Name, rpc.request, rpc.response))
+		}
+		for _, skipped := range service.skipped {
+			out.WriteString(fmt.Sprintf("  // %s\n", skipped))
+		}
+		out.WriteString("}\n")
+	}
+	out.WriteString(messages.String())
+	return out.String()
+}
+
+// message renders one message with its reserved numbers
+func (g *protoGenerator) message(name, doc string, fields []protoField) string {
+	var out strings.Builder
+	out.WriteString("\n")
+	if doc != "" {
+		out.WriteString(goComment(doc, ""))
+	}
+	if len(fields) == 0 && g.lock.Messages[name] != nil && len(g.lock.Messages[name].Reserved) == 0 {
+		out.WriteString(fmt.Sprintf("message %s {}\n", name))
+		return out.String()
+	}
+	out.WriteString(fmt.Sprintf("message %s {\n", name))
+	present := make(map[string]bool)
+	for _, field := range fields {
+		if field.typ == nil {
+			out.WriteString(fmt.Sprintf("  // %s %s has no protobuf equivalent\n", field.goName, field.goType))
+			continue
+		}
+		present[field.name] = true
+		out.WriteString(fmt.Sprintf("  %s %s = %d;\n", field.typ.spec(), field.name, field.number))
+	}
+	if m := g.lock.Messages[name]; m != nil && len(m.Reserved) > 0 {
+		var numbers []int
+		var names []string
+		for _, reserved := range m.Reserved {
+			numbers = append(numbers, reserved.Number)
+			if quoted := strconv.Quote(reserved.Name); !present[reserved.Name] && !contains(names, quoted) {
+				names = append(names, quoted)
+			}
+		}
+		sort.Ints(numbers)
+		var numberList []string
+		for i, number := range numbers {
+			if i == 0 || number != numbers[i-1] {
+				numberList = append(numberList, fmt.Sprint(number))
+			}
+		}
+		out.WriteString(fmt.Sprintf("  reserved %s;\n", strings.Join(numberList, ", ")))
+		if len(names) > 0 {
+			sort.Strings(names)
+			out.WriteString(fmt.Sprintf("  reserved %s;\n", strings.Join(names, ", ")))
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// messageKeys returns the struct keys of every message, including the
+// structs their fields refer to, sorted by message name
+func (g *protoGenerator) messageKeys() []string {
+	rendered := make(map[string]bool)
+	var keys []string
+	for {
+		var pending []string
+		for key := range g.types.names {
+			if !rendered[key] {
+				pending = append(pending, key)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		for _, key := range pending {
+			rendered[key] = true
+			// Mapping the fields names the structs they refer to
+			g.messageFields(key)
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return g.types.names[keys[i]] < g.types.names[keys[j]] })
+	return keys
+}
+
+// goPackageName is the package name protoc-gen-go uses for GoPackage
+func (g *protoGenerator) goPackageName() string {
+	base := g.options.GoPackage[strings.LastIndex(g.options.GoPackage, "/")+1:]
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, base)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// alias returns the adapter's import alias of a scanned package
+func (g *protoGenerator) alias(pkg *PackageInfo) string {
+	if alias, ok := g.aliases[pkg.ImportPath]; ok {
+		return alias
+	}
+	base := pkg.Name
+	alias := base
+	for n := 2; contains(g.taken, alias); n++ {
+		alias = fmt.Sprintf("%s%d", base, n)
+	}
+	g.aliasTaken(alias)
+	g.aliases[pkg.ImportPath] = alias
+	return alias
+}
+
+func (g *protoGenerator) aliasTaken(alias string) {
+	g.taken = append(g.taken, alias)
+}
+
+// adapter renders the Go package implementing the services
+func (g *protoGenerator) adapter() string {
+	var body strings.Builder
+	for _, service := range g.services {
+		body.WriteString(g.adapterService(service))
+	}
+	for _, key := range g.messageKeys() {
+		body.WriteString(g.converters(key))
+	}
+	body.WriteString(protoAdapterHelpers)
+
+	imports := map[string]bool{g.options.GoPackage: true}
+	for path := range g.aliases {
+		imports[path] = true
+	}
+	for _, needed := range []string{"context", "time"} {
+		if strings.Contains(body.String(), needed+".") {
+			imports[needed] = true
+		}
+	}
+	if strings.Contains(body.String(), "timestamppb.") {
+		imports["google.golang.org/protobuf/types/known/timestamppb"] = true
+	}
+	if strings.Contains(body.String(), "durationpb.") {
+		imports["google.golang.org/protobuf/types/known/durationpb"] = true
+	}
+	aliases := map[string]string{g.options.GoPackage: "pb"}
+	for path, alias := range g.aliases {
+		aliases[path] = alias
+	}
+
+	var out strings.Builder
+	out.WriteString("// Package grpcserver implements the services of api.proto by delegating to\n")
+	out.WriteString("// the scanned service structs. Register a service with its generated\n")
+	out.WriteString("// Register function, e.g. pb.RegisterOrderServiceServer(s, grpcserver.NewOrderService(svc)).\n")
+	out.WriteString("package grpcserver\n\n")
+	out.WriteString(renderImportBlock(imports, aliases))
+	out.WriteString(body.String())
+	return out.String()
+}
+
+// adapterService renders the server type of one service
+func (g *protoGenerator) adapterService(service *protoService) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf(`// %[1]s implements pb.%[1]sServer with a *%[2]s
+type %[1]s struct {
+	pb.Unimplemented%[1]sServer
+	service *%[2]s
+}
+
+// New%[1]s returns the gRPC server of service
+func New%[1]s(service *%[2]s) *%[1]s {
+	return &%[1]s{service: service}
+}
+
+`, service.name, service.goType))
+
+	for _, rpc := range service.rpcs {
+		out.WriteString(fmt.Sprintf("// %s calls %s.%s\n", rpc.method.Name, service.goType, rpc.method.Name))
+		out.WriteString(fmt.Sprintf("func (s *%s) %s(ctx context.Context, req *pb.%s) (*pb.%s, error) {\n", service.name, rpc.method.Name, rpc.request, rpc.response))
+
+		var args []string
+		if rpc.context {
+			args = append(args, "ctx")
+		}
+		if rpc.wrapReq {
+			for _, param := range rpc.params {
+				args = append(args, param.typ.from("req."+goCamelCase(param.name)))
+			}
+		} else {
+			args = append(args, rpc.params[0].typ.from("req"))
+		}
+		call := fmt.Sprintf("s.service.%s(%s)", rpc.method.Name, strings.Join(args, ", "))
+
+		switch {
+		case rpc.result != nil && rpc.returnsErr:
+			out.WriteString(fmt.Sprintf("\tresult, err := %s\n\tif err != nil {\n\t\treturn nil, err\n\t}\n", call))
+		case rpc.result != nil:
+			out.WriteString(fmt.Sprintf("\tresult := %s\n", call))
+		case rpc.returnsErr:
+			out.WriteString(fmt.Sprintf("\tif err := %s; err != nil {\n\t\treturn nil, err\n\t}\n", call))
+		default:
+			out.WriteString(fmt.Sprintf("\t%s\n", call))
+		}
+
+		switch {
+		case rpc.result == nil:
+			out.WriteString(fmt.Sprintf("\treturn &pb.%s{}, nil\n", rpc.response))
+		case rpc.wrapResp:
+			out.WriteString(fmt.Sprintf("\treturn &pb.%s{Result: %s}, nil\n", rpc.response, rpc.result.to("result")))
+		case strings.HasPrefix(rpc.result.goType, "*"):
+			out.WriteString(fmt.Sprintf("\tif result == nil {\n\t\treturn &pb.%s{}, nil\n\t}\n", rpc.response))
+			out.WriteString(fmt.Sprintf("\treturn %s(*result), nil\n", g.toFunc(rpc.result.elem)))
+		default:
+			out.WriteString(fmt.Sprintf("\treturn %s, nil\n", rpc.result.to("result")))
+		}
+		out.WriteString("}\n\n")
+	}
+	return out.String()
+}
+
+// converters renders the functions converting a struct to and from its
+// message
+func (g *protoGenerator) converters(key string) string {
+	ref := g.types.structs[key]
+	name := g.types.names[key]
+	goType := g.alias(ref.pkg) + "." + ref.info.Name
+	fields := g.messageFields(key)
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("// %sToProto converts a %s to its message\n", lowerFirst(name), goType))
+	out.WriteString(fmt.Sprintf("func %sToProto(in %s) *pb.%s {\n\treturn &pb.%s{\n", lowerFirst(name), goType, name, name))
+	for _, field := range fields {
+		if field.typ != nil {
+			out.WriteString(fmt.Sprintf("\t\t%s: %s,\n", goCamelCase(field.name), field.typ.to("in."+field.goName)))
+		}
+	}
+	out.WriteString("\t}\n}\n\n")
+
+	out.WriteString(fmt.Sprintf("// %sFromProto converts a message to a %s\n", lowerFirst(name), goType))
+	out.WriteString(fmt.Sprintf("func %sFromProto(in *pb.%s) %s {\n", lowerFirst(name), name, goType))
+	out.WriteString(fmt.Sprintf("\tif in == nil {\n\t\treturn %s{}\n\t}\n\treturn %s{\n", goType, goType))
+	for _, field := range fields {
+		if field.typ != nil {
+			out.WriteString(fmt.Sprintf("\t\t%s: %s,\n", field.goName, field.typ.from("in."+goCamelCase(field.name))))
+		}
+	}
+	out.WriteString("\t}\n}\n\n")
+	return out.String()
+}
+
+// protoFieldName returns the snake case proto name of a Go name
+func protoFieldName(name string) string {
+	words := NewInflector(nil).Words(name)
+	if len(words) == 0 {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(strings.Join(words, "_"))
+}
+
+// goCamelCase returns the Go name protoc-gen-go gives a proto field
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case c >= '0' && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// protoAdapterHelpers converts the values the messages carry
+const protoAdapterHelpers = `func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func convertSlice[T, U any](in []T, convert func(T) U) []U {
+	if in == nil {
+		return nil
+	}
+	out := make([]U, len(in))
+	for i, v := range in {
+		out[i] = convert(v)
+	}
+	return out
+}
+
+func convertMap[K1, K2 comparable, V1, V2 any](in map[K1]V1, key func(K1) K2, value func(V1) V2) map[K2]V2 {
+	if in == nil {
+		return nil
+	}
+	out := make(map[K2]V2, len(in))
+	for k, v := range in {
+		out[key(k)] = value(v)
+	}
+	return out
+}
+
+func convertPtr[T, U any](in *T, convert func(T) U) *U {
+	if in == nil {
+		return nil
+	}
+	v := convert(*in)
+	return &v
+}
+
+func convertRef[T, U any](in *T, convert func(T) *U) *U {
+	if in == nil {
+		return nil
+	}
+	return convert(*in)
+}
+
+func convertOptional[T, U any](in *T, convert func(*T) U) *U {
+	if in == nil {
+		return nil
+	}
+	v := convert(in)
+	return &v
+}
+`
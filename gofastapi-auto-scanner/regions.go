@@ -0,0 +1,328 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Generated Go, TypeScript, YAML and Dockerfile outputs start with a
+// checksum header. The checksum covers everything but the header and the
+// bodies of user regions, so a regeneration can tell a file it may replace
+// from one a developer changed by hand. User regions are delimited by
+//
+//	// gofastapi:begin <name> <checksum of the generated body>
+//	// gofastapi:end <name>
+//
+// and a region whose body no longer matches its begin marker's checksum
+// keeps its body when the file is written again.
+const (
+	regionBeginTag   = "gofastapi:begin"
+	regionEndTag     = "gofastapi:end"
+	checksumTag      = "gofastapi:checksum"
+	generatedNotice  = "Code generated by gofastapi. Edit only inside gofastapi:begin/end regions."
+	pendingSuffix    = ".new"
+	orphanedSuffix   = ".orphaned"
+	checksumHexBytes = 8
+)
+
+// EditedFileError reports a generated file that was changed outside its
+// user regions since it was written. Regeneration leaves it alone and
+// writes the fresh output to Pending for a manual merge. Headerless is set
+// for a file without a checksum header, such as one written before the
+// generator stamped them, which OutputSet.Adopt takes over instead.
+type EditedFileError struct {
+	Path       string
+	Pending    string
+	Headerless bool
+}
+
+func (e *EditedFileError) Error() string {
+	if e.Headerless {
+		return fmt.Sprintf("%s has no gofastapi checksum header: regenerate it with -adopt if gofastapi wrote it, or merge %s into it by hand", e.Path, e.Pending)
+	}
+	return fmt.Sprintf("%s was edited outside its gofastapi regions: merge %s into it by hand, or delete it to regenerate it", e.Path, e.Pending)
+}
+
+// userRegion wraps the generated body of a user region in its markers,
+// indented by depth tabs
+func userRegion(name, body string, depth int) string {
+	prefix := strings.Repeat("\t", depth)
+	return fmt.Sprintf("%s// %s %s %s\n%s%s// %s %s\n",
+		prefix, regionBeginTag, name, regionChecksum(body), body, prefix, regionEndTag, name)
+}
+
+// userImports returns an import declaration holding only the user region
+// for imports that edited handlers need
+func userImports() string {
+	return "import (\n" + userRegion("imports", "", 1) + ")\n\n"
+}
+
+// userDeclarations returns the top-level user region for helpers that
+// edited handlers share
+func userDeclarations() string {
+	return userRegion("declarations", "", 0)
+}
+
+// regionChecksum hashes text with all whitespace removed, so reformatting
+// a file does not count as editing it
+func regionChecksum(text string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+	sum := sha256.Sum256([]byte(stripped))
+	return hex.EncodeToString(sum[:checksumHexBytes])
+}
+
+// commentPrefix returns the line comment syntax of the file kinds that
+// carry a checksum header, or "" for files that are overwritten as is
+func commentPrefix(path string) string {
+	base := filepath.Base(path)
+	switch {
+	case strings.HasSuffix(base, ".go"), strings.HasSuffix(base, ".ts"):
+		return "//"
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"), base == "Dockerfile":
+		return "#"
+	}
+	return ""
+}
+
+// sourceRegion is a user region parsed from a file
+type sourceRegion struct {
+	Name     string
+	Checksum string
+	Body     string
+}
+
+// Edited reports whether the body differs from the generated one
+func (r sourceRegion) Edited() bool {
+	return regionChecksum(r.Body) != r.Checksum
+}
+
+// regionMarker parses a begin or end marker line, returning its fields
+// after the tag
+func regionMarker(line, tag string) ([]string, bool) {
+	text := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#"} {
+		if rest, ok := strings.CutPrefix(text, prefix); ok {
+			fields := strings.Fields(rest)
+			if len(fields) > 1 && fields[0] == tag {
+				return fields[1:], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// splitRegions returns content without the bodies of its user regions,
+// and the regions in file order. A region without an end marker is left
+// in the skeleton.
+func splitRegions(content string) (string, []sourceRegion) {
+	lines := strings.SplitAfter(content, "\n")
+	var skeleton strings.Builder
+	var regions []sourceRegion
+	for i := 0; i < len(lines); i++ {
+		skeleton.WriteString(lines[i])
+		fields, ok := regionMarker(lines[i], regionBeginTag)
+		if !ok {
+			continue
+		}
+		region := sourceRegion{Name: fields[0]}
+		if len(fields) > 1 {
+			region.Checksum = fields[1]
+		}
+		end := -1
+		for j := i + 1; j < len(lines); j++ {
+			if fields, ok := regionMarker(lines[j], regionEndTag); ok && fields[0] == region.Name {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		region.Body = strings.Join(lines[i+1:end], "")
+		regions = append(regions, region)
+		i = end - 1
+	}
+	return skeleton.String(), regions
+}
+
+// stampChecksum prepends the generated notice and checksum header, set
+// apart by a blank line so it never becomes a package comment
+func stampChecksum(content, prefix string) string {
+	skeleton, _ := splitRegions(content)
+	return fmt.Sprintf("%s %s\n%s %s %s\n\n", prefix, generatedNotice, prefix, checksumTag, regionChecksum(skeleton)) + content
+}
+
+// readChecksum splits the header from a generated file. ok is false when
+// the file has no header, which is the case for files written by hand or
+// by earlier versions of the generator.
+func readChecksum(content, prefix string) (checksum, body string, ok bool) {
+	notice, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimSpace(notice) != prefix+" "+generatedNotice {
+		return "", content, false
+	}
+	header, body, found := strings.Cut(rest, "\n")
+	if !found {
+		return "", content, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(header), prefix))
+	if len(fields) != 2 || fields[0] != checksumTag {
+		return "", content, false
+	}
+	return fields[1], strings.TrimPrefix(body, "\n"), true
+}
+
+// mergeRegions carries the edited user regions of previous into fresh
+// output. Edited regions that fresh no longer has are returned as orphans.
+func mergeRegions(fresh, previous string) (string, []sourceRegion) {
+	_, regions := splitRegions(previous)
+	edited := make(map[string]sourceRegion)
+	for _, region := range regions {
+		if region.Edited() {
+			edited[region.Name] = region
+		}
+	}
+	if len(edited) == 0 {
+		return fresh, nil
+	}
+
+	var merged strings.Builder
+	lines := strings.SplitAfter(fresh, "\n")
+	for i := 0; i < len(lines); i++ {
+		merged.WriteString(lines[i])
+		fields, ok := regionMarker(lines[i], regionBeginTag)
+		if !ok {
+			continue
+		}
+		region, found := edited[fields[0]]
+		if !found {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if fields, ok := regionMarker(lines[j], regionEndTag); ok && fields[0] == region.Name {
+				merged.WriteString(region.Body)
+				delete(edited, region.Name)
+				i = j - 1
+				break
+			}
+		}
+	}
+
+	var orphans []sourceRegion
+	for _, region := range regions {
+		if _, ok := edited[region.Name]; ok {
+			orphans = append(orphans, region)
+		}
+	}
+	return merged.String(), orphans
+}
+
+// writeOrphans appends user regions that are no longer generated to the
+// companion file of path, which regeneration never overwrites
+func writeOrphans(path, prefix string, orphans []sourceRegion) error {
+	file, err := os.OpenFile(path+orphanedSuffix, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, region := range orphans {
+		log.Printf("%s: region %s is no longer generated, its code was moved to %s", path, region.Name, path+orphanedSuffix)
+		_, err := fmt.Fprintf(file, "%s %s %s\n%s%s %s %s\n\n",
+			prefix, regionBeginTag, region.Name, region.Body, prefix, regionEndTag, region.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvedFile is a generated file as it would be written: the fresh
+// output with the edited user regions of the file on disk and a checksum
+// header. Conflict is set when the file on disk was edited outside its
+// regions, in which case Content is what goes to Conflict.Pending.
+type resolvedFile struct {
+	Path     string
+	Exists   bool
+	Previous string
+	Content  string
+	Orphans  []sourceRegion
+	Conflict *EditedFileError
+}
+
+// Changed reports whether writing the file would change the disk
+func (f *resolvedFile) Changed() bool {
+	return !f.Exists || f.Previous != f.Content || f.Conflict != nil
+}
+
+// resolveFile reads the file at path and resolves content against it. A
+// file without a checksum header counts as edited unless adopt is set, in
+// which case it is replaced like a generated file.
+func resolveFile(path, content string, adopt bool) (*resolvedFile, error) {
+	file := &resolvedFile{Path: path, Content: content}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file.Exists = err == nil
+	file.Previous = string(existing)
+
+	prefix := commentPrefix(path)
+	if prefix == "" {
+		return file, nil
+	}
+	if file.Exists {
+		checksum, previous, ok := readChecksum(file.Previous, prefix)
+		if ok || !adopt {
+			content, file.Orphans = mergeRegions(content, previous)
+			if skeleton, _ := splitRegions(previous); !ok || regionChecksum(skeleton) != checksum {
+				file.Conflict = &EditedFileError{Path: path, Pending: path + pendingSuffix, Headerless: !ok}
+				file.Orphans = nil
+			}
+		}
+	}
+	file.Content = stampChecksum(content, prefix)
+	return file, nil
+}
+
+// write puts the resolved file on disk. A conflicting file is left alone
+// and its content goes to the pending file instead.
+func (f *resolvedFile) write() error {
+	if f.Conflict != nil {
+		return os.WriteFile(f.Conflict.Pending, []byte(f.Content), 0644)
+	}
+	if err := createDirectory(filepath.Dir(f.Path)); err != nil {
+		return err
+	}
+	if len(f.Orphans) > 0 {
+		if err := writeOrphans(f.Path, commentPrefix(f.Path), f.Orphans); err != nil {
+			return fmt.Errorf("failed to keep removed regions of %s: %v", f.Path, err)
+		}
+	}
+	if err := os.Remove(f.Path + pendingSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if f.Exists && f.Previous == f.Content {
+		return nil
+	}
+	return os.WriteFile(f.Path, []byte(f.Content), 0644)
+}
+
+// writeFile writes a single generated file. Files that carry a checksum
+// header keep their edited user regions, and a file edited outside its
+// regions is not overwritten: the fresh output goes next to it and an
+// *EditedFileError is returned.
+func writeFile(path, content string) error {
+	out := NewOutputSet()
+	out.Add(path, content)
+	return out.Write()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RouteSource records how a route was produced. When two routes share a
+// method and path, the one from the source with the higher precedence is
+// kept: explicit annotations beat smart mapping, which beats auto CRUD.
+type RouteSource string
+
+const (
+	RouteSourceAnnotation RouteSource = "annotation"
+	RouteSourceSmart      RouteSource = "smart"
+	RouteSourceCRUD       RouteSource = "crud"
+)
+
+// precedence ranks the source; higher wins
+func (s RouteSource) precedence() int {
+	switch s {
+	case RouteSourceAnnotation:
+		return 3
+	case RouteSourceSmart:
+		return 2
+	case RouteSourceCRUD:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// describe names the source in diagnostics
+func (s RouteSource) describe() string {
+	switch s {
+	case RouteSourceAnnotation:
+		return "@api annotation"
+	case RouteSourceSmart:
+		return "smart mapping"
+	case RouteSourceCRUD:
+		return "auto CRUD"
+	default:
+		return "unknown source"
+	}
+}
+
+// RouteTable is the merged, conflict-free set of routes handed to the
+// generators. Every method and path pair appears at most once in Routes.
+// Unrouted holds routes without a method, which no generator can serve.
+type RouteTable struct {
+	Routes    []APIRoute      `json:"routes"`
+	Shadowed  []APIRoute      `json:"shadowed,omitempty"`
+	Conflicts []RouteConflict `json:"conflicts,omitempty"`
+	Unrouted  []APIRoute      `json:"unrouted,omitempty"`
+}
+
+// RouteConflict is a pair of routes for the same method and path that
+// precedence cannot separate. Kept stays in the table, Dropped does not.
+type RouteConflict struct {
+	Method  string   `json:"method"`
+	Path    string   `json:"path"`
+	Kept    APIRoute `json:"kept"`
+	Dropped APIRoute `json:"dropped"`
+}
+
+// Diagnostic reports the conflict at the dropped route, naming the position
+// of the route that was kept
+func (c RouteConflict) Diagnostic() Diagnostic {
+	return Diagnostic{
+		Pos:      c.Dropped.Pos,
+		Severity: SeverityError,
+		Code:     DiagRouteConflict,
+		Message: fmt.Sprintf("%s %s of %s (%s) conflicts with %s (%s) at %s",
+			c.Method, c.Path, routeTarget(c.Dropped), c.Dropped.Source.describe(),
+			routeTarget(c.Kept), c.Kept.Source.describe(), c.Kept.Pos),
+	}
+}
+
+// BuildRouteTable collects the candidate routes of the scanned packages and
+// merges them into a route table
+func (ag *APIGenerator) BuildRouteTable() *RouteTable {
+	return NewRouteTable(ag.collectRoutes())
+}
+
+// NewRouteTable merges candidate routes by method and path. Of overlapping
+// routes the one with the highest source precedence is kept and the others
+// are recorded as shadowed; overlaps between routes of equal precedence are
+// recorded as conflicts, keeping the route declared first. Routes without a
+// method, such as struct-level @api.route declarations, name no handler to
+// call and are recorded as unrouted instead.
+func NewRouteTable(candidates []APIRoute) *RouteTable {
+	sorted := append([]APIRoute(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Source.precedence() != b.Source.precedence() {
+			return a.Source.precedence() > b.Source.precedence()
+		}
+		if a.Pos.Filename != b.Pos.Filename {
+			return a.Pos.Filename < b.Pos.Filename
+		}
+		if a.Pos.Offset != b.Pos.Offset {
+			return a.Pos.Offset < b.Pos.Offset
+		}
+		return routeTarget(a) < routeTarget(b)
+	})
+
+	table := &RouteTable{}
+	index := make(map[string]int)
+	for _, route := range sorted {
+		if route.Method == "" {
+			table.Unrouted = append(table.Unrouted, route)
+			continue
+		}
+
+		key := routeKey(route.Method, route.Path)
+		i, exists := index[key]
+		if !exists {
+			index[key] = len(table.Routes)
+			table.Routes = append(table.Routes, route)
+			continue
+		}
+
+		kept := table.Routes[i]
+		switch {
+		case routeTarget(kept) == routeTarget(route) && kept.Source == route.Source:
+			// The same route produced twice
+		case kept.Source.precedence() > route.Source.precedence():
+			table.Shadowed = append(table.Shadowed, route)
+		default:
+			table.Conflicts = append(table.Conflicts, RouteConflict{
+				Method:  strings.ToUpper(route.Method),
+				Path:    route.Path,
+				Kept:    kept,
+				Dropped: route,
+			})
+		}
+	}
+
+	sort.SliceStable(table.Routes, func(i, j int) bool {
+		a, b := table.Routes[i], table.Routes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return methodOrder(a.Method) < methodOrder(b.Method)
+	})
+	return table
+}
+
+// Diagnostics returns a diagnostic for every conflict and every unrouted
+// route in the table
+func (rt *RouteTable) Diagnostics() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, conflict := range rt.Conflicts {
+		diagnostics = append(diagnostics, conflict.Diagnostic())
+	}
+	for _, route := range rt.Unrouted {
+		diagnostics = append(diagnostics, Diagnostic{
+			Pos:      route.Pos,
+			Severity: SeverityWarning,
+			Code:     DiagUnrouted,
+			Message: fmt.Sprintf("%s of %s names no handler and is not served; annotate its methods with @api.endpoint",
+				route.Path, route.Struct),
+		})
+	}
+	return diagnostics
+}
+
+// routeKey identifies the method and path a router dispatches on. Path
+// parameters match whatever their name and form, so "/users/{id}",
+// "/users/{userID}" and "/users/:id" share a key.
+func routeKey(method, path string) string {
+	path = templatePath(path)
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(method))
+	b.WriteByte(' ')
+	for {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			break
+		}
+		b.WriteString(path[:start])
+		b.WriteString("{}")
+		path = path[end+1:]
+	}
+	b.WriteString(path)
+
+	key := b.String()
+	if len(key) > 1 && strings.HasSuffix(key, "/") && !strings.HasSuffix(key, " /") {
+		key = strings.TrimSuffix(key, "/")
+	}
+	return key
+}
+
+// routeTarget names the function a route calls
+func routeTarget(route APIRoute) string {
+	name := route.Function
+	if route.Struct != "" {
+		name = route.Struct + "." + name
+	}
+	if route.ImportPath != "" {
+		return route.ImportPath + "." + name
+	}
+	return route.Package + "." + name
+}
+
+// methodOrder sorts routes of one path in the conventional CRUD order
+func methodOrder(method string) int {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return 0
+	case "POST":
+		return 1
+	case "PUT":
+		return 2
+	case "PATCH":
+		return 3
+	case "DELETE":
+		return 4
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,857 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Stdlib Framework Generator
+//
+// StdlibGenerator emits a server that depends on nothing outside the
+// standard library. Routes are registered on an http.ServeMux with the
+// method and wildcard patterns of Go 1.22, such as "GET /users/{id}", so the
+// generated module builds without network access.
+type StdlibGenerator struct{}
+
+func NewStdlibGenerator() FrameworkGenerator {
+	return &StdlibGenerator{}
+}
+
+func (g *StdlibGenerator) GetName() string        { return "net/http" }
+func (g *StdlibGenerator) GetType() FrameworkType { return FrameworkStdlib }
+func (g *StdlibGenerator) GetDefaultConfig() *FrameworkConfig {
+	return &FrameworkConfig{
+		Type:       FrameworkStdlib,
+		Version:    "go1.22",
+		Features:   []string{"middleware", "router", "cors", "jwt"},
+		Middleware: []string{"request_id", "logger", "recover", "security_headers", "cors", "auth"},
+		CORS: &CORSConfig{
+			Enabled:      true,
+			AllowOrigins: []string{"*"},
+			AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			MaxAge:       86400,
+		},
+		Docs: &DocumentationConfig{
+			Enabled: true,
+			Path:    "/docs",
+			Format:  "openapi",
+		},
+		Testing: &TestingConfig{
+			Enabled:   true,
+			Framework: "testing",
+			Coverage:  true,
+		},
+	}
+}
+
+func (g *StdlibGenerator) GenerateMainFile(routes []APIRoute, config *FrameworkConfig) (string, error) {
+	return `package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Config holds the server settings read from the environment
+type Config struct {
+	Port      string
+	JWTSecret string
+}
+
+// Server serves the generated API with the standard library router
+type Server struct {
+	config *Config
+	mux    *http.ServeMux
+}
+
+// NewServer creates a server and registers its routes
+func NewServer(config *Config) *Server {
+	s := &Server{config: config, mux: http.NewServeMux()}
+	s.setupRoutes()
+	return s
+}
+
+// Handler returns the router wrapped in the global middleware
+func (s *Server) Handler() http.Handler {
+	return s.setupMiddleware(s.mux)
+}
+
+func main() {
+	config := &Config{
+		Port:      getEnv("PORT", "8080"),
+		JWTSecret: getEnv("JWT_SECRET", ""),
+	}
+	if config.JWTSecret == "" {
+		log.Println("JWT_SECRET is not set; authenticated routes will reject every request")
+	}
+
+	server := NewServer(config)
+	httpServer := &http.Server{
+		Addr:              ":" + config.Port,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Graceful shutdown
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	log.Printf("Starting net/http server on port %s", config.Port)
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server exited")
+}
+
+// getEnv returns the environment variable key, or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+`, nil
+}
+
+func (g *StdlibGenerator) GenerateMiddleware(config *FrameworkConfig) (string, error) {
+	cors := config.CORS
+	if cors == nil {
+		cors = &CORSConfig{}
+	}
+
+	return fmt.Sprintf(`package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contextKey is the type of the request context keys set by the middleware
+type contextKey string
+
+const (
+	requestIDKey contextKey = "request_id"
+	claimsKey    contextKey = "claims"
+)
+
+// setupMiddleware wraps the router in the global middleware. The first
+// middleware listed runs first.
+func (s *Server) setupMiddleware(next http.Handler) http.Handler {
+	middleware := []func(http.Handler) http.Handler{
+		recoverMiddleware,
+		requestIDMiddleware,
+		loggerMiddleware,
+		securityHeadersMiddleware,
+	}
+	if %t {
+		middleware = append(middleware, corsMiddleware(%s, %s, %s, %t, %d))
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
+
+// recoverMiddleware turns a panicking handler into a 500 response
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("panic serving %%s %%s: %%v", r.Method, r.URL.Path, err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDMiddleware propagates the X-Request-ID header, generating an ID
+// when the client sent none
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err == nil {
+				id = hex.EncodeToString(buf)
+			}
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestID returns the ID assigned to the request by requestIDMiddleware
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// loggerMiddleware logs every request with its status and duration
+func loggerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%%s %%s %%d %%s [%%s]", r.Method, r.URL.Path, recorder.status, time.Since(start), RequestID(r.Context()))
+	})
+}
+
+// securityHeadersMiddleware adds security headers
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-XSS-Protection", "1; mode=block")
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware answers preflight requests and sets the CORS headers for
+// allowed origins
+func corsMiddleware(origins, methods, headers []string, credentials bool, maxAge int) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && (allowed["*"] || allowed[origin]) {
+				if allowed["*"] && !credentials {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Add("Vary", "Origin")
+				}
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+				if credentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthMiddleware creates JWT authentication middleware. Tokens must be
+// signed with HS256 using secret; their claims are stored in the request
+// context.
+func AuthMiddleware(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authorization header required"})
+				return
+			}
+
+			claims, err := verifyJWT(strings.TrimPrefix(authHeader, "Bearer "), secret)
+			if err != nil {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
+		})
+	}
+}
+
+// Claims returns the JWT claims of an authenticated request
+func Claims(ctx context.Context) map[string]interface{} {
+	claims, _ := ctx.Value(claimsKey).(map[string]interface{})
+	return claims
+}
+
+// verifyJWT checks the signature and the time claims of an HS256 token
+func verifyJWT(token, secret string) (map[string]interface{}, error) {
+	if secret == "" {
+		return nil, errors.New("no signing secret configured")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `+"`json:\"alg\"`"+`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "HS256" {
+		return nil, errors.New("unexpected signing method " + header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+`,
+		cors.Enabled,
+		formatStringSlice(cors.AllowOrigins),
+		formatStringSlice(cors.AllowMethods),
+		formatStringSlice(cors.AllowHeaders),
+		cors.AllowCredentials,
+		cors.MaxAge,
+	), nil
+}
+
+func (g *StdlibGenerator) GenerateHandlers(routes []APIRoute, config *FrameworkConfig) (string, error) {
+	routes = stdlibRoutes(routes)
+	if config.Wiring != nil && config.Wiring.Enabled || databaseEnabled(config) {
+		return generateWiredHandlers(routes, stdlibDialect{}, config)
+	}
+
+	var handlers strings.Builder
+	usesTime := false
+	names := handlerNames(routes)
+	for i, route := range routes {
+		handlerName := names[i]
+		path := templatePath(route.Path)
+		handlers.WriteString(fmt.Sprintf("// %s handles %s %s\n", handlerName, strings.ToUpper(route.Method), path))
+		handlers.WriteString(fmt.Sprintf("func (s *Server) %s(w http.ResponseWriter, r *http.Request) {\n", handlerName))
+
+		// The stub is a user region, kept when business logic replaced it
+		var handler strings.Builder
+		handler.WriteString(fmt.Sprintf("	// TODO: Implement business logic for %s\n", route.Function))
+
+		status := route.SuccessResponse().Status
+		if status == http.StatusNoContent {
+			handler.WriteString(fmt.Sprintf("	w.WriteHeader(%s)\n", statusExpr("http", status)))
+			handlers.WriteString(userRegion(handlerName, handler.String(), 1) + "}\n\n")
+			continue
+		}
+
+		handler.WriteString(fmt.Sprintf("	writeJSON(w, %s, map[string]interface{}{\n", statusExpr("http", status)))
+		handler.WriteString(fmt.Sprintf("		\"message\":        \"%s endpoint\",\n", route.Function))
+		handler.WriteString(fmt.Sprintf("		\"method\":         \"%s\",\n", strings.ToUpper(route.Method)))
+		handler.WriteString(fmt.Sprintf("		\"path\":           \"%s\",\n", path))
+		if wildcards := pathWildcards(path); len(wildcards) > 0 {
+			handler.WriteString("		\"params\": map[string]string{\n")
+			for _, name := range wildcards {
+				handler.WriteString(fmt.Sprintf("			%q: r.PathValue(%q),\n", name, name))
+			}
+			handler.WriteString("		},\n")
+		}
+		handler.WriteString("		\"timestamp\":      time.Now().UTC(),\n")
+		usesTime = true
+		handler.WriteString("		\"auto_generated\": true,\n")
+		handler.WriteString("	})\n")
+		handlers.WriteString(userRegion(handlerName, handler.String(), 1) + "}\n\n")
+	}
+
+	handlers.WriteString(stdlibDialect{}.Helpers() + "\n")
+	handlers.WriteString(userDeclarations())
+
+	imports := map[string]bool{"encoding/json": true, "net/http": true}
+	if usesTime {
+		imports["time"] = true
+	}
+	var out strings.Builder
+	out.WriteString("package main\n\n")
+	out.WriteString(renderImportBlock(imports, nil))
+	out.WriteString(userImports())
+	out.WriteString(handlers.String())
+	return out.String(), nil
+}
+
+func (g *StdlibGenerator) GenerateRoutes(routes []APIRoute, config *FrameworkConfig) (string, error) {
+	var routesBuilder strings.Builder
+
+	routesBuilder.WriteString("package main\n\n")
+	routesBuilder.WriteString("import (\n")
+	routesBuilder.WriteString("	\"net/http\"\n")
+	routesBuilder.WriteString("	\"time\"\n")
+	routesBuilder.WriteString(")\n\n")
+
+	routesBuilder.WriteString("// setupRoutes registers all API routes with method and wildcard patterns\n")
+	routesBuilder.WriteString("func (s *Server) setupRoutes() {\n")
+	routesBuilder.WriteString("	// Health check\n")
+	routesBuilder.WriteString("	s.mux.HandleFunc(\"GET /health\", s.healthCheckHandler)\n\n")
+	if docsEnabled(config) {
+		routesBuilder.WriteString(setupDocsCall)
+	}
+	if graphqlEnabled(config) {
+		routesBuilder.WriteString(setupGraphQLCall)
+	}
+	if databaseEnabled(config) {
+		routesBuilder.WriteString(setupDatabaseCall)
+	}
+
+	authEnabled := config.Auth != nil && config.Auth.Required
+	servable := stdlibRoutes(routes)
+	names := handlerNames(servable)
+	for i, route := range servable {
+		handlerName := names[i]
+		pattern := strings.ToUpper(route.Method) + " " + templatePath(route.Path)
+		if authEnabled && route.Auth.Required {
+			routesBuilder.WriteString(fmt.Sprintf("	s.mux.Handle(%q, AuthMiddleware(s.config.JWTSecret)(http.HandlerFunc(s.%s)))\n", pattern, handlerName))
+		} else {
+			routesBuilder.WriteString(fmt.Sprintf("	s.mux.HandleFunc(%q, s.%s)\n", pattern, handlerName))
+		}
+	}
+	routesBuilder.WriteString("}\n\n")
+
+	routesBuilder.WriteString("// healthCheckHandler returns the health status of the server\n")
+	routesBuilder.WriteString("func (s *Server) healthCheckHandler(w http.ResponseWriter, r *http.Request) {\n")
+	routesBuilder.WriteString("	writeJSON(w, http.StatusOK, map[string]interface{}{\n")
+	routesBuilder.WriteString("		\"status\":    \"healthy\",\n")
+	routesBuilder.WriteString("		\"timestamp\": time.Now().UTC(),\n")
+	routesBuilder.WriteString("		\"version\":   \"1.0.0\",\n")
+	routesBuilder.WriteString("		\"framework\": \"net/http\",\n")
+	routesBuilder.WriteString("	})\n")
+	routesBuilder.WriteString("}\n")
+
+	return routesBuilder.String(), nil
+}
+
+// stdlibModelNames are declared by the generated server and cannot be
+// used for models
+var stdlibModelNames = map[string]bool{
+	"Config": true, "Server": true, "Services": true, "NewServer": true, "NewServices": true,
+	"AuthMiddleware": true, "RequestID": true, "Claims": true,
+	"Repositories": true, "NewRepositories": true, "OpenDatabase": true, "ErrNotFound": true,
+	"Migration": true, "MigrateDatabase": true, "ErrConflict": true,
+}
+
+func (g *StdlibGenerator) GenerateModels(structs []StructInfo, config *FrameworkConfig) (string, error) {
+	var body strings.Builder
+	usesTime := false
+	for _, model := range modelStructs(structs) {
+		body.WriteString(fmt.Sprintf("// %s represents the %s entity\n", model.Name, strings.ToLower(model.Name)))
+		body.WriteString(fmt.Sprintf("type %s struct {\n", model.Name))
+		for _, field := range model.Fields {
+			usesTime = usesTime || strings.Contains(field.Type, "time.")
+			if field.Embedded {
+				body.WriteString(fmt.Sprintf("	%s\n", field.Type))
+				continue
+			}
+			body.WriteString(fmt.Sprintf("	%s %s `json:\"%s\"`\n", field.Name, field.Type, field.JSON))
+		}
+		body.WriteString("}\n\n")
+	}
+
+	var out strings.Builder
+	out.WriteString("package main\n\n")
+	if usesTime {
+		out.WriteString("import \"time\"\n\n")
+	}
+	out.WriteString(body.String())
+	return out.String(), nil
+}
+
+func (g *StdlibGenerator) GenerateTests(routes []APIRoute, config *FrameworkConfig) (string, error) {
+	routes = stdlibRoutes(routes)
+	wired := config.Wiring != nil && config.Wiring.Enabled || databaseEnabled(config)
+	authEnabled := config.Auth != nil && config.Auth.Required
+
+	var tests strings.Builder
+	tests.WriteString("package main\n\n")
+	tests.WriteString("import (\n")
+	tests.WriteString(`	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSecret = "test-secret"
+
+func setupTestServer() http.Handler {
+	return NewServer(&Config{JWTSecret: testSecret}).Handler()
+}
+
+// testToken signs an HS256 token for testSecret
+func testToken() string {
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := encode([]byte(` + "`" + `{"alg":"HS256","typ":"JWT"}` + "`" + `)) + "." + encode([]byte(` + "`" + `{"user_id":"test"}` + "`" + `))
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + encode(mac.Sum(nil))
+}
+
+func TestHealthCheck(t *testing.T) {
+	handler := setupTestServer()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response["status"] != "healthy" {
+		t.Errorf("expected healthy status, got %v", response["status"])
+	}
+	if rec.Header().Get("X-Request-ID") == "" {
+		t.Error("expected an X-Request-ID header")
+	}
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("expected security headers")
+	}
+}
+
+func TestAuthRejectsInvalidToken(t *testing.T) {
+	if _, err := verifyJWT(testToken(), testSecret); err != nil {
+		t.Fatalf("expected the test token to verify: %v", err)
+	}
+	if _, err := verifyJWT(testToken(), "other-secret"); err == nil {
+		t.Error("expected a token signed with another secret to be rejected")
+	}
+	token := testToken()
+	if _, err := verifyJWT(token[:strings.LastIndex(token, ".")+1]+"tampered", testSecret); err == nil {
+		t.Error("expected a tampered token to be rejected")
+	}
+}
+
+`)
+
+	if docsEnabled(config) {
+		tests.WriteString(fmt.Sprintf(`func TestDocsExplorer(t *testing.T) {
+	handler := setupTestServer()
+	for path, contentType := range map[string]string{%q: "text/html", %q: "application/json"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %%s: expected status %%d, got %%d", path, http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, contentType) {
+			t.Errorf("GET %%s: expected %%s, got %%s", path, contentType, got)
+		}
+	}
+}
+
+`, docsPath(config)+"/", docsPath(config)+"/openapi.json"))
+	}
+
+	names := handlerNames(routes)
+	for i, route := range routes {
+		path := templatePath(route.Path)
+		requestPath := wildcardPattern.ReplaceAllString(path, "123")
+		method := strings.ToUpper(route.Method)
+		status := route.SuccessResponse().Status
+		authRequired := authEnabled && route.Auth.Required
+
+		memory := servedByRepository(route, config) && memoryStore(config)
+		body := "{}"
+		if memory && route.Repository.Operation == "bulk_create" {
+			body = "[{}]"
+		}
+
+		tests.WriteString(fmt.Sprintf("func Test%s(t *testing.T) {\n", strings.TrimSuffix(names[i], "Handler")))
+		tests.WriteString("	handler := setupTestServer()\n")
+		switch method {
+		case "POST", "PUT", "PATCH":
+			tests.WriteString(fmt.Sprintf("	req := httptest.NewRequest(%q, %q, strings.NewReader(%q))\n", method, requestPath, body))
+			tests.WriteString("	req.Header.Set(\"Content-Type\", \"application/json\")\n")
+		default:
+			tests.WriteString(fmt.Sprintf("	req := httptest.NewRequest(%q, %q, nil)\n", method, requestPath))
+		}
+
+		if authRequired {
+			tests.WriteString("	rec := httptest.NewRecorder()\n")
+			tests.WriteString("	handler.ServeHTTP(rec, req)\n")
+			tests.WriteString("	if rec.Code != http.StatusUnauthorized {\n")
+			tests.WriteString("		t.Fatalf(\"expected status %d without a token, got %d\", http.StatusUnauthorized, rec.Code)\n")
+			tests.WriteString("	}\n")
+			tests.WriteString("	req.Header.Set(\"Authorization\", \"Bearer \"+testToken())\n")
+			tests.WriteString("	rec = httptest.NewRecorder()\n")
+		} else {
+			tests.WriteString("	rec := httptest.NewRecorder()\n")
+		}
+		tests.WriteString("	handler.ServeHTTP(rec, req)\n")
+
+		if memory {
+			// The in-memory store starts empty: keyed routes miss, the others succeed
+			expected, value := statusExpr("http", status), "map[string]interface{}"
+			switch route.Repository.Operation {
+			case "get", "update", "delete":
+				expected = "http.StatusNotFound"
+			case "list", "search", "bulk_create":
+				value = "[]map[string]interface{}"
+			}
+			tests.WriteString(fmt.Sprintf("	if rec.Code != %s {\n", expected))
+			tests.WriteString(fmt.Sprintf("		t.Fatalf(\"expected status %%d, got %%d: %%s\", %s, rec.Code, rec.Body.String())\n", expected))
+			tests.WriteString("	}\n")
+			tests.WriteString(fmt.Sprintf("	var response %s\n", value))
+			tests.WriteString("	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {\n")
+			tests.WriteString("		t.Fatal(err)\n")
+			tests.WriteString("	}\n")
+			tests.WriteString("}\n\n")
+			continue
+		}
+		if servedByRepository(route, config) {
+			// Repositories answer 404 in JSON for missing rows, the router in plain text
+			tests.WriteString("	if rec.Code == http.StatusMethodNotAllowed || rec.Code == http.StatusNotFound && !strings.HasPrefix(rec.Header().Get(\"Content-Type\"), \"application/json\") {\n")
+			tests.WriteString(fmt.Sprintf("		t.Fatalf(\"%s %s is not routed: %%d\", rec.Code)\n", method, path))
+			tests.WriteString("	}\n")
+			tests.WriteString("}\n\n")
+			continue
+		}
+		if wired {
+			// Wired handlers answer with whatever the service returns
+			tests.WriteString("	if rec.Code == http.StatusNotFound || rec.Code == http.StatusMethodNotAllowed {\n")
+			tests.WriteString(fmt.Sprintf("		t.Fatalf(\"%s %s is not routed: %%d\", rec.Code)\n", method, path))
+			tests.WriteString("	}\n")
+			tests.WriteString("}\n\n")
+			continue
+		}
+
+		tests.WriteString(fmt.Sprintf("	if rec.Code != %s {\n", statusExpr("http", status)))
+		tests.WriteString(fmt.Sprintf("		t.Fatalf(\"expected status %%d, got %%d\", %s, rec.Code)\n", statusExpr("http", status)))
+		tests.WriteString("	}\n")
+		if status == http.StatusNoContent {
+			tests.WriteString("	if rec.Body.Len() != 0 {\n")
+			tests.WriteString("		t.Errorf(\"expected an empty body, got %q\", rec.Body.String())\n")
+			tests.WriteString("	}\n")
+			tests.WriteString("}\n\n")
+			continue
+		}
+		tests.WriteString("	var response map[string]interface{}\n")
+		tests.WriteString("	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {\n")
+		tests.WriteString("		t.Fatal(err)\n")
+		tests.WriteString("	}\n")
+		tests.WriteString("	if response[\"auto_generated\"] != true {\n")
+		tests.WriteString("		t.Errorf(\"expected an auto-generated response, got %v\", response)\n")
+		tests.WriteString("	}\n")
+		tests.WriteString("}\n\n")
+	}
+
+	return tests.String(), nil
+}
+
+func (g *StdlibGenerator) GenerateDocs(routes []APIRoute, config *FrameworkConfig) (string, error) {
+	return (&GinGenerator{}).GenerateDocs(stdlibRoutes(routes), config)
+}
+
+func (g *StdlibGenerator) GenerateDockerfile(config *FrameworkConfig) (string, error) {
+	return `# Build stage
+FROM golang:1.22-alpine AS builder
+
+WORKDIR /app
+COPY . .
+RUN CGO_ENABLED=0 GOOS=linux go build -o main .
+
+# Runtime stage
+FROM alpine:latest
+
+RUN apk --no-cache add ca-certificates tzdata
+WORKDIR /root/
+
+COPY --from=builder /app/main .
+
+EXPOSE 8080
+
+CMD ["./main"]
+`, nil
+}
+
+func (g *StdlibGenerator) GenerateK8sManifests(config *FrameworkConfig) (map[string]string, error) {
+	return (&GinGenerator{}).GenerateK8sManifests(config)
+}
+
+// wildcardPattern matches a ServeMux wildcard such as {id} or {path...}
+var wildcardPattern = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*(\.\.\.)?\}`)
+
+// templatePath converts a route path to a path template as used by
+// ServeMux and OpenAPI: ":id" segments become "{id}" wildcards and a
+// trailing slash is dropped, since ServeMux would otherwise treat the path
+// as a subtree
+func templatePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// pathWildcards returns the wildcard names of a ServeMux path in order
+func pathWildcards(path string) []string {
+	var names []string
+	for _, wildcard := range wildcardPattern.FindAllString(path, -1) {
+		names = append(names, strings.TrimSuffix(strings.Trim(wildcard, "{}"), "..."))
+	}
+	return names
+}
+
+// stdlibRoutes returns the routes a ServeMux can register: routes without
+// a method or function are left out, as are repeats of a method and path,
+// which ServeMux would reject. The generators are handed the routes of a
+// route table, which reports both as unrouted and conflicting routes.
+func stdlibRoutes(routes []APIRoute) []APIRoute {
+	var servable []APIRoute
+	patterns := make(map[string]bool)
+	for _, route := range routes {
+		if route.Method == "" || route.Function == "" {
+			continue
+		}
+		key := routeKey(route.Method, route.Path)
+		if patterns[key] {
+			continue
+		}
+		patterns[key] = true
+		servable = append(servable, route)
+	}
+	return servable
+}
+
+// modelFieldIdent matches the identifiers in a field type
+var modelFieldIdent = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?`)
+
+// modelTypeNames are the identifiers a model field type may use besides
+// the names of other models
+var modelTypeNames = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true, "error": true, "any": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+	"map": true, "interface": true, "time.Time": true, "time.Duration": true,
+}
+
+// modelFieldType returns the type of a model field, falling back to
+// interface{} for types the generated package cannot refer to
+func modelFieldType(fieldType string, models map[string]bool) string {
+	for _, ident := range modelFieldIdent.FindAllString(fieldType, -1) {
+		if !modelTypeNames[ident] && !models[ident] {
+			return "interface{}"
+		}
+	}
+	return fieldType
+}
+
+// fieldJSONName returns the name in a field's json tag, or its snake case
+// name when it has none
+func fieldJSONName(field FieldInfo) string {
+	for _, tag := range field.Tags {
+		if tag.Key != "json" {
+			continue
+		}
+		if name := strings.Split(tag.Value, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return strings.Join(NewInflector(nil).Words(field.Name), "_")
+}
+
+// isExportedName reports whether name starts with an upper-case letter
+func isExportedName(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1] && strings.ToLower(name[:1]) != name[:1]
+}
+
+type stdlibDialect struct{}
+
+func (stdlibDialect) Signature(name string) string {
+	return fmt.Sprintf("func (s *Server) %s(w http.ResponseWriter, r *http.Request) {", name)
+}
+func (stdlibDialect) RequestContext() string       { return "r.Context()" }
+func (stdlibDialect) PathParam(name string) string { return fmt.Sprintf("r.PathValue(%q)", name) }
+func (stdlibDialect) QueryParam(name string) string {
+	return fmt.Sprintf("r.URL.Query().Get(%q)", name)
+}
+func (stdlibDialect) DecodeBody(target string) string {
+	return fmt.Sprintf("json.NewDecoder(r.Body).Decode(%s)", target)
+}
+func (stdlibDialect) Respond(status, value string) string {
+	return fmt.Sprintf("writeJSON(w, %s, %s)\nreturn", status, value)
+}
+func (stdlibDialect) RespondEmpty(status string) string {
+	return fmt.Sprintf("w.WriteHeader(%s)\nreturn", status)
+}
+func (stdlibDialect) Imports(uses DialectUses) []string {
+	return []string{"encoding/json"}
+}
+func (stdlibDialect) Helpers() string {
+	return chiDialect{}.Helpers()
+}
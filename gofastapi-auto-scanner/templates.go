@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// The Gin, Echo, Chi and Fiber servers are rendered from the text/template
+// files under templates/. An output file is rendered from the template of
+// its framework, such as gin/handlers.go.tmpl, or from the shared one in
+// common/ when the framework has none. FrameworkConfig.Templates names a
+// project directory laid out the same way: each of its templates shadows
+// the built-in template of the same name, and a framework template there
+// shadows the shared one.
+//
+// Templates execute with a *TemplateData. Besides the text/template
+// builtins they can call
+//
+//	include name data      the output of another template, as a string
+//	region name depth body body wrapped in user region markers
+//	userImports            the import declaration of the imports region
+//	userDeclarations       the top-level declarations region
+//	status pkg code        the Go expression of a status code
+//	statusText code        the reason phrase of a status code
+//	goStrings list         a Go []string literal of list
+//	join, replace, quote, title, upper, lower
+//
+// Go output must parse and is formatted like gofmt. The imports of the
+// generated server are fixed afterwards, so a template may leave out the
+// ones that depend on its data.
+//
+//go:embed templates
+var builtinTemplates embed.FS
+
+// TemplateData is the data every framework template executes with
+type TemplateData struct {
+	Framework FrameworkType    // framework rendered, e.g. "gin"
+	Title     string           // title-cased config type, e.g. "Gin"
+	BasePath  string           // path the API routes are mounted under
+	Routes    []TemplateRoute  // routes in registration order
+	Structs   []TemplateStruct // models declared by models.go, by name
+	Config    *FrameworkConfig // generator configuration
+	CORS      CORSConfig       // Config.CORS, zero when unset
+	Auth      bool             // whether routes requiring auth are protected
+	Docs      bool             // whether the API explorer is mounted
+	DocsPath  string           // path the API explorer is mounted at
+	GraphQL   bool             // whether the GraphQL endpoint is mounted
+	Database  bool             // whether repositories store the models
+	Wired     bool             // whether handlers call services or repositories
+}
+
+// TemplateRoute is a scanned route with the names and paths the templates
+// need
+type TemplateRoute struct {
+	APIRoute
+	Verb      string       // upper-case method, e.g. "GET"
+	ColonPath string       // path with :name wildcards
+	Pattern   string       // path with {name} wildcards
+	Handler   string       // handler method, e.g. "GetUserHandler"
+	Status    int          // status of the success response
+	Params    []string     // parameters a handler stub extracts
+	Secured   bool         // whether the route is behind the auth middleware
+	DocParams []DocParam   // scanned, then annotated-only parameters
+	Test      TemplateTest // request and checks of the generated test
+}
+
+// AnySecured reports whether any route is behind the auth middleware
+func (d *TemplateData) AnySecured() bool {
+	for _, route := range d.Routes {
+		if route.Secured {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateTest is the generated test of a route. Check selects the
+// assertions: "memory" decodes a response of type Response from the
+// in-memory store, "repository" and "wired" only check that the route is
+// served, "empty" expects Status without a body and "json" expects Status
+// with the stub response.
+type TemplateTest struct {
+	Name     string // test function, e.g. "TestGetUser"
+	Path     string // route path under BasePath
+	Target   string // request path with its wildcards filled in
+	Body     string // JSON request body, empty for GET and DELETE
+	Check    string // "memory", "repository", "wired", "empty" or "json"
+	Status   int    // expected status
+	Response string // Go type of the decoded response
+}
+
+// TemplateStruct is a model declared by the generated models.go
+type TemplateStruct struct {
+	Name   string
+	Fields []TemplateField
+}
+
+// TemplateField is a field of a model. An embedded field has only a Type.
+type TemplateField struct {
+	Name     string
+	Type     string
+	JSON     string
+	Embedded bool
+}
+
+// frameworkBasePaths are the paths frameworks mount the API routes under
+var frameworkBasePaths = map[FrameworkType]string{
+	FrameworkGin: "/api/v1",
+}
+
+// newTemplateData collects the data the templates of framework render
+func newTemplateData(framework FrameworkType, routes []APIRoute, structs []StructInfo, config *FrameworkConfig) *TemplateData {
+	data := &TemplateData{
+		Framework: framework,
+		Title:     strings.Title(string(config.Type)),
+		BasePath:  frameworkBasePaths[framework],
+		Structs:   modelStructs(structs),
+		Config:    config,
+		Auth:      config.Auth != nil && config.Auth.Required,
+		Docs:      docsEnabled(config),
+		GraphQL:   graphqlEnabled(config),
+		Database:  databaseEnabled(config),
+		Wired:     config.Wiring != nil && config.Wiring.Enabled || databaseEnabled(config),
+	}
+	if config.CORS != nil {
+		data.CORS = *config.CORS
+	}
+	if data.Docs {
+		data.DocsPath = docsPath(config)
+	}
+	handlers := handlerNames(routes)
+	for i, route := range routes {
+		data.Routes = append(data.Routes, data.templateRoute(route, handlers[i]))
+	}
+	return data
+}
+
+// templateRoute derives the template view of route, served by handler
+func (d *TemplateData) templateRoute(route APIRoute, handler string) TemplateRoute {
+	r := TemplateRoute{
+		APIRoute:  route,
+		Verb:      strings.ToUpper(route.Method),
+		ColonPath: colonPath(route.Path),
+		Pattern:   templatePath(route.Path),
+		Handler:   handler,
+		Status:    route.SuccessResponse().Status,
+		Params:    stubParams(route),
+		Secured:   d.Auth && route.Auth.Required,
+	}
+
+	// Annotations describe scanned parameters and may add others
+	described := make(map[string]DocParam)
+	for _, param := range route.Docs.Params {
+		described[param.Name] = param
+	}
+	for _, param := range route.Parameter {
+		r.DocParams = append(r.DocParams, DocParam{Name: param.Name, Type: param.Type, Description: described[param.Name].Description})
+		delete(described, param.Name)
+	}
+	for _, param := range route.Docs.Params {
+		if _, ok := described[param.Name]; ok {
+			r.DocParams = append(r.DocParams, param)
+		}
+	}
+
+	memory := servedByRepository(route, d.Config) && memoryStore(d.Config)
+	test := TemplateTest{
+		Name:     "Test" + strings.TrimSuffix(handler, "Handler"),
+		Path:     d.BasePath + r.Pattern,
+		Status:   r.Status,
+		Response: "map[string]interface{}",
+	}
+	test.Target = wildcardPattern.ReplaceAllString(test.Path, "123")
+	switch r.Verb {
+	case "POST", "PUT", "PATCH":
+		test.Body = "{}"
+		if memory && route.Repository.Operation == "bulk_create" {
+			test.Body = "[{}]"
+		}
+	}
+	switch {
+	case memory:
+		// The in-memory store starts empty: keyed routes miss, the others succeed
+		test.Check = "memory"
+		switch route.Repository.Operation {
+		case "get", "update", "delete":
+			test.Status = http.StatusNotFound
+		case "list", "search", "bulk_create":
+			test.Response = "[]map[string]interface{}"
+		}
+	case servedByRepository(route, d.Config):
+		test.Check = "repository"
+	case d.Wired:
+		test.Check = "wired"
+	case r.Status == http.StatusNoContent:
+		test.Check = "empty"
+	default:
+		test.Check = "json"
+	}
+	r.Test = test
+	return r
+}
+
+// modelStructs returns the models declared for structs: one per name,
+// sorted by name, without the names the generated server declares and
+// with the field types it can refer to
+func modelStructs(structs []StructInfo) []TemplateStruct {
+	declared := make(map[string]bool)
+	var models []StructInfo
+	for _, structInfo := range structs {
+		if declared[structInfo.Name] || stdlibModelNames[structInfo.Name] {
+			continue
+		}
+		declared[structInfo.Name] = true
+		models = append(models, structInfo)
+	}
+	sort.SliceStable(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+
+	result := make([]TemplateStruct, 0, len(models))
+	for _, structInfo := range models {
+		model := TemplateStruct{Name: structInfo.Name}
+		for _, field := range structInfo.Fields {
+			if field.Embedded {
+				// Embedding an undeclared type would not compile
+				if fieldType := modelFieldType(field.Type, declared); fieldType != "interface{}" {
+					model.Fields = append(model.Fields, TemplateField{Type: fieldType, Embedded: true})
+				}
+				continue
+			}
+			if field.Name == "" || !isExportedName(field.Name) {
+				continue
+			}
+			model.Fields = append(model.Fields, TemplateField{
+				Name: field.Name,
+				Type: modelFieldType(field.Type, declared),
+				JSON: fieldJSONName(field),
+			})
+		}
+		result = append(result, model)
+	}
+	return result
+}
+
+// loadTemplates parses the built-in templates, then the templates in dir
+// that shadow them. Templates are named by their path, such as
+// "gin/main.go.tmpl".
+func loadTemplates(dir string) (*template.Template, error) {
+	templates := template.New("")
+	templates.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			var buf bytes.Buffer
+			if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"region":           func(name string, depth int, body string) string { return userRegion(name, body, depth) },
+		"userImports":      userImports,
+		"userDeclarations": userDeclarations,
+		"status":           statusExpr,
+		"statusText":       http.StatusText,
+		"goStrings":        formatStringSlice,
+		"join":             strings.Join,
+		"quote":            func(s string) string { return fmt.Sprintf("%q", s) },
+		"replace":          strings.ReplaceAll,
+		"title":            strings.Title,
+		"upper":            strings.ToUpper,
+		"lower":            strings.ToLower,
+	})
+
+	parse := func(fsys fs.FS) error {
+		return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !strings.HasSuffix(name, ".tmpl") {
+				return nil
+			}
+			text, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			if _, err := templates.New(name).Parse(string(text)); err != nil {
+				return fmt.Errorf("failed to parse template: %v", err)
+			}
+			return nil
+		})
+	}
+
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := parse(builtin); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := parse(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("failed to load templates from %s: %v", dir, err)
+		}
+	}
+	return templates, nil
+}
+
+// renderTemplate renders the output file for data.Framework, from its
+// framework template or else from the shared one
+func renderTemplate(file string, data *TemplateData) (string, error) {
+	templates, err := loadTemplates(data.Config.Templates)
+	if err != nil {
+		return "", err
+	}
+	name := path.Join(string(data.Framework), file+".tmpl")
+	if templates.Lookup(name) == nil {
+		name = path.Join("common", file+".tmpl")
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %v", name, err)
+	}
+	if !strings.HasSuffix(file, ".go") {
+		return buf.String(), nil
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("template %s does not render valid Go: %v", name, err)
+	}
+	return string(formatted), nil
+}
+
+// k8sManifests are the Kubernetes manifests rendered from k8s/ templates
+var k8sManifests = []string{"deployment.yaml", "service.yaml", "ingress.yaml"}
+
+// renderManifests renders the Kubernetes manifests, by file name
+func renderManifests(data *TemplateData) (map[string]string, error) {
+	manifests := make(map[string]string)
+	for _, name := range k8sManifests {
+		content, err := renderTemplate(path.Join("k8s", name), data)
+		if err != nil {
+			return nil, err
+		}
+		manifests[name] = content
+	}
+	return manifests, nil
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -220,6 +224,312 @@ func (ps *ProductService) DeactivateProduct(id string) error {
 	}
 }
 
+// TestQualifiedTypes tests that parameter, return and field types are resolved to package-qualified types
+func (suite *TestSuite) TestQualifiedTypes() {
+	src := `package models
+
+import (
+	"context"
+	"time"
+)
+
+type User struct {
+	ID        string
+	Tags      []string
+	Manager   *User
+	CreatedAt time.Time
+}
+
+type UserService struct{}
+
+func (us *UserService) GetUser(ctx context.Context, id string) (*User, error) {
+	return &User{ID: id}, nil
+}
+`
+	dir := filepath.Join(suite.tempDir, "qualified")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	var importPath string
+	fields := make(map[string]string)
+	var method *MethodInfo
+	for _, pkg := range generator.pkgs {
+		importPath = pkg.ImportPath
+		for _, structInfo := range pkg.Structs {
+			for _, field := range structInfo.Fields {
+				fields[structInfo.Name+"."+field.Name] = field.QualifiedType
+			}
+			for i := range structInfo.Methods {
+				if structInfo.Methods[i].Name == "GetUser" {
+					method = &structInfo.Methods[i]
+				}
+			}
+		}
+	}
+	require.NotEmpty(suite.T(), importPath, "The package should be scanned")
+
+	assert.Equal(suite.T(), "string", fields["User.ID"])
+	assert.Equal(suite.T(), "[]string", fields["User.Tags"])
+	assert.Equal(suite.T(), "*"+importPath+".User", fields["User.Manager"])
+	assert.Equal(suite.T(), "time.Time", fields["User.CreatedAt"])
+
+	require.NotNil(suite.T(), method, "GetUser should be scanned")
+	require.Len(suite.T(), method.Parameters, 2)
+	assert.Equal(suite.T(), "context.Context", method.Parameters[0].QualifiedType)
+	assert.Equal(suite.T(), "string", method.Parameters[1].QualifiedType)
+	require.Len(suite.T(), method.Returns, 2)
+	assert.Equal(suite.T(), "*User", method.Returns[0].Type)
+	assert.Equal(suite.T(), "*"+importPath+".User", method.Returns[0].QualifiedType)
+	assert.Equal(suite.T(), "error", method.Returns[1].QualifiedType)
+}
+
+// TestModuleImportPaths tests that import paths are resolved from go.mod and go.work
+func (suite *TestSuite) TestModuleImportPaths() {
+	root := filepath.Join(suite.tempDir, "workspace")
+	files := map[string]string{
+		"go.work":                   "go 1.21\n\nuse (\n\t./app\n\t./lib // shared code\n)\n",
+		"app/go.mod":                "module example.com/app\n\ngo 1.21\n",
+		"app/services/user.go":      "package services\n\ntype UserService struct{}\n",
+		"lib/go.mod":                "module \"example.com/lib\"\n",
+		"lib/store/store.go":        "package store\n\ntype Store struct{}\n",
+		"standalone/go.mod":         "module example.com/standalone\n",
+		"standalone/api/handler.go": "package api\n\ntype Handler struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+
+	for importPath, module := range map[string]string{
+		"example.com/app/services":   "example.com/app",
+		"example.com/lib/store":      "example.com/lib",
+		"example.com/standalone/api": "example.com/standalone",
+	} {
+		pkg, exists := generator.pkgs[importPath]
+		if assert.True(suite.T(), exists, "Package %s should be keyed by import path", importPath) {
+			assert.Equal(suite.T(), importPath, pkg.ImportPath)
+			assert.Equal(suite.T(), module, pkg.Module)
+		}
+	}
+}
+
+// TestAnnotationParsing tests the annotation grammar and its flat compatibility view
+func (suite *TestSuite) TestAnnotationParsing() {
+	node, err := ParseAnnotation(`@api.doc.param("id", "path", "string", required=true)`)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "doc.param", node.Key())
+	assert.True(suite.T(), node.Call)
+	assert.Len(suite.T(), node.Positional(), 3)
+	required, ok := node.Named("required")
+	if assert.True(suite.T(), ok) {
+		assert.Equal(suite.T(), BoolValue, required.Kind)
+		assert.True(suite.T(), required.Bool)
+	}
+
+	node, err = ParseAnnotation("@api.response(200, []User)")
+	require.NoError(suite.T(), err)
+	args := node.Positional()
+	require.Len(suite.T(), args, 2)
+	assert.Equal(suite.T(), NumberValue, args[0].Kind)
+	assert.Equal(suite.T(), "[]User", args[1].String())
+
+	legacy, err := ParseAnnotation("@api.endpoint GET /users/{id} auth=required")
+	require.NoError(suite.T(), err)
+	compat := legacy.Compat()
+	assert.Equal(suite.T(), "/users/{id}", compat.Value)
+	assert.Equal(suite.T(), "GET", compat.Config["method"])
+	assert.Equal(suite.T(), "required", compat.Config["auth"])
+
+	tags, err := ParseAnnotation("@api.validation.required,max=100")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "validation.required", tags.Key())
+	assert.Equal(suite.T(), "100", tags.Compat().Config["max"])
+
+	_, err = ParseAnnotation(`@api.route("/users"`)
+	var syntaxErr *AnnotationSyntaxError
+	if assert.ErrorAs(suite.T(), err, &syntaxErr) {
+		assert.True(suite.T(), syntaxErr.Incomplete)
+	}
+
+	src := `package models
+
+// UserService manages users
+// @api.route("/users")
+// @api.methods(GET, POST)
+type UserService struct{}
+
+// CreateUser creates a user
+// @api.endpoint("/users")
+// @api.method(POST)
+// @api.auth.required
+// @api.doc.example({
+//   "name": "Jane",
+//   "tags": ["a", "b"]
+// })
+func CreateUser(name string) error { return nil }
+`
+	dir := filepath.Join(suite.tempDir, "annotations")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	var example *Annotation
+	for _, pkg := range generator.pkgs {
+		for _, fn := range pkg.Functions {
+			for i := range fn.Annotations {
+				if fn.Annotations[i].Key == "doc.example" {
+					example = &fn.Annotations[i]
+				}
+			}
+		}
+	}
+	if assert.NotNil(suite.T(), example, "Multi-line annotation should be parsed") {
+		require.Len(suite.T(), example.Node.Args, 1)
+		assert.Equal(suite.T(), JSONValue, example.Node.Args[0].Value.Kind)
+		assert.Equal(suite.T(), "Jane", example.Node.Args[0].Value.JSON.(map[string]interface{})["name"])
+		assert.Equal(suite.T(), 12, generator.fset.Position(example.Node.Pos).Line)
+	}
+
+	for _, route := range generator.collectRoutes() {
+		switch {
+		case route.Struct == "UserService":
+			assert.Equal(suite.T(), []string{"GET", "POST"}, route.Methods)
+		case route.Function == "CreateUser":
+			assert.Equal(suite.T(), "POST", route.Method)
+			assert.True(suite.T(), route.Auth.Required)
+		}
+	}
+}
+
+// TestLintDiagnostics tests that lint reports annotation problems at their source positions
+func (suite *TestSuite) TestLintDiagnostics() {
+	root := filepath.Join(suite.tempDir, "lint")
+	files := map[string]string{
+		"go.mod": "module example.com/lint\n\ngo 1.21\n",
+		"api/api.go": `package api
+
+// GetUser returns a user
+// @api.endpoint("/users/{id}")
+// @api.response(200, User)
+func GetUser(id string) (string, error) { return id, nil }
+
+// @api.endpoint POST /users
+// @api.method(GET)
+// @api.cache(60)
+// @api.response("ok")
+func CreateUser(name string) error { return nil }
+
+// @api.endpoint("/internal")
+func internal() {}
+
+// @api.doc.example({
+//   "name": "Jane",
+//   "tags": ["a" "b"]
+// })
+func Broken() {}
+
+func Count() int { return "many" }
+
+type Count struct{}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+
+	for _, fn := range generator.pkgs["example.com/lint/api"].Functions {
+		for _, annotation := range fn.Annotations {
+			assert.True(suite.T(), annotation.Pos.IsValid(), "Annotation %s should carry its position", annotation.Key)
+		}
+	}
+
+	diagnostics := generator.Lint(true)
+	found := make(map[string]int)
+	for _, d := range diagnostics {
+		assert.Equal(suite.T(), "api.go", filepath.Base(d.Pos.Filename))
+		found[d.Code] = d.Pos.Line
+	}
+	assert.Equal(suite.T(), map[string]int{
+		DiagMethodConflict:    9,
+		DiagUnknownAnnotation: 10,
+		DiagArgumentType:      11,
+		DiagUnbindable:        14,
+		DiagAnnotationSyntax:  19,
+		DiagTypeError:         25,
+	}, found)
+	assert.True(suite.T(), hasErrors(diagnostics))
+	assert.Contains(suite.T(), diagnostics[len(diagnostics)-1].Message, "other declaration of Count at ")
+	assert.Contains(suite.T(), diagnostics[len(diagnostics)-1].Message, "api.go:23:6")
+
+	// Without wiring the generated handlers do not call unbindable endpoints
+	unbindable := 0
+	for _, d := range generator.Lint(false) {
+		if d.Code == DiagUnbindable {
+			assert.Equal(suite.T(), SeverityWarning, d.Severity)
+			unbindable++
+		}
+	}
+	assert.Equal(suite.T(), 1, unbindable)
+
+	var out strings.Builder
+	require.NoError(suite.T(), WriteDiagnostics(&out, diagnostics, true))
+	var decoded []Diagnostic
+	require.NoError(suite.T(), json.Unmarshal([]byte(out.String()), &decoded))
+	assert.Len(suite.T(), decoded, len(diagnostics))
+}
+
+// TestLintProse tests that doc comments mentioning annotations in a sentence are not linted as annotations
+func (suite *TestSuite) TestLintProse() {
+	src := `package prose
+
+// Value is stored with a default when a field is tagged with
+// @api.field.default(value) gives it a default, and the
+// @api.resource annotation on it names its path.
+type Value struct{ ID string }
+
+// GetValue returns a value
+// @api.endpoint("/values/{id}")
+func GetValue(id string) (string, error) { return id, nil }
+`
+	dir := filepath.Join(suite.tempDir, "prose")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "prose.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	for _, d := range generator.Lint(false) {
+		assert.NotContains(suite.T(), []string{DiagAnnotationSyntax, DiagUnknownAnnotation, DiagArgumentCount}, d.Code, d.String())
+	}
+	var keys []string
+	for _, pkg := range generator.pkgs {
+		for _, structInfo := range pkg.Structs {
+			assert.Empty(suite.T(), structInfo.Annotations, "Prose should not be read as annotations")
+		}
+		for _, fn := range pkg.Functions {
+			for _, annotation := range fn.Annotations {
+				keys = append(keys, annotation.Key)
+			}
+		}
+	}
+	assert.Equal(suite.T(), []string{"endpoint"}, keys, "An annotation after a doc sentence should still be read")
+}
+
 // TestSmartMethodMapping tests intelligent method mapping functionality
 func (suite *TestSuite) TestSmartMethodMapping() {
 	testCases := []struct {
@@ -239,61 +549,2494 @@ func (suite *TestSuite) TestSmartMethodMapping() {
 		{"BulkCreateProducts", "ProductService", "bulk_create", "/products/bulk", true},
 		{"ActivateProduct", "ProductService", "activate", "/products/{id}/activate", true},
 		{"DeactivateProduct", "ProductService", "deactivate", "/products/{id}/deactivate", true},
-		{"RandomMethod", "TestService", "custom", "/testservice/randommethod", false},
+		{"GetAllProducts", "ProductService", "list", "/products", true},
+		{"QueryProducts", "ProductService", "search", "/products/search", true},
+		{"PublishPost", "PostService", "publish", "/posts/{id}/publish", true},
+		{"UnpublishPost", "PostService", "unpublish", "/posts/{id}/unpublish", true},
+		{"RandomMethod", "TestService", "custom", "/tests/random-method", false},
+	}
+
+	for _, tc := range testCases {
+		mapping, found := suite.generator.SmartMethodMapping(tc.methodName, tc.structName)
+
+		if tc.shouldMatch {
+			assert.True(suite.T(), found,
+				fmt.Sprintf("Method %s should match pattern", tc.methodName))
+			assert.Equal(suite.T(), tc.expectedOp, mapping.Operation,
+				fmt.Sprintf("Operation mismatch for %s", tc.methodName))
+		} else {
+			assert.False(suite.T(), found,
+				fmt.Sprintf("Method %s should not match any pattern", tc.methodName))
+		}
+		assert.Equal(suite.T(), tc.expectedPath, mapping.Path,
+			fmt.Sprintf("Path mismatch for %s", tc.methodName))
+	}
+}
+
+// TestMappingConfig tests project mapping rules loaded from a file
+func (suite *TestSuite) TestMappingConfig() {
+	file := filepath.Join(suite.tempDir, "gofastapi-mappings.yaml")
+	content := `rules:
+  - patterns: ["Query*"]
+    method: POST
+    path: /{resource}/query
+    operation: search
+  - patterns: ["re:^Export(?P<format>[A-Z][a-z]+)$"]
+    method: GET
+    path: /{resource}/export/{format}
+    operation: export
+  - patterns: ["{Approve,Accept}*"]
+    method: PUT
+    path: /{resource}/{id}/{verb}
+    operation: approve
+    priority: 10
+disable: [count]
+`
+	require.NoError(suite.T(), os.WriteFile(file, []byte(content), 0644))
+	assert.Equal(suite.T(), file, FindMappingFile(suite.tempDir))
+
+	mappings, err := LoadMappingConfig(file)
+	require.NoError(suite.T(), err)
+	generator := NewAPIGenerator(&GeneratorConfig{Mappings: mappings})
+
+	for _, tc := range []struct{ method, httpMethod, path, operation string }{
+		{"QueryOrders", "POST", "/orders/query", "search"},
+		{"ExportCsv", "GET", "/orders/export/csv", "export"},
+		{"AcceptOrder", "PUT", "/orders/{id}/accept", "approve"},
+		{"GetOrderByNumber", "GET", "/orders/by/number", "get_by"},
+	} {
+		mapping, found := generator.SmartMethodMapping(tc.method, "OrderService")
+		assert.True(suite.T(), found, tc.method)
+		assert.True(suite.T(), mapping.AutoGenerate, tc.method)
+		assert.Equal(suite.T(), tc.httpMethod, mapping.Method, tc.method)
+		assert.Equal(suite.T(), tc.path, mapping.Path, tc.method)
+		assert.Equal(suite.T(), tc.operation, mapping.Operation, tc.method)
+	}
+	_, found := generator.SmartMethodMapping("CountOrders", "OrderService")
+	assert.False(suite.T(), found, "Disabled default rules should not match")
+	_, found = generator.SmartMethodMapping("SearchOrders", "OrderService")
+	assert.False(suite.T(), found, "A project rule should replace the default rule of its operation")
+
+	// Replace mode drops the defaults
+	replaced := NewAPIGenerator(&GeneratorConfig{Mappings: &MappingConfig{Mode: MappingReplace, Rules: mappings.Rules}})
+	_, found = replaced.SmartMethodMapping("GetOrder", "OrderService")
+	assert.False(suite.T(), found)
+
+	for _, invalid := range []MappingConfig{
+		{Mode: "append"},
+		{Rules: []MappingRule{{Patterns: []string{"Get*"}, Method: "FETCH", Path: "/x", Operation: "get"}}},
+		{Rules: []MappingRule{{Patterns: []string{"re:("}, Method: "GET", Path: "/x", Operation: "get"}}},
+		{Rules: []MappingRule{{Patterns: []string{"Get["}, Method: "GET", Path: "/x", Operation: "get"}}},
+	} {
+		_, err := NewMethodMapper(&invalid)
+		assert.Error(suite.T(), err)
+	}
+}
+
+// TestInflection tests resource naming: plurals, casing styles and overrides
+func (suite *TestSuite) TestInflection() {
+	inflector := NewInflector(nil)
+	for singular, plural := range map[string]string{
+		"user": "users", "category": "categories", "address": "addresses", "status": "statuses",
+		"box": "boxes", "key": "keys", "person": "people", "child": "children",
+		"analysis": "analyses", "leaf": "leaves", "sheep": "sheep", "people": "people",
+	} {
+		assert.Equal(suite.T(), plural, inflector.Pluralize(singular), "Plural of %s", singular)
+	}
+
+	assert.Equal(suite.T(), []string{"http", "server", "config"}, inflector.Words("HTTPServerConfig"))
+	assert.Equal(suite.T(), []string{"user", "id"}, inflector.Words("UserID"))
+
+	for style, expected := range map[CaseStyle]string{
+		CaseKebab:      "framework-registries",
+		CaseSnake:      "framework_registries",
+		CaseLowerCamel: "frameworkRegistries",
+	} {
+		styled := NewInflector(&NamingConfig{Style: style})
+		assert.Equal(suite.T(), expected, styled.Resource("FrameworkRegistry"))
+	}
+	assert.Equal(suite.T(), "users", inflector.Resource("UserService"))
+	assert.Equal(suite.T(), "order-items", inflector.Resource("OrderItemController"))
+
+	// Words that are already plural keep their form, and only known "-is"
+	// nouns take "-es"
+	for _, plural := range []string{"users", "categories", "boxes", "addresses", "statuses", "analyses"} {
+		assert.Equal(suite.T(), plural, inflector.Pluralize(plural), "Plural of %s", plural)
+	}
+	assert.Equal(suite.T(), "axes", inflector.Pluralize("axis"))
+	assert.Equal(suite.T(), "crises", inflector.Pluralize("crisis"))
+	assert.Equal(suite.T(), "users", inflector.Resource("UsersService"))
+	assert.Equal(suite.T(), "categories", inflector.Resource("Categories"))
+	assert.Equal(suite.T(), "redises", inflector.Resource("RedisService"))
+
+	configured := NewInflector(&NamingConfig{
+		Irregular: map[string]string{"cactus": "cacti"},
+		Resources: map[string]string{"Person": "members"},
+	})
+	assert.Equal(suite.T(), "cacti", configured.Resource("Cactus"))
+	assert.Equal(suite.T(), "members", configured.Resource("Person"))
+
+	// The annotation override applies to smart and CRUD routes alike
+	src := `package shop
+
+// @api.resource("catalogue")
+type CategoryService struct{}
+
+func (s *CategoryService) GetCategory(id string) (string, error) { return id, nil }
+
+type Address struct{}
+`
+	dir := filepath.Join(suite.tempDir, "inflection")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	paths := make(map[string]bool)
+	for _, route := range generator.GenerateAPIRoutes() {
+		paths[route.Path] = true
+	}
+	assert.True(suite.T(), paths["/catalogue/{id}"], "Annotated resource name should be used")
+	assert.True(suite.T(), paths["/addresses"], "CRUD routes should use the plural")
+	assert.False(suite.T(), paths["/addresss"])
+
+	// A model scanned with its service gets CRUD routes once, from the model
+	src = `package tasks
+
+type Task struct{ ID string }
+
+type TaskService struct{}
+
+func (s *TaskService) GetTask(id string) (*Task, error) { return &Task{ID: id}, nil }
+
+func (s *TaskService) ListTasks() ([]Task, error) { return nil, nil }
+`
+	dir = filepath.Join(suite.tempDir, "inflection-service")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "tasks.go"), []byte(src), 0644))
+
+	generator = NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	table := generator.BuildRouteTable()
+	assert.Empty(suite.T(), table.Conflicts, "Model and service CRUD routes should not conflict")
+	served := make(map[string]string)
+	for _, route := range table.Routes {
+		served[routeKey(route.Method, route.Path)] = route.Function
+		assert.NotEqual(suite.T(), "GetTaskService", route.Function)
+	}
+	assert.Equal(suite.T(), "GetTask", served["GET /tasks/{}"])
+	assert.Equal(suite.T(), "ListTasks", served["GET /tasks"])
+	assert.Equal(suite.T(), "CreateTask", served["POST /tasks"], "The model should still get CRUD routes")
+}
+
+// TestRouteGeneration tests API route generation
+func (suite *TestSuite) TestRouteGeneration() {
+	// Set up test data with methods that should generate routes
+	suite.generator.pkgs["test"] = &PackageInfo{
+		Name: "test",
+		Structs: []StructInfo{
+			{
+				Name: "UserService",
+				Methods: []MethodInfo{
+					{Name: "GetUser", Receiver: "*UserService"},
+					{Name: "CreateUser", Receiver: "*UserService"},
+					{Name: "UpdateUser", Receiver: "*UserService"},
+					{Name: "DeleteUser", Receiver: "*UserService"},
+					{Name: "ListUsers", Receiver: "*UserService"},
+				},
+			},
+		},
+	}
+
+	routes := suite.generator.GenerateAPIRoutes()
+
+	assert.Greater(suite.T(), len(routes), 0, "Should generate routes")
+
+	// Verify route structure
+	for _, route := range routes {
+		assert.NotEmpty(suite.T(), route.Method, "Route should have HTTP method")
+		assert.NotEmpty(suite.T(), route.Path, "Route should have path")
+		assert.NotEmpty(suite.T(), route.Function, "Route should have function name")
+		assert.NotEmpty(suite.T(), route.Struct, "Route should have struct name")
+
+		// Verify auto-generated metadata
+		assert.True(suite.T(), route.Metadata["auto_generated"].(bool),
+			"Route should be marked as auto-generated")
+	}
+}
+
+// TestResponseAnnotations tests that @api.request and @api.response shape routes and generated code
+func (suite *TestSuite) TestResponseAnnotations() {
+	src := `package tasks
+
+type Task struct {
+	ID    string
+	Title string
+}
+
+type TaskCreateRequest struct {
+	Title string
+}
+
+type ErrorResponse struct {
+	Message string
+}
+
+// CreateTask creates a task
+// @api.endpoint("/tasks")
+// @api.method(POST)
+// @api.request(TaskCreateRequest)
+// @api.response(201, Task)
+// @api.response(400, ErrorResponse, description="Invalid task")
+func CreateTask(req TaskCreateRequest) (*Task, error) { return nil, nil }
+
+type JobService struct{}
+
+func (s *JobService) CreateJob(req TaskCreateRequest) (*Task, error) { return nil, nil }
+
+func (s *JobService) DeleteJob(id string) error { return nil }
+`
+	dir := filepath.Join(suite.tempDir, "responses")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "tasks.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	var endpoint, smartCreate, smartDelete *APIRoute
+	routes := generator.GenerateAPIRoutes()
+	for i := range routes {
+		switch {
+		case routes[i].Struct == "" && routes[i].Function == "CreateTask":
+			endpoint = &routes[i]
+		case routes[i].Struct == "JobService" && routes[i].Function == "CreateJob":
+			smartCreate = &routes[i]
+		case routes[i].Struct == "JobService" && routes[i].Function == "DeleteJob":
+			smartDelete = &routes[i]
+		}
+	}
+	require.NotNil(suite.T(), endpoint)
+	require.NotNil(suite.T(), smartCreate)
+	require.NotNil(suite.T(), smartDelete)
+
+	assert.Equal(suite.T(), "TaskCreateRequest", endpoint.RequestType)
+	assert.Equal(suite.T(), []ResponseSpec{
+		{Status: 201, Type: "Task"},
+		{Status: 400, Type: "ErrorResponse", Description: "Invalid task"},
+	}, endpoint.Responses)
+
+	assert.Equal(suite.T(), "TaskCreateRequest", smartCreate.RequestType)
+	assert.Equal(suite.T(), 201, smartCreate.SuccessResponse().Status)
+	assert.Equal(suite.T(), []ResponseSpec{{Status: 204}}, smartDelete.Responses)
+
+	gin := &GinGenerator{}
+	config := gin.GetDefaultConfig()
+	selected := []APIRoute{*endpoint, *smartDelete}
+
+	handlers, err := gin.GenerateHandlers(selected, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), handlers, "c.JSON(http.StatusCreated")
+	assert.Contains(suite.T(), handlers, "c.Status(http.StatusNoContent)")
+
+	tests, err := gin.GenerateTests(selected, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), tests, "assert.Equal(t, http.StatusCreated, w.Code)")
+	assert.Contains(suite.T(), tests, "assert.Equal(t, http.StatusNoContent, w.Code)")
+
+	docs, err := gin.GenerateDocs(selected, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), docs, "**Request Body**: `TaskCreateRequest`")
+	assert.Contains(suite.T(), docs, "- `400 Bad Request`: `ErrorResponse` - Invalid task")
+}
+
+// TestOpenAPI tests the OpenAPI document built from scanned routes and structs
+func (suite *TestSuite) TestOpenAPI() {
+	src := `package shop
+
+// Product is an item for sale
+type Product struct {
+	ID       string   ` + "`json:\"id\" validate:\"uuid\"`" + `
+	Name     string   ` + "`json:\"name\" validate:\"required,min=2\" example:\"Lamp\"`" + `
+	Price    float64  ` + "`json:\"price\" validate:\"gte=0\"`" + `
+	Tags     []string ` + "`json:\"tags,omitempty\"`" + `
+	Status   string   ` + "`json:\"status\" validate:\"oneof=draft live\"`" + `
+	Supplier *Supplier ` + "`json:\"supplier,omitempty\"`" + `
+	internal string
+}
+
+type Supplier struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// @api.model
+type Receipt struct {
+	Total float64 ` + "`json:\"total\"`" + `
+}
+
+// GetProduct looks up a product
+//
+// Products are matched by ID.
+// @api.endpoint("/products/:id")
+// @api.method(GET)
+// @api.auth.required
+// @api.response(200, Product)
+// @api.doc.param("id", "path", description="Product ID")
+// @api.doc.param("fields", "query", "string", "Fields to include")
+// @api.doc.example({"id": "p1", "name": "Lamp"}, status=200)
+func GetProduct(id string) (*Product, error) { return nil, nil }
+
+// @api.endpoint("/products")
+// @api.method(POST)
+// @api.doc.title("Create a product")
+// @api.request(Product)
+// @api.response(201, Product)
+func CreateProduct(p Product) (*Product, error) { return nil, nil }
+`
+	dir := filepath.Join(suite.tempDir, "openapi")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+	doc := generator.OpenAPI(OpenAPIOptions{Title: "Shop", Version: "2.0.0", Security: true})
+
+	assert.Equal(suite.T(), OpenAPIVersion, doc.OpenAPI)
+	item := doc.Paths["/products/{id}"]
+	require.NotNil(suite.T(), item)
+	require.NotNil(suite.T(), item.Get)
+	get := item.Get
+	assert.Equal(suite.T(), "GetProduct", get.OperationID)
+	assert.Equal(suite.T(), "GetProduct looks up a product", get.Summary)
+	assert.Contains(suite.T(), get.Description, "Products are matched by ID.")
+	assert.Equal(suite.T(), []string{"products"}, get.Tags)
+	assert.Equal(suite.T(), []map[string][]string{{"bearerAuth": {}}}, get.Security)
+	assert.Contains(suite.T(), get.Responses, "401")
+	require.Len(suite.T(), get.Parameters, 2)
+	assert.Equal(suite.T(), OpenAPIParameter{Name: "id", In: "path", Description: "Product ID", Required: true, Schema: &Schema{Type: "string"}}, get.Parameters[0])
+	assert.Equal(suite.T(), "Fields to include", get.Parameters[1].Description)
+	ok := get.Responses["200"].Content["application/json"]
+	assert.Equal(suite.T(), "#/components/schemas/Product", ok.Schema.Ref)
+	assert.Equal(suite.T(), map[string]interface{}{"id": "p1", "name": "Lamp"}, ok.Example)
+
+	post := doc.Paths["/products"].Post
+	require.NotNil(suite.T(), post)
+	assert.Equal(suite.T(), "Create a product", post.Summary)
+	assert.Empty(suite.T(), post.Security)
+	require.NotNil(suite.T(), post.RequestBody)
+	assert.Equal(suite.T(), "#/components/schemas/Product", post.RequestBody.Content["application/json"].Schema.Ref)
+
+	require.NotNil(suite.T(), doc.Components)
+	assert.Equal(suite.T(), "bearer", doc.Components.SecuritySchemes["bearerAuth"].Scheme)
+	product := doc.Components.Schemas["Product"]
+	require.NotNil(suite.T(), product)
+	assert.Equal(suite.T(), "Product is an item for sale", product.Description)
+	assert.ElementsMatch(suite.T(), []string{"id", "name", "price", "status"}, product.Required)
+	assert.NotContains(suite.T(), product.Properties, "internal")
+	assert.Equal(suite.T(), "uuid", product.Properties["id"].Format)
+	assert.Equal(suite.T(), 2, *product.Properties["name"].MinLength)
+	assert.Equal(suite.T(), []interface{}{"Lamp"}, product.Properties["name"].Examples)
+	assert.Equal(suite.T(), 0.0, *product.Properties["price"].Minimum)
+	assert.Equal(suite.T(), "array", product.Properties["tags"].Type)
+	assert.Equal(suite.T(), []interface{}{"draft", "live"}, product.Properties["status"].Enum)
+	assert.Equal(suite.T(), "#/components/schemas/Supplier", product.Properties["supplier"].Ref)
+	assert.Contains(suite.T(), doc.Components.Schemas, "Supplier")
+	assert.Contains(suite.T(), doc.Components.Schemas, "Receipt", "@api.model structs are always documented")
+
+	jsonData, err := doc.JSON()
+	require.NoError(suite.T(), err)
+	var decoded map[string]interface{}
+	require.NoError(suite.T(), json.Unmarshal(jsonData, &decoded))
+	assert.Equal(suite.T(), "3.1.0", decoded["openapi"])
+	assert.Contains(suite.T(), string(jsonData), `"$ref": "#/components/schemas/Product"`)
+
+	yamlData, err := doc.YAML()
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(yamlData), "openapi: 3.1.0")
+	assert.Contains(suite.T(), string(yamlData), "$ref: '#/components/schemas/Product'")
+}
+
+// TestOpenAPIImport tests that Go imported from an OpenAPI document scans
+// back into the document's routes
+func (suite *TestSuite) TestOpenAPIImport() {
+	spec := `openapi: 3.0.3
+info: {title: Pet Store, version: 1.0.0}
+security:
+  - bearerAuth: []
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List all pets
+      security: []
+      parameters:
+        - {name: limit, in: query, description: Page size, schema: {type: integer, format: int32}}
+      responses:
+        "200":
+          description: A page of pets
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: "#/components/schemas/Pet"}}
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/NewPet"}
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+        "400":
+          $ref: "#/components/responses/BadRequest"
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: integer, format: int64}}
+    delete:
+      operationId: deletePet
+      security: [{bearerAuth: []}, {}]
+      responses:
+        "204": {description: No Content}
+components:
+  responses:
+    BadRequest:
+      description: Invalid input
+      content:
+        application/json:
+          schema: {$ref: "#/components/schemas/Error"}
+  schemas:
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, minLength: 1}
+        photoUrls: {type: array, items: {type: string}}
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/NewPet"
+        - type: object
+          required: [id]
+          properties:
+            id: {type: integer, format: int64}
+    Error:
+      type: object
+      properties:
+        message: {type: string}
+`
+	specFile := filepath.Join(suite.tempDir, "openapi.yaml")
+	require.NoError(suite.T(), os.WriteFile(specFile, []byte(spec), 0644))
+	dir := filepath.Join(suite.tempDir, "imported")
+	require.NoError(suite.T(), ImportOpenAPI(specFile, dir, OpenAPIImportOptions{PackageName: "petstore"}))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/petstore\n\ngo 1.21\n"), 0644))
+
+	models, err := os.ReadFile(filepath.Join(dir, "models.go"))
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(models), "PhotoURLs []string `json:\"photoUrls,omitempty\"`")
+	assert.Contains(suite.T(), string(models), "Name      string   `json:\"name\" validate:\"min=1\"`")
+
+	generator := NewAPIGenerator(&GeneratorConfig{ScanAnnotations: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+	assert.Empty(suite.T(), generator.Diagnostics())
+	assert.Empty(suite.T(), generator.Lint(true))
+
+	routes := make(map[string]APIRoute)
+	for _, route := range generator.GenerateAPIRoutes() {
+		routes[route.Method+" "+route.Path] = route
+	}
+	require.Len(suite.T(), routes, 3, "Smart mapping must not add routes for explicitly routed methods")
+
+	list := routes["GET /pets"]
+	assert.Equal(suite.T(), "ListPets", list.Function)
+	assert.False(suite.T(), list.Auth.Required)
+	assert.Equal(suite.T(), "List all pets", list.Docs.Summary)
+	assert.Equal(suite.T(), []ResponseSpec{{Status: 200, Type: "[]Pet", Description: "A page of pets"}}, list.Responses)
+	require.NotNil(suite.T(), list.Binding)
+	assert.Equal(suite.T(), BoundParam{Name: "limit", Type: "int32", Source: SourceQuery, Key: "limit"}, list.Binding.Params[1])
+
+	create := routes["POST /pets"]
+	assert.True(suite.T(), create.Auth.Required)
+	assert.Equal(suite.T(), "NewPet", create.RequestType)
+	assert.Equal(suite.T(), []ResponseSpec{
+		{Status: 201, Type: "Pet"},
+		{Status: 400, Type: "Error", Description: "Invalid input"},
+	}, create.Responses)
+
+	remove := routes["DELETE /pets/{petId}"]
+	assert.Equal(suite.T(), "DeletePet", remove.Function)
+	assert.Equal(suite.T(), "optional", remove.Auth.Type)
+	assert.Equal(suite.T(), []ResponseSpec{{Status: 204}}, remove.Responses)
+
+	// Emitting the scanned routes gives back the document's operations
+	doc := generator.OpenAPI(OpenAPIOptions{Security: true})
+	assert.Equal(suite.T(), "ListPets", doc.Paths["/pets"].Get.OperationID)
+	assert.Equal(suite.T(), "#/components/schemas/NewPet", doc.Paths["/pets"].Post.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(suite.T(), []map[string][]string{{"bearerAuth": {}}, {}}, doc.Paths["/pets/{petId}"].Delete.Security)
+	assert.Equal(suite.T(), "path", doc.Paths["/pets/{petId}"].Delete.Parameters[0].In)
+	assert.Equal(suite.T(), "integer", doc.Paths["/pets/{petId}"].Delete.Parameters[0].Schema.Type)
+
+	_, err = ParseOpenAPI([]byte(`{"swagger": "2.0"}`))
+	assert.Error(suite.T(), err, "Swagger 2.0 documents are not supported")
+}
+
+// TestClientGenerator tests the typed Go client generated for a route table
+func (suite *TestSuite) TestClientGenerator() {
+	src := `package shop
+
+import "context"
+
+// Order is a placed order
+type Order struct {
+	ID     int64       ` + "`json:\"id\"`" + `
+	Items  []OrderItem ` + "`json:\"items,omitempty\"`" + `
+	secret string
+}
+
+type OrderItem struct {
+	SKU string ` + "`json:\"sku\"`" + `
+}
+
+type ErrorResponse struct {
+	Message string ` + "`json:\"message\"`" + `
+}
+
+type OrderService struct{}
+
+// @api.endpoint("/orders/{id}")
+// @api.method(GET)
+// @api.response(200, Order)
+// @api.response(404, ErrorResponse)
+func (s *OrderService) GetOrder(ctx context.Context, id int64) (*Order, error) { return nil, nil }
+
+// @api.endpoint("/orders")
+// @api.method(GET)
+func (s *OrderService) ListOrders(ctx context.Context, status string, limit int) ([]Order, error) { return nil, nil }
+
+// @api.endpoint("/orders")
+// @api.method(POST)
+// @api.response(201, Order)
+func (s *OrderService) CreateOrder(ctx context.Context, order Order) (*Order, error) { return nil, nil }
+`
+	dir := filepath.Join(suite.tempDir, "client-shop")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{ScanAnnotations: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+	client, err := GenerateClient(generator.GenerateAPIRoutes(), generator.pkgs, &FrameworkConfig{Type: FrameworkStdlib})
+	require.NoError(suite.T(), err)
+
+	assert.Contains(suite.T(), client, "func (c *Client) GetOrder(ctx context.Context, id int64) (*Order, error)")
+	assert.Contains(suite.T(), client, "func (c *Client) ListOrders(ctx context.Context, status string, limit int) ([]Order, error)")
+	assert.Contains(suite.T(), client, "func (c *Client) CreateOrder(ctx context.Context, body Order) (*Order, error)")
+	assert.Contains(suite.T(), client, "type OrderItem struct")
+	assert.Contains(suite.T(), client, "type ErrorResponseError struct")
+	assert.NotContains(suite.T(), client, "secret")
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated client")
+	}
+	clientDir := filepath.Join(suite.tempDir, "client-module")
+	require.NoError(suite.T(), os.MkdirAll(clientDir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(clientDir, "go.mod"), []byte("module example.com/client\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(clientDir, "client.go"), []byte(client), 0644))
+	clientTest := `package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.String() {
+		case "/orders/7":
+			w.Write([]byte(` + "`" + `{"id": 7, "items": [{"sku": "A1"}]}` + "`" + `))
+		case "/orders?limit=5&status=open":
+			w.Write([]byte(` + "`" + `[{"id": 1}]` + "`" + `))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(` + "`" + `{"message": "no such order"}` + "`" + `))
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL, WithBearerToken("token"), WithHTTPClient(server.Client()))
+
+	order, err := c.GetOrder(context.Background(), 7)
+	if err != nil || order.ID != 7 || order.Items[0].SKU != "A1" {
+		t.Fatalf("GetOrder: %+v, %v", order, err)
+	}
+	orders, err := c.ListOrders(context.Background(), "open", 5)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("ListOrders: %+v, %v", orders, err)
+	}
+	_, err = c.GetOrder(context.Background(), 8)
+	var notFound *ErrorResponseError
+	if !errors.As(err, &notFound) || notFound.StatusCode != 404 || notFound.Body.Message != "no such order" {
+		t.Fatalf("expected a typed 404 error, got %v", err)
+	}
+	_, err = New(server.URL).GetOrder(context.Background(), 7)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(clientDir, "client_test.go"), []byte(clientTest), 0644))
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = clientDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+		output, err := cmd.CombinedOutput()
+		assert.NoError(suite.T(), err, "go %s: %s", args[0], output)
+	}
+}
+
+// TestTypeScriptGenerator compares the TypeScript client of a fixture package
+// with the golden files in testdata/typescript. Run with UPDATE_GOLDEN=true
+// to rewrite them after an intended change.
+func (suite *TestSuite) TestTypeScriptGenerator() {
+	src := `package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Base is shared by every stored record
+type Base struct {
+	ID        int64     ` + "`json:\"id\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+type Audit struct {
+	UpdatedBy string ` + "`json:\"updated_by\"`" + `
+}
+
+type Tag struct {
+	Name string
+}
+
+type Dimensions struct {
+	Width  float64 ` + "`json:\"width\"`" + `
+	Height float64 ` + "`json:\"height\"`" + `
+}
+
+// Product is an item of the catalog.
+// Prices are in cents.
+type Product struct {
+	Base
+	*Audit
+	Dimensions  ` + "`json:\"dimensions\"`" + `
+	Name        string                 ` + "`json:\"name\" validate:\"required\"`" + `
+	Price       int                    ` + "`json:\"price\"`" + `
+	Discount    *float64               ` + "`json:\"discount\"`" + `
+	Description string                 ` + "`json:\"description,omitempty\"`" + `
+	Tags        []*Tag                 ` + "`json:\"tags\"`" + `
+	Attributes  map[string]interface{} ` + "`json:\"attributes\"`" + `
+	Stock       map[string][]int       ` + "`json:\"stock,omitempty\"`" + `
+	Thumbnail   []byte                 ` + "`json:\"thumbnail\"`" + `
+	Extra       json.RawMessage        ` + "`json:\"extra\"`" + `
+	TraceID     string                 ` + "`json:\"x-trace-id\"`" + `
+	ExpiresAt   *time.Time             ` + "`json:\"expires_at,omitempty\"`" + `
+	Active      bool
+	Internal    string ` + "`json:\"-\"`" + `
+	secret      string
+}
+
+type ErrorResponse struct {
+	Message string ` + "`json:\"message\"`" + `
+}
+
+type ProductService struct{}
+
+// @api.endpoint("/products/{id}")
+// @api.method(GET)
+// @api.doc.title("Get a product")
+// @api.response(200, Product)
+// @api.response(404, ErrorResponse)
+func (s *ProductService) GetProduct(ctx context.Context, id int64) (*Product, error) { return nil, nil }
+
+// @api.endpoint("/products")
+// @api.method(GET)
+func (s *ProductService) ListProducts(ctx context.Context, tag string, limit int) ([]Product, error) { return nil, nil }
+
+// @api.endpoint("/products")
+// @api.method(POST)
+// @api.response(201, Product)
+// @api.response(422, ErrorResponse)
+func (s *ProductService) CreateProduct(ctx context.Context, product Product) (*Product, error) { return nil, nil }
+
+// @api.endpoint("/products/{id}")
+// @api.method(DELETE)
+// @api.response(204)
+func (s *ProductService) Delete(ctx context.Context, id int64) error { return nil }
+`
+	dir := filepath.Join(suite.tempDir, "ts-catalog")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/catalog\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "catalog.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{ScanAnnotations: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+	files := GenerateTypeScript(generator.GenerateAPIRoutes(), generator.pkgs, &FrameworkConfig{Type: FrameworkStdlib})
+
+	for _, name := range []string{"types.ts", "client.ts"} {
+		golden := filepath.Join("testdata", "typescript", name+".golden")
+		if os.Getenv("UPDATE_GOLDEN") == "true" {
+			require.NoError(suite.T(), os.MkdirAll(filepath.Dir(golden), 0755))
+			require.NoError(suite.T(), os.WriteFile(golden, []byte(files[name]), 0644))
+		}
+		expected, err := os.ReadFile(golden)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), string(expected), files[name], "%s differs from %s", name, golden)
+	}
+}
+
+// TestProtoGenerator tests the proto services generated from a service
+// struct, the stability of field numbers and the gRPC adapter
+func (suite *TestSuite) TestProtoGenerator() {
+	src := `package shop
+
+import (
+	"context"
+	"time"
+)
+
+type Base struct {
+	ID        int64     ` + "`json:\"id\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+// Order is a placed order
+type Order struct {
+	Base
+	Items  []OrderItem            ` + "`json:\"items\"`" + `
+	Note   *string                ` + "`json:\"note\"`" + `
+	Counts map[string]int         ` + "`json:\"counts\"`" + `
+	Extra  map[string]interface{} ` + "`json:\"extra\"`" + `
+	Parent *Order                 ` + "`json:\"parent\"`" + `
+	secret string
+}
+
+type OrderItem struct {
+	SKU      string ` + "`json:\"sku\"`" + `
+	Quantity int    ` + "`json:\"quantity\"`" + `
+}
+
+// OrderService manages orders
+type OrderService struct{}
+
+// @api.endpoint("/orders/{id}")
+// @api.method(GET)
+func (s *OrderService) GetOrder(ctx context.Context, id int64) (*Order, error) { return nil, nil }
+
+func (s *OrderService) ListOrders(ctx context.Context, status string, limit int) ([]Order, error) {
+	return nil, nil
+}
+
+func (s *OrderService) CreateOrder(ctx context.Context, order Order) (Order, error) { return order, nil }
+
+func (s *OrderService) DeleteOrder(ctx context.Context, id int64) error { return nil }
+
+func (s *OrderService) Watch(ch chan int) {}
+`
+	dir := filepath.Join(suite.tempDir, "proto-shop")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+	generate := func() (*ProtoOutput, *ProtoLock) {
+		generator := NewAPIGenerator(&GeneratorConfig{ScanAnnotations: true})
+		require.NoError(suite.T(), generator.ScanDirectory(dir))
+		lock, err := LoadProtoLock(filepath.Join(dir, "api.proto.lock"))
+		require.NoError(suite.T(), err)
+		output, err := GenerateProto(generator.GenerateAPIRoutes(), generator.pkgs, lock, ProtoOptions{GoPackage: "example.com/shop/api"})
+		require.NoError(suite.T(), err)
+		require.NoError(suite.T(), lock.Save(filepath.Join(dir, "api.proto.lock")))
+		return output, lock
+	}
+
+	output, _ := generate()
+	assert.Contains(suite.T(), output.Proto, "package api.v1;")
+	assert.Contains(suite.T(), output.Proto, `option go_package = "example.com/shop/api;api";`)
+	assert.Contains(suite.T(), output.Proto, "rpc GetOrder(GetOrderRequest) returns (Order);")
+	assert.Contains(suite.T(), output.Proto, "rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);")
+	assert.Contains(suite.T(), output.Proto, "rpc CreateOrder(Order) returns (Order);")
+	assert.Contains(suite.T(), output.Proto, "rpc DeleteOrder(DeleteOrderRequest) returns (DeleteOrderResponse);")
+	assert.Contains(suite.T(), output.Proto, "// Watch is not an RPC")
+	assert.Contains(suite.T(), output.Proto, "  Base base = 1;\n  repeated OrderItem items = 2;\n  optional string note = 3;\n  map<string, int64> counts = 4;\n")
+	assert.Contains(suite.T(), output.Proto, "// Extra map[string]interface{} has no protobuf equivalent")
+	assert.Contains(suite.T(), output.Proto, "  Order parent = 5;\n")
+	assert.Contains(suite.T(), output.Proto, "google.protobuf.Timestamp created_at = 2;")
+	assert.Contains(suite.T(), output.Proto, "message DeleteOrderResponse {}")
+	assert.NotContains(suite.T(), output.Proto, "secret")
+	assert.Contains(suite.T(), output.Adapter, "func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {")
+	assert.Contains(suite.T(), output.Adapter, "s.service.ListOrders(ctx, req.Status, int(req.Limit))")
+	_, err := parser.ParseFile(token.NewFileSet(), "server.go", output.Adapter, 0)
+	assert.NoError(suite.T(), err)
+
+	// Removing a field reserves its number, a changed type gets a new number
+	// and new fields never reuse a number
+	changed := strings.Replace(src, "\tNote ", "\t// Note ", 1)
+	changed = strings.Replace(changed, "Counts map[string]int ", "Counts map[string]string ", 1)
+	changed = strings.Replace(changed, "secret string", "Tags []string\n\tsecret string", 1)
+	require.NotEqual(suite.T(), src, changed)
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(changed), 0644))
+	output, lock := generate()
+	assert.Contains(suite.T(), output.Proto, "  Base base = 1;\n  repeated OrderItem items = 2;\n  map<string, string> counts = 6;\n")
+	assert.Contains(suite.T(), output.Proto, "  Order parent = 5;\n  repeated string tags = 7;\n  reserved 3, 4;\n  reserved \"note\";\n")
+	assert.Equal(suite.T(), 7, lock.Messages["Order"].Fields["tags"].Number)
+
+	// Restoring the struct brings back the old numbers of the old fields
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+	output, _ = generate()
+	assert.Contains(suite.T(), output.Proto, "  optional string note = 3;\n  map<string, int64> counts = 4;\n")
+	assert.Contains(suite.T(), output.Proto, "  reserved 6, 7;\n  reserved \"tags\";\n")
+
+	// Compile the adapter against a stand-in for the protoc-gen-go output
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the gRPC adapter")
+	}
+	stub := `package api
+
+import "google.golang.org/protobuf/types/known/timestamppb"
+
+type Base struct {
+	Id        int64
+	CreatedAt *timestamppb.Timestamp
+}
+
+type Order struct {
+	Base   *Base
+	Items  []*OrderItem
+	Note   *string
+	Counts map[string]int64
+	Parent *Order
+}
+
+type OrderItem struct {
+	Sku      string
+	Quantity int64
+}
+
+type GetOrderRequest struct{ Id int64 }
+type ListOrdersRequest struct {
+	Status string
+	Limit  int64
+}
+type ListOrdersResponse struct{ Result []*Order }
+type DeleteOrderRequest struct{ Id int64 }
+type DeleteOrderResponse struct{}
+type UnimplementedOrderServiceServer struct{}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n\nrequire google.golang.org/protobuf v1.36.6\n"), 0644))
+	require.NoError(suite.T(), os.MkdirAll(filepath.Join(dir, "api"), 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "api", "api.go"), []byte(stub), 0644))
+	require.NoError(suite.T(), WriteProtoFiles(filepath.Join(dir, "api"), output))
+	cmd := exec.Command(goTool, "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if strings.Contains(string(out), "google.golang.org/protobuf") && strings.Contains(string(out), "GOPROXY=off") {
+			suite.T().Skip("google.golang.org/protobuf is not in the module cache")
+		}
+		assert.NoError(suite.T(), err, "go vet: %s", out)
+	}
+}
+
+// TestGraphQLGenerator tests the GraphQL schema and that the generated
+// executor resolves queries and mutations through the wired services
+func (suite *TestSuite) TestGraphQLGenerator() {
+	root := filepath.Join(suite.tempDir, "graphql")
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"catalog/catalog.go": `package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Base struct {
+	ID        string    ` + "`json:\"id\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+// Product is an item in the catalog
+type Product struct {
+	Base
+	Name  string   ` + "`json:\"name\"`" + `
+	Price float64  ` + "`json:\"price\"`" + `
+	Tags  []string ` + "`json:\"tags,omitempty\"`" + `
+}
+
+type NotFoundError struct{ ID string }
+
+func (e *NotFoundError) Error() string   { return fmt.Sprintf("product %s not found", e.ID) }
+func (e *NotFoundError) StatusCode() int { return 404 }
+
+type ProductService struct{ products map[string]Product }
+
+func NewProductService() *ProductService {
+	return &ProductService{products: map[string]Product{}}
+}
+
+func (ps *ProductService) GetProduct(ctx context.Context, id string) (*Product, error) {
+	product, ok := ps.products[id]
+	if !ok {
+		return nil, &NotFoundError{ID: id}
+	}
+	return &product, nil
+}
+
+func (ps *ProductService) ListProducts(limit int, offset int) ([]Product, error) {
+	var products []Product
+	for _, product := range ps.products {
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func (ps *ProductService) CreateProduct(product *Product) (*Product, error) {
+	ps.products[product.ID] = *product
+	return product, nil
+}
+
+func (ps *ProductService) DeleteProduct(id string) error {
+	delete(ps.products, id)
+	return nil
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+
+	generator := NewAPIGenerator(&GeneratorConfig{SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+	routes := generator.GenerateAPIRoutes()
+
+	registry := GetFrameworkRegistry()
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
+		frameworkGenerator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := frameworkGenerator.GetDefaultConfig()
+		config.Wiring = &WiringConfig{Enabled: true}
+		config.GraphQL = &GraphQLConfig{Enabled: true}
+
+		routesContent, err := frameworkGenerator.GenerateRoutes(routes, config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), routesContent, "s.setupGraphQL()", frameworkType)
+
+		output, err := GenerateGraphQL(routes, generator.pkgs, config)
+		require.NoError(suite.T(), err)
+		_, err = parser.ParseFile(token.NewFileSet(), "graphql.go", output["graphql.go"], 0)
+		assert.NoError(suite.T(), err, "graphql.go for %s should parse", frameworkType)
+		assert.Contains(suite.T(), output["graphql.go"], "services.ProductService.GetProduct(ctx, idArg)", frameworkType)
+	}
+
+	config := &FrameworkConfig{Type: FrameworkStdlib, GraphQL: &GraphQLConfig{Enabled: true, Path: "/api/graphql/"}}
+	output, err := GenerateGraphQL(routes, generator.pkgs, config)
+	require.NoError(suite.T(), err)
+	schema := output["schema.graphql"]
+	query := schema[strings.Index(schema, "type Query {"):strings.Index(schema, "type Mutation {")]
+	mutation := schema[strings.Index(schema, "type Mutation {"):]
+	assert.Contains(suite.T(), query, "getProduct(id: String!): Product\n")
+	assert.Contains(suite.T(), query, "listProducts(limit: Int, offset: Int): [Product!]\n")
+	assert.Contains(suite.T(), mutation, "createProduct(input: ProductInput!): Product\n")
+	assert.Contains(suite.T(), mutation, "deleteProduct(id: String!): Boolean\n")
+	assert.Contains(suite.T(), schema, "\"\"\"Product is an item in the catalog\"\"\"\ntype Product {\n  id: String!\n  created_at: Time!\n  name: String!\n  price: Float!\n  tags: [String!]\n}")
+	assert.Contains(suite.T(), schema, "input ProductInput {\n  id: String\n")
+	assert.Contains(suite.T(), schema, "scalar Time")
+	assert.Contains(suite.T(), output["graphql.go"], `const graphqlPath = "/api/graphql"`)
+	assert.Contains(suite.T(), output["graphql.go"], `errors.New("GetProduct is not implemented")`, "Resolvers need wiring")
+
+	// Build the wired server and run requests against its endpoint
+	frameworkGenerator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config = frameworkGenerator.GetDefaultConfig()
+	config.Wiring = &WiringConfig{Enabled: true}
+	config.GraphQL = &GraphQLConfig{Enabled: true}
+	outputDir := "./generated-stdlib-api"
+	defer os.RemoveAll(outputDir)
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes, generator.pkgs, config))
+	require.FileExists(suite.T(), filepath.Join(outputDir, "schema.graphql"))
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	endpointTest := `package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func serveGraphQL(t *testing.T, handler http.Handler, request *http.Request) (int, string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code, strings.TrimSpace(recorder.Body.String())
+}
+
+func graphqlPost(t *testing.T, handler http.Handler, query string, variables map[string]interface{}) (int, string) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	return serveGraphQL(t, handler, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+}
+
+func TestGraphQLEndpoint(t *testing.T) {
+	handler := NewServer(&Config{}).Handler()
+	cases := []struct {
+		query     string
+		variables map[string]interface{}
+		status    int
+		want      string
+	}{
+		{
+			query:     "mutation Create($product: ProductInput!) { createProduct(input: $product) { id name } }",
+			variables: map[string]interface{}{"product": map[string]interface{}{"id": "p1", "name": "Lamp", "price": 12.5}},
+			status:    200,
+			want:      "{\"data\":{\"createProduct\":{\"id\":\"p1\",\"name\":\"Lamp\"}}}",
+		},
+		{
+			query:  "query { lamp: getProduct(id: \"p1\") { ...fields __typename } listProducts(limit: 10) { name @include(if: true) price @skip(if: true) } } fragment fields on Product { name id }",
+			status: 200,
+			want:   "{\"data\":{\"lamp\":{\"name\":\"Lamp\",\"id\":\"p1\",\"__typename\":\"Product\"},\"listProducts\":[{\"name\":\"Lamp\"}]}}",
+		},
+		{
+			query:  "{ getProduct(id: \"nope\") { id } }",
+			status: 200,
+			want:   "{\"data\":{\"getProduct\":null},\"errors\":[{\"message\":\"product nope not found\",\"path\":[\"getProduct\"],\"extensions\":{\"status\":404}}]}",
+		},
+		{
+			query:  "{ getProduct(id: \"p1\") { sku } }",
+			status: 400,
+			want:   "{\"errors\":[{\"message\":\"cannot query field \\\"sku\\\" on type \\\"Product\\\"\"}]}",
+		},
+		{
+			query:  "mutation { deleteProduct(id: \"p1\") }",
+			status: 200,
+			want:   "{\"data\":{\"deleteProduct\":true}}",
+		},
+	}
+	for _, c := range cases {
+		status, body := graphqlPost(t, handler, c.query, c.variables)
+		if status != c.status || body != c.want {
+			t.Errorf("%s:\ngot  %d %s\nwant %d %s", c.query, status, body, c.status, c.want)
+		}
+	}
+
+	status, body := serveGraphQL(t, handler, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("mutation { deleteProduct(id: \"p1\") }"), nil))
+	if status != 400 || !strings.Contains(body, "mutations must be sent with POST") {
+		t.Errorf("GET mutation: got %d %s", status, body)
+	}
+	status, body = serveGraphQL(t, handler, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	if status != 200 || !strings.Contains(body, "type Query {") {
+		t.Errorf("GET schema: got %d %s", status, body)
+	}
+}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(outputDir, "graphql_endpoint_test.go"), []byte(endpointTest), 0644))
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "-run", "TestGraphQLEndpoint", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = outputDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+		output, err := cmd.CombinedOutput()
+		assert.NoError(suite.T(), err, "go %s: %s", args[0], output)
+	}
+}
+
+// TestDatabaseRepositories tests the repositories generated for database
+// models and runs them and the handlers using them against SQLite
+func (suite *TestSuite) TestDatabaseRepositories() {
+	root := filepath.Join(suite.tempDir, "database")
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"catalog/catalog.go": `package catalog
+
+import "time"
+
+type Base struct {
+	ID        int64     ` + "`json:\"id\" db:\"id\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\" db:\"created_at\"`" + `
+	UpdatedAt time.Time ` + "`json:\"updated_at\" db:\"updated_at\"`" + `
+}
+
+// Product is an item in the catalog
+type Product struct {
+	Base
+	Name    string   ` + "`json:\"name\" db:\"name\"`" + `
+	Price   float64  ` + "`json:\"price\" db:\"unit_price\"`" + `
+	InStock bool     ` + "`json:\"in_stock\"`" + `
+	Note    *string  ` + "`json:\"note,omitempty\"`" + `
+	Labels  []string ` + "`json:\"labels\"`" + `
+	Secret  string   ` + "`json:\"-\" db:\"-\"`" + `
+}
+
+// Tag labels products
+type Tag struct {
+	// @api.field.id
+	Slug  string ` + "`json:\"slug\"`" + `
+	Label string ` + "`json:\"label\" db:\"label\"`" + `
+}
+
+// Report is not stored
+type Report struct {
+	Total int
+}
+
+type ProductService struct {
+	// @api.db.table("catalog_products")
+	// @api.db.primary_key("id")
+	products []Product
+}
+
+func (s *ProductService) SearchProducts(q string) ([]Product, error) { return nil, nil }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+
+	generator := NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+	routes := generator.GenerateAPIRoutes()
+
+	config := &FrameworkConfig{Type: FrameworkStdlib, Database: &DatabaseConfig{Type: "sqlite", Name: "shop.db"}}
+	output, err := GenerateRepositories(generator.pkgs, config)
+	require.NoError(suite.T(), err)
+	source := output["repositories.go"]
+	migration, _, err := GenerateMigration(nil, generator.pkgs, config.Database)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), migration.SQL, "CREATE TABLE IF NOT EXISTS \"catalog_products\" (\n\t\"id\" INTEGER PRIMARY KEY,\n\t\"created_at\" TIMESTAMP NOT NULL,\n\t\"updated_at\" TIMESTAMP NOT NULL,\n\t\"name\" TEXT NOT NULL,\n\t\"unit_price\" REAL NOT NULL,\n\t\"in_stock\" BOOLEAN NOT NULL,\n\t\"note\" TEXT\n);")
+	assert.Contains(suite.T(), migration.SQL, "CREATE TABLE IF NOT EXISTS \"tags\" (\n\t\"slug\" TEXT PRIMARY KEY,")
+	assert.Contains(suite.T(), source, `defaultDatabaseURL = "shop.db"`)
+	assert.Contains(suite.T(), source, `_ "github.com/mattn/go-sqlite3"`)
+	assert.Contains(suite.T(), source, "func (r *ProductRepository) Get(ctx context.Context, key int64) (*Product, error) {")
+	assert.Contains(suite.T(), source, "func (r *TagRepository) Delete(ctx context.Context, key string) error {")
+	assert.Contains(suite.T(), source, `WHERE "name" LIKE ? ESCAPE '\' OR "note" LIKE ? ESCAPE '\' ORDER BY "id" LIMIT ? OFFSET ?`+"`, pattern, pattern, queryLimit(limit), offset)")
+	assert.NotContains(suite.T(), source, "ReportRepository", "Structs without db tags or tables are not models")
+	assert.NotContains(suite.T(), source, "BaseRepository", "Embedded structs are stored in the tables of their models")
+	assert.NotContains(suite.T(), source, "secret")
+
+	config.Database = &DatabaseConfig{Type: "postgres", Host: "db", User: "shop", Name: "catalog", SSL: true}
+	output, err = GenerateRepositories(generator.pkgs, config)
+	require.NoError(suite.T(), err)
+	source = output["repositories.go"]
+	migration, _, err = GenerateMigration(nil, generator.pkgs, config.Database)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), migration.SQL, `"id" BIGSERIAL PRIMARY KEY`)
+	assert.Contains(suite.T(), migration.SQL, `"unit_price" DOUBLE PRECISION NOT NULL`)
+	assert.Contains(suite.T(), source, `WHERE "name" ILIKE $1 ESCAPE '\' OR "note" ILIKE $1 ESCAPE '\' ORDER BY "id" LIMIT $2 OFFSET $3`+"`, pattern, queryLimit(limit), offset)")
+	assert.Contains(suite.T(), source, `defaultDatabaseURL = "postgres://shop@db:5432/catalog?sslmode=require"`)
+	assert.Contains(suite.T(), source, `_ "github.com/lib/pq"`)
+
+	config.Database = &DatabaseConfig{Type: "oracle"}
+	_, err = GenerateRepositories(generator.pkgs, config)
+	assert.Error(suite.T(), err)
+
+	// Every framework's handlers call the repositories of unbound routes
+	registry := GetFrameworkRegistry()
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
+		frameworkGenerator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := frameworkGenerator.GetDefaultConfig()
+		config.Database = &DatabaseConfig{Type: "sqlite"}
+		bound, err := bindRepositories(routes, generator.pkgs, config)
+		require.NoError(suite.T(), err)
+
+		handlers, err := frameworkGenerator.GenerateHandlers(bound, config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), handlers, "repositories.Product.Get(", frameworkType)
+		assert.Contains(suite.T(), handlers, "repositories.Product.Search(", frameworkType)
+		assert.Contains(suite.T(), handlers, "repositories.Tag.Create(", frameworkType)
+		_, err = parser.ParseFile(token.NewFileSet(), "handlers.go", handlers, 0)
+		assert.NoError(suite.T(), err, "handlers.go for %s should parse", frameworkType)
+
+		routesContent, err := frameworkGenerator.GenerateRoutes(bound, config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), routesContent, "s.setupDatabase()", frameworkType)
+	}
+
+	// Build the server and run its generated tests and requests against SQLite
+	frameworkGenerator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config = frameworkGenerator.GetDefaultConfig()
+	config.Database = &DatabaseConfig{Type: "sqlite"}
+	outputDir := "./generated-stdlib-api"
+	defer os.RemoveAll(outputDir)
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes, generator.pkgs, config))
+	goMod, err := os.ReadFile(filepath.Join(outputDir, "go.mod"))
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(goMod), "require github.com/mattn/go-sqlite3 v1.14.22")
+	require.FileExists(suite.T(), filepath.Join(outputDir, "repositories_test.go"))
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	endpointTest := `package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProductEndpoints(t *testing.T) {
+	handler := NewServer(&Config{}).Handler()
+	send := func(method, path, body string) (int, string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder.Code, strings.TrimSpace(recorder.Body.String())
+	}
+
+	status, body := send(http.MethodPost, "/products", ` + "`" + `{"name":"Desk lamp","price":12.5}` + "`" + `)
+	if status != http.StatusCreated {
+		t.Fatalf("POST /products: %d %s", status, body)
+	}
+	var created struct{ ID int64 }
+	if err := json.Unmarshal([]byte(body), &created); err != nil || created.ID == 0 {
+		t.Fatalf("POST /products returned %s: %v", body, err)
+	}
+	path := "/products/" + strconv.FormatInt(created.ID, 10)
+	if status, body := send(http.MethodGet, path, ""); status != http.StatusOK || !strings.Contains(body, ` + "`" + `"name":"Desk lamp"` + "`" + `) {
+		t.Errorf("GET %s: %d %s", path, status, body)
+	}
+	if status, body := send(http.MethodGet, "/products/search?q=LAMP", ""); status != http.StatusOK || strings.Count(body, ` + "`" + `"id":` + "`" + `) != 1 {
+		t.Errorf("GET /products/search: %d %s", status, body)
+	}
+	if status, body := send(http.MethodPut, path, ` + "`" + `{"name":"Floor lamp"}` + "`" + `); status != http.StatusOK || !strings.Contains(body, "Floor lamp") {
+		t.Errorf("PUT %s: %d %s", path, status, body)
+	}
+	if status, _ := send(http.MethodDelete, path, ""); status != http.StatusNoContent {
+		t.Errorf("DELETE %s: %d", path, status)
+	}
+	if status, body := send(http.MethodGet, path, ""); status != http.StatusNotFound || !strings.Contains(body, "record not found") {
+		t.Errorf("GET %s after DELETE: %d %s", path, status, body)
+	}
+	if status, _ := send(http.MethodGet, "/products/abc", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /products/abc: %d", status)
+	}
+}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(outputDir, "endpoint_test.go"), []byte(endpointTest), 0644))
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = outputDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off", "CGO_ENABLED=1")
+		output, err := cmd.CombinedOutput()
+		if err != nil && strings.Contains(string(output), "github.com/mattn/go-sqlite3") && strings.Contains(string(output), "GOPROXY=off") {
+			suite.T().Skip("github.com/mattn/go-sqlite3 is not in the module cache")
+		}
+		assert.NoError(suite.T(), err, "go %s: %s", args[0], output)
+	}
+}
+
+// TestSchemaMigrations tests the migrations generated from two scans of
+// changing models and applies them with the generated runner
+func (suite *TestSuite) TestSchemaMigrations() {
+	root := filepath.Join(suite.tempDir, "migrations")
+	model := filepath.Join(root, "crm", "crm.go")
+	scan := func(content string) *APIGenerator {
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(model), 0755))
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/crm\n\ngo 1.21\n"), 0644))
+		require.NoError(suite.T(), os.WriteFile(model, []byte(content), 0644))
+		generator := NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+		require.NoError(suite.T(), generator.ScanDirectory(root))
+		return generator
+	}
+	first := scan(`package crm
+
+type Customer struct {
+	ID int64 ` + "`json:\"id\" db:\"id\"`" + `
+	// @api.field.unique
+	Email string ` + "`json:\"email\" db:\"email\"`" + `
+	// @api.field.string(80)
+	Name     string  ` + "`json:\"name\" db:\"name\"`" + `
+	Nickname *string ` + "`json:\"nickname\" db:\"nickname\"`" + `
+	Legacy   string  ` + "`json:\"legacy\" db:\"legacy\"`" + `
+}
+
+type Order struct {
+	ID    string  ` + "`json:\"id\" db:\"id\"`" + `
+	Total float64 ` + "`json:\"total\" db:\"total\"`" + `
+}
+`)
+	sqlite := &DatabaseConfig{Type: "sqlite"}
+	postgres := &DatabaseConfig{Type: "postgres"}
+
+	migration, snapshot, err := GenerateMigration(nil, first.pkgs, sqlite)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), migration)
+	assert.Equal(suite.T(), "0001_create_tables.sql", migration.FileName())
+	assert.Empty(suite.T(), migration.Destructive())
+	assert.Contains(suite.T(), migration.SQL, "\t\"name\" VARCHAR(80) NOT NULL,\n")
+	assert.Contains(suite.T(), migration.SQL, "\t\"nickname\" TEXT,\n")
+	assert.Contains(suite.T(), migration.SQL, `CREATE UNIQUE INDEX IF NOT EXISTS "customers_email_key" ON "customers" ("email");`)
+	unchanged, again, err := GenerateMigration(snapshot, first.pkgs, sqlite)
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), unchanged, "An unchanged schema needs no migration")
+	assert.Equal(suite.T(), 1, again.Version)
+
+	second := scan(`package crm
+
+type Customer struct {
+	ID int64 ` + "`json:\"id\" db:\"id\"`" + `
+	// @api.field.unique
+	Email string ` + "`json:\"email\" db:\"email\"`" + `
+	// @api.field.string(80)
+	Name     string ` + "`json:\"name\" db:\"full_name\"`" + `
+	Nickname string ` + "`json:\"nickname\" db:\"nickname\"`" + `
+	// @api.field.index
+	// @api.field.default("basic")
+	Plan string ` + "`json:\"plan\" db:\"plan\"`" + `
+}
+`)
+
+	// PostgreSQL alters the table in place
+	before, err := BuildSchemaSnapshot(first.pkgs, postgres)
+	require.NoError(suite.T(), err)
+	after, err := BuildSchemaSnapshot(second.pkgs, postgres)
+	require.NoError(suite.T(), err)
+	changes, err := DiffSchemas(before, after)
+	require.NoError(suite.T(), err)
+	kinds := make(map[string]SchemaChange)
+	for _, change := range changes {
+		kinds[change.Kind+" "+change.Table+" "+change.Column] = change
+	}
+	assert.Equal(suite.T(), []string{`ALTER TABLE "customers" RENAME COLUMN "name" TO "full_name"`}, kinds["rename_column customers full_name"].Statements)
+	assert.Equal(suite.T(), []string{`ALTER TABLE "customers" ADD COLUMN "plan" TEXT NOT NULL DEFAULT 'basic'`}, kinds["add_column customers plan"].Statements)
+	assert.Equal(suite.T(), []string{`UPDATE "customers" SET "nickname" = '' WHERE "nickname" IS NULL`, `ALTER TABLE "customers" ALTER COLUMN "nickname" SET NOT NULL`}, kinds["alter_column customers nickname"].Statements)
+	assert.True(suite.T(), kinds["alter_column customers nickname"].Destructive)
+	assert.True(suite.T(), kinds["drop_column customers legacy"].Destructive)
+	assert.True(suite.T(), kinds["drop_table orders "].Destructive)
+	assert.Equal(suite.T(), []string{`CREATE INDEX IF NOT EXISTS "customers_plan_idx" ON "customers" ("plan")`}, kinds["add_index customers plan"].Statements)
+	assert.False(suite.T(), kinds["rename_column customers full_name"].Destructive)
+	assert.Equal(suite.T(), "add_index", changes[len(changes)-1].Kind, "Indexes are created after their columns")
+
+	// A new primary key rebuilds the table and keeps the serial counter
+	rekeyed := *after
+	rekeyed.Tables = []SchemaTable{{Name: "customers", Model: "Customer", Columns: []SchemaColumn{
+		{Name: "number", Field: "Number", Type: "BIGINT", Key: true, AutoKey: true},
+		{Name: "email", Field: "Email", Type: "TEXT", Unique: true},
+	}}}
+	changes, err = DiffSchemas(after, &rekeyed)
+	require.NoError(suite.T(), err)
+	var rebuild *SchemaChange
+	for i := range changes {
+		if changes[i].Kind == "rebuild_table" {
+			rebuild = &changes[i]
+		}
+	}
+	require.NotNil(suite.T(), rebuild)
+	assert.True(suite.T(), rebuild.Destructive)
+	assert.Contains(suite.T(), rebuild.Statements, `INSERT INTO "customers__rebuild" ("email") SELECT "email" FROM "customers"`)
+	assert.Contains(suite.T(), rebuild.Statements, `ALTER INDEX "customers__rebuild_pkey" RENAME TO "customers_pkey"`)
+	assert.Contains(suite.T(), rebuild.Statements[len(rebuild.Statements)-1], "setval(pg_get_serial_sequence('\"customers\"', 'number')")
+
+	_, err = DiffSchemas(snapshot, after)
+	assert.Error(suite.T(), err, "Migrations of one database cannot continue in another")
+
+	// Generate the server for both scans and apply their migrations to SQLite
+	registry := GetFrameworkRegistry()
+	frameworkGenerator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config := frameworkGenerator.GetDefaultConfig()
+	config.Database = sqlite
+	outputDir := "./generated-stdlib-api"
+	require.NoError(suite.T(), os.RemoveAll(outputDir))
+	defer os.RemoveAll(outputDir)
+	for _, generator := range []*APIGenerator{first, second, second} {
+		require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, generator.GenerateAPIRoutes(), generator.pkgs, config))
+	}
+	files, err := filepath.Glob(filepath.Join(outputDir, "migrations", "*.sql"))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), files, 2, "Regenerating an unchanged schema adds no migration")
+	content, err := os.ReadFile(files[1])
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "0002_update_schema.sql", filepath.Base(files[1]))
+	assert.Contains(suite.T(), string(content), `-- destructive: rebuild table "customers": rename column "name" to "full_name"`)
+	assert.Contains(suite.T(), string(content), `INSERT INTO "customers__rebuild" ("id", "email", "full_name", "nickname") SELECT "id", "email", "name", COALESCE("nickname", '') FROM "customers";`)
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	migrationTest := `package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationsKeepData(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "crm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrations, err := loadMigrations()
+	if err != nil || len(migrations) != 2 || migrations[0].Destructive || !migrations[1].Destructive {
+		t.Fatalf("loadMigrations: %v %+v", err, migrations)
+	}
+	if _, err := appliedMigrations(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyMigration(ctx, db, migrations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, ` + "`" + `INSERT INTO "customers" ("email", "name", "legacy") VALUES ('ada@example.com', 'Ada', 'x')` + "`" + `); err != nil {
+		t.Fatal(err)
+	}
+
+	if applied, err := MigrateDatabase(ctx, db, false); err == nil || len(applied) != 0 {
+		t.Fatalf("destructive migration applied without -allow-destructive: %v", err)
+	}
+	if applied, err := MigrateDatabase(ctx, db, true); err != nil || len(applied) != 1 {
+		t.Fatalf("MigrateDatabase: %v %v", applied, err)
+	}
+	if applied, err := MigrateDatabase(ctx, db, true); err != nil || len(applied) != 0 {
+		t.Fatalf("second MigrateDatabase: %v %v", applied, err)
+	}
+
+	repositories := NewRepositories(db)
+	customer, err := repositories.Customer.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Name != "Ada" || customer.Nickname != "" || customer.Plan != "basic" {
+		t.Errorf("customer after migration: %+v", customer)
+	}
+	if err := repositories.Customer.Create(ctx, &Customer{Email: "ada@example.com"}); err == nil {
+		t.Error("the unique index on email was not recreated")
+	}
+}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(outputDir, "migration_test.go"), []byte(migrationTest), 0644))
+	env := append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off", "CGO_ENABLED=1")
+	cmd := exec.Command(goTool, "test", "-run", "TestMigrationsKeepData", ".")
+	cmd.Dir = outputDir
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if err != nil && strings.Contains(string(output), "github.com/mattn/go-sqlite3") && strings.Contains(string(output), "GOPROXY=off") {
+		suite.T().Skip("github.com/mattn/go-sqlite3 is not in the module cache")
+	}
+	require.NoError(suite.T(), err, "go test: %s", output)
+
+	// The migrate command stops before the destructive migration
+	databaseURL := "DATABASE_URL=" + filepath.Join(suite.tempDir, "crm.db")
+	migrate := func(args ...string) (string, error) {
+		cmd := exec.Command(goTool, append([]string{"run", ".", "migrate"}, args...)...)
+		cmd.Dir = outputDir
+		cmd.Env = append(env, databaseURL)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	result, err := migrate()
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), result, "Applied 0001_create_tables")
+	assert.Contains(suite.T(), result, "-allow-destructive")
+	result, err = migrate("-status")
+	assert.NoError(suite.T(), err, result)
+	assert.Contains(suite.T(), result, "0002_update_schema\tpending, destructive")
+	result, err = migrate("-allow-destructive")
+	assert.NoError(suite.T(), err, result)
+	assert.Contains(suite.T(), result, "Applied 0002_update_schema")
+}
+
+// TestMemoryStore tests the in-memory repositories and runs the generated
+// server against them
+func (suite *TestSuite) TestMemoryStore() {
+	root := filepath.Join(suite.tempDir, "memory")
+	files := map[string]string{
+		"go.mod": "module example.com/notes\n\ngo 1.21\n",
+		"notes/notes.go": `package notes
+
+import "time"
+
+// Note is stored without db tags
+type Note struct {
+	ID        int       ` + "`json:\"id\"`" + `
+	Title     string    ` + "`json:\"title\"`" + `
+	Author    *string   ` + "`json:\"author,omitempty\"`" + `
+	Pinned    bool      ` + "`json:\"pinned\"`" + `
+	Tags      []string  ` + "`json:\"tags\"`" + `
+	Secret    string    ` + "`json:\"-\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+	UpdatedAt time.Time ` + "`json:\"updated_at\"`" + `
+}
+
+// Folder has a string key
+type Folder struct {
+	// @api.field.id
+	Slug string ` + "`json:\"slug\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// Summary has no key and is not stored
+type Summary struct {
+	Count int
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
 	}
 
-	for _, tc := range testCases {
-		mapping, found := suite.generator.SmartMethodMapping(tc.methodName, tc.structName)
+	generator := NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+	routes := generator.GenerateAPIRoutes()
 
-		if tc.shouldMatch {
-			assert.True(suite.T(), found,
-				fmt.Sprintf("Method %s should match pattern", tc.methodName))
-			assert.Equal(suite.T(), tc.expectedOp, mapping.Operation,
-				fmt.Sprintf("Operation mismatch for %s", tc.methodName))
+	config := &FrameworkConfig{Type: FrameworkStdlib, Database: &DatabaseConfig{Type: "memory"}, Testing: &TestingConfig{Enabled: true}}
+	output, err := GenerateRepositories(generator.pkgs, config)
+	require.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), output, "migrations.go")
+	source := output["repositories.go"]
+	assert.Contains(suite.T(), source, "func (r *NoteRepository) Filter(ctx context.Context, filter map[string]string, limit, offset int) ([]Note, error) {")
+	assert.Contains(suite.T(), source, "func (r *FolderRepository) Get(ctx context.Context, key string) (*Folder, error) {")
+	assert.Contains(suite.T(), source, "containsFold(item.Title, q) || item.Author != nil && containsFold(*item.Author, q)")
+	assert.NotContains(suite.T(), source, "SummaryRepository", "Structs without a key are not stored")
+	assert.NotContains(suite.T(), source, "database/sql")
+	assert.NotContains(suite.T(), source, `case "secret"`, "Fields hidden from JSON cannot be filtered")
+	_, _, err = GenerateMigration(nil, generator.pkgs, config.Database)
+	assert.Error(suite.T(), err)
+
+	bound, err := bindRepositories(routes, generator.pkgs, config)
+	require.NoError(suite.T(), err)
+	var filters []string
+	for _, route := range bound {
+		if route.Repository != nil && route.Repository.Model == "Note" && route.Repository.Operation == "list" {
+			filters = route.Repository.Filters
+		}
+	}
+	assert.Equal(suite.T(), []string{"id", "title", "author", "pinned", "created_at", "updated_at"}, filters)
 
-			// Check that path contains expected elements
-			assert.Contains(suite.T(), mapping.Path, strings.ToLower(tc.structName),
-				fmt.Sprintf("Path should contain struct name for %s", tc.methodName))
-		} else {
-			assert.False(suite.T(), found,
-				fmt.Sprintf("Method %s should not match any pattern", tc.methodName))
+	registry := GetFrameworkRegistry()
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
+		frameworkGenerator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := frameworkGenerator.GetDefaultConfig()
+		config.Database = &DatabaseConfig{Type: "memory"}
+		bound, err := bindRepositories(routes, generator.pkgs, config)
+		require.NoError(suite.T(), err)
+		handlers, err := frameworkGenerator.GenerateHandlers(bound, config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), handlers, "repositories.Note.Filter(", frameworkType)
+		_, err = parser.ParseFile(token.NewFileSet(), "handlers.go", handlers, 0)
+		assert.NoError(suite.T(), err, "handlers.go for %s should parse", frameworkType)
+	}
+
+	// Build the server and run its generated tests and requests
+	frameworkGenerator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config = frameworkGenerator.GetDefaultConfig()
+	config.Database = &DatabaseConfig{Type: "memory"}
+	outputDir := "./generated-stdlib-api"
+	require.NoError(suite.T(), os.RemoveAll(outputDir))
+	defer os.RemoveAll(outputDir)
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes, generator.pkgs, config))
+	goMod, err := os.ReadFile(filepath.Join(outputDir, "go.mod"))
+	require.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), string(goMod), "require")
+	assert.NoDirExists(suite.T(), filepath.Join(outputDir, "migrations"))
+	handlerTests, err := os.ReadFile(filepath.Join(outputDir, "handlers_test.go"))
+	require.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), string(handlerTests), "auto_generated")
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	endpointTest := `package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNoteEndpoints(t *testing.T) {
+	repositories = NewRepositories()
+	handler := NewServer(&Config{}).Handler()
+	send := func(method, path, body string) (int, string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder.Code, strings.TrimSpace(recorder.Body.String())
+	}
+	count := func(path string) int {
+		status, body := send(http.MethodGet, path, "")
+		var items []map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &items); status != http.StatusOK || err != nil {
+			t.Fatalf("GET %s: %d %s", path, status, body)
+		}
+		return len(items)
+	}
+
+	for _, title := range []string{"Groceries", "Ideas", "Groceries"} {
+		if status, body := send(http.MethodPost, "/notes", ` + "`" + `{"title":"` + "`" + `+title+` + "`" + `"}` + "`" + `); status != http.StatusCreated {
+			t.Fatalf("POST /notes: %d %s", status, body)
 		}
 	}
+	if status, body := send(http.MethodPost, "/notes", ` + "`" + `{"id":1,"title":"Again"}` + "`" + `); status != http.StatusConflict {
+		t.Errorf("POST /notes with a used id: %d %s", status, body)
+	}
+	if n := count("/notes"); n != 3 {
+		t.Errorf("GET /notes returned %d notes", n)
+	}
+	if n := count("/notes?title=Groceries"); n != 2 {
+		t.Errorf("GET /notes?title=Groceries returned %d notes", n)
+	}
+	if n := count("/notes?title=Groceries&pinned=true"); n != 0 {
+		t.Errorf("GET /notes?title=Groceries&pinned=true returned %d notes", n)
+	}
+	if n := count("/notes?limit=2&offset=2"); n != 1 {
+		t.Errorf("GET /notes?limit=2&offset=2 returned %d notes", n)
+	}
+	if status, body := send(http.MethodPut, "/notes/2", ` + "`" + `{"title":"Plans","pinned":true}` + "`" + `); status != http.StatusOK || !strings.Contains(body, ` + "`" + `"id":2` + "`" + `) {
+		t.Errorf("PUT /notes/2: %d %s", status, body)
+	}
+	if n := count("/notes?pinned=true"); n != 1 {
+		t.Errorf("GET /notes?pinned=true returned %d notes", n)
+	}
+	if status, _ := send(http.MethodDelete, "/notes/2", ""); status != http.StatusNoContent {
+		t.Errorf("DELETE /notes/2: %d", status)
+	}
+	if status, body := send(http.MethodGet, "/notes/2", ""); status != http.StatusNotFound || !strings.Contains(body, "record not found") {
+		t.Errorf("GET /notes/2 after DELETE: %d %s", status, body)
+	}
+}
+`
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(outputDir, "endpoint_test.go"), []byte(endpointTest), 0644))
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "-race", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = outputDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off", "CGO_ENABLED=1")
+		output, err := cmd.CombinedOutput()
+		assert.NoError(suite.T(), err, "go %s: %s", args[0], output)
+	}
 }
 
-// TestRouteGeneration tests API route generation
-func (suite *TestSuite) TestRouteGeneration() {
-	// Set up test data with methods that should generate routes
-	suite.generator.pkgs["test"] = &PackageInfo{
-		Name: "test",
-		Structs: []StructInfo{
-			{
-				Name: "UserService",
-				Methods: []MethodInfo{
-					{Name: "GetUser", Receiver: "*UserService"},
-					{Name: "CreateUser", Receiver: "*UserService"},
-					{Name: "UpdateUser", Receiver: "*UserService"},
-					{Name: "DeleteUser", Receiver: "*UserService"},
-					{Name: "ListUsers", Receiver: "*UserService"},
-				},
-			},
-		},
+// TestProtectedRegions tests that regeneration keeps edited user regions,
+// moves the regions of removed handlers aside and refuses to overwrite
+// files edited outside their regions
+func (suite *TestSuite) TestProtectedRegions() {
+	routes := []APIRoute{
+		{Method: "GET", Path: "/users", Function: "ListUsers"},
+		{Method: "GET", Path: "/users/{id}", Function: "GetUser"},
+		{Method: "DELETE", Path: "/users/{id}", Function: "DeleteUser", Responses: []ResponseSpec{{Status: http.StatusNoContent}}},
 	}
+	registry := GetFrameworkRegistry()
+	generator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config := generator.GetDefaultConfig()
+	config.Deployment = &DeploymentConfig{Type: "docker"}
 
-	routes := suite.generator.GenerateAPIRoutes()
+	outputDir := "./generated-stdlib-api"
+	require.NoError(suite.T(), os.RemoveAll(outputDir))
+	defer os.RemoveAll(outputDir)
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes, map[string]*PackageInfo{}, config))
 
-	assert.Greater(suite.T(), len(routes), 0, "Should generate routes")
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(outputDir, name))
+		require.NoError(suite.T(), err)
+		return string(data)
+	}
+	write := func(name, content string) {
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0644))
+	}
+	// fill replaces the body of a user region
+	fill := func(content, name, body string) string {
+		begin := strings.Index(content, "// gofastapi:begin "+name+" ")
+		require.GreaterOrEqual(suite.T(), begin, 0, "region %s", name)
+		start := begin + strings.Index(content[begin:], "\n") + 1
+		end := strings.Index(content, "// gofastapi:end "+name+"\n")
+		end = strings.LastIndex(content[:end], "\n") + 1
+		return content[:start] + body + content[end:]
+	}
 
-	// Verify route structure
+	handlers := read("handlers.go")
+	assert.True(suite.T(), strings.HasPrefix(handlers, "// "+generatedNotice+"\n// gofastapi:checksum "))
+	assert.Contains(suite.T(), handlers, "\n\npackage main\n", "The header is not a package comment")
+	for _, name := range []string{"imports", "ListUsersHandler", "GetUserHandler", "DeleteUserHandler", "declarations"} {
+		assert.Contains(suite.T(), handlers, "// gofastapi:begin "+name+" ")
+		assert.Contains(suite.T(), handlers, "// gofastapi:end "+name+"\n")
+	}
+	assert.True(suite.T(), strings.HasPrefix(read("Dockerfile"), "# "+generatedNotice+"\n# gofastapi:checksum "))
+	assert.False(suite.T(), strings.HasPrefix(read("go.mod"), "//"), "go.mod is not protected")
+
+	// Fill regions, reformat the file and regenerate without DeleteUser
+	handlers = fill(handlers, "imports", "\t\"strings\"\n")
+	handlers = fill(handlers, "ListUsersHandler", "\twriteJSON(w, http.StatusOK, []string{shout(\"ada\")})\n")
+	handlers = fill(handlers, "DeleteUserHandler", "\tlog.Printf(\"deleting %s\", r.PathValue(\"id\"))\n\tw.WriteHeader(http.StatusNoContent)\n")
+	handlers = fill(handlers, "declarations", "// shout upper-cases a name\nfunc shout(name string) string {\n\treturn strings.ToUpper(name)\n}\n")
+	write("handlers.go", strings.ReplaceAll(handlers, "\n\n", "\n\n\n"))
+
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes[:2], map[string]*PackageInfo{}, config))
+	handlers = read("handlers.go")
+	assert.Contains(suite.T(), handlers, "\t\"strings\"\n")
+	assert.Contains(suite.T(), handlers, "writeJSON(w, http.StatusOK, []string{shout(\"ada\")})")
+	assert.Contains(suite.T(), handlers, "func shout(name string) string {")
+	assert.Regexp(suite.T(), `"message": +"GetUser endpoint"`, handlers, "Untouched regions are regenerated")
+	assert.NotContains(suite.T(), handlers, "DeleteUserHandler")
+	assert.NotContains(suite.T(), handlers, "\n\n\n", "Reformatting is not an edit")
+	orphaned := read("handlers.go" + orphanedSuffix)
+	assert.Contains(suite.T(), orphaned, "// gofastapi:begin DeleteUserHandler\n")
+	assert.Contains(suite.T(), orphaned, "log.Printf(\"deleting %s\", r.PathValue(\"id\"))")
+
+	// A second regeneration keeps the regions and leaves the companion file
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes[:2], map[string]*PackageInfo{}, config))
+	assert.Equal(suite.T(), handlers, read("handlers.go"))
+	assert.Equal(suite.T(), orphaned, read("handlers.go"+orphanedSuffix))
+
+	// Reverting a region to its generated body makes it generated again
+	handler, err := generator.GenerateHandlers(routes[:1], config)
+	require.NoError(suite.T(), err)
+	_, regions := splitRegions(handler)
+	write("handlers.go", fill(handlers, "ListUsersHandler", regions[1].Body))
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes[:2], map[string]*PackageInfo{}, config))
+	assert.NotContains(suite.T(), read("handlers.go"), "shout(\"ada\")")
+	assert.Contains(suite.T(), read("handlers.go"), "func shout(name string) string {")
+
+	// Edits outside the regions stop the regeneration
+	mainSource := strings.Replace(read("main.go"), "package main\n", "package main\n\n// Tuned by hand\n", 1)
+	write("main.go", mainSource)
+	err = registry.GenerateForFramework(FrameworkStdlib, routes[:2], map[string]*PackageInfo{}, config)
+	require.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "main.go was edited outside its gofastapi regions")
+	assert.Equal(suite.T(), mainSource, read("main.go"))
+	assert.NotContains(suite.T(), read("main.go"+pendingSuffix), "Tuned by hand")
+
+	// So does a file without a header
+	routesPath := filepath.Join(outputDir, "routes.go")
+	var conflict *EditedFileError
+	require.NoError(suite.T(), os.WriteFile(routesPath, []byte("package main\n"), 0644))
+	err = writeFile(routesPath, "package main\n\nfunc setupRoutes() {}\n")
+	require.ErrorAs(suite.T(), err, &conflict)
+	assert.Equal(suite.T(), routesPath+pendingSuffix, conflict.Pending)
+	assert.True(suite.T(), conflict.Headerless)
+	assert.Contains(suite.T(), err.Error(), "routes.go has no gofastapi checksum header: regenerate it with -adopt")
+
+	// Adopting takes over files without a header, but not edited ones
+	out := NewOutputSet()
+	out.Adopt = true
+	out.Add(routesPath, "package main\n\nfunc setupRoutes() {}\n")
+	require.NoError(suite.T(), out.Write())
+	assert.True(suite.T(), strings.HasPrefix(read("routes.go"), "// "+generatedNotice+"\n// gofastapi:checksum "))
+	assert.NoFileExists(suite.T(), routesPath+pendingSuffix)
+	out.Add(filepath.Join(outputDir, "main.go"), "package main\n")
+	err = out.Write()
+	assert.ErrorContains(suite.T(), err, "main.go was edited outside its gofastapi regions")
+
+	// Deleting the edited files hands them back to the generator
+	require.NoError(suite.T(), os.Remove(filepath.Join(outputDir, "main.go")))
+	require.NoError(suite.T(), os.Remove(routesPath))
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes[:2], map[string]*PackageInfo{}, config))
+	assert.NotContains(suite.T(), read("main.go"), "Tuned by hand")
+	assert.NoFileExists(suite.T(), filepath.Join(outputDir, "main.go"+pendingSuffix))
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	cmd := exec.Command(goTool, "vet", "./...")
+	cmd.Dir = outputDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(suite.T(), err, "go vet: %s", output)
+}
+
+// TestOutputPreview tests rendering into an output set, the diff against
+// the disk and the staleness check
+func (suite *TestSuite) TestOutputPreview() {
+	routes := []APIRoute{
+		{Method: "GET", Path: "/users", Function: "ListUsers"},
+	}
+	registry := GetFrameworkRegistry()
+	generator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+	config := generator.GetDefaultConfig()
+
+	outputDir := "./generated-stdlib-api"
+	require.NoError(suite.T(), os.RemoveAll(outputDir))
+	defer os.RemoveAll(outputDir)
+
+	out, err := registry.RenderFramework(FrameworkStdlib, routes, map[string]*PackageInfo{}, config)
+	require.NoError(suite.T(), err)
+	assert.NoDirExists(suite.T(), outputDir, "Rendering writes nothing")
+	assert.Contains(suite.T(), out.Paths(), filepath.Join("generated-stdlib-api", "handlers.go"))
+	assert.Contains(suite.T(), out.Paths(), filepath.Join("generated-stdlib-api", "client", "client.go"))
+	handlers, ok := out.Content(filepath.Join(outputDir, "handlers.go"))
+	require.True(suite.T(), ok)
+	assert.Contains(suite.T(), handlers, "func (s *Server) ListUsersHandler(")
+
+	var diff strings.Builder
+	changed, err := out.Diff(&diff)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), changed, len(out.Paths()), "Every file is missing")
+	assert.Contains(suite.T(), diff.String(), "--- /dev/null\n+++ b/generated-stdlib-api/handlers.go\n@@ -0,0 +1,")
+	assert.Contains(suite.T(), diff.String(), "\n+// gofastapi:checksum ", "The diff shows the content as written")
+	assert.Equal(suite.T(), 1, reportOutput(out, false, true))
+	assert.Equal(suite.T(), 0, reportOutput(out, false, false))
+
+	// Once written, the same output is up to date
+	require.NoError(suite.T(), out.Write())
+	info, err := os.Stat(filepath.Join(outputDir, "handlers.go"))
+	require.NoError(suite.T(), err)
+	changed, err = out.Changes()
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), changed)
+	assert.Equal(suite.T(), 0, reportOutput(out, false, true))
+	require.NoError(suite.T(), out.Write())
+	again, err := os.Stat(filepath.Join(outputDir, "handlers.go"))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), info.ModTime(), again.ModTime(), "Unchanged files are not rewritten")
+
+	// A new route makes the written code stale
+	routes = append(routes, APIRoute{Method: "DELETE", Path: "/users/{id}", Function: "DeleteUser", Responses: []ResponseSpec{{Status: http.StatusNoContent}}})
+	out, err = registry.RenderFramework(FrameworkStdlib, routes, map[string]*PackageInfo{}, config)
+	require.NoError(suite.T(), err)
+	diff.Reset()
+	changed, err = out.Diff(&diff)
+	require.NoError(suite.T(), err)
+	var paths []string
+	for _, file := range changed {
+		assert.True(suite.T(), file.Exists)
+		paths = append(paths, filepath.Base(file.Path))
+	}
+	assert.Contains(suite.T(), paths, "handlers.go")
+	assert.Contains(suite.T(), paths, "routes.go")
+	assert.NotContains(suite.T(), paths, "go.mod")
+	assert.Contains(suite.T(), diff.String(), "--- a/generated-stdlib-api/routes.go\n+++ b/generated-stdlib-api/routes.go\n")
+	assert.Contains(suite.T(), diff.String(), "\n+func (s *Server) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {\n")
+	assert.Equal(suite.T(), 1, reportOutput(out, false, true))
+	assert.NotContains(suite.T(), readFileString(suite.T(), filepath.Join(outputDir, "handlers.go")), "DeleteUserHandler", "Diffing writes nothing")
+
+	// Hand edits show up as conflicts, and writing then changes no file
+	routesPath := filepath.Join(outputDir, "routes.go")
+	edited := readFileString(suite.T(), routesPath) + "\n// Tuned by hand\n"
+	require.NoError(suite.T(), os.WriteFile(routesPath, []byte(edited), 0644))
+	changed, err = out.Changes()
+	require.NoError(suite.T(), err)
+	var conflicts []string
+	for _, file := range changed {
+		if file.Conflict != nil {
+			conflicts = append(conflicts, file.Path)
+		}
+	}
+	assert.Equal(suite.T(), []string{routesPath}, conflicts)
+	err = out.Write()
+	var conflict *EditedFileError
+	require.ErrorAs(suite.T(), err, &conflict)
+	assert.Equal(suite.T(), routesPath, conflict.Path)
+	assert.Equal(suite.T(), edited, readFileString(suite.T(), routesPath))
+	assert.NotContains(suite.T(), readFileString(suite.T(), filepath.Join(outputDir, "handlers.go")), "DeleteUserHandler")
+	assert.FileExists(suite.T(), routesPath+pendingSuffix)
+}
+
+// readFileString returns the content of path
+func readFileString(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestGeneratedCodeFormatting tests that generated Go files are gofmt'd with
+// their imports fixed, that code which does not parse fails generation and
+// that the type check reports errors by route
+func (suite *TestSuite) TestGeneratedCodeFormatting() {
+	routes := []APIRoute{
+		{Method: "GET", Path: "/users", Function: "ListUsers", Parameter: []Parameter{{Name: "limit", Type: "int"}, {Name: "q", Type: "string"}}},
+		{Method: "GET", Path: "/users/{id}", Function: "GetUser", Parameter: []Parameter{{Name: "id", Type: "string"}}, Auth: AuthConfig{Required: true}},
+		{Method: "DELETE", Path: "/users/{id}", Function: "DeleteUser", Parameter: []Parameter{{Name: "id", Type: "string"}}, Responses: []ResponseSpec{{Status: http.StatusNoContent}}},
+	}
+	packages := map[string]*PackageInfo{"models": {Name: "models", Structs: []StructInfo{
+		{Name: "User", Fields: []FieldInfo{{Name: "ID", Type: "int"}, {Name: "Name", Type: "string"}}},
+	}}}
+	registry := GetFrameworkRegistry()
+
+	// Every framework server is formatted and type-checks
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber} {
+		generator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := generator.GetDefaultConfig()
+		config.Auth = &AuthConfig{Required: true}
+		config.TypeCheck = true
+
+		out, err := registry.RenderFramework(frameworkType, routes, packages, config)
+		require.NoError(suite.T(), err, frameworkType)
+		for _, path := range out.Paths() {
+			if !strings.HasSuffix(path, ".go") {
+				continue
+			}
+			content, _ := out.Content(path)
+			formatted, err := format.Source([]byte(content))
+			require.NoError(suite.T(), err, path)
+			assert.Equal(suite.T(), string(formatted), content, "%s is gofmt'd", path)
+		}
+		handlers, _ := out.Content(filepath.Join(fmt.Sprintf("generated-%s-api", frameworkType), "handlers.go"))
+		assert.Contains(suite.T(), handlers, "\t\"time\"\n", "%s handlers import what they use", frameworkType)
+		assert.Contains(suite.T(), handlers, "map[string]interface{}{\"limit\": limit, \"q\": q},\n", "%s stubs use their parameters", frameworkType)
+	}
+
+	// Missing imports are added and unused ones dropped
+	formatted, err := formatGoSource("main.go", "package main\n\nimport (\n\t\"os\"\n\n\t\"github.com/gin-gonic/gin\"\n)\n\nfunc handler(c *gin.Context) {\n\tc.String(http.StatusOK, strings.ToUpper(\"ok\"))\n}\n", nil)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "package main\n\nimport (\n\t\"net/http\"\n\t\"strings\"\n\n\t\"github.com/gin-gonic/gin\"\n)\n\nfunc handler(c *gin.Context) {\n\tc.String(http.StatusOK, strings.ToUpper(\"ok\"))\n}\n", formatted)
+	formatted, err = formatGoSource("models.go", "package main\n\ntype Event struct {\n\tAt time.Time\n}\n", map[string]bool{"Event": true})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "package main\n\nimport (\n\t\"time\"\n)\n\ntype Event struct {\n\tAt time.Time\n}\n", formatted)
+
+	// Code that does not parse fails loudly
+	out := NewOutputSet()
+	out.Add("generated/main.go", "package main\n\nimport (\n\t\"net/http\"\n}\n")
+	err = out.FormatGo()
+	require.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "generated code does not parse")
+	assert.Contains(suite.T(), err.Error(), "main.go:5:1")
+
+	// Type errors name the route whose handler holds them
+	out = NewOutputSet()
+	out.Add("generated/main.go", "package main\n\nimport \"github.com/gin-gonic/gin\"\n\ntype Server struct{}\n\nfunc main() { gin.New() }\n")
+	out.Add("generated/handlers.go", "package main\n\nimport \"github.com/gin-gonic/gin\"\n\nfunc (s *Server) GetUserHandler(c *gin.Context) {\n\tid := c.Param(\"id\")\n}\n\nfunc helper() int { return \"one\" }\n")
+	report := out.TypeCheck(routes)
+	assert.Equal(suite.T(), []string{"github.com/gin-gonic/gin"}, report.Skipped)
+	require.Len(suite.T(), report.Errors, 2)
+	assert.Equal(suite.T(), "GET /users/{id}", report.Errors[0].Route)
+	assert.Contains(suite.T(), report.Errors[0].Error(), "handlers.go:6:2: declared and not used: id (route GET /users/{id})")
+	assert.Empty(suite.T(), report.Errors[1].Route)
+	require.Error(suite.T(), report.Err())
+	assert.Contains(suite.T(), report.Err().Error(), "generated code does not type-check")
+}
+
+// TestTemplates tests the template data of a route and that override
+// templates shadow the built-in ones
+func (suite *TestSuite) TestTemplates() {
+	routes := []APIRoute{
+		{Method: "get", Path: "/users/{id}", Function: "GetUser", Parameter: []Parameter{{Name: "id", Type: "string"}}, Auth: AuthConfig{Required: true},
+			Docs: RouteDocs{Params: []DocParam{{Name: "id", Description: "User ID"}, {Name: "X-Tenant", In: "header"}}}},
+		{Method: "POST", Path: "/users", Function: "CreateUser", Responses: []ResponseSpec{{Status: http.StatusCreated}}},
+	}
+	config := (&GinGenerator{}).GetDefaultConfig()
+	config.Auth = &AuthConfig{Required: true}
+
+	// The data model names the generated code the way every template needs
+	data := newTemplateData(FrameworkGin, routes, nil, config)
+	assert.True(suite.T(), data.Auth)
+	assert.True(suite.T(), data.AnySecured())
+	assert.Equal(suite.T(), "/swagger", data.DocsPath)
+	get := data.Routes[0]
+	assert.Equal(suite.T(), "GET", get.Verb)
+	assert.Equal(suite.T(), "/users/:id", get.ColonPath)
+	assert.Equal(suite.T(), "/users/{id}", get.Pattern)
+	assert.Equal(suite.T(), "GetUserHandler", get.Handler)
+	assert.Equal(suite.T(), []string{"id"}, get.Params)
+	assert.True(suite.T(), get.Secured)
+	assert.Equal(suite.T(), []DocParam{{Name: "id", Type: "string", Description: "User ID"}, {Name: "X-Tenant", In: "header"}}, get.DocParams)
+	assert.Equal(suite.T(), TemplateTest{Name: "TestGetUser", Path: "/api/v1/users/{id}", Target: "/api/v1/users/123", Check: "json", Status: http.StatusOK, Response: "map[string]interface{}"}, get.Test)
+	assert.Equal(suite.T(), "{}", data.Routes[1].Test.Body)
+	assert.False(suite.T(), data.Routes[1].Secured)
+
+	// Every framework renders each of its files from a framework or shared template
+	templates, err := loadTemplates("")
+	require.NoError(suite.T(), err)
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber} {
+		for _, file := range []string{"main.go", "middleware.go", "handlers.go", "routes.go", "models.go", "handlers_test.go", "docs.md", "Dockerfile", "k8s/deployment.yaml"} {
+			found := templates.Lookup(string(frameworkType)+"/"+file+".tmpl") != nil || templates.Lookup("common/"+file+".tmpl") != nil
+			assert.True(suite.T(), found, "%s has a template for %s", frameworkType, file)
+		}
+	}
+
+	// An override directory shadows single templates and adds framework ones
+	dir, err := os.MkdirTemp("", "gofastapi-templates-*")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		require.NoError(suite.T(), os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("gin/handler_stub.tmpl", "\tc.JSON(http.StatusOK, s.lookup({{quote .Function}}))\n")
+	write("echo/docs.md.tmpl", "# {{.Title}} routes\n{{range .Routes}}- {{.Verb}} {{.Path}}\n{{end}}")
+	config.Templates = dir
+
+	handlers, err := (&GinGenerator{}).GenerateHandlers(routes, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), handlers, "c.JSON(http.StatusOK, s.lookup(\"GetUser\"))")
+	assert.NotContains(suite.T(), handlers, "auto_generated")
+	assert.Contains(suite.T(), handlers, "// gofastapi:begin GetUserHandler ", "Overridden stubs stay user regions")
+	routesContent, err := (&GinGenerator{}).GenerateRoutes(routes, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), routesContent, "auth.GET(\"/users/:id\", s.GetUserHandler)", "Other templates stay built in")
+
+	echoConfig := (&EchoGenerator{}).GetDefaultConfig()
+	echoConfig.Templates = dir
+	docs, err := (&EchoGenerator{}).GenerateDocs(routes, echoConfig)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "# Echo routes\n- GET /users/{id}\n- POST /users\n", docs)
+	docs, err = (&ChiGenerator{}).GenerateDocs(routes, (&ChiGenerator{}).GetDefaultConfig())
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), docs, "- `id` (string): User ID\n- `X-Tenant` in header\n", "Chi keeps the shared docs template")
+
+	// Broken overrides fail generation with the template at fault
+	write("gin/routes.go.tmpl", "package main\n\nfunc (s *Server) setupRoutes() {\n")
+	_, err = (&GinGenerator{}).GenerateRoutes(routes, config)
+	require.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "template gin/routes.go.tmpl does not render valid Go")
+	write("gin/routes.go.tmpl", "{{range .Routes}")
+	_, err = (&GinGenerator{}).GenerateRoutes(routes, config)
+	require.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "failed to load templates from "+dir)
+	config.Templates = filepath.Join(dir, "missing")
+	_, err = (&GinGenerator{}).GenerateMainFile(routes, config)
+	assert.Error(suite.T(), err)
+}
+
+// TestHandlerNames tests that services sharing a method name get handlers
+// of their own, named after the receiver, in every framework server
+func (suite *TestSuite) TestHandlerNames() {
+	src := `package shop
+
+import "context"
+
+type OrderService struct{}
+
+// @api.endpoint("/orders/{id}/name")
+// @api.method(GET)
+func (s *OrderService) GetName(ctx context.Context, id string) (string, error) { return "", nil }
+
+type UserService struct{}
+
+// @api.endpoint("/users/{id}/name")
+// @api.method(GET)
+func (s *UserService) GetName(ctx context.Context, id string) (string, error) { return "", nil }
+
+// @api.endpoint("/users/{id}")
+// @api.method(GET)
+func (s *UserService) GetUser(ctx context.Context, id string) (string, error) { return "", nil }
+`
+	dir := filepath.Join(suite.tempDir, "handler-names")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{ScanAnnotations: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+	routes := generator.GenerateAPIRoutes()
+	require.Len(suite.T(), routes, 3)
+
+	registry := GetFrameworkRegistry()
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
+		generator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := generator.GetDefaultConfig()
+		config.TypeCheck = true
+
+		// Repeated handler names would fail the type check
+		out, err := registry.RenderFramework(frameworkType, routes, nil, config)
+		require.NoError(suite.T(), err, frameworkType)
+		outputDir := fmt.Sprintf("generated-%s-api", frameworkType)
+		handlers, _ := out.Content(filepath.Join(outputDir, "handlers.go"))
+		for _, name := range []string{"OrderGetNameHandler", "UserGetNameHandler", "GetUserHandler"} {
+			assert.Contains(suite.T(), handlers, ") "+name+"(", "%s declares %s", frameworkType, name)
+		}
+		tests, _ := out.Content(filepath.Join(outputDir, "handlers_test.go"))
+		assert.Contains(suite.T(), tests, "func TestOrderGetName(t *testing.T)", frameworkType)
+		assert.Contains(suite.T(), tests, "func TestUserGetName(t *testing.T)", frameworkType)
+	}
+
+	// Names that still repeat are numbered
+	names := handlerNames([]APIRoute{
+		{Struct: "UserService", Function: "GetName"},
+		{Struct: "UserService", Function: "GetName"},
+		{Struct: "Order", Function: "ListOrders"},
+		{Function: "health_check"},
+	})
+	assert.Equal(suite.T(), []string{"UserGetNameHandler", "UserGetName2Handler", "ListOrdersHandler", "HealthCheckHandler"}, names)
+}
+
+// TestProjectConfig tests loading gofastapi.yaml and gofastapi.json: the
+// defaults, sections layered over the framework defaults, environment
+// overlays and variables, validation and the scan patterns
+func (suite *TestSuite) TestProjectConfig() {
+	dir, err := os.MkdirTemp("", "gofastapi-project-*")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	// Without a project file main used to hard-code these settings
+	project, err := NewProjectConfig("")
+	require.NoError(suite.T(), err)
+	config := project.GeneratorConfig()
+	assert.Equal(suite.T(), []string{"."}, config.ScanRoots())
+	assert.Equal(suite.T(), []string{"*.go"}, config.IncludePatterns)
+	assert.Equal(suite.T(), "./generated-api", config.OutputDir)
+	assert.Equal(suite.T(), "autogenerated-api", config.PackageName)
+	assert.True(suite.T(), config.AutoCRUD && config.SmartMapping && config.ScanAnnotations)
+	assert.Equal(suite.T(), FrameworkGin, project.FrameworkConfig().Type, "The default server is rendered from the Gin templates")
+	assert.Equal(suite.T(), "./generated-api", project.FrameworkConfig().OutputDir)
+	assert.Equal(suite.T(), "autogenerated-api", project.FrameworkConfig().Module)
+	project, err = NewProjectConfig(FrameworkEcho)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "./generated-echo-api", project.FrameworkConfig().OutputDir)
+	assert.Equal(suite.T(), "generated-echo-api", project.FrameworkConfig().Module)
+
+	// Sections are layered over the defaults and overlays over the file
+	write("src/api/user.go", "package api\n\ntype User struct {\n\tID string `json:\"id\"`\n}\n\nfunc (u *User) GetUser(id string) (*User, error) { return u, nil }\n")
+	write("src/api/user_test.go", "package api\n\ntype Fixture struct{}\n")
+	write("src/vendor/lib/lib.go", "package lib\n\ntype Vendored struct{}\n")
+	write("src/server/main.go", "package main\n\ntype Server struct{}\n")
+	file := write("gofastapi.yaml", `roots: [src]
+framework: gin
+module: example.com/api
+output_dir: src/server
+features:
+  typecheck: ${GOFASTAPI_TEST_TYPECHECK:-false}
+cors:
+  allow_origins: ["${GOFASTAPI_TEST_ORIGIN}"]
+  max_age: ${GOFASTAPI_TEST_MAX_AGE:-600}
+database:
+  type: memory
+  password: "${GOFASTAPI_TEST_PASSWORD}"
+docs:
+  path: /reference
+environments:
+  production:
+    cors:
+      allow_origins: [https://example.com]
+    database:
+      type: postgres
+    deployment:
+      type: kubernetes
+`)
+	suite.T().Setenv("GOFASTAPI_TEST_ORIGIN", "http://localhost:3000")
+	suite.T().Setenv("GOFASTAPI_TEST_MAX_AGE", "")
+	suite.T().Setenv("GOFASTAPI_TEST_PASSWORD", "0123")
+
+	project, err = LoadProjectConfig(file, ProjectOptions{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{filepath.Join(dir, "src")}, project.Roots)
+	assert.Equal(suite.T(), filepath.Join(dir, "src", "server"), project.OutputDir)
+	framework := project.FrameworkConfig()
+	require.NotNil(suite.T(), framework)
+	assert.Equal(suite.T(), FrameworkGin, framework.Type)
+	assert.Equal(suite.T(), "example.com/api", framework.Module)
+	assert.False(suite.T(), framework.TypeCheck)
+	assert.Equal(suite.T(), []string{"http://localhost:3000"}, framework.CORS.AllowOrigins)
+	assert.Equal(suite.T(), 600, framework.CORS.MaxAge, "Empty variables take their default")
+	assert.True(suite.T(), framework.CORS.Enabled, "Unset fields keep the gin default")
+	assert.Equal(suite.T(), "0123", framework.Database.Password, "Quoted values stay strings")
+	assert.Equal(suite.T(), "/reference", framework.Docs.Path)
+	assert.Equal(suite.T(), "swagger", framework.Docs.Format)
+	assert.Nil(suite.T(), framework.Deployment)
+
+	project, err = LoadProjectConfig(file, ProjectOptions{Env: "production", Framework: FrameworkChi})
+	require.NoError(suite.T(), err)
+	framework = project.FrameworkConfig()
+	assert.Equal(suite.T(), "production", project.Environment)
+	assert.Equal(suite.T(), FrameworkChi, framework.Type)
+	assert.Equal(suite.T(), []string{"https://example.com"}, framework.CORS.AllowOrigins)
+	assert.Equal(suite.T(), "postgres", framework.Database.Type)
+	assert.Equal(suite.T(), "0123", framework.Database.Password, "Overlays merge mappings key by key")
+	assert.Equal(suite.T(), "openapi", framework.Docs.Format, "Sections are layered over the chi defaults")
+	assert.Equal(suite.T(), "kubernetes", framework.Deployment.Type)
+
+	// Patterns match below the roots and the output directory is skipped
+	generator := NewAPIGenerator(project.GeneratorConfig())
+	for _, root := range project.GeneratorConfig().ScanRoots() {
+		require.NoError(suite.T(), generator.ScanDirectory(root))
+	}
+	var structs []string
+	for _, pkg := range generator.pkgs {
+		for _, structInfo := range pkg.Structs {
+			structs = append(structs, structInfo.Name)
+		}
+	}
+	assert.Equal(suite.T(), []string{"User"}, structs)
+	assert.True(suite.T(), matchScanPattern("*_test.go", "api/user_test.go"))
+	assert.True(suite.T(), matchScanPattern("vendor/*", "vendor/lib/lib.go"))
+	assert.True(suite.T(), matchScanPattern("./server/*", "server/main.go"))
+	assert.False(suite.T(), matchScanPattern("vendor/*", "api/vendor.go"))
+
+	// The server is written to the configured directory and module
+	out, err := GetFrameworkRegistry().RenderFramework(framework.Type, generator.GenerateAPIRoutes(), generator.pkgs, framework)
+	require.NoError(suite.T(), err)
+	goMod, ok := out.Content(filepath.Join(dir, "src", "server", "go.mod"))
+	require.True(suite.T(), ok)
+	assert.True(suite.T(), strings.HasPrefix(goMod, "module example.com/api\n"))
+
+	// JSON files take the same settings
+	jsonFile := write("json/gofastapi.json", `{"include": ["api/*.go"], "features": {"auto_crud": false}, "cors": {"max_age": 60}, "templates": "."}`)
+	project, err = LoadProjectConfig(jsonFile, ProjectOptions{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"api/*.go"}, project.Include)
+	assert.False(suite.T(), project.Features.AutoCRUD)
+	assert.True(suite.T(), project.Features.SmartMapping)
+	assert.Equal(suite.T(), filepath.Join(dir, "json", "generated-api"), project.OutputDir)
+
+	// Without a framework the gin server is generated, templates included
+	framework = project.FrameworkConfig()
+	assert.Equal(suite.T(), FrameworkGin, framework.Type)
+	assert.Equal(suite.T(), 60, framework.CORS.MaxAge)
+	assert.True(suite.T(), framework.CORS.Enabled, "Sections are layered over the gin defaults")
+	assert.Equal(suite.T(), filepath.Join(dir, "json"), framework.Templates)
+	_, err = LoadProjectConfig(write("json/bad.json", `{"roots": [".",]}`), ProjectOptions{})
+	assert.ErrorContains(suite.T(), err, "failed to parse")
+
+	// Problems are reported with their field and, from the file, their line
+	invalid := []struct {
+		content string
+		options ProjectOptions
+		errors  []string
+	}{
+		{"cors:\n  alow_origins: [x]\nenvironments:\n  qa:\n    outputdir: x\n", ProjectOptions{},
+			[]string{"line 2: cors.alow_origins: unknown field", "line 5: environments.qa.outputdir: unknown field"}},
+		{"module: ${GOFASTAPI_TEST_UNSET}\n", ProjectOptions{}, []string{"line 1: module: environment variable GOFASTAPI_TEST_UNSET is not set"}},
+		{"environments:\n  qa: {}\n", ProjectOptions{Env: "production"}, []string{`environment "production" is not defined, expected one of: qa`}},
+		{"cors:\n  max_age: many\n", ProjectOptions{}, []string{"failed to parse", "line 2"}},
+		{"framework: gim\ndocs:\n  format: pdf\ncors:\n  allow_methods: [FETCH]\ndatabase:\n  type: mongo\nroots: [missing]\n", ProjectOptions{},
+			[]string{`framework: unsupported framework "gim", expected one of: chi, echo, fiber, gin, stdlib`, `docs.format: unsupported format "pdf"`,
+				`cors.allow_methods: unknown HTTP method "FETCH"`, `database.type: unsupported database type "mongo"`, "roots: " + filepath.Join(dir, "missing") + " is not a directory"}},
+	}
+	for _, tc := range invalid {
+		_, err := LoadProjectConfig(write("invalid.yaml", tc.content), tc.options)
+		require.Error(suite.T(), err, tc.content)
+		for _, message := range tc.errors {
+			assert.Contains(suite.T(), err.Error(), message)
+		}
+	}
+}
+
+// TestDocsExplorer tests that every generator mounts the embedded API explorer
+func (suite *TestSuite) TestDocsExplorer() {
+	registry := GetFrameworkRegistry()
+	routes := []APIRoute{{Method: "GET", Path: "/users", Function: "ListUsers"}}
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
+		generator, err := registry.GetGenerator(frameworkType)
+		require.NoError(suite.T(), err)
+		config := generator.GetDefaultConfig()
+		config.Docs.Path = "/explorer/"
+
+		routesContent, err := generator.GenerateRoutes(routes, config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), routesContent, "s.setupDocs()", frameworkType)
+
+		docsContent, err := GenerateDocsServer(config)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), docsContent, "//go:embed docs/index.html docs/openapi.json", frameworkType)
+		assert.Contains(suite.T(), docsContent, `const docsPath = "/explorer"`, frameworkType)
+
+		config.Docs.Enabled = false
+		routesContent, err = generator.GenerateRoutes(routes, config)
+		require.NoError(suite.T(), err)
+		assert.NotContains(suite.T(), routesContent, "s.setupDocs()", frameworkType)
+	}
+
+	assert.NotRegexp(suite.T(), `(src|href)="(https?:)?//`, explorerHTML, "The explorer must not load external assets")
+	assert.Contains(suite.T(), explorerHTML, `fetch(base + "/openapi.json")`)
+}
+
+// TestRouteTable tests route merging by method and path with source precedence
+func (suite *TestSuite) TestRouteTable() {
+	at := func(line int) token.Position { return token.Position{Filename: "users.go", Line: line, Offset: line * 10} }
+	table := NewRouteTable([]APIRoute{
+		{Method: "GET", Path: "/users/{id}", Struct: "User", Function: "GetUser", Source: RouteSourceCRUD, Pos: at(3)},
+		{Method: "GET", Path: "/users/{userID}", Struct: "UserService", Function: "GetUser", Source: RouteSourceSmart, Pos: at(10)},
+		{Method: "GET", Path: "/users/{id}", Function: "FetchUser", Source: RouteSourceAnnotation, Pos: at(20)},
+		{Method: "DELETE", Path: "/users/{id}", Struct: "UserService", Function: "DeleteUser", Source: RouteSourceSmart, Pos: at(12)},
+		{Method: "DELETE", Path: "/users/:id", Struct: "UserService", Function: "RemoveUser", Source: RouteSourceSmart, Pos: at(14)},
+	})
+
+	require.Len(suite.T(), table.Routes, 2)
+	assert.Equal(suite.T(), "FetchUser", table.Routes[0].Function, "Annotated route should win")
+	assert.Equal(suite.T(), "DeleteUser", table.Routes[1].Function, "First declared route should be kept")
+	assert.Len(suite.T(), table.Shadowed, 2)
+
+	require.Len(suite.T(), table.Conflicts, 1)
+	diagnostic := table.Conflicts[0].Diagnostic()
+	assert.Equal(suite.T(), DiagRouteConflict, diagnostic.Code)
+	assert.Equal(suite.T(), 14, diagnostic.Pos.Line, "Colon wildcards conflict with braced ones")
+	assert.Contains(suite.T(), diagnostic.Message, "users.go:12")
+
+	// Struct-level routes name no handler and never reach the generators
+	table = NewRouteTable([]APIRoute{
+		{Path: "/tasks", Struct: "TaskService", Methods: []string{"GET", "POST"}, Source: RouteSourceAnnotation, Pos: at(30)},
+		{Method: "GET", Path: "/tasks", Struct: "TaskService", Function: "ListTasks", Source: RouteSourceSmart, Pos: at(32)},
+	})
+	require.Len(suite.T(), table.Routes, 1)
+	assert.Equal(suite.T(), "ListTasks", table.Routes[0].Function)
+	require.Len(suite.T(), table.Unrouted, 1)
+	diagnostics := table.Diagnostics()
+	require.Len(suite.T(), diagnostics, 1)
+	assert.Equal(suite.T(), DiagUnrouted, diagnostics[0].Code)
+	assert.Equal(suite.T(), SeverityWarning, diagnostics[0].Severity)
+	assert.Equal(suite.T(), 30, diagnostics[0].Pos.Line)
+
+	// Overlapping smart and CRUD routes of a scanned package
+	src := `package gen
+
+type GinGenerator struct{}
+
+func (g *GinGenerator) GetName() string { return "gin" }
+
+func (g *GinGenerator) GetType() string { return "gin" }
+`
+	dir := filepath.Join(suite.tempDir, "routetable")
+	require.NoError(suite.T(), os.MkdirAll(dir, 0755))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "gen.go"), []byte(src), 0644))
+
+	generator := NewAPIGenerator(&GeneratorConfig{AutoCRUD: true, SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(dir))
+
+	seen := make(map[string]bool)
+	for _, route := range generator.GenerateAPIRoutes() {
+		key := routeKey(route.Method, route.Path)
+		assert.False(suite.T(), seen[key], "Route %s should appear once", key)
+		seen[key] = true
+	}
+
+	conflicts := generator.BuildRouteTable().Conflicts
+	if assert.Len(suite.T(), conflicts, 1) {
+		assert.Equal(suite.T(), "GetName", conflicts[0].Kept.Function)
+		assert.Equal(suite.T(), "GetType", conflicts[0].Dropped.Function)
+		assert.Equal(suite.T(), 5, conflicts[0].Kept.Pos.Line)
+		assert.Equal(suite.T(), 7, conflicts[0].Dropped.Pos.Line)
+	}
+}
+
+// TestWiredHandlers tests that wiring mode generates handlers calling the scanned methods
+func (suite *TestSuite) TestWiredHandlers() {
+	root := filepath.Join(suite.tempDir, "wired")
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"catalog/catalog.go": `package catalog
+
+import "context"
+
+type Product struct {
+	ID   string ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type ProductService struct{ products map[string]Product }
+
+func NewProductService() (*ProductService, error) {
+	return &ProductService{products: map[string]Product{}}, nil
+}
+
+func (ps *ProductService) GetProduct(ctx context.Context, id string) (*Product, error) {
+	product := ps.products[id]
+	return &product, nil
+}
+
+func (ps *ProductService) CreateProduct(product *Product) (*Product, error) {
+	ps.products[product.ID] = *product
+	return product, nil
+}
+
+func (ps *ProductService) ListProducts(limit int, offset int) ([]Product, error) {
+	return nil, nil
+}
+`,
+		"catalog/category.go": `package catalog
+
+import "context"
+
+type CategoryService struct{}
+
+func NewCategoryService() *CategoryService { return &CategoryService{} }
+
+func (cs *CategoryService) GetProduct(ctx context.Context, id string) (*Product, error) {
+	return nil, nil
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+
+	generator := NewAPIGenerator(&GeneratorConfig{SmartMapping: true})
+	require.NoError(suite.T(), generator.ScanDirectory(root))
+	routes := generator.GenerateAPIRoutes()
+
+	bindings := make(map[string]*RouteBinding)
 	for _, route := range routes {
-		assert.NotEmpty(suite.T(), route.Method, "Route should have HTTP method")
-		assert.NotEmpty(suite.T(), route.Path, "Route should have path")
-		assert.NotEmpty(suite.T(), route.Function, "Route should have function name")
-		assert.NotEmpty(suite.T(), route.Struct, "Route should have struct name")
+		if route.Binding != nil {
+			bindings[route.Function] = route.Binding
+		}
+	}
+	require.Contains(suite.T(), bindings, "GetProduct")
+	get := bindings["GetProduct"]
+	assert.Equal(suite.T(), "example.com/shop/catalog", get.ImportPath)
+	assert.Equal(suite.T(), SourceContext, get.Params[0].Source)
+	assert.Equal(suite.T(), SourcePath, get.Params[1].Source)
+	assert.True(suite.T(), get.ReturnsError)
+	require.Contains(suite.T(), bindings, "CreateProduct")
+	assert.Equal(suite.T(), SourceBody, bindings["CreateProduct"].Params[0].Source)
+	require.Contains(suite.T(), bindings, "ListProducts")
+	assert.Equal(suite.T(), SourceQuery, bindings["ListProducts"].Params[0].Source)
+
+	config := &FrameworkConfig{Type: FrameworkGin, Wiring: &WiringConfig{Enabled: true}}
+	handlers, err := (&GinGenerator{}).GenerateHandlers(routes, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), handlers, "services.ProductService.GetProduct(c.Request.Context(), idParam)")
+	assert.Contains(suite.T(), handlers, "var productParam catalog.Product")
 
-		// Verify auto-generated metadata
-		assert.True(suite.T(), route.Metadata["auto_generated"].(bool),
-			"Route should be marked as auto-generated")
+	// A method shared by two services is wired into a handler per service
+	assert.Contains(suite.T(), handlers, "func (s *Server) GetProductHandler(c *gin.Context)")
+	assert.Contains(suite.T(), handlers, "func (s *Server) CategoryGetProductHandler(c *gin.Context)")
+	assert.Contains(suite.T(), handlers, "services.CategoryService.GetProduct(c.Request.Context(), idParam)")
+	assert.NotContains(suite.T(), handlers, "auto_generated")
+
+	services, replaces, err := GenerateServices(routes, generator.pkgs, config.Wiring)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), services, "catalog.NewProductService()")
+	assert.Contains(suite.T(), replaces, "example.com/shop")
+
+	// Both files must be valid Go
+	for name, content := range map[string]string{"handlers.go": handlers, "wiring.go": services} {
+		_, err := parser.ParseFile(token.NewFileSet(), name, content, 0)
+		assert.NoError(suite.T(), err, "%s should parse", name)
 	}
 }
 
@@ -390,6 +3133,7 @@ func (suite *TestSuite) TestFrameworkGenerators() {
 	assert.Contains(suite.T(), frameworks, FrameworkEcho)
 	assert.Contains(suite.T(), frameworks, FrameworkChi)
 	assert.Contains(suite.T(), frameworks, FrameworkFiber)
+	assert.Contains(suite.T(), frameworks, FrameworkStdlib)
 
 	testRoutes := []APIRoute{
 		{
@@ -404,7 +3148,7 @@ func (suite *TestSuite) TestFrameworkGenerators() {
 	}
 
 	// Test each framework generator
-	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber} {
+	for _, frameworkType := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkChi, FrameworkFiber, FrameworkStdlib} {
 		generator, err := registry.GetGenerator(frameworkType)
 		require.NoError(suite.T(), err)
 
@@ -426,6 +3170,54 @@ func (suite *TestSuite) TestFrameworkGenerators() {
 	}
 }
 
+// TestStdlibGenerator tests that the net/http server has no dependencies
+// and that it builds and passes its own tests
+func (suite *TestSuite) TestStdlibGenerator() {
+	routes := []APIRoute{
+		{Method: "GET", Path: "/users", Function: "ListUsers"},
+		{Method: "GET", Path: "/users/:id", Function: "GetUser", Auth: AuthConfig{Required: true}},
+		{Method: "POST", Path: "/users", Function: "CreateUser", Responses: []ResponseSpec{{Status: http.StatusCreated}}},
+		{Method: "DELETE", Path: "/users/{id}", Function: "DeleteUser", Responses: []ResponseSpec{{Status: http.StatusNoContent}}},
+		{Method: "GET", Path: "/users/{userID}", Function: "FindUser"},
+		{Method: "GET", Path: "/admins/{id}", Struct: "AdminService", Function: "GetUser"},
+		{Path: "/admin", Struct: "AdminService"},
+	}
+
+	registry := GetFrameworkRegistry()
+	generator, err := registry.GetGenerator(FrameworkStdlib)
+	require.NoError(suite.T(), err)
+
+	config := generator.GetDefaultConfig()
+	config.Auth = &AuthConfig{Required: true, Type: "jwt"}
+	routesContent, err := generator.GenerateRoutes(routes, config)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), routesContent, `s.mux.HandleFunc("GET /users", s.ListUsersHandler)`)
+	assert.Contains(suite.T(), routesContent, `s.mux.Handle("GET /users/{id}", AuthMiddleware(s.config.JWTSecret)(http.HandlerFunc(s.GetUserHandler)))`)
+	assert.NotContains(suite.T(), routesContent, "FindUserHandler", "Duplicate patterns would make ServeMux panic")
+	assert.Contains(suite.T(), routesContent, `s.mux.HandleFunc("GET /admins/{id}", s.AdminGetUserHandler)`, "A shared method name is no duplicate")
+
+	outputDir := "./generated-stdlib-api"
+	defer os.RemoveAll(outputDir)
+	require.NoError(suite.T(), registry.GenerateForFramework(FrameworkStdlib, routes, map[string]*PackageInfo{}, config))
+
+	goMod, err := os.ReadFile(filepath.Join(outputDir, "go.mod"))
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), string(goMod), "go 1.22")
+	assert.NotContains(suite.T(), string(goMod), "require")
+
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		suite.T().Skip("Skipping build of the generated server")
+	}
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = outputDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+		output, err := cmd.CombinedOutput()
+		assert.NoError(suite.T(), err, "go %s: %s", args[0], output)
+	}
+}
+
 // TestPerformance tests performance characteristics
 func (suite *TestSuite) TestPerformance() {
 	// Create a large test file with many structs and methods
@@ -722,11 +3514,6 @@ func createDirectory(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
-// Helper function to write file
-func writeFile(path, content string) error {
-	return os.WriteFile(path, []byte(content), 0644)
-}
-
 // Helper function to assert directory exists
 func assertDirExists(t *testing.T, path string, msgAndArgs ...interface{}) {
 	info, err := os.Stat(path)
@@ -1612,7 +4399,7 @@ func cleanupTest() {
 		os.RemoveAll(testDir)
 	}
 
-	for _, framework := range []string{"gin", "echo", "chi", "fiber"} {
+	for _, framework := range []string{"gin", "echo", "chi", "fiber", "stdlib"} {
 		if outputDir := fmt.Sprintf("./generated-%s-api", framework); os.Getenv("TEST_ENV") == "true" {
 			os.RemoveAll(outputDir)
 		}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TypeError is a type error in generated code. Route names the route whose
+// handler or test holds the error, and is empty for shared code.
+type TypeError struct {
+	Pos   token.Position
+	Msg   string
+	Route string
+}
+
+func (e TypeError) Error() string {
+	if e.Route == "" {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s (route %s)", e.Pos, e.Msg, e.Route)
+}
+
+// TypeCheckReport is the outcome of type-checking generated code. Imports
+// that are not in the standard library cannot be resolved offline: they
+// are listed in Skipped and the code using them is checked only as far as
+// it does not depend on them.
+type TypeCheckReport struct {
+	Errors  []TypeError
+	Skipped []string
+}
+
+// Err returns the errors of the report as one error, or nil
+func (r *TypeCheckReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	errs := make([]error, len(r.Errors))
+	for i, typeErr := range r.Errors {
+		errs[i] = typeErr
+	}
+	return fmt.Errorf("generated code does not type-check:\n%v", errors.Join(errs...))
+}
+
+// typeCheckCache holds the file set and source importer shared by type
+// checks, so the standard library is type-checked from source only once
+var typeCheckCache struct {
+	sync.Mutex
+	fset   *token.FileSet
+	source types.ImporterFrom
+}
+
+// offlineImporter type-checks standard library imports from source and
+// refuses every other import, which would need the module cache or the
+// network. It records the refused paths.
+type offlineImporter struct {
+	source  types.ImporterFrom
+	skipped map[string]bool
+}
+
+func (i *offlineImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+func (i *offlineImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if !isStdlibPath(path) {
+		// The type checker treats a package returned with an error as a
+		// stand-in whose uses it does not check, under the returned name
+		i.skipped[path] = true
+		name, _ := importName(path)
+		return types.NewPackage(path, name), fmt.Errorf("%s is not checked offline", path)
+	}
+	return i.source.ImportFrom(path, dir, mode)
+}
+
+// routeFunctions maps the names of the generated functions that belong to a
+// route, its handler and its test, to the route
+func routeFunctions(routes []APIRoute) map[string]string {
+	functions := make(map[string]string)
+	handlers := handlerNames(routes)
+	for i, route := range routes {
+		name := fmt.Sprintf("%s %s", strings.ToUpper(route.Method), route.Path)
+		functions[route.Function] = name
+		functions[handlers[i]] = name
+		functions["Test"+strings.TrimSuffix(handlers[i], "Handler")] = name
+	}
+	return functions
+}
+
+// TypeCheck type-checks each Go package of the set, tests included, and
+// maps the errors back to the routes that produced them
+func (o *OutputSet) TypeCheck(routes []APIRoute) *TypeCheckReport {
+	typeCheckCache.Lock()
+	defer typeCheckCache.Unlock()
+	if typeCheckCache.fset == nil {
+		typeCheckCache.fset = token.NewFileSet()
+		typeCheckCache.source = newImporter(typeCheckCache.fset, "").(types.ImporterFrom)
+	}
+	fset := typeCheckCache.fset
+
+	packages := make(map[string][]string)
+	var keys []string
+	files := make(map[string]*ast.File)
+	for _, filename := range o.Paths() {
+		if !strings.HasSuffix(filename, ".go") {
+			continue
+		}
+		file, err := parseGoSource(fset, filename, o.files[filename])
+		if err != nil {
+			// FormatGo reports syntax errors before type-checking
+			continue
+		}
+		files[filename] = file
+		key := filepath.Dir(filename) + " " + file.Name.Name
+		if _, ok := packages[key]; !ok {
+			keys = append(keys, key)
+		}
+		packages[key] = append(packages[key], filename)
+	}
+	sort.Strings(keys)
+
+	report := &TypeCheckReport{}
+	imp := &offlineImporter{
+		source:  typeCheckCache.source,
+		skipped: make(map[string]bool),
+	}
+	functions := routeFunctions(routes)
+	for _, key := range keys {
+		var pkgFiles []*ast.File
+		for _, filename := range packages[key] {
+			pkgFiles = append(pkgFiles, files[filename])
+		}
+		conf := types.Config{
+			Importer:    imp,
+			FakeImportC: true,
+			Error: func(err error) {
+				typeErr, ok := err.(types.Error)
+				if !ok {
+					report.Errors = append(report.Errors, TypeError{Msg: err.Error()})
+					return
+				}
+				// A refused import is listed once as skipped
+				if strings.HasPrefix(typeErr.Msg, "could not import ") {
+					return
+				}
+				report.Errors = append(report.Errors, TypeError{
+					Pos:   fset.Position(typeErr.Pos),
+					Msg:   typeErr.Msg,
+					Route: functions[enclosingFunction(pkgFiles, typeErr.Pos)],
+				})
+			},
+		}
+		conf.Check(strings.Fields(key)[0], fset, pkgFiles, nil)
+	}
+
+	for path := range imp.skipped {
+		report.Skipped = append(report.Skipped, path)
+	}
+	sort.Strings(report.Skipped)
+	return report
+}
+
+// checkOutput type-checks out, logging the imports the check skipped
+func checkOutput(out *OutputSet, routes []APIRoute) error {
+	report := out.TypeCheck(routes)
+	if len(report.Skipped) > 0 {
+		log.Printf("Type check skipped imports outside the standard library: %s", strings.Join(report.Skipped, ", "))
+	}
+	return report.Err()
+}
+
+// enclosingFunction returns the name of the top-level function or method
+// declared around pos, or "" when pos is outside any
+func enclosingFunction(files []*ast.File, pos token.Pos) string {
+	for _, file := range files {
+		if pos < file.FileStart || pos > file.FileEnd {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Pos() <= pos && pos <= fn.End() {
+				return fn.Name.Name
+			}
+		}
+	}
+	return ""
+}
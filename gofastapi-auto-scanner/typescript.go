@@ -0,0 +1,428 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tsIdentifier matches property names that need no quotes
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsIdentifierToken matches the identifiers inside a type
+var tsIdentifierToken = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// tsReservedWords cannot name client functions or parameters
+var tsReservedWords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+	"implements": true, "interface": true, "let": true, "package": true, "private": true,
+	"protected": true, "public": true, "static": true, "yield": true, "await": true,
+	"arguments": true, "eval": true, "config": true, "body": true, "query": true, "request": true,
+}
+
+// tsClientIdentifiers are declared by the generated client module and
+// cannot be used for types
+var tsClientIdentifiers = []string{"ClientConfig", "APIError", "Query"}
+
+// GenerateTypeScript returns the TypeScript sources of a client for routes:
+// types.ts declares an interface per scanned struct the routes refer to and
+// client.ts a fetch-based function per route
+func GenerateTypeScript(routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) map[string]string {
+	g := &tsGenerator{
+		types:  newOpenAPIBuilder(packages),
+		prefix: routePrefix(config),
+		names:  make(map[string]bool),
+		used:   make(map[string]bool),
+		title:  strings.Title(string(config.Type)),
+	}
+	for _, name := range tsClientIdentifiers {
+		g.types.taken[name] = true
+	}
+
+	var functions strings.Builder
+	for _, route := range stdlibRoutes(routes) {
+		functions.WriteString(g.function(route))
+	}
+
+	var client strings.Builder
+	client.WriteString(fmt.Sprintf("// Client for the generated %s API. It is generated together with the\n", g.title))
+	client.WriteString("// server; regenerate both after changing routes.\n\n")
+	if len(g.used) > 0 {
+		var used []string
+		for name := range g.used {
+			used = append(used, name)
+		}
+		sort.Strings(used)
+		client.WriteString(fmt.Sprintf("import type { %s } from \"./types\";\n\n", strings.Join(used, ", ")))
+	}
+	client.WriteString(tsClientRuntime)
+	client.WriteString(functions.String())
+
+	var types strings.Builder
+	types.WriteString(fmt.Sprintf("// Types of the generated %s API. They are generated together with the\n", g.title))
+	types.WriteString("// server; regenerate both after changing the scanned structs.\n")
+	types.WriteString(g.interfaces())
+
+	return map[string]string{
+		"types.ts":  types.String(),
+		"client.ts": strings.TrimSuffix(client.String(), "\n"),
+	}
+}
+
+// writeTypeScript adds the TypeScript client in outputDir/typescript to out
+func writeTypeScript(out *OutputSet, outputDir string, routes []APIRoute, packages map[string]*PackageInfo, config *FrameworkConfig) {
+	for name, content := range GenerateTypeScript(routes, packages, config) {
+		out.Add(filepath.Join(outputDir, "typescript", name), content)
+	}
+}
+
+// tsGenerator collects the functions and interfaces of a TypeScript client
+type tsGenerator struct {
+	types  *openAPIBuilder // resolves and names the scanned structs
+	prefix string          // path the server mounts routes under
+	names  map[string]bool // function names in use
+	used   map[string]bool // interfaces client.ts refers to
+	title  string
+}
+
+// function renders the client function of one route
+func (g *tsGenerator) function(route APIRoute) string {
+	name := tsSafeName(goName(route.Function, false))
+	for base, n := name, 2; g.names[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	g.names[name] = true
+
+	path := templatePath(route.Path)
+	bound := make(map[string]BoundParam)
+	var query []BoundParam
+	var bodyType string
+	if route.Binding != nil {
+		for _, param := range route.Binding.Params {
+			switch param.Source {
+			case SourcePath:
+				bound[param.Key] = param
+			case SourceQuery:
+				query = append(query, param)
+			case SourceBody:
+				bodyType = g.clientType(param.Type, route.ImportPath)
+			}
+		}
+	}
+	if bodyType == "" && route.RequestType != "" {
+		bodyType = g.clientType(route.RequestType, route.ImportPath)
+	}
+
+	params := []string{"config: ClientConfig"}
+	pathExpr := strconv.Quote(g.prefix + path)
+	if wildcards := pathWildcards(path); len(wildcards) > 0 {
+		template := g.prefix + path
+		for _, wildcard := range wildcards {
+			paramType := "string"
+			if binding, ok := bound[wildcard]; ok {
+				paramType = g.clientType(binding.Type, route.ImportPath)
+			}
+			paramName := tsSafeName(goName(wildcard, false))
+			params = append(params, paramName+": "+paramType)
+			template = strings.Replace(template, "{"+wildcard+"}", "${encodeURIComponent(String("+paramName+"))}", 1)
+		}
+		pathExpr = "`" + template + "`"
+	}
+	if bodyType != "" {
+		params = append(params, "body: "+bodyType)
+	}
+	if len(query) > 0 {
+		var fields []string
+		for _, param := range query {
+			fields = append(fields, fmt.Sprintf("%s?: %s", tsPropertyName(param.Key), g.clientType(param.Type, route.ImportPath)))
+		}
+		params = append(params, "query: { "+strings.Join(fields, "; ")+" } = {}")
+	}
+
+	success := route.SuccessResponse()
+	resultType := "void"
+	if success.Type != "" && success.Status != http.StatusNoContent {
+		resultType = g.clientType(success.Type, route.ImportPath)
+	}
+
+	args := []string{"config", strconv.Quote(strings.ToUpper(route.Method)), pathExpr}
+	switch {
+	case len(query) > 0 && bodyType != "":
+		args = append(args, "query", "body")
+	case len(query) > 0:
+		args = append(args, "query")
+	case bodyType != "":
+		args = append(args, "undefined", "body")
+	}
+
+	doc := fmt.Sprintf("%s calls %s %s", name, strings.ToUpper(route.Method), g.prefix+path)
+	if summary := route.Docs.Summary; summary != "" {
+		doc += ": " + summary
+	}
+	for _, response := range route.Responses {
+		if response.Status < 400 {
+			continue
+		}
+		doc += fmt.Sprintf("\n@throws {APIError} %d", response.Status)
+		if response.Type != "" {
+			doc += fmt.Sprintf(" (%s)", g.tsType(response.Type, route.ImportPath))
+		}
+	}
+
+	var f strings.Builder
+	f.WriteString(tsDocComment(doc, ""))
+	f.WriteString(fmt.Sprintf("export function %s(%s): Promise<%s> {\n", name, strings.Join(params, ", "), resultType))
+	f.WriteString(fmt.Sprintf("  return request<%s>(%s);\n}\n\n", resultType, strings.Join(args, ", ")))
+	return f.String()
+}
+
+// clientType returns the TypeScript type of a scanned type used by client.ts
+// and records the interfaces it imports
+func (g *tsGenerator) clientType(typ, importPath string) string {
+	tsType := g.tsType(typ, importPath)
+	declared := make(map[string]bool)
+	for _, name := range g.types.names {
+		declared[name] = true
+	}
+	for _, ident := range tsIdentifierToken.FindAllString(tsType, -1) {
+		if declared[ident] {
+			g.used[ident] = true
+		}
+	}
+	return tsType
+}
+
+// tsType maps a scanned Go type to TypeScript the way encoding/json encodes
+// it: numbers become number, time.Time an RFC 3339 string, []byte a base64
+// string, maps Record<string, V> and slices arrays. Structs become their
+// interface; types that cannot be described become unknown.
+func (g *tsGenerator) tsType(typ, importPath string) string {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return g.tsType(typ[1:], importPath)
+	case typ == "[]byte" || typ == "[]uint8":
+		return "string"
+	case strings.HasPrefix(typ, "["):
+		end := strings.Index(typ, "]")
+		if end < 0 {
+			return "unknown"
+		}
+		elem := g.tsType(typ[end+1:], importPath)
+		if strings.ContainsAny(elem, " |") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case strings.HasPrefix(typ, "map["):
+		end := matchingBracket(typ, len("map"))
+		if end < 0 {
+			return "unknown"
+		}
+		return "Record<string, " + g.tsType(typ[end+1:], importPath) + ">"
+	}
+	if schema := builtinSchema(typ); schema != nil {
+		switch schema.Type {
+		case "string":
+			return "string"
+		case "boolean":
+			return "boolean"
+		case "integer", "number":
+			return "number"
+		}
+		return "unknown"
+	}
+	if key := g.types.resolve(typ, importPath); key != "" {
+		return g.types.component(key)
+	}
+	return "unknown"
+}
+
+// interfaces renders an interface for every scanned struct the functions
+// refer to, including the structs their fields refer to
+func (g *tsGenerator) interfaces() string {
+	rendered := make(map[string]bool)
+	var out strings.Builder
+	for {
+		var keys []string
+		for key := range g.types.names {
+			if !rendered[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return out.String()
+		}
+		sort.Slice(keys, func(i, j int) bool { return g.types.names[keys[i]] < g.types.names[keys[j]] })
+		for _, key := range keys {
+			rendered[key] = true
+			out.WriteString(g.tsInterface(key))
+		}
+	}
+}
+
+// tsInterface renders the interface of one scanned struct. Embedded structs
+// whose fields encoding/json promotes are extended; a pointer to one is
+// extended as Partial since a nil pointer omits all of its fields.
+func (g *tsGenerator) tsInterface(key string) string {
+	ref := g.types.structs[key]
+	var extends []string
+	var fields strings.Builder
+	for _, field := range ref.info.Fields {
+		typ := field.Type
+		if field.QualifiedType != "" {
+			typ = field.QualifiedType
+		}
+		if promotedField(field) {
+			if embedded := g.types.resolve(strings.TrimPrefix(typ, "*"), ref.pkg.ImportPath); embedded != "" {
+				name := g.types.component(embedded)
+				if strings.HasPrefix(typ, "*") {
+					name = "Partial<" + name + ">"
+				}
+				extends = append(extends, name)
+				continue
+			}
+		}
+		jsonName, omitEmpty, skip := fieldJSON(field)
+		if skip {
+			continue
+		}
+		fieldType := g.tsType(typ, ref.pkg.ImportPath)
+		optional := ""
+		switch {
+		case omitEmpty:
+			optional = "?"
+		case strings.HasPrefix(typ, "*"):
+			// A nil pointer is encoded as null
+			optional = "?"
+			fieldType += " | null"
+		}
+		fields.WriteString(fmt.Sprintf("  %s%s: %s;\n", tsPropertyName(jsonName), optional, fieldType))
+	}
+
+	var out strings.Builder
+	out.WriteString("\n")
+	if doc := docText(ref.info.Doc); doc != "" {
+		out.WriteString(tsDocComment(doc, ""))
+	}
+	out.WriteString("export interface " + g.types.names[key])
+	if len(extends) > 0 {
+		out.WriteString(" extends " + strings.Join(extends, ", "))
+	}
+	out.WriteString(" {\n")
+	out.WriteString(fields.String())
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// tsSafeName returns name, suffixed when it is a reserved word
+func tsSafeName(name string) string {
+	if tsReservedWords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+// tsPropertyName quotes a property name that is not an identifier
+func tsPropertyName(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// tsDocComment renders text as a JSDoc comment with the given indent
+func tsDocComment(text, indent string) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "*/", "* /")), "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + strings.TrimSpace(lines[0]) + " */\n"
+	}
+	var comment strings.Builder
+	comment.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		comment.WriteString(strings.TrimRight(indent+" * "+strings.TrimSpace(line), " ") + "\n")
+	}
+	comment.WriteString(indent + " */\n")
+	return comment.String()
+}
+
+// tsClientRuntime declares the client configuration, its error type and
+// the request helper every function calls
+const tsClientRuntime = `/** ClientConfig configures the client functions */
+export interface ClientConfig {
+  /** Server URL, such as "http://localhost:8080" */
+  baseUrl: string;
+  /** fetch implementation, the global fetch by default */
+  fetch?: typeof fetch;
+  /** Headers sent with every request, such as an API key */
+  headers?: Record<string, string>;
+  /** Bearer token sent in the Authorization header, or a function returning it */
+  token?: string | (() => string | Promise<string>);
+}
+
+/** APIError is thrown for responses with a non-2xx status */
+export class APIError extends Error {
+  readonly status: number;
+  /** The decoded JSON body, or the text of a body that is not JSON */
+  readonly body: unknown;
+
+  constructor(status: number, body: unknown) {
+    super(typeof body === "string" && body !== "" ? status + ": " + body : "request failed with status " + status);
+    this.name = "APIError";
+    this.status = status;
+    this.body = body;
+  }
+}
+
+type Query = Record<string, string | number | boolean | null | undefined>;
+
+async function request<T>(config: ClientConfig, method: string, path: string, query?: Query, body?: unknown): Promise<T> {
+  let url = config.baseUrl.replace(/\/+$/, "") + path;
+  const search = new URLSearchParams();
+  for (const [key, value] of Object.entries(query ?? {})) {
+    if (value !== undefined && value !== null) {
+      search.set(key, String(value));
+    }
+  }
+  if (search.toString() !== "") {
+    url += "?" + search.toString();
+  }
+
+  const headers: Record<string, string> = { Accept: "application/json", ...config.headers };
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+  if (config.token !== undefined) {
+    const token = typeof config.token === "function" ? await config.token() : config.token;
+    headers["Authorization"] = "Bearer " + token;
+  }
+
+  const response = await (config.fetch ?? fetch)(url, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const text = await response.text();
+  let data: unknown = undefined;
+  if (text !== "") {
+    try {
+      data = JSON.parse(text);
+    } catch {
+      data = text;
+    }
+  }
+  if (!response.ok) {
+    throw new APIError(response.status, data);
+  }
+  return data as T;
+}
+
+`
@@ -52,10 +52,10 @@ type ValidationEngine struct {
 
 // ValidationConfig contains configuration for the validation engine
 type ValidationConfig struct {
-	StopOnFirstError bool     `json:"stop_on_first_error"`
-	StrictMode       bool     `json:"strict_mode"`
-	DefaultRules     []string `json:"default_rules"`
-	CustomRulesPath  string   `json:"custom_rules_path"`
+	StopOnFirstError bool     `json:"stop_on_first_error" yaml:"stop_on_first_error"`
+	StrictMode       bool     `json:"strict_mode" yaml:"strict_mode"`
+	DefaultRules     []string `json:"default_rules" yaml:"default_rules"`
+	CustomRulesPath  string   `json:"custom_rules_path" yaml:"custom_rules_path"`
 }
 
 // NewValidationEngine creates a new validation engine instance
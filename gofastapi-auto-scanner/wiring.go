@@ -0,0 +1,916 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WiringConfig controls the opt-in wiring mode, in which generated handlers
+// call the scanned service methods instead of returning placeholder bodies
+type WiringConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Constructors registers the function that builds each service struct,
+	// keyed by struct name or by "import/path.Struct". Structs without an
+	// entry use an @api.constructor annotation, then a New<Struct> function,
+	// and finally their zero value.
+	Constructors map[string]string `json:"constructors" yaml:"constructors"`
+}
+
+// RouteBinding describes how an HTTP request maps onto a Go call
+type RouteBinding struct {
+	ImportPath   string       `json:"import_path"`
+	Receiver     string       `json:"receiver,omitempty"`
+	Function     string       `json:"function"`
+	Params       []BoundParam `json:"params"`
+	Result       string       `json:"result,omitempty"`
+	ReturnsError bool         `json:"returns_error"`
+}
+
+// BoundParam is a method parameter together with the part of the request it
+// is decoded from: "path", "query", "body" or "context"
+type BoundParam struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Key    string `json:"key,omitempty"`
+}
+
+// Parameter sources
+const (
+	SourcePath    = "path"
+	SourceQuery   = "query"
+	SourceBody    = "body"
+	SourceContext = "context"
+)
+
+// scalarKinds lists the builtin types that can be decoded from a path
+// segment or query string, with the strconv bit size used to parse them
+var scalarKinds = map[string]int{
+	"string": 0, "bool": 0,
+	"int": 0, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": 0, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+	"float32": 32, "float64": 64,
+}
+
+// bindRoute works out how to call method for a request to path. It fails
+// when the method cannot be called from a generated server, for example
+// because it is unexported, lives in package main or takes a parameter that
+// cannot be decoded from a request.
+func bindRoute(pkg *PackageInfo, method MethodInfo, path string) (*RouteBinding, error) {
+	if pkg.Module == "" {
+		return nil, fmt.Errorf("package %s is not part of a Go module", pkg.ImportPath)
+	}
+	if pkg.Name == "main" {
+		return nil, fmt.Errorf("package main cannot be imported")
+	}
+	if strings.HasSuffix(pkg.Name, "_test") {
+		return nil, fmt.Errorf("test package %s cannot be imported", pkg.Name)
+	}
+
+	receiver := strings.TrimPrefix(method.Receiver, "*")
+	if receiver != "" && !ast.IsExported(receiver) {
+		return nil, fmt.Errorf("receiver %s is unexported", receiver)
+	}
+	if !ast.IsExported(method.Name) {
+		return nil, fmt.Errorf("%s is unexported", method.Name)
+	}
+
+	binding := &RouteBinding{
+		ImportPath: pkg.ImportPath,
+		Receiver:   receiver,
+		Function:   method.Name,
+	}
+
+	placeholders := pathPlaceholders(path)
+	claimed := make(map[string]bool)
+	hasBody := false
+
+	// First pass: assign parameters whose name matches a path placeholder
+	sources := make([]string, len(method.Parameters))
+	keys := make([]string, len(method.Parameters))
+	for i, param := range method.Parameters {
+		if _, scalar := scalarKinds[param.QualifiedType]; !scalar {
+			continue
+		}
+		for _, placeholder := range placeholders {
+			if !claimed[placeholder] && strings.EqualFold(placeholder, param.Name) {
+				sources[i], keys[i] = SourcePath, placeholder
+				claimed[placeholder] = true
+				break
+			}
+		}
+	}
+
+	// Second pass: classify the rest, handing unclaimed placeholders to the
+	// remaining scalar parameters in order
+	for i, param := range method.Parameters {
+		if sources[i] != "" {
+			continue
+		}
+
+		switch {
+		case param.QualifiedType == "":
+			return nil, fmt.Errorf("type of parameter %s could not be resolved", paramDisplayName(param, i))
+		case param.QualifiedType == "context.Context":
+			sources[i] = SourceContext
+		case isScalarType(param.QualifiedType):
+			sources[i], keys[i] = SourceQuery, param.Name
+			for _, placeholder := range placeholders {
+				if !claimed[placeholder] {
+					sources[i], keys[i] = SourcePath, placeholder
+					claimed[placeholder] = true
+					break
+				}
+			}
+			if sources[i] == SourceQuery && (param.Name == "" || param.Name == "_") {
+				return nil, fmt.Errorf("unnamed parameter %d cannot be bound to a query parameter", i)
+			}
+		case isBodyType(param.QualifiedType):
+			if hasBody {
+				return nil, fmt.Errorf("parameter %s is a second request body", paramDisplayName(param, i))
+			}
+			hasBody = true
+			sources[i] = SourceBody
+		default:
+			return nil, fmt.Errorf("parameter %s of type %s cannot be decoded from a request", paramDisplayName(param, i), param.Type)
+		}
+	}
+
+	for i, param := range method.Parameters {
+		binding.Params = append(binding.Params, BoundParam{
+			Name:   paramDisplayName(param, i),
+			Type:   param.QualifiedType,
+			Source: sources[i],
+			Key:    keys[i],
+		})
+	}
+
+	// Returns: nothing, error, T or (T, error)
+	returns := method.Returns
+	if len(returns) > 0 && returns[len(returns)-1].QualifiedType == "error" {
+		binding.ReturnsError = true
+		returns = returns[:len(returns)-1]
+	}
+	switch len(returns) {
+	case 0:
+	case 1:
+		if returns[0].QualifiedType == "" {
+			return nil, fmt.Errorf("result type of %s could not be resolved", method.Name)
+		}
+		binding.Result = returns[0].QualifiedType
+	default:
+		return nil, fmt.Errorf("%s returns %d values; only T, error or (T, error) can be mapped to a response", method.Name, len(method.Returns))
+	}
+
+	return binding, nil
+}
+
+// routeBinding is bindRoute for callers that treat unbindable methods as
+// routes without a binding
+func routeBinding(pkg *PackageInfo, method MethodInfo, path string) *RouteBinding {
+	binding, err := bindRoute(pkg, method, path)
+	if err != nil {
+		return nil
+	}
+	return binding
+}
+
+// findStructMethod looks up a method of structInfo by name
+func findStructMethod(structInfo StructInfo, name string) (MethodInfo, bool) {
+	for _, method := range structInfo.Methods {
+		if method.Name == name {
+			return method, true
+		}
+	}
+	return MethodInfo{}, false
+}
+
+// pathPlaceholders returns the {name} segments of a route path in order
+func pathPlaceholders(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
+
+func paramDisplayName(param Parameter, index int) string {
+	if param.Name == "" || param.Name == "_" {
+		return fmt.Sprintf("arg%d", index)
+	}
+	return param.Name
+}
+
+func isScalarType(qualified string) bool {
+	_, ok := scalarKinds[qualified]
+	return ok
+}
+
+// isBodyType reports whether a type is decoded from a JSON request body
+func isBodyType(qualified string) bool {
+	t := strings.TrimPrefix(qualified, "*")
+	switch {
+	case strings.HasPrefix(t, "[]"), strings.HasPrefix(t, "map["):
+		return true
+	case strings.HasPrefix(t, "func"), strings.HasPrefix(t, "chan"), strings.HasPrefix(t, "<-chan"),
+		strings.HasPrefix(t, "interface"), t == "any", t == "error", t == "unsafe.Pointer":
+		return false
+	}
+	// Named types from any package, e.g. "example.com/app/models.User"
+	return strings.Contains(t, ".") || strings.HasPrefix(t, "struct{")
+}
+
+// typeTokenBoundary reports whether r separates the package-qualified
+// identifiers inside a type string
+func typeTokenBoundary(r byte) bool {
+	return strings.IndexByte("[]*(){},; \t", r) >= 0
+}
+
+// qualifiedTypeImports returns the import paths referenced by a type string
+// printed with fully qualified package paths
+func qualifiedTypeImports(qualified string) []string {
+	var paths []string
+	forEachQualifiedName(qualified, func(path, name string) string {
+		paths = append(paths, path)
+		return ""
+	})
+	return paths
+}
+
+// renderQualifiedType respells a fully qualified type string using the
+// given import aliases, e.g. "[]example.com/app/models.User" becomes
+// "[]models.User"
+func renderQualifiedType(qualified string, aliases map[string]string) string {
+	return forEachQualifiedName(qualified, func(path, name string) string {
+		if alias, ok := aliases[path]; ok {
+			return alias + "." + name
+		}
+		return path + "." + name
+	})
+}
+
+// forEachQualifiedName calls fn for every "path.Name" token of a qualified
+// type string and returns the string with each token replaced by fn's result
+func forEachQualifiedName(qualified string, fn func(path, name string) string) string {
+	var out strings.Builder
+	for i := 0; i < len(qualified); {
+		if typeTokenBoundary(qualified[i]) {
+			out.WriteByte(qualified[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(qualified) && !typeTokenBoundary(qualified[j]) {
+			j++
+		}
+		token := qualified[i:j]
+		if dot := strings.LastIndex(token, "."); dot > 0 && !strings.HasPrefix(token, "...") {
+			token = fn(token[:dot], token[dot+1:])
+		}
+		out.WriteString(token)
+		i = j
+	}
+	return out.String()
+}
+
+// HandlerDialect describes how a framework's handlers read request data and
+// write responses, so that wired handlers can be generated once for every
+// framework
+type HandlerDialect interface {
+	// Signature opens a handler method, including the opening brace
+	Signature(handlerName string) string
+	// RequestContext is an expression yielding the request's context.Context
+	RequestContext() string
+	// PathParam and QueryParam are string expressions for a request value
+	PathParam(name string) string
+	QueryParam(name string) string
+	// DecodeBody is an error expression that decodes the JSON body into target
+	DecodeBody(target string) string
+	// Respond and RespondEmpty are statements that write the response and
+	// leave the handler
+	Respond(status, value string) string
+	RespondEmpty(status string) string
+	// Imports lists the packages the handlers file needs for the given uses
+	Imports(uses DialectUses) []string
+	// Helpers returns supporting declarations appended to the handlers file
+	Helpers() string
+}
+
+// DialectUses records which dialect features a handlers file relies on
+type DialectUses struct {
+	Path  bool
+	Query bool
+	Body  bool
+}
+
+// wiringPlan assigns import aliases and service field names for a set of
+// bound routes
+type wiringPlan struct {
+	aliases  map[string]string
+	services []wiredService
+	fields   map[string]string // keyed by importPath + "." + receiver
+}
+
+// wiredService is one service struct instance held by the generated server
+type wiredService struct {
+	ImportPath string
+	Struct     string
+	Field      string
+}
+
+// reservedWiringNames are identifiers used by generated wiring code that
+// import aliases must not shadow
+var reservedWiringNames = map[string]bool{
+	"services": true, "result": true, "err": true, "raw": true, "value": true,
+	"s": true, "c": true, "w": true, "r": true, "main": true,
+	"fmt": true, "log": true, "errors": true, "http": true, "strconv": true, "json": true,
+	"context": true, "time": true, "gin": true, "echo": true, "chi": true, "fiber": true,
+}
+
+// newWiringPlan derives a deterministic plan from the routes' bindings
+func newWiringPlan(routes []APIRoute) *wiringPlan {
+	plan := &wiringPlan{
+		aliases: make(map[string]string),
+		fields:  make(map[string]string),
+	}
+
+	pathSet := make(map[string]bool)
+	serviceSet := make(map[string]wiredService)
+	for _, route := range routes {
+		if route.Binding == nil {
+			continue
+		}
+		pathSet[route.Binding.ImportPath] = true
+		for _, param := range route.Binding.Params {
+			for _, path := range qualifiedTypeImports(param.Type) {
+				pathSet[path] = true
+			}
+		}
+		if route.Binding.Receiver != "" {
+			key := route.Binding.ImportPath + "." + route.Binding.Receiver
+			serviceSet[key] = wiredService{ImportPath: route.Binding.ImportPath, Struct: route.Binding.Receiver}
+		}
+	}
+
+	paths := make([]string, 0, len(pathSet))
+	for path := range pathSet {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	used := make(map[string]bool)
+	for _, path := range paths {
+		base := path[strings.LastIndex(path, "/")+1:]
+		if isStdlibPath(path) {
+			plan.aliases[path] = base
+			used[base] = true
+			continue
+		}
+		base = strings.Map(func(r rune) rune {
+			if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(base))
+		if base == "" || base[0] >= '0' && base[0] <= '9' {
+			base = "pkg" + base
+		}
+		alias := base
+		for n := 2; reservedWiringNames[alias] || used[alias]; n++ {
+			alias = fmt.Sprintf("%s%d", base, n)
+		}
+		used[alias] = true
+		plan.aliases[path] = alias
+	}
+
+	keys := make([]string, 0, len(serviceSet))
+	structCount := make(map[string]int)
+	for key, service := range serviceSet {
+		keys = append(keys, key)
+		structCount[service.Struct]++
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		service := serviceSet[key]
+		service.Field = service.Struct
+		if structCount[service.Struct] > 1 {
+			service.Field = strings.Title(plan.aliases[service.ImportPath]) + service.Struct
+		}
+		plan.fields[key] = service.Field
+		plan.services = append(plan.services, service)
+	}
+
+	return plan
+}
+
+// generateWiredHandlers renders a handlers file whose handlers decode the
+// request, call the bound service method and encode its result. Routes
+// without a service method are served by their generated repository when
+// they have one. Service methods are only called when wiring is enabled.
+func generateWiredHandlers(routes []APIRoute, dialect HandlerDialect, config *FrameworkConfig) (string, error) {
+	wired := config.Wiring != nil && config.Wiring.Enabled
+	if !wired {
+		unbound := make([]APIRoute, len(routes))
+		for i, route := range routes {
+			route.Binding = nil
+			unbound[i] = route
+		}
+		routes = unbound
+	}
+	plan := newWiringPlan(routes)
+
+	var body strings.Builder
+	var uses DialectUses
+	imports := map[string]bool{"net/http": true}
+
+	names := handlerNames(routes)
+	for i, route := range routes {
+		handlerName := names[i]
+		body.WriteString(fmt.Sprintf("// %s handles %s %s\n", handlerName, strings.ToUpper(route.Method), route.Path))
+		body.WriteString(dialect.Signature(handlerName) + "\n")
+
+		// The body is a user region, kept when it was edited
+		var handler strings.Builder
+		if route.Binding == nil && route.Repository != nil {
+			writeRepositoryHandler(&handler, dialect, route, &uses, imports)
+			body.WriteString(userRegion(handlerName, handler.String(), 1) + "}\n\n")
+			continue
+		}
+		if route.Binding == nil {
+			handler.WriteString(fmt.Sprintf("	// %s is not bound to a service method\n", route.Function))
+			handler.WriteString(indent(finalStatement(dialect.Respond("http.StatusNotImplemented",
+				fmt.Sprintf(`map[string]string{"error": %q}`, route.Function+" is not implemented"))), 1))
+			body.WriteString(userRegion(handlerName, handler.String(), 1) + "}\n\n")
+			continue
+		}
+
+		binding := route.Binding
+		var args []string
+		for _, param := range binding.Params {
+			variable := param.Name + "Param"
+			switch param.Source {
+			case SourceContext:
+				args = append(args, dialect.RequestContext())
+				continue
+			case SourcePath:
+				uses.Path = true
+				writeScalarDecode(&handler, dialect, variable, param, dialect.PathParam(param.Key), imports)
+			case SourceQuery:
+				uses.Query = true
+				writeScalarDecode(&handler, dialect, variable, param, dialect.QueryParam(param.Key), imports)
+			case SourceBody:
+				uses.Body = true
+				for _, path := range qualifiedTypeImports(param.Type) {
+					imports[path] = true
+				}
+				bodyType := renderQualifiedType(strings.TrimPrefix(param.Type, "*"), plan.aliases)
+				handler.WriteString(fmt.Sprintf("	var %s %s\n", variable, bodyType))
+				handler.WriteString(fmt.Sprintf("	if err := %s; err != nil {\n", dialect.DecodeBody("&"+variable)))
+				handler.WriteString(indent(dialect.Respond("http.StatusBadRequest",
+					`map[string]string{"error": "invalid request body: " + err.Error()}`), 2))
+				handler.WriteString("	}\n\n")
+				if strings.HasPrefix(param.Type, "*") {
+					variable = "&" + variable
+				}
+			}
+			args = append(args, variable)
+		}
+
+		callee := plan.aliases[binding.ImportPath] + "." + binding.Function
+		if binding.Receiver != "" {
+			callee = "services." + plan.fields[binding.ImportPath+"."+binding.Receiver] + "." + binding.Function
+		} else {
+			imports[binding.ImportPath] = true
+		}
+		call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+
+		// A declared 204 response drops the result
+		status := route.SuccessResponse().Status
+		result := "result"
+		if status == http.StatusNoContent {
+			result = "_"
+		}
+
+		switch {
+		case binding.Result != "" && binding.ReturnsError:
+			handler.WriteString(fmt.Sprintf("	%s, err := %s\n", result, call))
+		case binding.Result != "" && result == "_":
+			handler.WriteString(fmt.Sprintf("	_ = %s\n", call))
+		case binding.Result != "":
+			handler.WriteString(fmt.Sprintf("	result := %s\n", call))
+		case binding.ReturnsError:
+			handler.WriteString(fmt.Sprintf("	err := %s\n", call))
+		default:
+			handler.WriteString(fmt.Sprintf("	%s\n", call))
+		}
+		if binding.ReturnsError {
+			handler.WriteString("	if err != nil {\n")
+			handler.WriteString(indent(dialect.Respond("statusForError(err)", `map[string]string{"error": err.Error()}`), 2))
+			handler.WriteString("	}\n")
+		}
+		handler.WriteString("\n")
+
+		switch {
+		case binding.Result != "" && result != "_":
+			handler.WriteString(indent(finalStatement(dialect.Respond(statusExpr("http", status), "result")), 1))
+		case binding.Result == "" && status == http.StatusOK:
+			// Nothing to encode, so a default 200 becomes 204
+			handler.WriteString(indent(finalStatement(dialect.RespondEmpty("http.StatusNoContent")), 1))
+		default:
+			handler.WriteString(indent(finalStatement(dialect.RespondEmpty(statusExpr("http", status))), 1))
+		}
+		body.WriteString(userRegion(handlerName, handler.String(), 1) + "}\n\n")
+	}
+
+	for _, path := range dialect.Imports(uses) {
+		imports[path] = true
+	}
+
+	var handlers strings.Builder
+	handlers.WriteString("package main\n\n")
+	handlers.WriteString(renderImportBlock(imports, plan.aliases))
+	handlers.WriteString(userImports())
+	handlers.WriteString(body.String())
+	if helpers := dialect.Helpers(); helpers != "" {
+		handlers.WriteString(helpers + "\n")
+	}
+	handlers.WriteString(userDeclarations())
+
+	return handlers.String(), nil
+}
+
+// writeScalarDecode emits code that reads a path or query value into a
+// variable of the parameter's builtin type, rejecting malformed input
+func writeScalarDecode(b *strings.Builder, dialect HandlerDialect, variable string, param BoundParam, source string, imports map[string]bool) {
+	if param.Type == "string" {
+		b.WriteString(fmt.Sprintf("	%s := %s\n\n", variable, source))
+		return
+	}
+
+	imports["strconv"] = true
+	bits := scalarKinds[param.Type]
+	var parse string
+	switch {
+	case param.Type == "bool":
+		parse = "strconv.ParseBool(raw)"
+	case strings.HasPrefix(param.Type, "int"):
+		parse = fmt.Sprintf("strconv.ParseInt(raw, 10, %d)", bits)
+	case strings.HasPrefix(param.Type, "uint"):
+		parse = fmt.Sprintf("strconv.ParseUint(raw, 10, %d)", bits)
+	default:
+		parse = fmt.Sprintf("strconv.ParseFloat(raw, %d)", bits)
+	}
+
+	b.WriteString(fmt.Sprintf("	var %s %s\n", variable, param.Type))
+	b.WriteString(fmt.Sprintf("	if raw := %s; raw != \"\" {\n", source))
+	b.WriteString(fmt.Sprintf("		value, err := %s\n", parse))
+	b.WriteString("		if err != nil {\n")
+	b.WriteString(indent(dialect.Respond("http.StatusBadRequest",
+		fmt.Sprintf(`map[string]string{"error": "invalid %s: " + err.Error()}`, param.Key)), 3))
+	b.WriteString("		}\n")
+	if param.Type == "bool" || param.Type == "float64" || param.Type == "int64" || param.Type == "uint64" {
+		b.WriteString(fmt.Sprintf("		%s = value\n", variable))
+	} else {
+		b.WriteString(fmt.Sprintf("		%s = %s(value)\n", variable, param.Type))
+	}
+	b.WriteString("	}\n\n")
+}
+
+// GenerateServices renders the file that constructs the service structs
+// called by wired handlers. It also returns the local directory of every
+// module the generated server imports, keyed by module path, so that the
+// generated go.mod can replace them.
+func GenerateServices(routes []APIRoute, packages map[string]*PackageInfo, config *WiringConfig) (string, map[string]string, error) {
+	plan := newWiringPlan(routes)
+	replaces := make(map[string]string)
+	imports := map[string]bool{"errors": true, "log": true, "net/http": true}
+
+	for path := range plan.aliases {
+		if pkg := packages[path]; pkg != nil && pkg.Module != "" {
+			replaces[pkg.Module] = pkg.ModuleDir
+		}
+	}
+
+	var constructors strings.Builder
+	for _, service := range plan.services {
+		pkg := packages[service.ImportPath]
+		if pkg == nil {
+			return "", nil, fmt.Errorf("package %s of service %s was not scanned", service.ImportPath, service.Struct)
+		}
+		alias := plan.aliases[service.ImportPath]
+		imports[service.ImportPath] = true
+
+		ctor, err := findConstructor(pkg, service.Struct, config)
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable := strings.ToLower(service.Field[:1]) + service.Field[1:]
+		switch {
+		case ctor == nil:
+			constructors.WriteString(fmt.Sprintf("	// %s has no registered constructor; its zero value is used\n", service.Struct))
+			constructors.WriteString(fmt.Sprintf("	services.%s = &%s.%s{}\n\n", service.Field, alias, service.Struct))
+			continue
+		case len(ctor.Returns) == 2:
+			imports["fmt"] = true
+			constructors.WriteString(fmt.Sprintf("	%s, err := %s.%s()\n", variable, alias, ctor.Name))
+			constructors.WriteString("	if err != nil {\n")
+			constructors.WriteString(fmt.Sprintf("		return nil, fmt.Errorf(\"failed to construct %s: %%v\", err)\n", service.Struct))
+			constructors.WriteString("	}\n")
+		default:
+			constructors.WriteString(fmt.Sprintf("	%s := %s.%s()\n", variable, alias, ctor.Name))
+		}
+		if strings.HasPrefix(ctor.Returns[0].Type, "*") {
+			constructors.WriteString(fmt.Sprintf("	services.%s = %s\n\n", service.Field, variable))
+		} else {
+			constructors.WriteString(fmt.Sprintf("	services.%s = &%s\n\n", service.Field, variable))
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString("package main\n\n")
+	content.WriteString(renderImportBlock(imports, plan.aliases))
+
+	content.WriteString("// Services holds the scanned service instances that wired handlers call into\n")
+	content.WriteString("type Services struct {\n")
+	for _, service := range plan.services {
+		content.WriteString(fmt.Sprintf("	%s *%s.%s\n", service.Field, plan.aliases[service.ImportPath], service.Struct))
+	}
+	content.WriteString("}\n\n")
+
+	content.WriteString("// NewServices constructs every service through its registered constructor\n")
+	content.WriteString("func NewServices() (*Services, error) {\n")
+	content.WriteString("	services := &Services{}\n\n")
+	content.WriteString(constructors.String())
+	content.WriteString("	return services, nil\n")
+	content.WriteString("}\n\n")
+
+	content.WriteString(`// services is built once at startup; tests may replace it with fakes
+var services = mustNewServices()
+
+func mustNewServices() *Services {
+	services, err := NewServices()
+	if err != nil {
+		log.Fatalf("Failed to construct services: %v", err)
+	}
+	return services
+}
+
+// statusForError maps a service error to an HTTP status code. Errors choose
+// their own status by implementing StatusCode() int.
+func statusForError(err error) int {
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) {
+		return coder.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+`)
+
+	return content.String(), replaces, nil
+}
+
+// findConstructor resolves the registered constructor of a service struct.
+// It returns nil when the struct has none and its zero value should be used.
+func findConstructor(pkg *PackageInfo, structName string, config *WiringConfig) (*MethodInfo, error) {
+	name, explicit := "", false
+	if config != nil {
+		if n, ok := config.Constructors[pkg.ImportPath+"."+structName]; ok {
+			name, explicit = n, true
+		} else if n, ok := config.Constructors[structName]; ok {
+			name, explicit = n, true
+		}
+	}
+	if !explicit {
+		for _, structInfo := range pkg.Structs {
+			if structInfo.Name != structName {
+				continue
+			}
+			for _, annotation := range structInfo.Annotations {
+				if annotation.Key == "constructor" && annotation.Value != "" {
+					name, explicit = annotation.Value, true
+				}
+			}
+		}
+	}
+	if !explicit {
+		name = "New" + structName
+	}
+
+	for i := range pkg.Functions {
+		fn := &pkg.Functions[i]
+		if fn.Receiver != "" || fn.Name != name {
+			continue
+		}
+		if err := checkConstructor(fn, structName); err != nil {
+			if explicit {
+				return nil, err
+			}
+			return nil, nil
+		}
+		return fn, nil
+	}
+
+	if explicit {
+		return nil, fmt.Errorf("constructor %s for %s not found in %s", name, structName, pkg.ImportPath)
+	}
+	return nil, nil
+}
+
+// checkConstructor verifies that fn can build structName without arguments
+func checkConstructor(fn *MethodInfo, structName string) error {
+	if !ast.IsExported(fn.Name) {
+		return fmt.Errorf("constructor %s is unexported", fn.Name)
+	}
+	if len(fn.Parameters) > 0 {
+		return fmt.Errorf("constructor %s must not take parameters", fn.Name)
+	}
+	if len(fn.Returns) == 0 || len(fn.Returns) > 2 ||
+		len(fn.Returns) == 2 && fn.Returns[1].Type != "error" {
+		return fmt.Errorf("constructor %s must return %s or (%s, error)", fn.Name, structName, structName)
+	}
+	if strings.TrimPrefix(fn.Returns[0].Type, "*") != structName {
+		return fmt.Errorf("constructor %s returns %s, not %s", fn.Name, fn.Returns[0].Type, structName)
+	}
+	return nil
+}
+
+// renderImportBlock renders a sorted import block, standard library first.
+// Packages whose alias differs from their last path element are imported
+// under that alias.
+func renderImportBlock(imports map[string]bool, aliases map[string]string) string {
+	var std, other []string
+	for path := range imports {
+		if isStdlibPath(path) {
+			std = append(std, path)
+		} else {
+			other = append(other, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, path := range std {
+		b.WriteString(fmt.Sprintf("	%q\n", path))
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, path := range other {
+		alias := aliases[path]
+		if alias != "" && alias != path[strings.LastIndex(path, "/")+1:] {
+			b.WriteString(fmt.Sprintf("	%s %q\n", alias, path))
+		} else {
+			b.WriteString(fmt.Sprintf("	%q\n", path))
+		}
+	}
+	b.WriteString(")\n\n")
+	return b.String()
+}
+
+// isStdlibPath reports whether an import path names a standard library package
+func isStdlibPath(path string) bool {
+	if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(path)))
+	return err == nil && info.IsDir()
+}
+
+// finalStatement drops the bare return that ends a response written as the
+// last statement of a handler without results
+func finalStatement(code string) string {
+	return strings.TrimSuffix(code, "\nreturn")
+}
+
+// indent prefixes every line of code with depth tabs
+func indent(code string, depth int) string {
+	prefix := strings.Repeat("	", depth)
+	lines := strings.Split(strings.TrimRight(code, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Framework dialects
+
+type ginDialect struct{}
+
+func (ginDialect) Signature(name string) string {
+	return fmt.Sprintf("func (s *Server) %s(c *gin.Context) {", name)
+}
+func (ginDialect) RequestContext() string        { return "c.Request.Context()" }
+func (ginDialect) PathParam(name string) string  { return fmt.Sprintf("c.Param(%q)", name) }
+func (ginDialect) QueryParam(name string) string { return fmt.Sprintf("c.Query(%q)", name) }
+func (ginDialect) DecodeBody(target string) string {
+	return fmt.Sprintf("c.ShouldBindJSON(%s)", target)
+}
+func (ginDialect) Respond(status, value string) string {
+	return fmt.Sprintf("c.JSON(%s, %s)\nreturn", status, value)
+}
+func (ginDialect) RespondEmpty(status string) string {
+	return fmt.Sprintf("c.Status(%s)\nreturn", status)
+}
+func (ginDialect) Imports(uses DialectUses) []string {
+	return []string{"github.com/gin-gonic/gin"}
+}
+func (ginDialect) Helpers() string { return "" }
+
+type echoDialect struct{}
+
+func (echoDialect) Signature(name string) string {
+	return fmt.Sprintf("func (s *Server) %s(c echo.Context) error {", name)
+}
+func (echoDialect) RequestContext() string        { return "c.Request().Context()" }
+func (echoDialect) PathParam(name string) string  { return fmt.Sprintf("c.Param(%q)", name) }
+func (echoDialect) QueryParam(name string) string { return fmt.Sprintf("c.QueryParam(%q)", name) }
+func (echoDialect) DecodeBody(target string) string {
+	return fmt.Sprintf("json.NewDecoder(c.Request().Body).Decode(%s)", target)
+}
+func (echoDialect) Respond(status, value string) string {
+	return fmt.Sprintf("return c.JSON(%s, %s)", status, value)
+}
+func (echoDialect) RespondEmpty(status string) string {
+	return fmt.Sprintf("return c.NoContent(%s)", status)
+}
+func (echoDialect) Imports(uses DialectUses) []string {
+	imports := []string{"github.com/labstack/echo/v4"}
+	if uses.Body {
+		imports = append(imports, "encoding/json")
+	}
+	return imports
+}
+func (echoDialect) Helpers() string { return "" }
+
+type chiDialect struct{}
+
+func (chiDialect) Signature(name string) string {
+	return fmt.Sprintf("func (s *Server) %s(w http.ResponseWriter, r *http.Request) {", name)
+}
+func (chiDialect) RequestContext() string        { return "r.Context()" }
+func (chiDialect) PathParam(name string) string  { return fmt.Sprintf("chi.URLParam(r, %q)", name) }
+func (chiDialect) QueryParam(name string) string { return fmt.Sprintf("r.URL.Query().Get(%q)", name) }
+func (chiDialect) DecodeBody(target string) string {
+	return fmt.Sprintf("json.NewDecoder(r.Body).Decode(%s)", target)
+}
+func (chiDialect) Respond(status, value string) string {
+	return fmt.Sprintf("writeJSON(w, %s, %s)\nreturn", status, value)
+}
+func (chiDialect) RespondEmpty(status string) string {
+	return fmt.Sprintf("w.WriteHeader(%s)\nreturn", status)
+}
+func (chiDialect) Imports(uses DialectUses) []string {
+	imports := []string{"encoding/json"}
+	if uses.Path {
+		imports = append(imports, "github.com/go-chi/chi/v5")
+	}
+	return imports
+}
+func (chiDialect) Helpers() string {
+	return `// writeJSON encodes value as the JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+`
+}
+
+type fiberDialect struct{}
+
+func (fiberDialect) Signature(name string) string {
+	return fmt.Sprintf("func (s *Server) %s(c *fiber.Ctx) error {", name)
+}
+func (fiberDialect) RequestContext() string          { return "c.UserContext()" }
+func (fiberDialect) PathParam(name string) string    { return fmt.Sprintf("c.Params(%q)", name) }
+func (fiberDialect) QueryParam(name string) string   { return fmt.Sprintf("c.Query(%q)", name) }
+func (fiberDialect) DecodeBody(target string) string { return fmt.Sprintf("c.BodyParser(%s)", target) }
+func (fiberDialect) Respond(status, value string) string {
+	return fmt.Sprintf("return c.Status(%s).JSON(%s)", status, value)
+}
+func (fiberDialect) RespondEmpty(status string) string {
+	return fmt.Sprintf("return c.SendStatus(%s)", status)
+}
+func (fiberDialect) Imports(uses DialectUses) []string {
+	return []string{"github.com/gofiber/fiber/v2"}
+}
+func (fiberDialect) Helpers() string { return "" }